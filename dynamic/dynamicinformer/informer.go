@@ -45,6 +45,7 @@ func NewFilteredDynamicSharedInformerFactory(client dynamic.Interface, defaultRe
 		namespace:        namespace,
 		informers:        map[schema.GroupVersionResource]informers.GenericInformer{},
 		startedInformers: make(map[schema.GroupVersionResource]bool),
+		informerStops:    make(map[schema.GroupVersionResource]chan struct{}),
 		tweakListOptions: tweakListOptions,
 	}
 }
@@ -59,6 +60,13 @@ type dynamicSharedInformerFactory struct {
 	// startedInformers is used for tracking which informers have been started.
 	// This allows Start() to be called multiple times safely.
 	startedInformers map[schema.GroupVersionResource]bool
+	// informerStops holds the channels stopping each started informer on
+	// its own.
+	informerStops map[schema.GroupVersionResource]chan struct{}
+	// wg tracks the running informers, for Shutdown to wait for.
+	wg sync.WaitGroup
+	// shuttingDown is set by Shutdown, which makes Start a no-op.
+	shuttingDown     bool
 	tweakListOptions TweakListOptionsFunc
 }
 
@@ -85,14 +93,67 @@ func (f *dynamicSharedInformerFactory) Start(stopCh <-chan struct{}) {
 	f.lock.Lock()
 	defer f.lock.Unlock()
 
+	if f.shuttingDown {
+		return
+	}
 	for informerType, informer := range f.informers {
 		if !f.startedInformers[informerType] {
-			go informer.Informer().Run(stopCh)
+			informerStopCh := make(chan struct{})
+			f.wg.Add(1)
+			go func(informer cache.SharedIndexInformer) {
+				defer f.wg.Done()
+				runInformer(informer, stopCh, informerStopCh)
+			}(informer.Informer())
 			f.startedInformers[informerType] = true
+			f.informerStops[informerType] = informerStopCh
 		}
 	}
 }
 
+// ShutdownInformer stops the informer of gvr, if it was started, and forgets
+// it, so that a later ForResource creates a new one.
+func (f *dynamicSharedInformerFactory) ShutdownInformer(gvr schema.GroupVersionResource) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if informerStopCh, started := f.informerStops[gvr]; started {
+		close(informerStopCh)
+	}
+	delete(f.informers, gvr)
+	delete(f.startedInformers, gvr)
+	delete(f.informerStops, gvr)
+}
+
+// Shutdown stops the started informers and waits for them and their
+// handlers to return.  Informers cannot be started anymore afterwards.
+func (f *dynamicSharedInformerFactory) Shutdown() {
+	func() {
+		f.lock.Lock()
+		defer f.lock.Unlock()
+
+		f.shuttingDown = true
+		for informerType, informerStopCh := range f.informerStops {
+			close(informerStopCh)
+			delete(f.informerStops, informerType)
+		}
+	}()
+
+	f.wg.Wait()
+}
+
+// runInformer runs informer until either stopCh or informerStopCh is closed.
+func runInformer(informer cache.SharedIndexInformer, stopCh, informerStopCh <-chan struct{}) {
+	mergedStopCh := make(chan struct{})
+	go func() {
+		defer close(mergedStopCh)
+		select {
+		case <-stopCh:
+		case <-informerStopCh:
+		}
+	}()
+	informer.Run(mergedStopCh)
+}
+
 // WaitForCacheSync waits for all started informers' cache were synced.
 func (f *dynamicSharedInformerFactory) WaitForCacheSync(stopCh <-chan struct{}) map[schema.GroupVersionResource]bool {
 	informers := func() map[schema.GroupVersionResource]cache.SharedIndexInformer {
@@ -22,11 +22,14 @@ import (
 	"time"
 
 	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/diff"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/dynamic/fake"
 	"k8s.io/client-go/tools/cache"
@@ -145,6 +148,103 @@ func TestDynamicSharedInformerFactory(t *testing.T) {
 	}
 }
 
+func TestDynamicSharedInformerFactoryShutdownInformer(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), wait.ForeverTestTimeout)
+	defer cancel()
+	deployments := schema.GroupVersionResource{Group: "extensions", Version: "v1beta1", Resource: "deployments"}
+	widgets := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+	fakeClient := fake.NewSimpleDynamicClient(runtime.NewScheme())
+	target := dynamicinformer.NewDynamicSharedInformerFactory(fakeClient, 0)
+
+	deploymentInformer := target.ForResource(deployments).Informer()
+	widgetInformer := target.ForResource(widgets).Informer()
+	target.Start(ctx.Done())
+	if synced := target.WaitForCacheSync(ctx.Done()); !synced[deployments] || !synced[widgets] {
+		t.Fatalf("informers haven't synced: %v", synced)
+	}
+
+	target.ShutdownInformer(widgets)
+	select {
+	case <-widgetInformer.(cache.ContextRunner).Done():
+	case <-ctx.Done():
+		t.Fatalf("the informer was not shut down")
+	}
+	select {
+	case <-deploymentInformer.(cache.ContextRunner).Done():
+		t.Errorf("expected the other informers to keep running")
+	default:
+	}
+	if synced := target.WaitForCacheSync(ctx.Done()); len(synced) != 1 {
+		t.Errorf("expected the informer to be forgotten, got %v", synced)
+	}
+	if target.ForResource(widgets).Informer() == widgetInformer {
+		t.Errorf("expected a new informer after shutting one down")
+	}
+}
+
+func TestDynamicSharedInformerFactoryShutdown(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "extensions", Version: "v1beta1", Resource: "deployments"}
+	fakeClient := fake.NewSimpleDynamicClient(runtime.NewScheme(), newUnstructured("extensions/v1beta1", "Deployment", "ns-foo", "name-foo"))
+	target := dynamicinformer.NewDynamicSharedInformerFactory(fakeClient, 0)
+	informer := target.ForResource(gvr).Informer()
+	handling, handled := make(chan struct{}), make(chan struct{})
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			close(handling)
+			time.Sleep(100 * time.Millisecond)
+			close(handled)
+		},
+	})
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	target.Start(stopCh)
+	target.WaitForCacheSync(stopCh)
+	<-handling
+
+	target.Shutdown()
+	select {
+	case <-handled:
+	default:
+		t.Errorf("expected Shutdown to wait for the handlers")
+	}
+	select {
+	case <-informer.(cache.ContextRunner).Done():
+	default:
+		t.Errorf("expected the informer to be stopped")
+	}
+}
+
+func TestDynamicSharedInformerFactoryLister(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+	fakeClient := fake.NewSimpleDynamicClient(runtime.NewScheme(),
+		newUnstructured("example.com/v1", "Widget", "ns-foo", "name-foo"),
+		newUnstructured("example.com/v1", "Widget", "ns-bar", "name-bar"),
+	)
+	target := dynamicinformer.NewDynamicSharedInformerFactory(fakeClient, 0)
+	lister := target.ForResource(gvr).Lister()
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	target.Start(stopCh)
+	if synced := target.WaitForCacheSync(stopCh); !synced[gvr] {
+		t.Fatalf("informer for %s hasn't synced", gvr)
+	}
+
+	objs, err := lister.List(labels.Everything())
+	if err != nil || len(objs) != 2 {
+		t.Errorf("expected both objects, got %v, %v", objs, err)
+	}
+	obj, err := lister.ByNamespace("ns-foo").Get("name-foo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if name := obj.(*unstructured.Unstructured).GetName(); name != "name-foo" {
+		t.Errorf("expected name-foo, got %s", name)
+	}
+	if _, err := lister.ByNamespace("ns-bar").Get("name-foo"); !errors.IsNotFound(err) {
+		t.Errorf("expected a NotFound error, got %v", err)
+	}
+}
+
 func newUnstructured(apiVersion, kind, namespace, name string) *unstructured.Unstructured {
 	return &unstructured.Unstructured{
 		Object: map[string]interface{}{
@@ -27,6 +27,12 @@ type DynamicSharedInformerFactory interface {
 	Start(stopCh <-chan struct{})
 	ForResource(gvr schema.GroupVersionResource) informers.GenericInformer
 	WaitForCacheSync(stopCh <-chan struct{}) map[schema.GroupVersionResource]bool
+	// ShutdownInformer stops the informer of gvr and forgets it, such as
+	// when its resource is removed.  Its handlers are not called anymore.
+	ShutdownInformer(gvr schema.GroupVersionResource)
+	// Shutdown stops the started informers and waits for them and their
+	// handlers to return.
+	Shutdown()
 }
 
 // TweakListOptionsFunc defines the signature of a helper function
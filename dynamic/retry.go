@@ -0,0 +1,71 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dynamic
+
+import (
+	"encoding/json"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+)
+
+// UnstructuredFuncs returns the retry.ObjectFuncs of a dynamic client, for
+// use by retry.CreateOrUpdate and retry.GetOrCreate.
+func UnstructuredFuncs(client ResourceInterface) retry.ObjectFuncs {
+	return retry.ObjectFuncs{
+		Get: func(name string) (runtime.Object, error) {
+			return client.Get(name, metav1.GetOptions{})
+		},
+		Create: func(obj runtime.Object) (runtime.Object, error) {
+			return client.Create(obj.(*unstructured.Unstructured), metav1.CreateOptions{})
+		},
+		Update: func(obj runtime.Object) (runtime.Object, error) {
+			return client.Update(obj.(*unstructured.Unstructured), metav1.UpdateOptions{})
+		},
+	}
+}
+
+// ApplyOrUpdate makes obj the state of the fields managed by fieldManager,
+// using server-side apply.  If the server does not support server-side apply,
+// it falls back to retry.CreateOrUpdate, replacing the whole object with obj;
+// fields set by others are lost in that case.
+func ApplyOrUpdate(backoff wait.Backoff, client ResourceInterface, obj *unstructured.Unstructured, fieldManager string, force bool) (*unstructured.Unstructured, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := client.Patch(obj.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{FieldManager: fieldManager, Force: &force})
+	if !errors.IsUnsupportedMediaType(err) {
+		return applied, err
+	}
+	updated, _, err := retry.CreateOrUpdate(backoff, UnstructuredFuncs(client), obj.GetName(), func(existing runtime.Object) (runtime.Object, error) {
+		desired := obj.DeepCopy()
+		if existing != nil {
+			desired.SetResourceVersion(existing.(*unstructured.Unstructured).GetResourceVersion())
+		}
+		return desired, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated.(*unstructured.Unstructured), nil
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dynamic_test
+
+import (
+	"net/http"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/util/retry"
+)
+
+func TestApplyOrUpdateFallback(t *testing.T) {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	applied := 0
+	client.PrependReactor("patch", "configmaps", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		applied++
+		return true, nil, errors.NewGenericServerResponse(http.StatusUnsupportedMediaType, "patch", gvr.GroupResource(), "cm", "", 0, false)
+	})
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"namespace": "ns", "name": "cm"},
+		"data":       map[string]interface{}{"key": "a"},
+	}}
+	resource := client.Resource(gvr).Namespace("ns")
+	for _, value := range []string{"a", "b"} {
+		unstructured.SetNestedField(obj.Object, value, "data", "key")
+		result, err := dynamic.ApplyOrUpdate(retry.DefaultRetry, resource, obj, "test", false)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got, _, _ := unstructured.NestedString(result.Object, "data", "key"); got != value {
+			t.Errorf("expected %q, got %q", value, got)
+		}
+	}
+	if applied != 2 {
+		t.Errorf("expected server-side apply to be attempted first, got %d attempts", applied)
+	}
+}
@@ -123,8 +123,7 @@ func (c *Controller) Run(threadiness int, stopCh chan struct{}) {
 	go c.informer.Run(stopCh)
 
 	// Wait for all involved caches to be synced, before processing items from the queue is started
-	if !cache.WaitForCacheSync(stopCh, c.informer.HasSynced) {
-		runtime.HandleError(fmt.Errorf("Timed out waiting for caches to sync"))
+	if !cache.WaitForNamedCacheSync("Pod", stopCh, c.informer.HasSynced) {
 		return
 	}
 
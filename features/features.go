@@ -0,0 +1,189 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package features lets users of client-go turn its optional behaviors on
+// and off.  A feature is enabled by default or not depending on its maturity,
+// can be overridden with an environment variable named after it, such as
+// KUBE_FEATURE_WatchListClient=true, and programmatically with Set, which
+// takes precedence over the environment.
+package features // import "k8s.io/client-go/features"
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+
+	"k8s.io/klog"
+)
+
+// Feature is the name of an optional behavior of client-go.
+type Feature string
+
+// PreRelease describes the maturity of a feature.
+type PreRelease string
+
+const (
+	// Alpha features are disabled by default and may change or go away.
+	Alpha = PreRelease("ALPHA")
+	// Beta features are usually enabled by default.
+	Beta = PreRelease("BETA")
+	// GA features are stable, and are usually locked to their default.
+	GA = PreRelease("")
+)
+
+// FeatureSpec describes a feature.
+type FeatureSpec struct {
+	// Default is whether the feature is enabled unless overridden.
+	Default bool
+	// LockToDefault makes attempts to override the default fail.
+	LockToDefault bool
+	// PreRelease is the maturity of the feature.
+	PreRelease PreRelease
+}
+
+// envVarPrefix prefixes the name of the environment variable overriding the
+// default of a feature.
+const envVarPrefix = "KUBE_FEATURE_"
+
+// Gates tells whether features are enabled.
+type Gates interface {
+	// Enabled returns whether feature is enabled.  It panics if feature is
+	// unknown.
+	Enabled(feature Feature) bool
+}
+
+// Registry holds a set of known features and whether they are enabled.  It is
+// safe for concurrent use.
+type Registry struct {
+	lock     sync.RWMutex
+	known    map[Feature]FeatureSpec
+	enabled  map[Feature]bool
+	lookupFn func(key string) (string, bool)
+}
+
+var _ Gates = &Registry{}
+
+// NewRegistry returns a Registry of the given features, reading their
+// overrides from the environment.
+func NewRegistry(features map[Feature]FeatureSpec) *Registry {
+	r := &Registry{
+		known:    map[Feature]FeatureSpec{},
+		enabled:  map[Feature]bool{},
+		lookupFn: os.LookupEnv,
+	}
+	if err := r.Add(features); err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Add registers features, reading their overrides from the environment.  It
+// fails if one of them is already known with a different spec.
+func (r *Registry) Add(features map[Feature]FeatureSpec) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for name, spec := range features {
+		if existing, found := r.known[name]; found {
+			if existing == spec {
+				continue
+			}
+			return fmt.Errorf("feature %q was already registered with a different spec", name)
+		}
+		r.known[name] = spec
+		r.enabled[name] = spec.Default
+		if value, found := r.lookupFn(envVarPrefix + string(name)); found {
+			if err := r.setLocked(name, value); err != nil {
+				klog.Warningf("Ignoring environment variable %s%s: %v", envVarPrefix, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Enabled implements Gates.
+func (r *Registry) Enabled(feature Feature) bool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	enabled, found := r.enabled[feature]
+	if !found {
+		panic(fmt.Errorf("feature %q is not registered", feature))
+	}
+	return enabled
+}
+
+// Set enables or disables feature.  It fails if feature is unknown or locked
+// to its default.
+func (r *Registry) Set(feature Feature, enabled bool) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.setLocked(feature, strconv.FormatBool(enabled))
+}
+
+func (r *Registry) setLocked(feature Feature, value string) error {
+	spec, found := r.known[feature]
+	if !found {
+		return fmt.Errorf("unknown feature %q", feature)
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("invalid value %q for feature %q: %v", value, feature, err)
+	}
+	if spec.LockToDefault && enabled != spec.Default {
+		return fmt.Errorf("feature %q is locked to %v", feature, spec.Default)
+	}
+	r.enabled[feature] = enabled
+	return nil
+}
+
+// KnownFeatures returns the known features, with their maturity and default,
+// sorted by name.
+func (r *Registry) KnownFeatures() []string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	var known []string
+	for name, spec := range r.known {
+		pre := ""
+		if spec.PreRelease != GA {
+			pre = fmt.Sprintf("%s - ", spec.PreRelease)
+		}
+		known = append(known, fmt.Sprintf("%s=true|false (%sdefault=%t)", name, pre, spec.Default))
+	}
+	sort.Strings(known)
+	return known
+}
+
+var defaultRegistry = NewRegistry(defaultFeatures)
+
+// DefaultRegistry returns the registry of the features of client-go.
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// Enabled returns whether a feature of client-go is enabled.
+func Enabled(feature Feature) bool {
+	return defaultRegistry.Enabled(feature)
+}
+
+// Set enables or disables a feature of client-go.  Features are usually read
+// when clients and informers are created, so Set should be called first.
+func Set(feature Feature, enabled bool) error {
+	return defaultRegistry.Set(feature, enabled)
+}
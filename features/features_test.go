@@ -0,0 +1,86 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package features
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	env := map[string]string{
+		"KUBE_FEATURE_FromEnv": "true",
+		"KUBE_FEATURE_Invalid": "maybe",
+		"KUBE_FEATURE_Locked":  "false",
+	}
+	r := &Registry{
+		known:   map[Feature]FeatureSpec{},
+		enabled: map[Feature]bool{},
+		lookupFn: func(key string) (string, bool) {
+			value, found := env[key]
+			return value, found
+		},
+	}
+	err := r.Add(map[Feature]FeatureSpec{
+		"Default": {Default: true, PreRelease: Beta},
+		"FromEnv": {PreRelease: Alpha},
+		"Invalid": {PreRelease: Alpha},
+		"Locked":  {Default: true, LockToDefault: true, PreRelease: GA},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for feature, expected := range map[Feature]bool{"Default": true, "FromEnv": true, "Invalid": false, "Locked": true} {
+		if enabled := r.Enabled(feature); enabled != expected {
+			t.Errorf("%s: expected enabled=%v, got %v", feature, expected, enabled)
+		}
+	}
+
+	if err := r.Set("FromEnv", false); err != nil || r.Enabled("FromEnv") {
+		t.Errorf("expected Set to override the environment, got %v", err)
+	}
+	if err := r.Set("Locked", false); err == nil || !r.Enabled("Locked") {
+		t.Errorf("expected a locked feature not to change")
+	}
+	if err := r.Set("Unknown", true); err == nil {
+		t.Errorf("expected an error setting an unknown feature")
+	}
+	if err := r.Add(map[Feature]FeatureSpec{"Default": {Default: true, PreRelease: Beta}}); err != nil {
+		t.Errorf("expected adding the same spec again to succeed, got %v", err)
+	}
+	if err := r.Add(map[Feature]FeatureSpec{"Default": {PreRelease: Alpha}}); err == nil {
+		t.Errorf("expected an error adding a different spec")
+	}
+
+	expected := []string{
+		"Default=true|false (BETA - default=true)",
+		"FromEnv=true|false (ALPHA - default=false)",
+		"Invalid=true|false (ALPHA - default=false)",
+		"Locked=true|false (default=true)",
+	}
+	if known := r.KnownFeatures(); !reflect.DeepEqual(known, expected) {
+		t.Errorf("expected %v, got %v", expected, known)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected querying an unknown feature to panic")
+		}
+	}()
+	r.Enabled("Unknown")
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package features
+
+const (
+	// Every feature gate should add a key here following this template:
+	//
+	// // owner: @username
+	// // alpha: v1.X
+	// MyFeature Feature = "MyFeature"
+
+	// alpha: v1.16
+	//
+	// Makes REST clients without an explicit content type, whose serializer
+	// supports it, use protobuf instead of JSON.  Only suitable for clients
+	// of built-in types.
+	ProtobufContentType Feature = "ProtobufContentType"
+
+	// alpha: v1.16
+	//
+	// Makes reflectors initialize their store from a watch sending the
+	// initial state of the objects instead of a list, see
+	// cache.Reflector.UseWatchList.
+	WatchListClient Feature = "WatchListClient"
+
+	// alpha: v1.16
+	//
+	// Makes reflectors validate every watch event by default, see
+	// cache.Reflector.StrictValidation.
+	StrictReflectorValidation Feature = "StrictReflectorValidation"
+)
+
+// defaultFeatures are the features of client-go.  To add a feature, define a
+// key for it above and add it here.
+var defaultFeatures = map[Feature]FeatureSpec{
+	ProtobufContentType:       {Default: false, PreRelease: Alpha},
+	StrictReflectorValidation: {Default: false, PreRelease: Alpha},
+	WatchListClient:           {Default: false, PreRelease: Alpha},
+}
@@ -0,0 +1,524 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	admissionregistration "k8s.io/client-go/informers/admissionregistration"
+	apps "k8s.io/client-go/informers/apps"
+	auditregistration "k8s.io/client-go/informers/auditregistration"
+	autoscaling "k8s.io/client-go/informers/autoscaling"
+	batch "k8s.io/client-go/informers/batch"
+	certificates "k8s.io/client-go/informers/certificates"
+	coordination "k8s.io/client-go/informers/coordination"
+	core "k8s.io/client-go/informers/core"
+	discovery "k8s.io/client-go/informers/discovery"
+	events "k8s.io/client-go/informers/events"
+	extensions "k8s.io/client-go/informers/extensions"
+	internalinterfaces "k8s.io/client-go/informers/internalinterfaces"
+	networking "k8s.io/client-go/informers/networking"
+	node "k8s.io/client-go/informers/node"
+	policy "k8s.io/client-go/informers/policy"
+	rbac "k8s.io/client-go/informers/rbac"
+	scheduling "k8s.io/client-go/informers/scheduling"
+	settings "k8s.io/client-go/informers/settings"
+	storage "k8s.io/client-go/informers/storage"
+	"k8s.io/client-go/tools/cache"
+)
+
+// LifecycleSharedInformerFactory is a SharedInformerFactory controlling when
+// its informers start and stop.  factory.go is generated by informer-gen,
+// so these capabilities are added by wrapping the generated factory with
+// NewLifecycleSharedInformerFactory.
+type LifecycleSharedInformerFactory interface {
+	SharedInformerFactory
+	// StopReasons returns why each started informer stopped, or nil for
+	// those still running.
+	StopReasons() map[reflect.Type]error
+	// ShutdownInformer stops the informer of the type of obj and forgets
+	// it.  Its handlers are not called anymore.
+	ShutdownInformer(obj runtime.Object)
+	// Shutdown stops the started informers and waits for them and their
+	// handlers to return.
+	Shutdown()
+}
+
+// LifecycleOption configures a LifecycleSharedInformerFactory.
+type LifecycleOption func(*lifecycleFactory)
+
+// lifecycleFactory wraps a sharedInformerFactory, whose lock also guards
+// the fields below.  It requests the informers of its groups through its
+// own InformerFor, so that it sees every informer it hands out.
+type lifecycleFactory struct {
+	*sharedInformerFactory
+
+	// informerStops holds the channels stopping each started informer on
+	// its own.
+	informerStops map[reflect.Type]chan struct{}
+	// wg tracks the running informers, for Shutdown to wait for.
+	wg sync.WaitGroup
+	// shuttingDown is set by Shutdown, which makes Start a no-op.
+	shuttingDown bool
+
+	// lazyStart is set by WithLazyStart.  Informers are then only started
+	// once used, with the stop channel of the first Start.
+	lazyStart  bool
+	lazyStopCh <-chan struct{}
+	// usage tracks how the informers are used in lazy start mode.
+	usage map[reflect.Type]*informerUsage
+}
+
+// NewLifecycleSharedInformerFactory returns a LifecycleSharedInformerFactory
+// wrapping factory, which must have been made by one of the
+// NewSharedInformerFactory functions and not have been used yet.  Informers
+// must then be requested from and started by the returned factory only.
+func NewLifecycleSharedInformerFactory(factory SharedInformerFactory, options ...LifecycleOption) LifecycleSharedInformerFactory {
+	f, ok := factory.(*sharedInformerFactory)
+	if !ok {
+		panic(fmt.Errorf("%T is not a factory made by NewSharedInformerFactory", factory))
+	}
+	lifecycle := &lifecycleFactory{
+		sharedInformerFactory: f,
+		informerStops:         map[reflect.Type]chan struct{}{},
+		usage:                 map[reflect.Type]*informerUsage{},
+	}
+	for _, option := range options {
+		option(lifecycle)
+	}
+	return lifecycle
+}
+
+// Start starts the informers that were not started yet, except in lazy start
+// mode those that were not used yet.
+func (f *lifecycleFactory) Start(stopCh <-chan struct{}) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.shuttingDown {
+		return
+	}
+	if f.lazyStart && f.lazyStopCh == nil {
+		f.lazyStopCh = stopCh
+	}
+	for informerType := range f.informers {
+		if f.lazyStart && f.usage[informerType] == nil {
+			continue
+		}
+		f.startInformerLocked(informerType, stopCh)
+	}
+}
+
+// startInformerLocked starts the informer of informerType unless it was
+// started already.
+func (f *lifecycleFactory) startInformerLocked(informerType reflect.Type, stopCh <-chan struct{}) {
+	if f.startedInformers[informerType] {
+		return
+	}
+	informerStopCh := make(chan struct{})
+	informer := f.informers[informerType]
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		runInformer(informer, stopCh, informerStopCh)
+	}()
+	f.startedInformers[informerType] = true
+	f.informerStops[informerType] = informerStopCh
+}
+
+// runInformer runs informer until either stopCh or informerStopCh is closed.
+func runInformer(informer cache.SharedIndexInformer, stopCh, informerStopCh <-chan struct{}) {
+	mergedStopCh := make(chan struct{})
+	go func() {
+		defer close(mergedStopCh)
+		select {
+		case <-stopCh:
+		case <-informerStopCh:
+		}
+	}()
+	informer.Run(mergedStopCh)
+}
+
+func (f *lifecycleFactory) InformerFor(obj runtime.Object, newFunc internalinterfaces.NewInformerFunc) cache.SharedIndexInformer {
+	informer := f.sharedInformerFactory.InformerFor(obj, newFunc)
+	if !f.lazyStart {
+		return informer
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.trackLocked(reflect.TypeOf(obj), informer)
+}
+
+// ForResource returns the generic informer of resource.  The generated
+// ForResource requests it from the wrapped factory, so it is tracked here.
+func (f *lifecycleFactory) ForResource(resource schema.GroupVersionResource) (GenericInformer, error) {
+	informer, err := f.sharedInformerFactory.ForResource(resource)
+	if err != nil || !f.lazyStart {
+		return informer, err
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for informerType, existing := range f.informers {
+		if existing == informer.Informer() {
+			return &genericInformer{informer: f.trackLocked(informerType, existing), resource: resource.GroupResource()}, nil
+		}
+	}
+	return informer, nil
+}
+
+// StopReasons returns why each started informer stopped, or nil for those
+// still running or not implementing cache.StopReasonReporter.
+func (f *lifecycleFactory) StopReasons() map[reflect.Type]error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	res := map[reflect.Type]error{}
+	for informerType, informer := range f.informers {
+		if f.startedInformers[informerType] {
+			res[informerType] = stopReason(informer)
+		}
+	}
+	return res
+}
+
+// stopReason returns the StopReason of informer, or nil if it does not
+// report one.
+func stopReason(informer cache.SharedIndexInformer) error {
+	if reporter, ok := informer.(cache.StopReasonReporter); ok {
+		return reporter.StopReason()
+	}
+	return nil
+}
+
+// ShutdownInformer stops the informer of the type of obj, if it was started,
+// and forgets it, so that a later request for it creates a new one.
+func (f *lifecycleFactory) ShutdownInformer(obj runtime.Object) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.shutdownInformerLocked(reflect.TypeOf(obj))
+}
+
+func (f *lifecycleFactory) shutdownInformerLocked(informerType reflect.Type) {
+	if informerStopCh, started := f.informerStops[informerType]; started {
+		close(informerStopCh)
+	}
+	delete(f.informers, informerType)
+	delete(f.startedInformers, informerType)
+	delete(f.informerStops, informerType)
+	delete(f.usage, informerType)
+}
+
+// Shutdown stops the started informers and waits for them and their
+// handlers to return.  Informers cannot be started anymore afterwards.
+func (f *lifecycleFactory) Shutdown() {
+	func() {
+		f.lock.Lock()
+		defer f.lock.Unlock()
+
+		f.shuttingDown = true
+		for informerType, informerStopCh := range f.informerStops {
+			close(informerStopCh)
+			delete(f.informerStops, informerType)
+		}
+	}()
+
+	f.wg.Wait()
+}
+
+// informerUsage tracks the use of an informer in lazy start mode.
+type informerUsage struct {
+	handlers int
+	// store is set once the store of the informer, hence a lister, has
+	// been requested.  The informer then runs until the factory stops.
+	store bool
+}
+
+// WithLazyStart makes the factory start an informer only once an event handler
+// is added to it or its store or indexer, which listers use, is requested,
+// instead of whenever Start is called.  Informers used after Start are then
+// started right away.  An informer whose store was never requested is stopped
+// when its last event handler is removed, and forgotten like with
+// ShutdownInformer: handlers must then be added to a new informer obtained
+// from the factory.
+func WithLazyStart() LifecycleOption {
+	return func(factory *lifecycleFactory) {
+		factory.lazyStart = true
+	}
+}
+
+// trackLocked returns the informer of informerType the factory hands out in
+// lazy start mode, which reports its uses.
+func (f *lifecycleFactory) trackLocked(informerType reflect.Type, informer cache.SharedIndexInformer) cache.SharedIndexInformer {
+	if _, tracked := informer.(*lazyInformer); tracked || f.informers[informerType] != informer {
+		return informer
+	}
+	tracked := &lazyInformer{SharedIndexInformer: informer, factory: f, informerType: informerType}
+	f.informers[informerType] = tracked
+	return tracked
+}
+
+// lazyInformer reports the uses of an informer to its factory in lazy start
+// mode.  It forwards the optional capabilities of the cache package's
+// informers, which the informers of the generated constructors have.
+type lazyInformer struct {
+	cache.SharedIndexInformer
+	factory      *lifecycleFactory
+	informerType reflect.Type
+}
+
+func (i *lazyInformer) AddEventHandler(handler cache.ResourceEventHandler) (cache.ResourceEventHandlerRegistration, error) {
+	return i.handlerAdded(i.SharedIndexInformer.AddEventHandler(handler))
+}
+
+func (i *lazyInformer) AddEventHandlerWithResyncPeriod(handler cache.ResourceEventHandler, resyncPeriod time.Duration) (cache.ResourceEventHandlerRegistration, error) {
+	return i.handlerAdded(i.SharedIndexInformer.AddEventHandlerWithResyncPeriod(handler, resyncPeriod))
+}
+
+func (i *lazyInformer) AddEventHandlerWithOptions(handler cache.ResourceEventHandler, options cache.HandlerOptions) (cache.ResourceEventHandlerRegistration, error) {
+	adder, ok := i.SharedIndexInformer.(cache.HandlerOptionsAdder)
+	if !ok {
+		return nil, fmt.Errorf("%T does not support handler options", i.SharedIndexInformer)
+	}
+	return i.handlerAdded(adder.AddEventHandlerWithOptions(handler, options))
+}
+
+func (i *lazyInformer) handlerAdded(handle cache.ResourceEventHandlerRegistration, err error) (cache.ResourceEventHandlerRegistration, error) {
+	if err == nil {
+		i.factory.informerUsed(i, func(usage *informerUsage) { usage.handlers++ })
+	}
+	return handle, err
+}
+
+func (i *lazyInformer) RemoveEventHandler(handle cache.ResourceEventHandlerRegistration) error {
+	if err := i.SharedIndexInformer.RemoveEventHandler(handle); err != nil {
+		return err
+	}
+	i.factory.handlerRemoved(i)
+	return nil
+}
+
+func (i *lazyInformer) StopReason() error {
+	return stopReason(i.SharedIndexInformer)
+}
+
+func (i *lazyInformer) SetWatchErrorHandler(handler cache.WatchErrorHandler) error {
+	setter, ok := i.SharedIndexInformer.(cache.WatchErrorHandlerSetter)
+	if !ok {
+		return fmt.Errorf("%T does not support watch error handlers", i.SharedIndexInformer)
+	}
+	return setter.SetWatchErrorHandler(handler)
+}
+
+func (i *lazyInformer) RunWithContext(ctx context.Context) error {
+	if runner, ok := i.SharedIndexInformer.(cache.ContextRunner); ok {
+		return runner.RunWithContext(ctx)
+	}
+	i.SharedIndexInformer.Run(ctx.Done())
+	return stopReason(i.SharedIndexInformer)
+}
+
+// Done returns nil, which is never closed, for informers that do not tell
+// when they have stopped.
+func (i *lazyInformer) Done() <-chan struct{} {
+	if runner, ok := i.SharedIndexInformer.(cache.ContextRunner); ok {
+		return runner.Done()
+	}
+	return nil
+}
+
+// SetResyncCheckPeriod has no effect on informers whose resync periods cannot
+// be changed.
+func (i *lazyInformer) SetResyncCheckPeriod(resyncCheckPeriod time.Duration) {
+	if setter, ok := i.SharedIndexInformer.(cache.ResyncPeriodSetter); ok {
+		setter.SetResyncCheckPeriod(resyncCheckPeriod)
+	}
+}
+
+func (i *lazyInformer) SetHandlerResyncPeriod(handle cache.ResourceEventHandlerRegistration, resyncPeriod time.Duration) error {
+	setter, ok := i.SharedIndexInformer.(cache.ResyncPeriodSetter)
+	if !ok {
+		return fmt.Errorf("%T does not support changing resync periods", i.SharedIndexInformer)
+	}
+	return setter.SetHandlerResyncPeriod(handle, resyncPeriod)
+}
+
+// Pause has no effect on informers whose notifications cannot be held back.
+func (i *lazyInformer) Pause() {
+	if pauser, ok := i.SharedIndexInformer.(cache.Pauser); ok {
+		pauser.Pause()
+	}
+}
+
+func (i *lazyInformer) Resume() {
+	if pauser, ok := i.SharedIndexInformer.(cache.Pauser); ok {
+		pauser.Resume()
+	}
+}
+
+// Health reports the zero ReflectorHealth for informers that do not report
+// their health.
+func (i *lazyInformer) Health() cache.ReflectorHealth {
+	if checker, ok := i.SharedIndexInformer.(cache.HealthChecker); ok {
+		return checker.Health()
+	}
+	return cache.ReflectorHealth{}
+}
+
+func (i *lazyInformer) SaveSnapshot(path string) error {
+	snapshotter, ok := i.SharedIndexInformer.(cache.Snapshotter)
+	if !ok {
+		return fmt.Errorf("%T does not support snapshots", i.SharedIndexInformer)
+	}
+	return snapshotter.SaveSnapshot(path)
+}
+
+func (i *lazyInformer) LoadSnapshot(path string) error {
+	snapshotter, ok := i.SharedIndexInformer.(cache.Snapshotter)
+	if !ok {
+		return fmt.Errorf("%T does not support snapshots", i.SharedIndexInformer)
+	}
+	return snapshotter.LoadSnapshot(path)
+}
+
+func (i *lazyInformer) GetStore() cache.Store {
+	i.factory.informerUsed(i, func(usage *informerUsage) { usage.store = true })
+	return i.SharedIndexInformer.GetStore()
+}
+
+func (i *lazyInformer) GetIndexer() cache.Indexer {
+	i.factory.informerUsed(i, func(usage *informerUsage) { usage.store = true })
+	return i.SharedIndexInformer.GetIndexer()
+}
+
+// informerUsed records a use of informer, starting it if the factory was
+// started.
+func (f *lifecycleFactory) informerUsed(informer *lazyInformer, use func(*informerUsage)) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.informers[informer.informerType] != informer {
+		// The informer was shut down.
+		return
+	}
+	usage := f.usage[informer.informerType]
+	if usage == nil {
+		usage = &informerUsage{}
+		f.usage[informer.informerType] = usage
+	}
+	use(usage)
+	if f.lazyStopCh != nil && !f.shuttingDown {
+		f.startInformerLocked(informer.informerType, f.lazyStopCh)
+	}
+}
+
+// handlerRemoved records the removal of an event handler of informer,
+// shutting it down if it is not used anymore.
+func (f *lifecycleFactory) handlerRemoved(informer *lazyInformer) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	usage := f.usage[informer.informerType]
+	if f.informers[informer.informerType] != informer || usage == nil {
+		return
+	}
+	usage.handlers--
+	if usage.handlers == 0 && !usage.store {
+		f.shutdownInformerLocked(informer.informerType)
+	}
+}
+
+// The accessors of the groups are those of the generated factory, but with
+// the wrapper as the factory of the informers.
+
+func (f *lifecycleFactory) Admissionregistration() admissionregistration.Interface {
+	return admissionregistration.New(f, f.namespace, f.tweakListOptions)
+}
+
+func (f *lifecycleFactory) Apps() apps.Interface {
+	return apps.New(f, f.namespace, f.tweakListOptions)
+}
+
+func (f *lifecycleFactory) Auditregistration() auditregistration.Interface {
+	return auditregistration.New(f, f.namespace, f.tweakListOptions)
+}
+
+func (f *lifecycleFactory) Autoscaling() autoscaling.Interface {
+	return autoscaling.New(f, f.namespace, f.tweakListOptions)
+}
+
+func (f *lifecycleFactory) Batch() batch.Interface {
+	return batch.New(f, f.namespace, f.tweakListOptions)
+}
+
+func (f *lifecycleFactory) Certificates() certificates.Interface {
+	return certificates.New(f, f.namespace, f.tweakListOptions)
+}
+
+func (f *lifecycleFactory) Coordination() coordination.Interface {
+	return coordination.New(f, f.namespace, f.tweakListOptions)
+}
+
+func (f *lifecycleFactory) Core() core.Interface {
+	return core.New(f, f.namespace, f.tweakListOptions)
+}
+
+func (f *lifecycleFactory) Discovery() discovery.Interface {
+	return discovery.New(f, f.namespace, f.tweakListOptions)
+}
+
+func (f *lifecycleFactory) Events() events.Interface {
+	return events.New(f, f.namespace, f.tweakListOptions)
+}
+
+func (f *lifecycleFactory) Extensions() extensions.Interface {
+	return extensions.New(f, f.namespace, f.tweakListOptions)
+}
+
+func (f *lifecycleFactory) Networking() networking.Interface {
+	return networking.New(f, f.namespace, f.tweakListOptions)
+}
+
+func (f *lifecycleFactory) Node() node.Interface {
+	return node.New(f, f.namespace, f.tweakListOptions)
+}
+
+func (f *lifecycleFactory) Policy() policy.Interface {
+	return policy.New(f, f.namespace, f.tweakListOptions)
+}
+
+func (f *lifecycleFactory) Rbac() rbac.Interface {
+	return rbac.New(f, f.namespace, f.tweakListOptions)
+}
+
+func (f *lifecycleFactory) Scheduling() scheduling.Interface {
+	return scheduling.New(f, f.namespace, f.tweakListOptions)
+}
+
+func (f *lifecycleFactory) Settings() settings.Interface {
+	return settings.New(f, f.namespace, f.tweakListOptions)
+}
+
+func (f *lifecycleFactory) Storage() storage.Interface {
+	return storage.New(f, f.namespace, f.tweakListOptions)
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informers
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestSharedInformerFactoryShutdownInformer(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	factory := NewLifecycleSharedInformerFactory(NewSharedInformerFactory(fake.NewSimpleClientset(), 0))
+	pods := factory.Core().V1().Pods().Informer()
+	services := factory.Core().V1().Services().Informer()
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+
+	factory.ShutdownInformer(&v1.Pod{})
+	select {
+	case <-pods.(cache.ContextRunner).Done():
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatalf("the informer was not shut down")
+	}
+	select {
+	case <-services.(cache.ContextRunner).Done():
+		t.Errorf("expected the other informers to keep running")
+	default:
+	}
+	if factory.Core().V1().Pods().Informer() == pods {
+		t.Errorf("expected a new informer after shutting one down")
+	}
+	if reasons := factory.StopReasons(); len(reasons) != 1 {
+		t.Errorf("expected the informer to be forgotten, got %v", reasons)
+	}
+}
+
+func TestSharedInformerFactoryShutdown(t *testing.T) {
+	client := fake.NewSimpleClientset(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod1"}})
+	factory := NewLifecycleSharedInformerFactory(NewSharedInformerFactory(client, 0))
+	var handled int32
+	handling := make(chan struct{})
+	factory.Core().V1().Pods().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			close(handling)
+			time.Sleep(100 * time.Millisecond)
+			atomic.StoreInt32(&handled, 1)
+		},
+	})
+	services := factory.Core().V1().Services().Informer()
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+	<-handling
+
+	factory.Shutdown()
+	if atomic.LoadInt32(&handled) != 1 {
+		t.Errorf("expected Shutdown to wait for the handlers")
+	}
+	select {
+	case <-services.(cache.ContextRunner).Done():
+	default:
+		t.Errorf("expected every informer to be stopped")
+	}
+
+	// Informers requested afterwards are not started.
+	factory.Core().V1().Nodes().Informer()
+	factory.Start(stopCh)
+	if reasons := factory.StopReasons(); len(reasons) != 2 {
+		t.Errorf("expected no informer to be started after Shutdown, got %v", reasons)
+	}
+}
+
+func TestSharedInformerFactoryLazyStart(t *testing.T) {
+	client := fake.NewSimpleClientset(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod1"}})
+	factory := NewLifecycleSharedInformerFactory(NewSharedInformerFactory(client, 0), WithLazyStart())
+	pods := factory.Core().V1().Pods().Informer()
+	if generic, err := factory.ForResource(v1.SchemeGroupVersion.WithResource("pods")); err != nil || generic.Informer() != pods {
+		t.Errorf("expected the generic informer to be the tracked one, got %v, %v", generic, err)
+	}
+	factory.Core().V1().Nodes().Informer()
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	factory.Start(stopCh)
+	if reasons := factory.StopReasons(); len(reasons) != 0 {
+		t.Errorf("expected no informer to be started before being used, got %v", reasons)
+	}
+
+	added := make(chan struct{})
+	handle, err := pods.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { close(added) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-added:
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatalf("the informer was not started when a handler was added")
+	}
+	services := factory.Core().V1().Services().Lister()
+	if synced := factory.WaitForCacheSync(stopCh); len(synced) != 2 {
+		t.Errorf("expected the informers used to be started, got %v", synced)
+	}
+	if _, err := services.List(labels.Everything()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := pods.RemoveEventHandler(handle); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-pods.(cache.ContextRunner).Done():
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatalf("the informer was not stopped when its last handler was removed")
+	}
+	if factory.Core().V1().Pods().Informer() == pods {
+		t.Errorf("expected a new informer after the last handler was removed")
+	}
+	if reasons := factory.StopReasons(); len(reasons) != 1 {
+		t.Errorf("expected only the informer with a lister to keep running, got %v", reasons)
+	}
+}
+
+func TestSharedInformerFactoryScoping(t *testing.T) {
+	pod := func(namespace, name, app string) *v1.Pod {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: map[string]string{"app": app}}}
+	}
+	client := fake.NewSimpleClientset(pod("ns", "mine", "a"), pod("ns", "other-app", "b"), pod("other-ns", "other-namespace", "a"))
+	factory := NewSharedInformerFactoryWithOptions(client, 0,
+		WithNamespace("ns"),
+		WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = "app=a"
+		}),
+	)
+	lister := factory.Core().V1().Pods().Lister()
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+
+	pods, err := lister.List(labels.Everything())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pods) != 1 || pods[0].Name != "mine" {
+		t.Errorf("expected only the pod in the namespace matching the selector, got %v", pods)
+	}
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informers
+
+import (
+	"context"
+
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/klog"
+)
+
+// LeaderElectedInformers runs informers only while holding leadership.  Every
+// leadership term gets a new SharedInformerFactory that is started when the
+// term begins and shut down when it ends, so a new leader always serves freshly
+// listed caches and a former leader keeps no informers or cached objects.
+type LeaderElectedInformers struct {
+	// NewFactory returns the factory for a new leadership term.  It must
+	// return a new factory made by one of the NewSharedInformerFactory
+	// functions on every call.  Required.
+	NewFactory func() SharedInformerFactory
+
+	// Setup, if set, requests the informers needed from factory before it
+	// is started.
+	Setup func(factory SharedInformerFactory)
+
+	// Run is called once the caches of factory have synced.  ctx is
+	// cancelled when leadership is lost, at which point Run must stop using
+	// factory and return.  Required.
+	Run func(ctx context.Context, factory SharedInformerFactory)
+
+	// OnStoppedLeading, if set, is called when leadership is lost.
+	OnStoppedLeading func()
+}
+
+// Callbacks returns the LeaderCallbacks to use in a LeaderElectionConfig.
+func (l *LeaderElectedInformers) Callbacks() leaderelection.LeaderCallbacks {
+	return leaderelection.LeaderCallbacks{
+		OnStartedLeading: l.lead,
+		OnStoppedLeading: func() {
+			if l.OnStoppedLeading != nil {
+				l.OnStoppedLeading()
+			}
+		},
+	}
+}
+
+// lead runs a single leadership term.  The informers of the term are started
+// with ctx.Done() as their stop channel and shut down before lead returns, so
+// that no informer or handler of the term outlives it.
+func (l *LeaderElectedInformers) lead(ctx context.Context) {
+	factory := NewLifecycleSharedInformerFactory(l.NewFactory())
+	if l.Setup != nil {
+		l.Setup(factory)
+	}
+	factory.Start(ctx.Done())
+	defer factory.Shutdown()
+	for informerType, synced := range factory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			klog.V(2).Infof("Leadership lost before the %v informer synced", informerType)
+			return
+		}
+	}
+	l.Run(ctx, factory)
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informers
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestLeaderElectedInformers(t *testing.T) {
+	client := fake.NewSimpleClientset(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod1"}})
+
+	var factories []SharedInformerFactory
+	var listed []int
+	var informers []cache.SharedIndexInformer
+	stopped := 0
+	l := &LeaderElectedInformers{
+		NewFactory: func() SharedInformerFactory {
+			factory := NewSharedInformerFactory(client, 0)
+			factories = append(factories, factory)
+			return factory
+		},
+		Setup: func(factory SharedInformerFactory) {
+			informers = append(informers, factory.Core().V1().Pods().Informer())
+		},
+		Run: func(ctx context.Context, factory SharedInformerFactory) {
+			pods, err := factory.Core().V1().Pods().Lister().List(labels.Everything())
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			listed = append(listed, len(pods))
+		},
+		OnStoppedLeading: func() { stopped++ },
+	}
+	callbacks := l.Callbacks()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	callbacks.OnStartedLeading(ctx)
+	cancel()
+	callbacks.OnStoppedLeading()
+
+	// A pod created while not leading must be seen on the next term.
+	if _, err := client.CoreV1().Pods("ns").Create(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod2"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	callbacks.OnStartedLeading(ctx)
+	cancel()
+	callbacks.OnStoppedLeading()
+
+	if len(factories) != 2 || factories[0] == factories[1] {
+		t.Errorf("expected a new factory for every term, got %d", len(factories))
+	}
+	if len(listed) != 2 || listed[0] != 1 || listed[1] != 2 {
+		t.Errorf("expected synced caches of 1 and 2 pods, got %v", listed)
+	}
+	for _, informer := range informers {
+		select {
+		case <-informer.(cache.ContextRunner).Done():
+		default:
+			t.Errorf("expected the informers of a term to be shut down when it ends")
+		}
+	}
+	if stopped != 2 {
+		t.Errorf("expected OnStoppedLeading to be called twice, got %d", stopped)
+	}
+
+	// A term that ends before the caches sync never calls Run.
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	callbacks.OnStartedLeading(ctx)
+	if len(listed) != 2 {
+		t.Errorf("expected Run not to be called once leadership is lost")
+	}
+}
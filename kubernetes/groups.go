@@ -0,0 +1,287 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"fmt"
+	"net/http"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	discovery "k8s.io/client-go/discovery"
+	admissionregistrationv1 "k8s.io/client-go/kubernetes/typed/admissionregistration/v1"
+	admissionregistrationv1beta1 "k8s.io/client-go/kubernetes/typed/admissionregistration/v1beta1"
+	appsv1 "k8s.io/client-go/kubernetes/typed/apps/v1"
+	appsv1beta1 "k8s.io/client-go/kubernetes/typed/apps/v1beta1"
+	appsv1beta2 "k8s.io/client-go/kubernetes/typed/apps/v1beta2"
+	auditregistrationv1alpha1 "k8s.io/client-go/kubernetes/typed/auditregistration/v1alpha1"
+	authenticationv1 "k8s.io/client-go/kubernetes/typed/authentication/v1"
+	authenticationv1beta1 "k8s.io/client-go/kubernetes/typed/authentication/v1beta1"
+	authorizationv1 "k8s.io/client-go/kubernetes/typed/authorization/v1"
+	authorizationv1beta1 "k8s.io/client-go/kubernetes/typed/authorization/v1beta1"
+	autoscalingv1 "k8s.io/client-go/kubernetes/typed/autoscaling/v1"
+	autoscalingv2beta1 "k8s.io/client-go/kubernetes/typed/autoscaling/v2beta1"
+	autoscalingv2beta2 "k8s.io/client-go/kubernetes/typed/autoscaling/v2beta2"
+	batchv1 "k8s.io/client-go/kubernetes/typed/batch/v1"
+	batchv1beta1 "k8s.io/client-go/kubernetes/typed/batch/v1beta1"
+	batchv2alpha1 "k8s.io/client-go/kubernetes/typed/batch/v2alpha1"
+	certificatesv1beta1 "k8s.io/client-go/kubernetes/typed/certificates/v1beta1"
+	coordinationv1 "k8s.io/client-go/kubernetes/typed/coordination/v1"
+	coordinationv1beta1 "k8s.io/client-go/kubernetes/typed/coordination/v1beta1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	discoveryv1alpha1 "k8s.io/client-go/kubernetes/typed/discovery/v1alpha1"
+	eventsv1beta1 "k8s.io/client-go/kubernetes/typed/events/v1beta1"
+	extensionsv1beta1 "k8s.io/client-go/kubernetes/typed/extensions/v1beta1"
+	networkingv1 "k8s.io/client-go/kubernetes/typed/networking/v1"
+	networkingv1beta1 "k8s.io/client-go/kubernetes/typed/networking/v1beta1"
+	nodev1alpha1 "k8s.io/client-go/kubernetes/typed/node/v1alpha1"
+	nodev1beta1 "k8s.io/client-go/kubernetes/typed/node/v1beta1"
+	policyv1beta1 "k8s.io/client-go/kubernetes/typed/policy/v1beta1"
+	rbacv1 "k8s.io/client-go/kubernetes/typed/rbac/v1"
+	rbacv1alpha1 "k8s.io/client-go/kubernetes/typed/rbac/v1alpha1"
+	rbacv1beta1 "k8s.io/client-go/kubernetes/typed/rbac/v1beta1"
+	schedulingv1 "k8s.io/client-go/kubernetes/typed/scheduling/v1"
+	schedulingv1alpha1 "k8s.io/client-go/kubernetes/typed/scheduling/v1alpha1"
+	schedulingv1beta1 "k8s.io/client-go/kubernetes/typed/scheduling/v1beta1"
+	settingsv1alpha1 "k8s.io/client-go/kubernetes/typed/settings/v1alpha1"
+	storagev1 "k8s.io/client-go/kubernetes/typed/storage/v1"
+	storagev1alpha1 "k8s.io/client-go/kubernetes/typed/storage/v1alpha1"
+	storagev1beta1 "k8s.io/client-go/kubernetes/typed/storage/v1beta1"
+	rest "k8s.io/client-go/rest"
+	flowcontrol "k8s.io/client-go/util/flowcontrol"
+)
+
+// groupClientBuilders builds the clients of every version of an API group, keyed by group name.
+var groupClientBuilders = map[string]func(cs *Clientset, c *rest.Config) error{
+	"admissionregistration.k8s.io": func(cs *Clientset, c *rest.Config) (err error) {
+		if cs.admissionregistrationV1, err = admissionregistrationv1.NewForConfig(c); err != nil {
+			return err
+		}
+		cs.admissionregistrationV1beta1, err = admissionregistrationv1beta1.NewForConfig(c)
+		return err
+	},
+	"apps": func(cs *Clientset, c *rest.Config) (err error) {
+		if cs.appsV1, err = appsv1.NewForConfig(c); err != nil {
+			return err
+		}
+		if cs.appsV1beta1, err = appsv1beta1.NewForConfig(c); err != nil {
+			return err
+		}
+		cs.appsV1beta2, err = appsv1beta2.NewForConfig(c)
+		return err
+	},
+	"auditregistration.k8s.io": func(cs *Clientset, c *rest.Config) (err error) {
+		cs.auditregistrationV1alpha1, err = auditregistrationv1alpha1.NewForConfig(c)
+		return err
+	},
+	"authentication.k8s.io": func(cs *Clientset, c *rest.Config) (err error) {
+		if cs.authenticationV1, err = authenticationv1.NewForConfig(c); err != nil {
+			return err
+		}
+		cs.authenticationV1beta1, err = authenticationv1beta1.NewForConfig(c)
+		return err
+	},
+	"authorization.k8s.io": func(cs *Clientset, c *rest.Config) (err error) {
+		if cs.authorizationV1, err = authorizationv1.NewForConfig(c); err != nil {
+			return err
+		}
+		cs.authorizationV1beta1, err = authorizationv1beta1.NewForConfig(c)
+		return err
+	},
+	"autoscaling": func(cs *Clientset, c *rest.Config) (err error) {
+		if cs.autoscalingV1, err = autoscalingv1.NewForConfig(c); err != nil {
+			return err
+		}
+		if cs.autoscalingV2beta1, err = autoscalingv2beta1.NewForConfig(c); err != nil {
+			return err
+		}
+		cs.autoscalingV2beta2, err = autoscalingv2beta2.NewForConfig(c)
+		return err
+	},
+	"batch": func(cs *Clientset, c *rest.Config) (err error) {
+		if cs.batchV1, err = batchv1.NewForConfig(c); err != nil {
+			return err
+		}
+		if cs.batchV1beta1, err = batchv1beta1.NewForConfig(c); err != nil {
+			return err
+		}
+		cs.batchV2alpha1, err = batchv2alpha1.NewForConfig(c)
+		return err
+	},
+	"certificates.k8s.io": func(cs *Clientset, c *rest.Config) (err error) {
+		cs.certificatesV1beta1, err = certificatesv1beta1.NewForConfig(c)
+		return err
+	},
+	"coordination.k8s.io": func(cs *Clientset, c *rest.Config) (err error) {
+		if cs.coordinationV1, err = coordinationv1.NewForConfig(c); err != nil {
+			return err
+		}
+		cs.coordinationV1beta1, err = coordinationv1beta1.NewForConfig(c)
+		return err
+	},
+	"": func(cs *Clientset, c *rest.Config) (err error) {
+		cs.coreV1, err = corev1.NewForConfig(c)
+		return err
+	},
+	"discovery.k8s.io": func(cs *Clientset, c *rest.Config) (err error) {
+		cs.discoveryV1alpha1, err = discoveryv1alpha1.NewForConfig(c)
+		return err
+	},
+	"events.k8s.io": func(cs *Clientset, c *rest.Config) (err error) {
+		cs.eventsV1beta1, err = eventsv1beta1.NewForConfig(c)
+		return err
+	},
+	"extensions": func(cs *Clientset, c *rest.Config) (err error) {
+		cs.extensionsV1beta1, err = extensionsv1beta1.NewForConfig(c)
+		return err
+	},
+	"networking.k8s.io": func(cs *Clientset, c *rest.Config) (err error) {
+		if cs.networkingV1, err = networkingv1.NewForConfig(c); err != nil {
+			return err
+		}
+		cs.networkingV1beta1, err = networkingv1beta1.NewForConfig(c)
+		return err
+	},
+	"node.k8s.io": func(cs *Clientset, c *rest.Config) (err error) {
+		if cs.nodeV1alpha1, err = nodev1alpha1.NewForConfig(c); err != nil {
+			return err
+		}
+		cs.nodeV1beta1, err = nodev1beta1.NewForConfig(c)
+		return err
+	},
+	"policy": func(cs *Clientset, c *rest.Config) (err error) {
+		cs.policyV1beta1, err = policyv1beta1.NewForConfig(c)
+		return err
+	},
+	"rbac.authorization.k8s.io": func(cs *Clientset, c *rest.Config) (err error) {
+		if cs.rbacV1, err = rbacv1.NewForConfig(c); err != nil {
+			return err
+		}
+		if cs.rbacV1alpha1, err = rbacv1alpha1.NewForConfig(c); err != nil {
+			return err
+		}
+		cs.rbacV1beta1, err = rbacv1beta1.NewForConfig(c)
+		return err
+	},
+	"scheduling.k8s.io": func(cs *Clientset, c *rest.Config) (err error) {
+		if cs.schedulingV1, err = schedulingv1.NewForConfig(c); err != nil {
+			return err
+		}
+		if cs.schedulingV1alpha1, err = schedulingv1alpha1.NewForConfig(c); err != nil {
+			return err
+		}
+		cs.schedulingV1beta1, err = schedulingv1beta1.NewForConfig(c)
+		return err
+	},
+	"settings.k8s.io": func(cs *Clientset, c *rest.Config) (err error) {
+		cs.settingsV1alpha1, err = settingsv1alpha1.NewForConfig(c)
+		return err
+	},
+	"storage.k8s.io": func(cs *Clientset, c *rest.Config) (err error) {
+		if cs.storageV1, err = storagev1.NewForConfig(c); err != nil {
+			return err
+		}
+		if cs.storageV1alpha1, err = storagev1alpha1.NewForConfig(c); err != nil {
+			return err
+		}
+		cs.storageV1beta1, err = storagev1beta1.NewForConfig(c)
+		return err
+	},
+}
+
+// GroupClientset is a Clientset holding working clients for only some API groups. The clients
+// of the other groups fail every request with an error naming their group.
+type GroupClientset struct {
+	*Clientset
+	groups sets.String
+}
+
+// GroupsInterface is the interface of a GroupClientset. It is an Interface whose clients for API
+// groups that were not requested fail every request.
+type GroupsInterface interface {
+	Interface
+	// HasGroup returns true if the clients of the named API group were built.
+	HasGroup(group string) bool
+}
+
+var _ GroupsInterface = &GroupClientset{}
+
+// HasGroup returns true if the clients of the named API group were built.
+func (c *GroupClientset) HasGroup(group string) bool {
+	return c.groups.Has(group)
+}
+
+// NewForGroups creates a clientset for the given config holding only the clients of the named API
+// groups, for example NewForGroups(config, corev1.GroupName, appsv1.GroupName). The clients of all
+// versions of each group are built; the core group is named "". The clients of the other groups
+// are stubs that return an error from every request, so a tool using a group it did not ask for
+// fails clearly instead of panicking. This package still links every group into the binary; to
+// keep the other groups out of it, use the typed group packages directly instead.
+func NewForGroups(c *rest.Config, groups ...string) (*GroupClientset, error) {
+	configShallowCopy := *c
+	if configShallowCopy.RateLimiter == nil && configShallowCopy.QPS > 0 {
+		if configShallowCopy.Burst <= 0 {
+			return nil, fmt.Errorf("Burst is required to be greater than 0 when RateLimiter is not set and QPS is set to greater than 0")
+		}
+		configShallowCopy.RateLimiter = flowcontrol.NewTokenBucketRateLimiter(configShallowCopy.QPS, configShallowCopy.Burst)
+	}
+	cs := &GroupClientset{Clientset: &Clientset{}, groups: sets.NewString()}
+	for _, group := range groups {
+		build, ok := groupClientBuilders[group]
+		if !ok {
+			return nil, fmt.Errorf("unknown API group %q", group)
+		}
+		if cs.groups.Has(group) {
+			continue
+		}
+		if err := build(cs.Clientset, &configShallowCopy); err != nil {
+			return nil, err
+		}
+		cs.groups.Insert(group)
+	}
+
+	// Give the other groups clients that fail instead of nil clients that panic. They share no
+	// transport and make no connection, so they cost little to build.
+	for group, build := range groupClientBuilders {
+		if cs.groups.Has(group) {
+			continue
+		}
+		stubConfig := &rest.Config{
+			Transport:   unrequestedGroupTransport{group: group},
+			RateLimiter: flowcontrol.NewFakeAlwaysRateLimiter(),
+		}
+		if err := build(cs.Clientset, stubConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	cs.DiscoveryClient, err = discovery.NewDiscoveryClientForConfig(&configShallowCopy)
+	if err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+// unrequestedGroupTransport fails the requests of the clients of an API group that was not
+// requested from NewForGroups.
+type unrequestedGroupTransport struct {
+	group string
+}
+
+func (t unrequestedGroupTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, fmt.Errorf("the clients of API group %q were not requested from NewForGroups", t.group)
+}
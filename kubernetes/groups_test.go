@@ -0,0 +1,51 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	rest "k8s.io/client-go/rest"
+)
+
+func TestNewForGroups(t *testing.T) {
+	config := &rest.Config{Host: "localhost"}
+	cs, err := NewForGroups(config, corev1.GroupName, appsv1.GroupName)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cs.HasGroup(corev1.GroupName) || !cs.HasGroup(appsv1.GroupName) || cs.HasGroup("batch") {
+		t.Errorf("unexpected groups %v", cs.groups.List())
+	}
+	if cs.coreV1 == nil || cs.appsV1 == nil || cs.appsV1beta2 == nil || cs.DiscoveryClient == nil {
+		t.Errorf("expected the clients of the requested groups to be built")
+	}
+	if _, err := cs.BatchV1().Jobs("ns").Get("job", metav1.GetOptions{}); err == nil || !strings.Contains(err.Error(), "not requested") {
+		t.Errorf("expected the clients of other groups to fail, got %v", err)
+	}
+
+	if _, err := NewForGroups(config, "unknown.example.com"); err == nil {
+		t.Errorf("expected an unknown group to be rejected")
+	}
+	if len(groupClientBuilders) != 20 {
+		t.Errorf("expected a builder for every API group of the clientset, got %d", len(groupClientBuilders))
+	}
+}
@@ -70,10 +70,6 @@ type PersistentVolumeClaimListerExpansion interface{}
 // PersistentVolumeClaimNamespaceLister.
 type PersistentVolumeClaimNamespaceListerExpansion interface{}
 
-// PodListerExpansion allows custom methods to be added to
-// PodLister.
-type PodListerExpansion interface{}
-
 // PodNamespaceListerExpansion allows custom methods to be added to
 // PodNamespaceLister.
 type PodNamespaceListerExpansion interface{}
@@ -0,0 +1,55 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// PodNodeNameIndex is the name of the index used by ByNodeName. Informers
+// that register PodNodeNameIndexFunc under this name let ByNodeName avoid
+// scanning every pod.
+const PodNodeNameIndex = "spec.nodeName"
+
+// PodNodeNameIndexFunc indexes pods by the node they are bound to.
+func PodNodeNameIndexFunc(obj interface{}) ([]string, error) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return nil, fmt.Errorf("expected *v1.Pod, got %T", obj)
+	}
+	return []string{pod.Spec.NodeName}, nil
+}
+
+// PodListerExpansion allows custom methods to be added to
+// PodLister.
+type PodListerExpansion interface {
+	// ByNodeName lists all Pods bound to the given node.
+	ByNodeName(nodeName string) ([]*v1.Pod, error)
+}
+
+// ByNodeName lists all Pods bound to the given node, using the
+// PodNodeNameIndex index when the indexer has it.
+func (s *podLister) ByNodeName(nodeName string) (ret []*v1.Pod, err error) {
+	err = cache.ListByIndex(s.indexer, PodNodeNameIndex, nodeName, PodNodeNameIndexFunc, labels.Everything(), func(m interface{}) {
+		ret = append(ret, m.(*v1.Pod))
+	})
+	return ret, err
+}
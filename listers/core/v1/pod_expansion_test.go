@@ -0,0 +1,75 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"sort"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestPodListerByNodeName(t *testing.T) {
+	pods := []*v1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "a"}, Spec: v1.PodSpec{NodeName: "node1"}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "ns2", Name: "b"}, Spec: v1.PodSpec{NodeName: "node1"}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "c"}, Spec: v1.PodSpec{NodeName: "node2"}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "d"}},
+	}
+
+	for name, indexers := range map[string]cache.Indexers{
+		"indexed":   {PodNodeNameIndex: PodNodeNameIndexFunc},
+		"unindexed": {},
+	} {
+		t.Run(name, func(t *testing.T) {
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, indexers)
+			for _, pod := range pods {
+				indexer.Add(pod)
+			}
+			lister := NewPodLister(indexer)
+
+			for node, expected := range map[string][]string{
+				"node1": {"a", "b"},
+				"node2": {"c"},
+				"":      {"d"},
+				"node3": nil,
+			} {
+				got, err := lister.ByNodeName(node)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				var names []string
+				for _, pod := range got {
+					names = append(names, pod.Name)
+				}
+				sort.Strings(names)
+				if len(names) != len(expected) {
+					t.Errorf("%q: expected %v, got %v", node, expected, names)
+					continue
+				}
+				for i := range names {
+					if names[i] != expected[i] {
+						t.Errorf("%q: expected %v, got %v", node, expected, names)
+						break
+					}
+				}
+			}
+		})
+	}
+}
@@ -26,6 +26,7 @@ import (
 
 	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/diff"
@@ -156,6 +157,39 @@ func TestMetadataSharedInformerFactory(t *testing.T) {
 	}
 }
 
+func TestMetadataSharedInformerFactoryLister(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "extensions", Version: "v1beta1", Resource: "deployments"}
+	scheme := runtime.NewScheme()
+	metav1.AddMetaToScheme(scheme)
+	fakeClient := fake.NewSimpleMetadataClient(scheme,
+		newPartialObjectMetadata("extensions/v1beta1", "Deployment", "ns-foo", "name-foo"),
+		newPartialObjectMetadata("extensions/v1beta1", "Deployment", "ns-bar", "name-bar"),
+	)
+	target := NewSharedInformerFactory(fakeClient, 0)
+	informer := target.ForResource(gvr)
+	if target.ForResource(gvr).Informer() != informer.Informer() {
+		t.Errorf("expected the informer of a resource to be shared")
+	}
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	target.Start(stopCh)
+	if synced := target.WaitForCacheSync(stopCh); !synced[gvr] {
+		t.Fatalf("informer for %s hasn't synced", gvr)
+	}
+
+	objs, err := informer.Lister().List(labels.Everything())
+	if err != nil || len(objs) != 2 {
+		t.Errorf("expected both objects, got %v, %v", objs, err)
+	}
+	obj, err := informer.Lister().ByNamespace("ns-foo").Get("name-foo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if name := obj.(*metav1.PartialObjectMetadata).Name; name != "name-foo" {
+		t.Errorf("expected name-foo, got %s", name)
+	}
+}
+
 func newPartialObjectMetadata(apiVersion, kind, namespace, name string) *metav1.PartialObjectMetadata {
 	return &metav1.PartialObjectMetadata{
 		TypeMeta: metav1.TypeMeta{
@@ -0,0 +1,145 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadatainformer
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/tools/cache"
+)
+
+var namespacesResource = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+
+// NamespaceDeletionOptions configures DeleteNamespaceAndWait.
+type NamespaceDeletionOptions struct {
+	// Resources are the namespaced resources to wait for.  They are usually
+	// those returned by discovery.ServerPreferredNamespacedResources that
+	// support the list and watch verbs.
+	Resources []schema.GroupVersionResource
+	// DeleteOptions are passed to the deletion of the namespace.
+	DeleteOptions *metav1.DeleteOptions
+	// Timeout bounds the wait.  Zero means no timeout.
+	Timeout time.Duration
+	// Progress, if set, is called with the number of objects left of every
+	// resource, including the namespace itself, whenever it changes.
+	// Resources without objects left are omitted.
+	Progress func(remaining map[schema.GroupVersionResource]int)
+}
+
+// DeleteNamespaceAndWait deletes namespace and waits for its objects of the
+// given resources, and for the namespace itself, to be gone.  It returns an
+// error listing what remains if the timeout expires or stopCh is closed
+// first.  A namespace that is already gone is not an error.
+func DeleteNamespaceAndWait(client metadata.Interface, namespace string, options NamespaceDeletionOptions, stopCh <-chan struct{}) error {
+	err := client.Resource(namespacesResource).Delete(namespace, options.DeleteOptions)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	abort := make(chan struct{})
+	var reason string
+	go func() {
+		var timeout <-chan time.Time
+		if options.Timeout > 0 {
+			timer := time.NewTimer(options.Timeout)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+		select {
+		case <-timeout:
+			reason = "timed out"
+		case <-stopCh:
+			reason = "stopped"
+		case <-stop:
+			return
+		}
+		close(abort)
+	}()
+
+	changed := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { notify() },
+		UpdateFunc: func(interface{}, interface{}) { notify() },
+		DeleteFunc: func(interface{}) { notify() },
+	}
+	informers := map[schema.GroupVersionResource]cache.SharedIndexInformer{
+		namespacesResource: NewFilteredMetadataInformer(client, namespacesResource, metav1.NamespaceAll, 0, cache.Indexers{}, func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", namespace).String()
+		}).Informer(),
+	}
+	for _, resource := range options.Resources {
+		informers[resource] = NewFilteredMetadataInformer(client, resource, namespace, 0, cache.Indexers{}, nil).Informer()
+	}
+	var hasSynced []cache.InformerSynced
+	for _, informer := range informers {
+		if _, err := informer.AddEventHandler(handler); err != nil {
+			return err
+		}
+		hasSynced = append(hasSynced, informer.HasSynced)
+		go informer.Run(stop)
+	}
+
+	remaining := func() map[schema.GroupVersionResource]int {
+		counts := map[schema.GroupVersionResource]int{}
+		for resource, informer := range informers {
+			if resource == namespacesResource {
+				if _, exists, _ := informer.GetStore().GetByKey(namespace); exists {
+					counts[resource] = 1
+				}
+				continue
+			}
+			if n := len(informer.GetStore().ListKeys()); n > 0 {
+				counts[resource] = n
+			}
+		}
+		return counts
+	}
+	if !cache.WaitForCacheSync(abort, hasSynced...) {
+		return fmt.Errorf("%s waiting for the caches of namespace %q to sync", reason, namespace)
+	}
+	var last map[schema.GroupVersionResource]int
+	for {
+		counts := remaining()
+		if options.Progress != nil && (last == nil || !reflect.DeepEqual(counts, last)) {
+			options.Progress(counts)
+		}
+		last = counts
+		if len(counts) == 0 {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-abort:
+			return fmt.Errorf("%s waiting for namespace %q to be deleted, remaining: %v", reason, namespace, counts)
+		}
+	}
+}
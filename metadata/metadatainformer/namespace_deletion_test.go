@@ -0,0 +1,97 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadatainformer
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/metadata/fake"
+)
+
+func TestDeleteNamespaceAndWait(t *testing.T) {
+	configMaps := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	scheme := runtime.NewScheme()
+	metav1.AddMetaToScheme(scheme)
+	client := fake.NewSimpleMetadataClient(scheme,
+		newPartialObjectMetadata("v1", "Namespace", "", "ns-foo"),
+		newPartialObjectMetadata("v1", "ConfigMap", "ns-foo", "cm1"),
+		newPartialObjectMetadata("v1", "ConfigMap", "ns-foo", "cm2"),
+		newPartialObjectMetadata("v1", "ConfigMap", "ns-bar", "cm3"),
+	)
+
+	var lock sync.Mutex
+	var progress []int
+	done := make(chan error)
+	go func() {
+		done <- DeleteNamespaceAndWait(client, "ns-foo", NamespaceDeletionOptions{
+			Resources: []schema.GroupVersionResource{configMaps},
+			Timeout:   wait.ForeverTestTimeout,
+			Progress: func(remaining map[schema.GroupVersionResource]int) {
+				lock.Lock()
+				defer lock.Unlock()
+				progress = append(progress, remaining[configMaps])
+				if remaining[namespacesResource] != 0 {
+					t.Errorf("expected the namespace to be deleted, got %v", remaining)
+				}
+			},
+		}, nil)
+	}()
+
+	// The fake client does not cascade the deletion of the namespace.
+	for _, name := range []string{"cm1", "cm2"} {
+		err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			return len(progress) > 0, nil
+		})
+		if err != nil {
+			t.Fatalf("no progress was reported")
+		}
+		if err := client.Resource(configMaps).Namespace("ns-foo").Delete(name, nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if progress[0] != 2 || progress[len(progress)-1] != 0 {
+		t.Errorf("expected progress from 2 to 0 objects, got %v", progress)
+	}
+}
+
+func TestDeleteNamespaceAndWaitTimeout(t *testing.T) {
+	configMaps := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	scheme := runtime.NewScheme()
+	metav1.AddMetaToScheme(scheme)
+	client := fake.NewSimpleMetadataClient(scheme, newPartialObjectMetadata("v1", "ConfigMap", "ns-foo", "cm1"))
+
+	err := DeleteNamespaceAndWait(client, "ns-foo", NamespaceDeletionOptions{
+		Resources: []schema.GroupVersionResource{configMaps},
+		Timeout:   200 * time.Millisecond,
+	}, nil)
+	if err == nil {
+		t.Fatalf("expected a timeout while objects remain")
+	}
+}
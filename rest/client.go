@@ -29,6 +29,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/features"
 	"k8s.io/client-go/util/flowcontrol"
 )
 
@@ -78,6 +79,13 @@ type RESTClient struct {
 
 	// Set specific behavior of the client.  If not set http.DefaultClient will be used.
 	Client *http.Client
+
+	// warningHandler handles warnings returned by the server. If nil, the default
+	// warning handler is used.
+	warningHandler WarningHandler
+
+	// maxResponseBodyBytes bounds the decoded size of response bodies. Zero means no limit.
+	maxResponseBodyBytes int64
 }
 
 type Serializers struct {
@@ -103,7 +111,10 @@ func NewRESTClient(baseURL *url.URL, versionedAPIPath string, config ContentConf
 		config.GroupVersion = &schema.GroupVersion{}
 	}
 	if len(config.ContentType) == 0 {
-		config.ContentType = "application/json"
+		config.ContentType = defaultContentType(config)
+		if len(config.AcceptContentTypes) == 0 && config.ContentType == protobufContentType {
+			config.AcceptContentTypes = protobufContentType + ",application/json"
+		}
 	}
 	serializers, err := createSerializers(config)
 	if err != nil {
@@ -153,6 +164,20 @@ func readExpBackoffConfig() BackoffManager {
 			time.Duration(backoffDurationInt)*time.Second)}
 }
 
+const protobufContentType = "application/vnd.kubernetes.protobuf"
+
+// defaultContentType returns the content type of a client that does not set
+// one: JSON, or protobuf if the ProtobufContentType feature is enabled and
+// the serializer of the client supports it.
+func defaultContentType(config ContentConfig) string {
+	if features.Enabled(features.ProtobufContentType) && config.NegotiatedSerializer != nil {
+		if _, ok := runtime.SerializerInfoForMediaType(config.NegotiatedSerializer.SupportedMediaTypes(), protobufContentType); ok {
+			return protobufContentType
+		}
+	}
+	return "application/json"
+}
+
 // createSerializers creates all necessary serializers for given contentType.
 // TODO: the negotiated serializer passed to this method should probably return
 //   serializers that control decoding and versioning without this package
@@ -221,10 +246,15 @@ func createSerializers(config ContentConfig) (*Serializers, error) {
 func (c *RESTClient) Verb(verb string) *Request {
 	backoff := c.createBackoffMgr()
 
+	var request *Request
 	if c.Client == nil {
-		return NewRequest(nil, verb, c.base, c.versionedAPIPath, c.contentConfig, c.serializers, backoff, c.Throttle, 0)
+		request = NewRequest(nil, verb, c.base, c.versionedAPIPath, c.contentConfig, c.serializers, backoff, c.Throttle, 0)
+	} else {
+		request = NewRequest(c.Client, verb, c.base, c.versionedAPIPath, c.contentConfig, c.serializers, backoff, c.Throttle, c.Client.Timeout)
 	}
-	return NewRequest(c.Client, verb, c.base, c.versionedAPIPath, c.contentConfig, c.serializers, backoff, c.Throttle, c.Client.Timeout)
+	request.warningHandler = c.warningHandler
+	request.maxResponseBodyBytes = c.maxResponseBodyBytes
+	return request
 }
 
 // Post begins a POST request. Short for c.Verb("POST").
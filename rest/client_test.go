@@ -32,8 +32,10 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/diff"
+	"k8s.io/client-go/features"
 	"k8s.io/client-go/kubernetes/scheme"
 	utiltesting "k8s.io/client-go/util/testing"
 )
@@ -69,6 +71,26 @@ func TestSerializer(t *testing.T) {
 	}
 }
 
+func TestDefaultContentType(t *testing.T) {
+	jsonOnly := serializer.NegotiatedSerializerWrapper(runtime.SerializerInfo{MediaType: "application/json"})
+	for _, enabled := range []bool{false, true} {
+		if err := features.Set(features.ProtobufContentType, enabled); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := "application/json"
+		if enabled {
+			expected = protobufContentType
+		}
+		if contentType := defaultContentType(ContentConfig{NegotiatedSerializer: scheme.Codecs}); contentType != expected {
+			t.Errorf("enabled=%v: expected %s, got %s", enabled, expected, contentType)
+		}
+		if contentType := defaultContentType(ContentConfig{NegotiatedSerializer: jsonOnly}); contentType != "application/json" {
+			t.Errorf("enabled=%v: expected JSON without a protobuf serializer, got %s", enabled, contentType)
+		}
+	}
+	features.Set(features.ProtobufContentType, false)
+}
+
 func TestDoRequestSuccess(t *testing.T) {
 	testServer, fakeHandler, status := testServerEnv(t, 200)
 	defer testServer.Close()
@@ -128,6 +128,20 @@ type Config struct {
 	// Dial specifies the dial function for creating unencrypted TCP connections.
 	Dial func(ctx context.Context, network, address string) (net.Conn, error)
 
+	// WarningHandler handles warnings returned by the server in Warning response headers.
+	// If not set, the handler set with SetDefaultWarningHandler is used.
+	WarningHandler WarningHandler
+
+	// MaxResponseBodyBytes bounds the decoded size of response bodies the client reads into
+	// memory. Gzip encoded bodies are decompressed as they are read, so the budget applies to
+	// the decompressed data and a request is aborted as soon as it is exceeded. Zero means
+	// no limit. Watches and streams are not limited.
+	MaxResponseBodyBytes int64
+
+	// Hedging, if set, sends the reads that are slow to be answered to
+	// alternative apiservers as well.
+	Hedging *HedgingPolicy
+
 	// Version forces a specific version to be used (if registered)
 	// Do we need this?
 	// Version string
@@ -301,6 +315,9 @@ func RESTClientFor(config *Config) (*RESTClient, error) {
 	if err != nil {
 		return nil, err
 	}
+	if transport, err = hedgingRoundTripperFor(config, transport); err != nil {
+		return nil, err
+	}
 
 	var httpClient *http.Client
 	if transport != http.DefaultTransport {
@@ -310,7 +327,12 @@ func RESTClientFor(config *Config) (*RESTClient, error) {
 		}
 	}
 
-	return NewRESTClient(baseURL, versionedAPIPath, config.ContentConfig, qps, burst, config.RateLimiter, httpClient)
+	restClient, err := NewRESTClient(baseURL, versionedAPIPath, config.ContentConfig, qps, burst, config.RateLimiter, httpClient)
+	if err == nil {
+		restClient.warningHandler = config.WarningHandler
+		restClient.maxResponseBodyBytes = config.MaxResponseBodyBytes
+	}
+	return restClient, err
 }
 
 // UnversionedRESTClientFor is the same as RESTClientFor, except that it allows
@@ -329,6 +351,9 @@ func UnversionedRESTClientFor(config *Config) (*RESTClient, error) {
 	if err != nil {
 		return nil, err
 	}
+	if transport, err = hedgingRoundTripperFor(config, transport); err != nil {
+		return nil, err
+	}
 
 	var httpClient *http.Client
 	if transport != http.DefaultTransport {
@@ -344,7 +369,12 @@ func UnversionedRESTClientFor(config *Config) (*RESTClient, error) {
 		versionConfig.GroupVersion = &v
 	}
 
-	return NewRESTClient(baseURL, versionedAPIPath, versionConfig, config.QPS, config.Burst, config.RateLimiter, httpClient)
+	restClient, err := NewRESTClient(baseURL, versionedAPIPath, versionConfig, config.QPS, config.Burst, config.RateLimiter, httpClient)
+	if err == nil {
+		restClient.warningHandler = config.WarningHandler
+		restClient.maxResponseBodyBytes = config.MaxResponseBodyBytes
+	}
+	return restClient, err
 }
 
 // SetKubernetesDefaults sets default values on the provided client config for accessing the
@@ -512,13 +542,16 @@ func AnonymousClientConfig(config *Config) *Config {
 			CAData:     config.TLSClientConfig.CAData,
 			NextProtos: config.TLSClientConfig.NextProtos,
 		},
-		RateLimiter:        config.RateLimiter,
-		UserAgent:          config.UserAgent,
-		DisableCompression: config.DisableCompression,
-		QPS:                config.QPS,
-		Burst:              config.Burst,
-		Timeout:            config.Timeout,
-		Dial:               config.Dial,
+		RateLimiter:          config.RateLimiter,
+		UserAgent:            config.UserAgent,
+		DisableCompression:   config.DisableCompression,
+		QPS:                  config.QPS,
+		Burst:                config.Burst,
+		Timeout:              config.Timeout,
+		Dial:                 config.Dial,
+		WarningHandler:       config.WarningHandler,
+		MaxResponseBodyBytes: config.MaxResponseBodyBytes,
+		Hedging:              config.Hedging,
 	}
 }
 
@@ -551,14 +584,17 @@ func CopyConfig(config *Config) *Config {
 			CAData:     config.TLSClientConfig.CAData,
 			NextProtos: config.TLSClientConfig.NextProtos,
 		},
-		UserAgent:          config.UserAgent,
-		DisableCompression: config.DisableCompression,
-		Transport:          config.Transport,
-		WrapTransport:      config.WrapTransport,
-		QPS:                config.QPS,
-		Burst:              config.Burst,
-		RateLimiter:        config.RateLimiter,
-		Timeout:            config.Timeout,
-		Dial:               config.Dial,
+		UserAgent:            config.UserAgent,
+		DisableCompression:   config.DisableCompression,
+		Transport:            config.Transport,
+		WrapTransport:        config.WrapTransport,
+		QPS:                  config.QPS,
+		Burst:                config.Burst,
+		RateLimiter:          config.RateLimiter,
+		Timeout:              config.Timeout,
+		Dial:                 config.Dial,
+		WarningHandler:       config.WarningHandler,
+		MaxResponseBodyBytes: config.MaxResponseBodyBytes,
+		Hedging:              config.Hedging,
 	}
 }
@@ -0,0 +1,194 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+)
+
+// HedgingPolicy makes a client send idempotent reads, GETs and HEADs other
+// than watches and streams, to a second apiserver when the first has not
+// responded within Delay.  The first response wins and the other request is
+// cancelled.  This trims the tail latency of reads in setups where several
+// apiservers serve the same cluster, at the price of extra requests, which are
+// not subject to the rate limiter of the client.
+type HedgingPolicy struct {
+	// Hosts are the alternative apiservers, in the form of Config.Host.  They
+	// are used in turn.  Only their scheme and host are used: they must serve
+	// the API under the same path as Config.Host.
+	Hosts []string
+	// Delay is how long a read waits for a response before it is sent to
+	// one of the Hosts.
+	Delay time.Duration
+}
+
+// hedgingRoundTripperFor wraps rt with the HedgingPolicy of config, if any.
+func hedgingRoundTripperFor(config *Config, rt http.RoundTripper) (http.RoundTripper, error) {
+	if config.Hedging == nil || len(config.Hedging.Hosts) == 0 {
+		return rt, nil
+	}
+	if config.Hedging.Delay <= 0 {
+		return nil, fmt.Errorf("the hedging delay must be positive")
+	}
+	hosts := make([]*url.URL, 0, len(config.Hedging.Hosts))
+	for _, host := range config.Hedging.Hosts {
+		hostConfig := *config
+		hostConfig.Host = host
+		hostURL, _, err := defaultServerUrlFor(&hostConfig)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hedging host %q: %v", host, err)
+		}
+		hosts = append(hosts, hostURL)
+	}
+	return &hedgingRoundTripper{delegate: rt, hosts: hosts, delay: config.Hedging.Delay}, nil
+}
+
+type hedgingRoundTripper struct {
+	delegate http.RoundTripper
+	hosts    []*url.URL
+	delay    time.Duration
+	// next is the index of the next host to send a hedged request to.
+	next uint32
+}
+
+var _ http.RoundTripper = &hedgingRoundTripper{}
+
+// hedgedResult is the outcome of one of the requests of a hedged read.
+type hedgedResult struct {
+	attempt int
+	resp    *http.Response
+	err     error
+}
+
+func (rt *hedgingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !isHedgeable(req) {
+		return rt.delegate.RoundTrip(req)
+	}
+
+	results := make(chan hedgedResult, 2)
+	var cancels []context.CancelFunc
+	send := func(req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		attempt := len(cancels)
+		cancels = append(cancels, cancel)
+		go func() {
+			resp, err := rt.delegate.RoundTrip(req.WithContext(ctx))
+			results <- hedgedResult{attempt: attempt, resp: resp, err: err}
+		}()
+	}
+	send(req)
+	inflight := 1
+
+	timer := time.NewTimer(rt.delay)
+	defer timer.Stop()
+	hedge := timer.C
+	for {
+		select {
+		case <-hedge:
+			hedge = nil
+			send(rt.hedgedRequest(req))
+			inflight++
+		case result := <-results:
+			inflight--
+			if result.err != nil {
+				cancels[result.attempt]()
+				if inflight > 0 {
+					// The other request may still succeed.
+					continue
+				}
+				return nil, result.err
+			}
+			// Cancel the request that lost the race, if any.
+			for attempt, cancel := range cancels {
+				if attempt != result.attempt {
+					cancel()
+				}
+			}
+			if inflight > 0 {
+				go discardHedgedResult(results)
+			}
+			result.resp.Body = &cancelOnClose{ReadCloser: result.resp.Body, cancel: cancels[result.attempt]}
+			return result.resp, nil
+		}
+	}
+}
+
+// hedgedRequest returns a copy of req sent to the next of the hosts.
+func (rt *hedgingRoundTripper) hedgedRequest(req *http.Request) *http.Request {
+	host := rt.hosts[int(atomic.AddUint32(&rt.next, 1)-1)%len(rt.hosts)]
+	hedged := new(http.Request)
+	*hedged = *req
+	hedgedURL := *req.URL
+	hedgedURL.Scheme = host.Scheme
+	hedgedURL.Host = host.Host
+	hedged.URL = &hedgedURL
+	hedged.Host = ""
+	hedged.Header = make(http.Header, len(req.Header))
+	for key, values := range req.Header {
+		hedged.Header[key] = append([]string(nil), values...)
+	}
+	return hedged
+}
+
+// discardHedgedResult releases the response of the request that lost the
+// race, if it arrives despite being cancelled.
+func discardHedgedResult(results <-chan hedgedResult) {
+	if result := <-results; result.resp != nil {
+		result.resp.Body.Close()
+	}
+}
+
+// isHedgeable returns whether req is a read that can be sent twice and whose
+// response is read whole: watches, followed logs and connection upgrades
+// stream for a long time, and requests with a body cannot be replayed.
+func isHedgeable(req *http.Request) bool {
+	if req.Method != "GET" && req.Method != "HEAD" {
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		return false
+	}
+	if len(req.Header.Get("Upgrade")) > 0 {
+		return false
+	}
+	query := req.URL.Query()
+	for _, streaming := range []string{"watch", "follow"} {
+		if value := query.Get(streaming); value == "true" || value == "1" {
+			return false
+		}
+	}
+	return true
+}
+
+// cancelOnClose cancels the context of a request once its response body is
+// closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+func TestHedging(t *testing.T) {
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod"}}
+	body, err := runtime.Encode(scheme.Codecs.LegacyCodec(v1.SchemeGroupVersion), pod)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The primary apiserver only answers once its request is cancelled.
+	cancelled := make(chan struct{}, 1)
+	var primaryRequests, alternateRequests int32
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&primaryRequests, 1)
+		if req.Method == "GET" {
+			<-req.Context().Done()
+			cancelled <- struct{}{}
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}))
+	defer primary.Close()
+	alternate := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&alternateRequests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}))
+	defer alternate.Close()
+
+	client, err := RESTClientFor(&Config{
+		Host: primary.URL,
+		ContentConfig: ContentConfig{
+			GroupVersion:         &v1.SchemeGroupVersion,
+			NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		},
+		Hedging: &HedgingPolicy{Hosts: []string{alternate.URL}, Delay: 10 * time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := &v1.Pod{}
+	if err := client.Get().Namespace("ns").Resource("pods").Name("pod").Do().Into(result); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Name != "pod" {
+		t.Errorf("expected pod, got %v", result)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(30 * time.Second):
+		t.Fatalf("the request to the primary apiserver was not cancelled")
+	}
+
+	// Writes are never hedged.
+	if err := client.Put().Namespace("ns").Resource("pods").Name("pod").Body(pod).Do().Error(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if primary, alternate := atomic.LoadInt32(&primaryRequests), atomic.LoadInt32(&alternateRequests); primary != 2 || alternate != 1 {
+		t.Errorf("expected 2 requests to the primary apiserver and 1 to the alternate, got %d and %d", primary, alternate)
+	}
+}
+
+func TestIsHedgeable(t *testing.T) {
+	for _, test := range []struct {
+		method, url string
+		expected    bool
+	}{
+		{"GET", "https://server/api/v1/pods", true},
+		{"HEAD", "https://server/api/v1/pods", true},
+		{"GET", "https://server/api/v1/pods?watch=true", false},
+		{"GET", "https://server/api/v1/namespaces/ns/pods/pod/log?follow=1", false},
+		{"POST", "https://server/api/v1/pods", false},
+		{"DELETE", "https://server/api/v1/namespaces/ns/pods/pod", false},
+	} {
+		req, err := http.NewRequest(test.method, test.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hedgeable := isHedgeable(req); hedgeable != test.expected {
+			t.Errorf("%s %s: expected hedgeable=%v, got %v", test.method, test.url, test.expected, hedgeable)
+		}
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"io/ioutil"
 	"mime"
 	"net/http"
+	"net/textproto"
 	"net/url"
 	"path"
 	"reflect"
@@ -109,6 +110,17 @@ type Request struct {
 
 	backoffMgr BackoffManager
 	throttle   flowcontrol.RateLimiter
+
+	// capturedHeaders receives the response headers named in captureHeaderNames.
+	capturedHeaders    http.Header
+	captureHeaderNames []string
+
+	// warningHandler handles warnings returned by the server. If nil, the default
+	// warning handler is used.
+	warningHandler WarningHandler
+
+	// maxResponseBodyBytes bounds the decoded size of the response body. Zero means no limit.
+	maxResponseBodyBytes int64
 }
 
 // NewRequest creates a new request helper object for accessing runtime.Objects on a server.
@@ -357,6 +369,56 @@ func (r *Request) SetHeader(key string, values ...string) *Request {
 	return r
 }
 
+// CaptureHeaders records the named headers of the server's response into headers
+// once a response is received, for example to log the Audit-Id of a request. If no
+// names are given, every response header is recorded. When a request is retried,
+// only the headers of the final response are recorded.
+func (r *Request) CaptureHeaders(headers http.Header, names ...string) *Request {
+	if r.err != nil {
+		return r
+	}
+	if headers == nil {
+		r.err = fmt.Errorf("headers must not be nil")
+		return r
+	}
+	r.capturedHeaders = headers
+	r.captureHeaderNames = names
+	return r
+}
+
+// MaxResponseBodyBytes bounds the decoded size of the response body read by Do and DoRaw,
+// overriding the limit of the client. Zero means no limit.
+func (r *Request) MaxResponseBodyBytes(limit int64) *Request {
+	r.maxResponseBodyBytes = limit
+	return r
+}
+
+// WarningHandler sets the handler for warnings the server returns for this request,
+// overriding the handler of the client.
+func (r *Request) WarningHandler(handler WarningHandler) *Request {
+	r.warningHandler = handler
+	return r
+}
+
+// recordHeaders copies the requested headers of resp into the map passed to CaptureHeaders.
+func (r *Request) recordHeaders(resp *http.Response) {
+	if r.capturedHeaders == nil || resp == nil {
+		return
+	}
+	if len(r.captureHeaderNames) == 0 {
+		for key, values := range resp.Header {
+			r.capturedHeaders[key] = values
+		}
+		return
+	}
+	for _, name := range r.captureHeaderNames {
+		key := textproto.CanonicalMIMEHeaderKey(name)
+		if values, ok := resp.Header[key]; ok {
+			r.capturedHeaders[key] = values
+		}
+	}
+}
+
 // Timeout makes the request use the given duration as an overall timeout for the
 // request. Additionally, if set passes the value as "timeout" parameter in URL.
 func (r *Request) Timeout(d time.Duration) *Request {
@@ -597,6 +659,8 @@ func (r *Request) WatchWithSpecificDecoders(wrapperDecoderFn func(io.ReadCloser)
 		}
 		return nil, err
 	}
+	r.recordHeaders(resp)
+	handleWarnings(resp.Header, r.warningHandler)
 	if resp.StatusCode != http.StatusOK {
 		defer resp.Body.Close()
 		if result := r.transformResponse(resp, req); result.err != nil {
@@ -673,6 +737,8 @@ func (r *Request) Stream() (io.ReadCloser, error) {
 	if err != nil {
 		return nil, err
 	}
+	r.recordHeaders(resp)
+	handleWarnings(resp.Header, r.warningHandler)
 
 	switch {
 	case (resp.StatusCode >= 200) && (resp.StatusCode < 300):
@@ -793,6 +859,8 @@ func (r *Request) request(fn func(*http.Request, *http.Response)) error {
 					_, err := seeker.Seek(0, 0)
 					if err != nil {
 						klog.V(4).Infof("Could not retry request, can't Seek() back to beginning of body for %T", r.body)
+						r.recordHeaders(resp)
+						handleWarnings(resp.Header, r.warningHandler)
 						fn(req, resp)
 						return true
 					}
@@ -802,6 +870,8 @@ func (r *Request) request(fn func(*http.Request, *http.Response)) error {
 				r.backoffMgr.Sleep(time.Duration(seconds) * time.Second)
 				return false
 			}
+			r.recordHeaders(resp)
+			handleWarnings(resp.Header, r.warningHandler)
 			fn(req, resp)
 			return true
 		}()
@@ -842,7 +912,10 @@ func (r *Request) DoRaw() ([]byte, error) {
 
 	var result Result
 	err := r.request(func(req *http.Request, resp *http.Response) {
-		result.body, result.err = ioutil.ReadAll(resp.Body)
+		result.body, result.err = r.readResponseBody(resp)
+		if result.err != nil {
+			return
+		}
 		glogBody("Response Body", result.body)
 		if resp.StatusCode < http.StatusOK || resp.StatusCode > http.StatusPartialContent {
 			result.err = r.transformUnstructuredResponseError(resp, req, result.body)
@@ -858,10 +931,12 @@ func (r *Request) DoRaw() ([]byte, error) {
 func (r *Request) transformResponse(resp *http.Response, req *http.Request) Result {
 	var body []byte
 	if resp.Body != nil {
-		data, err := ioutil.ReadAll(resp.Body)
+		data, err := r.readResponseBody(resp)
 		switch err.(type) {
 		case nil:
 			body = data
+		case *ResponseTooLargeError:
+			return Result{err: err}
 		case http2.StreamError:
 			// This is trying to catch the scenario that the server may close the connection when sending the
 			// response body. This can be caused by server timeout due to a slow network connection.
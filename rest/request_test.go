@@ -1639,6 +1639,38 @@ func TestWasCreated(t *testing.T) {
 	fakeHandler.ValidateRequest(t, requestURL, "PUT", &tmpStr)
 }
 
+func TestCaptureHeaders(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Audit-Id", "1234")
+		w.Header().Set("Warning", `299 - "deprecated"`)
+		w.Header().Set("X-Other", "ignored")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+	c := testRESTClient(t, testServer)
+
+	selected := http.Header{}
+	if _, err := c.Verb("GET").Prefix("foo").CaptureHeaders(selected, "audit-id", "Warning", "X-Missing").DoRaw(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := http.Header{"Audit-Id": {"1234"}, "Warning": {`299 - "deprecated"`}}
+	if !reflect.DeepEqual(expected, selected) {
+		t.Errorf("expected %v, got %v", expected, selected)
+	}
+
+	all := http.Header{}
+	if err := c.Verb("GET").Prefix("foo").CaptureHeaders(all).Do().Error(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if all.Get("X-Other") != "ignored" || all.Get("Audit-Id") != "1234" {
+		t.Errorf("expected all headers to be captured, got %v", all)
+	}
+
+	if err := c.Verb("GET").CaptureHeaders(nil).Do().Error(); err == nil {
+		t.Errorf("expected an error capturing into a nil header")
+	}
+}
+
 func TestVerbs(t *testing.T) {
 	c := testRESTClient(t, nil)
 	if r := c.Post(); r.verb != "POST" {
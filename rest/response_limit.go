@@ -0,0 +1,96 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// ResponseTooLargeError is returned when the decoded body of a response exceeds the
+// limit set with Config.MaxResponseBodyBytes or Request.MaxResponseBodyBytes.
+type ResponseTooLargeError struct {
+	// Limit is the number of decoded bytes the response was allowed to have.
+	Limit int64
+	// Compressed is true if the response was gzip encoded on the wire.
+	Compressed bool
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	if e.Compressed {
+		return fmt.Sprintf("the decompressed response body exceeds the limit of %d bytes", e.Limit)
+	}
+	return fmt.Sprintf("the response body exceeds the limit of %d bytes", e.Limit)
+}
+
+// IsResponseTooLarge returns true if err is a ResponseTooLargeError.
+func IsResponseTooLarge(err error) bool {
+	_, ok := err.(*ResponseTooLargeError)
+	return ok
+}
+
+// readResponseBody reads the body of resp, decompressing it as it is read if the server gzip
+// encoded it and the transport did not already do so. The compressed body is never buffered,
+// but the decoded one is, as decoders need it whole. When the request has a body limit,
+// reading stops as soon as the decoded data exceeds it, so at most the limit is ever buffered,
+// and an uncompressed body announcing a larger Content-Length is rejected without being read.
+// Errors reading resp.Body are returned as is, so that callers can tell stream errors apart.
+func (r *Request) readResponseBody(resp *http.Response) ([]byte, error) {
+	var body io.Reader = resp.Body
+	compressed := resp.Uncompressed
+	if !resp.Uncompressed && strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		buffered := bufio.NewReader(resp.Body)
+		body = buffered
+		// The responses without a body, such as those of HEAD requests or
+		// with a 204 status, have nothing to decompress.
+		if _, err := buffered.Peek(1); err != io.EOF {
+			if err != nil {
+				return nil, err
+			}
+			gzipReader, err := gzip.NewReader(buffered)
+			if err == gzip.ErrHeader || err == io.ErrUnexpectedEOF {
+				return nil, fmt.Errorf("unable to decompress response body: %v", err)
+			}
+			if err != nil {
+				return nil, err
+			}
+			defer gzipReader.Close()
+			body = gzipReader
+			compressed = true
+		}
+	}
+	if r.maxResponseBodyBytes <= 0 {
+		return ioutil.ReadAll(body)
+	}
+	if !compressed && resp.ContentLength > r.maxResponseBodyBytes {
+		return nil, &ResponseTooLargeError{Limit: r.maxResponseBodyBytes}
+	}
+
+	data, err := ioutil.ReadAll(&io.LimitedReader{R: body, N: r.maxResponseBodyBytes + 1})
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > r.maxResponseBodyBytes {
+		return nil, &ResponseTooLargeError{Limit: r.maxResponseBodyBytes, Compressed: compressed}
+	}
+	return data, nil
+}
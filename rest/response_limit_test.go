@@ -0,0 +1,114 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/http2"
+)
+
+func TestMaxResponseBodyBytes(t *testing.T) {
+	body := strings.Repeat("x", 1000)
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Accept-Encoding") == "gzip" {
+			w.Header().Set("Content-Encoding", "gzip")
+			w.WriteHeader(http.StatusOK)
+			gzipWriter := gzip.NewWriter(w)
+			gzipWriter.Write([]byte(body))
+			gzipWriter.Close()
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(body))
+	}))
+	defer testServer.Close()
+	c := testRESTClient(t, testServer)
+
+	data, err := c.Get().SetHeader("Accept-Encoding", "gzip").MaxResponseBodyBytes(1000).DoRaw()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(data) != body {
+		t.Errorf("expected the body to be decompressed, got %d bytes", len(data))
+	}
+
+	c.maxResponseBodyBytes = 999
+	err = c.Get().SetHeader("Accept-Encoding", "gzip").Do().Error()
+	if tooLarge, ok := err.(*ResponseTooLargeError); !ok || !tooLarge.Compressed || tooLarge.Limit != 999 {
+		t.Errorf("expected a compressed body over the limit to be rejected, got %#v", err)
+	}
+	if _, err := c.Get().DoRaw(); !IsResponseTooLarge(err) {
+		t.Errorf("expected an uncompressed body over the limit to be rejected, got %v", err)
+	}
+	if _, err := c.Get().MaxResponseBodyBytes(0).DoRaw(); err != nil {
+		t.Errorf("expected the request limit to override the client limit, got %v", err)
+	}
+}
+
+func TestReadResponseBodyGzipEncoding(t *testing.T) {
+	gzipHeader := http.Header{"Content-Encoding": []string{"gzip"}}
+	r := &Request{maxResponseBodyBytes: 1000}
+
+	// A response without a body, such as one with a 204 status.
+	data, err := r.readResponseBody(&http.Response{StatusCode: http.StatusNoContent, Header: gzipHeader, Body: http.NoBody})
+	if err != nil || len(data) != 0 {
+		t.Errorf("expected an empty body, got %q, %v", data, err)
+	}
+
+	// A body the transport decompressed already.
+	data, err = r.readResponseBody(&http.Response{Header: gzipHeader, Uncompressed: true, Body: ioutil.NopCloser(strings.NewReader("plain"))})
+	if err != nil || string(data) != "plain" {
+		t.Errorf("expected the body not to be decompressed twice, got %q, %v", data, err)
+	}
+
+	// Errors reading the body keep their type, before and after the gzip header.
+	streamErr := http2.StreamError{StreamID: 1, Code: http2.ErrCodeInternal}
+	_, err = r.readResponseBody(&http.Response{Header: gzipHeader, Body: ioutil.NopCloser(&failingReader{err: streamErr})})
+	if _, ok := err.(http2.StreamError); !ok {
+		t.Errorf("expected the stream error reading the body, got %#v", err)
+	}
+	var compressed bytes.Buffer
+	gzipWriter := gzip.NewWriter(&compressed)
+	gzipWriter.Write([]byte(strings.Repeat("x", 100)))
+	gzipWriter.Close()
+	_, err = r.readResponseBody(&http.Response{Header: gzipHeader, Body: ioutil.NopCloser(&failingReader{data: compressed.Bytes()[:20], err: streamErr})})
+	if _, ok := err.(http2.StreamError); !ok {
+		t.Errorf("expected the stream error decompressing the body, got %#v", err)
+	}
+}
+
+// failingReader returns data, then err.
+type failingReader struct {
+	data []byte
+	err  error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, r.err
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
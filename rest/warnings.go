@@ -0,0 +1,247 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/golang/groupcache/lru"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/klog"
+)
+
+// WarningRecord is a single warning returned by the server in a Warning response header.
+type WarningRecord struct {
+	// Code is the warn-code of the header, 299 for warnings sent by the apiserver.
+	Code int
+	// Agent identifies the server that added the warning, or "-" if unknown.
+	Agent string
+	// Text is the human readable warning message.
+	Text string
+}
+
+// WarningHandler handles warnings returned by the server.  Implementations must be
+// safe for concurrent use, as one handler is shared by every request of a client.
+type WarningHandler interface {
+	HandleWarning(warning WarningRecord)
+}
+
+// WarningHandlerFunc is an adapter to allow the use of ordinary functions as WarningHandlers.
+type WarningHandlerFunc func(warning WarningRecord)
+
+// HandleWarning calls f(warning).
+func (f WarningHandlerFunc) HandleWarning(warning WarningRecord) {
+	f(warning)
+}
+
+// NoWarnings is a WarningHandler that drops every warning.
+type NoWarnings struct{}
+
+// HandleWarning drops the warning.
+func (NoWarnings) HandleWarning(WarningRecord) {}
+
+// WarningLogger is a WarningHandler that logs every warning.
+type WarningLogger struct{}
+
+// HandleWarning logs the warning text.
+func (WarningLogger) HandleWarning(warning WarningRecord) {
+	klog.Warning(warning.Text)
+}
+
+var defaultWarningHandler = struct {
+	lock    sync.RWMutex
+	handler WarningHandler
+}{
+	handler: NoWarnings{},
+}
+
+// SetDefaultWarningHandler sets the handler used by clients whose Config does not set a
+// WarningHandler.  By default warnings are dropped.  Passing nil drops all warnings.
+func SetDefaultWarningHandler(handler WarningHandler) {
+	if handler == nil {
+		handler = NoWarnings{}
+	}
+	defaultWarningHandler.lock.Lock()
+	defer defaultWarningHandler.lock.Unlock()
+	defaultWarningHandler.handler = handler
+}
+
+// getDefaultWarningHandler returns the handler set with SetDefaultWarningHandler.
+func getDefaultWarningHandler() WarningHandler {
+	defaultWarningHandler.lock.RLock()
+	defer defaultWarningHandler.lock.RUnlock()
+	return defaultWarningHandler.handler
+}
+
+// NewWarningHandlers returns a WarningHandler that passes every warning to each of the
+// given handlers in turn, so warnings can be logged, counted, and recorded as events at once.
+func NewWarningHandlers(handlers ...WarningHandler) WarningHandler {
+	return multiWarningHandler(handlers)
+}
+
+type multiWarningHandler []WarningHandler
+
+func (m multiWarningHandler) HandleWarning(warning WarningRecord) {
+	for _, handler := range m {
+		handler.HandleWarning(warning)
+	}
+}
+
+// maxDeduplicatedWarnings is the number of warning texts a deduplicating handler remembers.
+const maxDeduplicatedWarnings = 1024
+
+// NewDeduplicatingWarningHandler returns a WarningHandler that passes each distinct warning
+// text to next only the first time it is seen.  Only the most recently seen texts are
+// remembered, so a text forgotten among many others is passed again.  Share one instance
+// across clients to deduplicate warnings for the whole process.
+func NewDeduplicatingWarningHandler(next WarningHandler) WarningHandler {
+	return &deduplicatingWarningHandler{next: next, seen: lru.New(maxDeduplicatedWarnings)}
+}
+
+type deduplicatingWarningHandler struct {
+	next WarningHandler
+
+	lock sync.Mutex
+	seen *lru.Cache
+}
+
+func (d *deduplicatingWarningHandler) HandleWarning(warning WarningRecord) {
+	d.lock.Lock()
+	if _, seen := d.seen.Get(warning.Text); seen {
+		d.lock.Unlock()
+		return
+	}
+	d.seen.Add(warning.Text, nil)
+	d.lock.Unlock()
+	d.next.HandleWarning(warning)
+}
+
+// NewRateLimitedWarningHandler returns a WarningHandler that passes warnings to next only
+// while limiter accepts them, dropping the rest, so a chatty server cannot flood the logs.
+func NewRateLimitedWarningHandler(next WarningHandler, limiter flowcontrol.RateLimiter) WarningHandler {
+	return &rateLimitedWarningHandler{next: next, limiter: limiter}
+}
+
+type rateLimitedWarningHandler struct {
+	next    WarningHandler
+	limiter flowcontrol.RateLimiter
+}
+
+func (r *rateLimitedWarningHandler) HandleWarning(warning WarningRecord) {
+	if !r.limiter.TryAccept() {
+		klog.V(4).Infof("Dropping rate limited warning: %s", warning.Text)
+		return
+	}
+	r.next.HandleWarning(warning)
+}
+
+// handleWarnings parses the Warning headers of a response and passes them to handler.
+func handleWarnings(headers http.Header, handler WarningHandler) {
+	if handler == nil {
+		handler = getDefaultWarningHandler()
+	}
+	warnings, errs := ParseWarningHeaders(headers["Warning"])
+	for _, err := range errs {
+		klog.V(4).Infof("Ignoring malformed warning header: %v", err)
+	}
+	for _, warning := range warnings {
+		handler.HandleWarning(warning)
+	}
+}
+
+// ParseWarningHeaders extracts the warnings from Warning header values of the form
+// `code agent "text" ["date"]`, as described in RFC 7234 section 5.5.  A single header
+// value may hold several comma separated warnings.  Malformed warnings are skipped and
+// reported in the returned errors.
+func ParseWarningHeaders(headers []string) ([]WarningRecord, []error) {
+	var (
+		results []WarningRecord
+		errs    []error
+	)
+	for _, header := range headers {
+		for len(header) > 0 {
+			result, remainder, err := parseWarningHeader(header)
+			if err != nil {
+				errs = append(errs, err)
+				break
+			}
+			results = append(results, result)
+			header = remainder
+		}
+	}
+	return results, errs
+}
+
+func parseWarningHeader(header string) (WarningRecord, string, error) {
+	header = strings.TrimLeft(header, " ,")
+
+	parts := strings.SplitN(header, " ", 3)
+	if len(parts) != 3 {
+		return WarningRecord{}, "", fmt.Errorf("invalid warning header %q: fewer than 3 segments", header)
+	}
+	code, err := strconv.Atoi(parts[0])
+	if err != nil || len(parts[0]) != 3 {
+		return WarningRecord{}, "", fmt.Errorf("invalid warning header %q: code segment is not a 3-digit number", header)
+	}
+	agent := parts[1]
+	if len(agent) == 0 {
+		return WarningRecord{}, "", fmt.Errorf("invalid warning header %q: empty agent segment", header)
+	}
+
+	text, remainder, err := parseQuotedString(parts[2])
+	if err != nil {
+		return WarningRecord{}, "", fmt.Errorf("invalid warning header %q: %v", header, err)
+	}
+	// skip an optional quoted warn-date
+	if remainder = strings.TrimLeft(remainder, " "); strings.HasPrefix(remainder, `"`) {
+		if _, remainder, err = parseQuotedString(remainder); err != nil {
+			return WarningRecord{}, "", fmt.Errorf("invalid warning header %q: %v", header, err)
+		}
+	}
+	if remainder = strings.TrimLeft(remainder, " "); len(remainder) > 0 && remainder[0] != ',' {
+		return WarningRecord{}, "", fmt.Errorf("invalid warning header %q: unexpected trailing content", header)
+	}
+	return WarningRecord{Code: code, Agent: agent, Text: text}, remainder, nil
+}
+
+// parseQuotedString parses a leading quoted-string and returns its unescaped content
+// and whatever follows the closing quote.
+func parseQuotedString(s string) (string, string, error) {
+	if len(s) == 0 || s[0] != '"' {
+		return "", "", fmt.Errorf("missing opening quote")
+	}
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return b.String(), s[i+1:], nil
+		case '\\':
+			if i+1 == len(s) {
+				return "", "", fmt.Errorf("unterminated escape")
+			}
+			i++
+			b.WriteByte(s[i])
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", "", fmt.Errorf("missing closing quote")
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/golang/groupcache/lru"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+type recordingWarningHandler struct {
+	warnings []WarningRecord
+}
+
+func (r *recordingWarningHandler) HandleWarning(warning WarningRecord) {
+	r.warnings = append(r.warnings, warning)
+}
+
+func TestParseWarningHeaders(t *testing.T) {
+	testCases := []struct {
+		name      string
+		headers   []string
+		expected  []WarningRecord
+		errsCount int
+	}{
+		{
+			name:     "simple",
+			headers:  []string{`299 - "deprecated"`},
+			expected: []WarningRecord{{Code: 299, Agent: "-", Text: "deprecated"}},
+		},
+		{
+			name:    "multiple values with date and escapes",
+			headers: []string{`299 apiserver "a \"quoted\" text" "Wed, 21 Oct 2015 07:28:00 GMT", 199 - "second"`, `299 - "third"`},
+			expected: []WarningRecord{
+				{Code: 299, Agent: "apiserver", Text: `a "quoted" text`},
+				{Code: 199, Agent: "-", Text: "second"},
+				{Code: 299, Agent: "-", Text: "third"},
+			},
+		},
+		{
+			name:      "malformed values are skipped",
+			headers:   []string{`abc - "bad code"`, `299 - unquoted`, `299 - "unterminated`, `299 - "valid"`},
+			expected:  []WarningRecord{{Code: 299, Agent: "-", Text: "valid"}},
+			errsCount: 3,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			warnings, errs := ParseWarningHeaders(tc.headers)
+			if !reflect.DeepEqual(tc.expected, warnings) {
+				t.Errorf("expected %#v, got %#v", tc.expected, warnings)
+			}
+			if len(errs) != tc.errsCount {
+				t.Errorf("expected %d errors, got %v", tc.errsCount, errs)
+			}
+		})
+	}
+}
+
+func TestDeduplicatingWarningHandler(t *testing.T) {
+	recorder := &recordingWarningHandler{}
+	handler := NewDeduplicatingWarningHandler(recorder)
+	for _, text := range []string{"a", "b", "a", "b", "c"} {
+		handler.HandleWarning(WarningRecord{Code: 299, Agent: "-", Text: text})
+	}
+	var texts []string
+	for _, warning := range recorder.warnings {
+		texts = append(texts, warning.Text)
+	}
+	if expected := []string{"a", "b", "c"}; !reflect.DeepEqual(expected, texts) {
+		t.Errorf("expected %v, got %v", expected, texts)
+	}
+}
+
+func TestDeduplicatingWarningHandlerForgetsOldWarnings(t *testing.T) {
+	recorder := &recordingWarningHandler{}
+	handler := &deduplicatingWarningHandler{next: recorder, seen: lru.New(2)}
+	for _, text := range []string{"a", "b", "a", "c", "b", "a"} {
+		handler.HandleWarning(WarningRecord{Code: 299, Agent: "-", Text: text})
+	}
+	var texts []string
+	for _, warning := range recorder.warnings {
+		texts = append(texts, warning.Text)
+	}
+	if expected := []string{"a", "b", "c", "b", "a"}; !reflect.DeepEqual(expected, texts) {
+		t.Errorf("expected %v, got %v", expected, texts)
+	}
+}
+
+func TestRateLimitedWarningHandler(t *testing.T) {
+	recorder := &recordingWarningHandler{}
+	NewRateLimitedWarningHandler(recorder, flowcontrol.NewFakeNeverRateLimiter()).HandleWarning(WarningRecord{Text: "dropped"})
+	NewRateLimitedWarningHandler(recorder, flowcontrol.NewFakeAlwaysRateLimiter()).HandleWarning(WarningRecord{Text: "kept"})
+	if len(recorder.warnings) != 1 || recorder.warnings[0].Text != "kept" {
+		t.Errorf("expected only the accepted warning, got %v", recorder.warnings)
+	}
+}
+
+func TestRequestWarningHandler(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Add("Warning", `299 - "first"`)
+		w.Header().Add("Warning", `299 - "second"`)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+
+	clientHandler := &recordingWarningHandler{}
+	c := testRESTClient(t, testServer)
+	c.warningHandler = clientHandler
+	if err := c.Get().Do().Error(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []WarningRecord{{Code: 299, Agent: "-", Text: "first"}, {Code: 299, Agent: "-", Text: "second"}}
+	if !reflect.DeepEqual(expected, clientHandler.warnings) {
+		t.Errorf("expected %#v, got %#v", expected, clientHandler.warnings)
+	}
+
+	requestHandler := &recordingWarningHandler{}
+	sink := &recordingWarningHandler{}
+	if err := c.Get().WarningHandler(NewWarningHandlers(requestHandler, sink)).Do().Error(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(clientHandler.warnings) != 2 {
+		t.Errorf("expected the request handler to override the client handler")
+	}
+	if !reflect.DeepEqual(expected, requestHandler.warnings) || !reflect.DeepEqual(expected, sink.warnings) {
+		t.Errorf("expected every handler to receive %#v, got %#v and %#v", expected, requestHandler.warnings, sink.warnings)
+	}
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package table provides a client retrieving resources as tables, with the
+// columns and cells the server prints them with, for clients that display
+// resources without knowing their types.
+package table // import "k8s.io/client-go/table"
+
+import (
+	"fmt"
+	"time"
+
+	metainternalversion "k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/rest"
+)
+
+var parameterScheme = runtime.NewScheme()
+var dynamicParameterCodec = runtime.NewParameterCodec(parameterScheme)
+
+var versionV1 = schema.GroupVersion{Version: "v1"}
+
+func init() {
+	metav1.AddToGroupVersion(parameterScheme, versionV1)
+}
+
+// acceptTable requests tables, preferring meta.k8s.io/v1 which servers
+// support from Kubernetes 1.15.  Tables are only served as JSON.
+const acceptTable = "application/json;as=Table;g=meta.k8s.io;v=v1,application/json;as=Table;g=meta.k8s.io;v=v1beta1"
+
+// Client allows callers to retrieve any Kubernetes-compatible API endpoint as
+// tables.  The rows of the tables include the metadata of their objects, as
+// PartialObjectMetadata.
+type Client struct {
+	client *rest.RESTClient
+}
+
+var _ Interface = &Client{}
+
+// ConfigFor returns a copy of the provided config with the
+// appropriate table client defaults set.
+func ConfigFor(inConfig *rest.Config) *rest.Config {
+	config := rest.CopyConfig(inConfig)
+	config.AcceptContentTypes = "application/json"
+	config.ContentType = "application/json"
+	config.NegotiatedSerializer = metainternalversion.Codecs.WithoutConversion()
+	if config.UserAgent == "" {
+		config.UserAgent = rest.DefaultKubernetesUserAgent()
+	}
+	return config
+}
+
+// NewForConfigOrDie creates a new table client for the given config and
+// panics if there is an error in the config.
+func NewForConfigOrDie(c *rest.Config) Interface {
+	ret, err := NewForConfig(c)
+	if err != nil {
+		panic(err)
+	}
+	return ret
+}
+
+// NewForConfig creates a new table client that can retrieve any Kubernetes
+// object (core, aggregated, or custom resource based) in the form of Table
+// objects, or returns an error.
+func NewForConfig(inConfig *rest.Config) (Interface, error) {
+	config := ConfigFor(inConfig)
+	// for serializing the options
+	config.GroupVersion = &schema.GroupVersion{}
+	config.APIPath = "/this-value-should-never-be-sent"
+
+	restClient, err := rest.RESTClientFor(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{client: restClient}, nil
+}
+
+type client struct {
+	client    *Client
+	namespace string
+	resource  schema.GroupVersionResource
+}
+
+// Resource returns an interface that can access cluster or namespace
+// scoped instances of resource.
+func (c *Client) Resource(resource schema.GroupVersionResource) Getter {
+	return &client{client: c, resource: resource}
+}
+
+// Namespace returns an interface that can access namespace-scoped instances of the
+// provided resource.
+func (c *client) Namespace(ns string) ResourceInterface {
+	ret := *c
+	ret.namespace = ns
+	return &ret
+}
+
+// List returns all resources within the specified scope (namespace or cluster)
+// as a table.
+func (c *client) List(opts metav1.ListOptions) (*metav1.Table, error) {
+	obj, err := c.client.client.Get().AbsPath(c.makeURLSegments()...).
+		SetHeader("Accept", acceptTable).
+		SpecificallyVersionedParams(&opts, dynamicParameterCodec, versionV1).
+		Param("includeObject", string(metav1.IncludeMetadata)).
+		Do().
+		Get()
+	if err != nil {
+		return nil, err
+	}
+	table, ok := obj.(*metav1.Table)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object, expected Table but got %T", obj)
+	}
+	return table, nil
+}
+
+// Watch finds all changes to the resources in the specified scope (namespace
+// or cluster).  Every event holds a table whose rows are the changed objects;
+// servers usually only send the column definitions with the first event.
+func (c *client) Watch(opts metav1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.client.Get().
+		AbsPath(c.makeURLSegments()...).
+		SetHeader("Accept", acceptTable).
+		SpecificallyVersionedParams(&opts, dynamicParameterCodec, versionV1).
+		Param("includeObject", string(metav1.IncludeMetadata)).
+		Timeout(timeout).
+		Watch()
+}
+
+func (c *client) makeURLSegments() []string {
+	url := []string{}
+	if len(c.resource.Group) == 0 {
+		url = append(url, "api")
+	} else {
+		url = append(url, "apis", c.resource.Group)
+	}
+	url = append(url, c.resource.Version)
+
+	if len(c.namespace) > 0 {
+		url = append(url, "namespaces", c.namespace)
+	}
+	url = append(url, c.resource.Resource)
+	return url
+}
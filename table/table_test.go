@@ -0,0 +1,97 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package table
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/rest"
+)
+
+func TestClient(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "group", Version: "v1", Resource: "resource"}
+	table := &metav1.Table{
+		TypeMeta: metav1.TypeMeta{Kind: "Table", APIVersion: "meta.k8s.io/v1"},
+		ListMeta: metav1.ListMeta{ResourceVersion: "253"},
+		ColumnDefinitions: []metav1.TableColumnDefinition{
+			{Name: "Name", Type: "string", Format: "name"},
+			{Name: "Age", Type: "string"},
+		},
+		Rows: []metav1.TableRow{{
+			Cells:  []interface{}{"name", "5m"},
+			Object: runtime.RawExtension{Raw: []byte(`{"kind":"PartialObjectMetadata","apiVersion":"meta.k8s.io/v1","metadata":{"name":"name","namespace":"ns"}}`)},
+		}},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if accept := req.Header.Get("Accept"); accept != acceptTable {
+			t.Errorf("unexpected accept header %q", accept)
+		}
+		if req.URL.Path != "/apis/group/v1/namespaces/ns/resource" {
+			t.Errorf("unexpected path %q", req.URL.Path)
+		}
+		if include := req.URL.Query().Get("includeObject"); include != "Metadata" {
+			t.Errorf("expected the metadata of the objects to be included, got %q", include)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		var obj interface{} = table
+		if req.URL.Query().Get("watch") == "true" {
+			obj = &metav1.WatchEvent{Type: string(watch.Added), Object: runtime.RawExtension{Object: table}}
+		}
+		if err := json.NewEncoder(w).Encode(obj); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := client.Resource(gvr).Namespace("ns").List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.ResourceVersion != "253" || !reflect.DeepEqual(list.ColumnDefinitions, table.ColumnDefinitions) || len(list.Rows) != 1 {
+		t.Errorf("unexpected table %#v", list)
+	}
+	if cells := list.Rows[0].Cells; !reflect.DeepEqual(cells, table.Rows[0].Cells) {
+		t.Errorf("expected cells %v, got %v", table.Rows[0].Cells, cells)
+	}
+
+	w, err := client.Resource(gvr).Namespace("ns").Watch(metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+	event, ok := <-w.ResultChan()
+	if !ok {
+		t.Fatalf("expected an event")
+	}
+	if got, ok := event.Object.(*metav1.Table); event.Type != watch.Added || !ok || len(got.Rows) != 1 {
+		t.Errorf("unexpected event %#v", event)
+	}
+}
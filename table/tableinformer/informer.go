@@ -0,0 +1,235 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tableinformer provides an informer caching resources as the rows of
+// a table printed by the server, for clients such as dashboards that display
+// resources without knowing their types.
+package tableinformer // import "k8s.io/client-go/table/tableinformer"
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/table"
+	"k8s.io/client-go/tools/cache"
+)
+
+// TweakListOptionsFunc defines the signature of a helper function
+// that wants to provide more listing options to API
+type TweakListOptionsFunc func(*metav1.ListOptions)
+
+// TableInformer caches the rows of a resource, as *Row objects, along with
+// the columns of the table they belong to.
+type TableInformer struct {
+	informer cache.SharedIndexInformer
+
+	lock    sync.RWMutex
+	columns []metav1.TableColumnDefinition
+}
+
+// NewTableInformer constructs a new informer for the rows of a resource.
+func NewTableInformer(client table.Interface, gvr schema.GroupVersionResource, namespace string, resyncPeriod time.Duration, indexers cache.Indexers) *TableInformer {
+	return NewFilteredTableInformer(client, gvr, namespace, resyncPeriod, indexers, nil)
+}
+
+// NewFilteredTableInformer constructs a new informer for the rows of a
+// resource, filtered by tweakListOptions.
+func NewFilteredTableInformer(client table.Interface, gvr schema.GroupVersionResource, namespace string, resyncPeriod time.Duration, indexers cache.Indexers, tweakListOptions TweakListOptionsFunc) *TableInformer {
+	i := &TableInformer{}
+	i.informer = cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				t, err := client.Resource(gvr).Namespace(namespace).List(options)
+				if err != nil {
+					return nil, err
+				}
+				i.setColumns(t.ColumnDefinitions)
+				return rowsFor(t)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				w, err := client.Resource(gvr).Namespace(namespace).Watch(options)
+				if err != nil {
+					return nil, err
+				}
+				return i.watchRows(w), nil
+			},
+		},
+		&Row{},
+		resyncPeriod,
+		indexers,
+	)
+	return i
+}
+
+// Informer returns the informer of the rows.
+func (i *TableInformer) Informer() cache.SharedIndexInformer {
+	return i.informer
+}
+
+// Columns returns the column definitions of the rows.  They are updated
+// whenever the server sends new ones, typically when it lists the rows again,
+// before the rows matching them are delivered.
+func (i *TableInformer) Columns() []metav1.TableColumnDefinition {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	return i.columns
+}
+
+// Rows returns the cached rows, sorted by namespace and name.  The rows must
+// not be modified.
+func (i *TableInformer) Rows() []*Row {
+	objs := i.informer.GetStore().List()
+	rows := make([]*Row, 0, len(objs))
+	for _, obj := range objs {
+		rows = append(rows, obj.(*Row))
+	}
+	sort.Slice(rows, func(a, b int) bool {
+		if rows[a].Namespace != rows[b].Namespace {
+			return rows[a].Namespace < rows[b].Namespace
+		}
+		return rows[a].Name < rows[b].Name
+	})
+	return rows
+}
+
+func (i *TableInformer) setColumns(columns []metav1.TableColumnDefinition) {
+	if len(columns) == 0 {
+		return
+	}
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.columns = columns
+}
+
+// watchRows turns a watch of tables into a watch of their rows.
+func (i *TableInformer) watchRows(w watch.Interface) watch.Interface {
+	ch := make(chan watch.Event)
+	proxy := watch.NewProxyWatcher(ch)
+	go func() {
+		defer close(ch)
+		defer w.Stop()
+		for {
+			var event watch.Event
+			var ok bool
+			select {
+			case <-proxy.StopChan():
+				return
+			case event, ok = <-w.ResultChan():
+				if !ok {
+					return
+				}
+			}
+			for _, rowEvent := range i.rowEvents(event) {
+				select {
+				case <-proxy.StopChan():
+					return
+				case ch <- rowEvent:
+				}
+			}
+		}
+	}()
+	return proxy
+}
+
+// rowEvents returns an event for every row of the table of event.
+func (i *TableInformer) rowEvents(event watch.Event) []watch.Event {
+	if event.Type == watch.Error {
+		return []watch.Event{event}
+	}
+	t, ok := event.Object.(*metav1.Table)
+	if !ok {
+		// Bookmarks only need a resource version.
+		if accessor, err := meta.Accessor(event.Object); err == nil && event.Type == watch.Bookmark {
+			return []watch.Event{{Type: event.Type, Object: &Row{ObjectMeta: metav1.ObjectMeta{ResourceVersion: accessor.GetResourceVersion()}}}}
+		}
+		return []watch.Event{errorEvent(fmt.Errorf("unexpected object, expected Table but got %T", event.Object))}
+	}
+	i.setColumns(t.ColumnDefinitions)
+	if event.Type == watch.Bookmark && len(t.Rows) == 0 {
+		return []watch.Event{{Type: event.Type, Object: &Row{ObjectMeta: metav1.ObjectMeta{ResourceVersion: t.ResourceVersion}}}}
+	}
+	events := make([]watch.Event, 0, len(t.Rows))
+	for j := range t.Rows {
+		row, err := rowFor(&t.Rows[j])
+		if err != nil {
+			return append(events, errorEvent(err))
+		}
+		events = append(events, watch.Event{Type: event.Type, Object: row})
+	}
+	return events
+}
+
+func errorEvent(err error) watch.Event {
+	status := apierrors.NewInternalError(err).ErrStatus
+	return watch.Event{Type: watch.Error, Object: &status}
+}
+
+// RowEventHandlerFuncs is a cache.ResourceEventHandler for the rows of a
+// TableInformer, passing the cells that changed with every update.  Any of
+// the functions may be nil.
+type RowEventHandlerFuncs struct {
+	AddFunc func(row *Row)
+	// UpdateFunc is called with the indexes of the cells that differ
+	// between the rows, which is empty if only the metadata of the object
+	// changed or on resyncs.
+	UpdateFunc func(oldRow, newRow *Row, changedCells []int)
+	// DeleteFunc is called with the last known state of the row.
+	DeleteFunc func(row *Row)
+}
+
+var _ cache.ResourceEventHandler = RowEventHandlerFuncs{}
+
+// OnAdd calls AddFunc if it's not nil.
+func (r RowEventHandlerFuncs) OnAdd(obj interface{}) {
+	if r.AddFunc != nil {
+		r.AddFunc(obj.(*Row))
+	}
+}
+
+// OnUpdate calls UpdateFunc if it's not nil.
+func (r RowEventHandlerFuncs) OnUpdate(oldObj, newObj interface{}) {
+	if r.UpdateFunc != nil {
+		oldRow, newRow := oldObj.(*Row), newObj.(*Row)
+		r.UpdateFunc(oldRow, newRow, ChangedCells(oldRow, newRow))
+	}
+}
+
+// OnDelete calls DeleteFunc if it's not nil.
+func (r RowEventHandlerFuncs) OnDelete(obj interface{}) {
+	if r.DeleteFunc == nil {
+		return
+	}
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	if row, ok := obj.(*Row); ok {
+		r.DeleteFunc(row)
+	}
+}
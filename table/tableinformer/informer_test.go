@@ -0,0 +1,148 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tableinformer
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/table"
+	"k8s.io/client-go/tools/cache"
+)
+
+// fakeClient serves a fixed table, followed by the events of a fake watch.
+type fakeClient struct {
+	table   *metav1.Table
+	watcher *watch.FakeWatcher
+}
+
+func (c *fakeClient) Resource(schema.GroupVersionResource) table.Getter { return c }
+func (c *fakeClient) Namespace(string) table.ResourceInterface          { return c }
+func (c *fakeClient) List(metav1.ListOptions) (*metav1.Table, error)    { return c.table, nil }
+func (c *fakeClient) Watch(metav1.ListOptions) (watch.Interface, error) { return c.watcher, nil }
+
+func newTable(rv string, columns []metav1.TableColumnDefinition, rows ...metav1.TableRow) *metav1.Table {
+	return &metav1.Table{ListMeta: metav1.ListMeta{ResourceVersion: rv}, ColumnDefinitions: columns, Rows: rows}
+}
+
+func newRow(name, rv string, cells ...interface{}) metav1.TableRow {
+	return metav1.TableRow{
+		Cells:  cells,
+		Object: runtime.RawExtension{Raw: []byte(fmt.Sprintf(`{"metadata":{"name":%q,"namespace":"ns","resourceVersion":%q}}`, name, rv))},
+	}
+}
+
+func TestTableInformer(t *testing.T) {
+	columns := []metav1.TableColumnDefinition{{Name: "Name", Type: "string"}, {Name: "Status", Type: "string"}}
+	client := &fakeClient{
+		table:   newTable("2", columns, newRow("foo", "1", "foo", "Pending"), newRow("bar", "2", "bar", "Running")),
+		watcher: watch.NewFake(),
+	}
+	informer := NewTableInformer(client, schema.GroupVersionResource{Resource: "pods"}, "ns", 0, cache.Indexers{})
+
+	var lock sync.Mutex
+	var events []string
+	record := func(event string) {
+		lock.Lock()
+		defer lock.Unlock()
+		events = append(events, event)
+	}
+	informer.Informer().AddEventHandler(RowEventHandlerFuncs{
+		AddFunc: func(row *Row) { record(fmt.Sprintf("add %s %v", row.Name, row.Cells)) },
+		UpdateFunc: func(oldRow, newRow *Row, changedCells []int) {
+			record(fmt.Sprintf("update %s %v %v", newRow.Name, newRow.Cells, changedCells))
+		},
+		DeleteFunc: func(row *Row) { record(fmt.Sprintf("delete %s", row.Name)) },
+	})
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Informer().Run(stop)
+	if !cache.WaitForCacheSync(stop, informer.Informer().HasSynced) {
+		t.Fatalf("informer did not sync")
+	}
+	if got := informer.Columns(); !reflect.DeepEqual(got, columns) {
+		t.Errorf("expected columns %v, got %v", columns, got)
+	}
+
+	// Servers only send the columns with the first event.
+	client.watcher.Modify(newTable("", columns, newRow("foo", "3", "foo", "Running")))
+	client.watcher.Delete(newTable("", nil, newRow("bar", "4", "bar", "Running")))
+	client.watcher.Add(newTable("", nil, newRow("baz", "5", "baz", "Pending")))
+	client.watcher.Action(watch.Bookmark, newTable("6", nil))
+
+	err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		return informer.Informer().LastSyncResourceVersion() == "6", nil
+	})
+	if err != nil {
+		t.Fatalf("expected the bookmark to be handled, got resource version %q", informer.Informer().LastSyncResourceVersion())
+	}
+	err = wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(events) == 5, nil
+	})
+	expected := []string{
+		"add foo [foo Pending]",
+		"add bar [bar Running]",
+		"update foo [foo Running] [1]",
+		"delete bar",
+		"add baz [baz Pending]",
+	}
+	lock.Lock()
+	// The rows of the initial list are added in no particular order.
+	if len(events) == len(expected) && events[0] == expected[1] {
+		events[0], events[1] = events[1], events[0]
+	}
+	if err != nil || !reflect.DeepEqual(events, expected) {
+		t.Errorf("expected events %v, got %v", expected, events)
+	}
+	lock.Unlock()
+
+	var names []string
+	for _, row := range informer.Rows() {
+		names = append(names, row.Name)
+	}
+	if expected := []string{"baz", "foo"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected rows %v, got %v", expected, names)
+	}
+}
+
+func TestChangedCells(t *testing.T) {
+	row := func(cells ...interface{}) *Row { return &Row{Cells: cells} }
+	for _, test := range []struct {
+		oldRow, newRow *Row
+		expected       []int
+	}{
+		{row("a", int64(1)), row("a", int64(1)), nil},
+		{row("a", int64(1)), row("b", int64(2)), []int{0, 1}},
+		{row("a"), row("a", "b"), []int{1}},
+		{row("a", "b"), row("a"), []int{1}},
+	} {
+		if changed := ChangedCells(test.oldRow, test.newRow); !reflect.DeepEqual(changed, test.expected) {
+			t.Errorf("%v -> %v: expected %v, got %v", test.oldRow.Cells, test.newRow.Cells, test.expected, changed)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tableinformer
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Row is a row of a table, identified by the metadata of its object.  Its
+// cells match the column definitions of the informer that returned it.
+type Row struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Cells are the values of the columns of the row, as printed by the
+	// server.
+	Cells []interface{} `json:"cells"`
+	// Conditions describe additional status of the row.
+	Conditions []metav1.TableRowCondition `json:"conditions,omitempty"`
+}
+
+// RowList is a list of rows.
+type RowList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []Row `json:"items"`
+}
+
+// DeepCopyInto copies the receiver into out.
+func (in *Row) DeepCopyInto(out *Row) {
+	*out = *in
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	if in.Cells != nil {
+		out.Cells = make([]interface{}, len(in.Cells))
+		for i := range in.Cells {
+			out.Cells[i] = runtime.DeepCopyJSONValue(in.Cells[i])
+		}
+	}
+	if in.Conditions != nil {
+		out.Conditions = make([]metav1.TableRowCondition, len(in.Conditions))
+		copy(out.Conditions, in.Conditions)
+	}
+}
+
+// DeepCopy copies the receiver, creating a new Row.
+func (in *Row) DeepCopy() *Row {
+	if in == nil {
+		return nil
+	}
+	out := new(Row)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject copies the receiver, creating a new runtime.Object.
+func (in *Row) DeepCopyObject() runtime.Object {
+	return in.DeepCopy()
+}
+
+// DeepCopyObject copies the receiver, creating a new runtime.Object.
+func (in *RowList) DeepCopyObject() runtime.Object {
+	if in == nil {
+		return nil
+	}
+	out := new(RowList)
+	*out = *in
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		out.Items = make([]Row, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+	return out
+}
+
+// ChangedCells returns the indexes of the cells that differ between two
+// versions of a row, in increasing order.
+func ChangedCells(oldRow, newRow *Row) []int {
+	var changed []int
+	for i := 0; i < len(oldRow.Cells) || i < len(newRow.Cells); i++ {
+		if i >= len(oldRow.Cells) || i >= len(newRow.Cells) || !reflect.DeepEqual(oldRow.Cells[i], newRow.Cells[i]) {
+			changed = append(changed, i)
+		}
+	}
+	return changed
+}
+
+// rowFor returns the Row of a table row, which must include the metadata of
+// its object.
+func rowFor(tableRow *metav1.TableRow) (*Row, error) {
+	row := &Row{Cells: tableRow.Cells, Conditions: tableRow.Conditions}
+	switch {
+	case tableRow.Object.Object != nil:
+		accessor, err := meta.Accessor(tableRow.Object.Object)
+		if err != nil {
+			return nil, err
+		}
+		row.ObjectMeta = metav1.ObjectMeta{
+			Name:              accessor.GetName(),
+			Namespace:         accessor.GetNamespace(),
+			UID:               accessor.GetUID(),
+			ResourceVersion:   accessor.GetResourceVersion(),
+			Generation:        accessor.GetGeneration(),
+			CreationTimestamp: accessor.GetCreationTimestamp(),
+			DeletionTimestamp: accessor.GetDeletionTimestamp(),
+			Labels:            accessor.GetLabels(),
+			Annotations:       accessor.GetAnnotations(),
+			OwnerReferences:   accessor.GetOwnerReferences(),
+			Finalizers:        accessor.GetFinalizers(),
+		}
+	case len(tableRow.Object.Raw) > 0:
+		var partial metav1.PartialObjectMetadata
+		if err := json.Unmarshal(tableRow.Object.Raw, &partial); err != nil {
+			return nil, fmt.Errorf("unable to decode the object of a row as PartialObjectMetadata: %v", err)
+		}
+		row.ObjectMeta = partial.ObjectMeta
+	default:
+		return nil, fmt.Errorf("the rows of the table do not include their objects")
+	}
+	return row, nil
+}
+
+// rowsFor returns the rows of a table as a list.
+func rowsFor(table *metav1.Table) (*RowList, error) {
+	list := &RowList{ListMeta: table.ListMeta, Items: make([]Row, 0, len(table.Rows))}
+	for i := range table.Rows {
+		row, err := rowFor(&table.Rows[i])
+		if err != nil {
+			return nil, err
+		}
+		list.Items = append(list.Items, *row)
+	}
+	return list, nil
+}
@@ -0,0 +1,336 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// TrackerBehavior emulates a behavior of the apiserver on top of an
+// ObjectTracker.  Every field is optional.
+type TrackerBehavior struct {
+	// OnWrite is called before obj is stored by action.  old is the
+	// stored object, or nil if obj is being created.  OnWrite may modify
+	// obj, or reject the write with an error.
+	OnWrite func(action Action, old, obj runtime.Object) error
+	// OnDelete is called before the stored obj is deleted.  If it returns
+	// true, obj, which OnDelete may have modified, is stored instead.
+	OnDelete func(obj runtime.Object) (keep bool, err error)
+	// Remove is called after obj was written and returns true if the
+	// object must be deleted right away.
+	Remove func(obj runtime.Object) bool
+}
+
+// ObjectReactionWithBehaviors returns a ReactionFunc like ObjectReaction that
+// also applies the given behaviors to every create, update, patch and delete.
+// For example
+//
+//	fake.PrependReactor("*", "*", ObjectReactionWithBehaviors(tracker,
+//	        ResourceVersionBehavior(), GenerationBehavior(), FinalizerBehavior()))
+//
+// makes the fake bump resource versions and generations and keep objects
+// with finalizers until the finalizers are removed.
+func ObjectReactionWithBehaviors(tracker ObjectTracker, behaviors ...TrackerBehavior) ReactionFunc {
+	return func(action Action) (bool, runtime.Object, error) {
+		return ObjectReaction(&behaviorTracker{ObjectTracker: tracker, behaviors: behaviors, action: action})(action)
+	}
+}
+
+// behaviorTracker applies behaviors to the writes of a single action.
+type behaviorTracker struct {
+	ObjectTracker
+	behaviors []TrackerBehavior
+	action    Action
+
+	// removed is the last state of an object deleted by a Remove behavior,
+	// returned by Get so that the action still returns the object.
+	removed runtime.Object
+}
+
+func (t *behaviorTracker) Get(gvr schema.GroupVersionResource, ns, name string) (runtime.Object, error) {
+	if t.removed != nil {
+		if objMeta, err := meta.Accessor(t.removed); err == nil && objMeta.GetName() == name {
+			return t.removed.DeepCopyObject(), nil
+		}
+	}
+	return t.ObjectTracker.Get(gvr, ns, name)
+}
+
+func (t *behaviorTracker) Create(gvr schema.GroupVersionResource, obj runtime.Object, ns string) error {
+	for _, behavior := range t.behaviors {
+		if behavior.OnWrite == nil {
+			continue
+		}
+		if err := behavior.OnWrite(t.action, nil, obj); err != nil {
+			return err
+		}
+	}
+	return t.ObjectTracker.Create(gvr, obj, ns)
+}
+
+func (t *behaviorTracker) Update(gvr schema.GroupVersionResource, obj runtime.Object, ns string) error {
+	objMeta, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	old, err := t.ObjectTracker.Get(gvr, ns, objMeta.GetName())
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	for _, behavior := range t.behaviors {
+		if behavior.OnWrite == nil {
+			continue
+		}
+		if err := behavior.OnWrite(t.action, old, obj); err != nil {
+			return err
+		}
+	}
+	for _, behavior := range t.behaviors {
+		if behavior.Remove != nil && behavior.Remove(obj) {
+			t.removed = obj.DeepCopyObject()
+			return t.ObjectTracker.Delete(gvr, ns, objMeta.GetName())
+		}
+	}
+	return t.ObjectTracker.Update(gvr, obj, ns)
+}
+
+func (t *behaviorTracker) Delete(gvr schema.GroupVersionResource, ns, name string) error {
+	obj, err := t.ObjectTracker.Get(gvr, ns, name)
+	if err != nil {
+		return err
+	}
+	for _, behavior := range t.behaviors {
+		if behavior.OnDelete == nil {
+			continue
+		}
+		keep, err := behavior.OnDelete(obj)
+		if err != nil {
+			return err
+		}
+		if keep {
+			return t.Update(gvr, obj, ns)
+		}
+	}
+	return t.ObjectTracker.Delete(gvr, ns, name)
+}
+
+// ResourceVersionBehavior assigns every written object a new, increasing
+// resource version, and rejects updates carrying a resource version other
+// than the stored one with a Conflict error.  Updates without a resource
+// version are unconditional.
+func ResourceVersionBehavior() TrackerBehavior {
+	var lock sync.Mutex
+	var last uint64
+	return TrackerBehavior{
+		OnWrite: func(action Action, old, obj runtime.Object) error {
+			objMeta, err := meta.Accessor(obj)
+			if err != nil {
+				return err
+			}
+			if old != nil {
+				oldMeta, err := meta.Accessor(old)
+				if err != nil {
+					return err
+				}
+				if rv := objMeta.GetResourceVersion(); len(rv) > 0 && rv != oldMeta.GetResourceVersion() {
+					return errors.NewConflict(action.GetResource().GroupResource(), objMeta.GetName(),
+						fmt.Errorf("the object has been modified; please apply your changes to the latest version and try again"))
+				}
+			}
+			lock.Lock()
+			last++
+			rv := last
+			lock.Unlock()
+			objMeta.SetResourceVersion(strconv.FormatUint(rv, 10))
+			return nil
+		},
+	}
+}
+
+// GenerationBehavior sets the generation of created objects to 1 and
+// increments it whenever an update changes anything but the metadata and
+// status of an object.
+func GenerationBehavior() TrackerBehavior {
+	return TrackerBehavior{
+		OnWrite: func(action Action, old, obj runtime.Object) error {
+			objMeta, err := meta.Accessor(obj)
+			if err != nil {
+				return err
+			}
+			if old == nil {
+				objMeta.SetGeneration(1)
+				return nil
+			}
+			oldMeta, err := meta.Accessor(old)
+			if err != nil {
+				return err
+			}
+			oldContent, err := specContent(old)
+			if err != nil {
+				return err
+			}
+			newContent, err := specContent(obj)
+			if err != nil {
+				return err
+			}
+			generation := oldMeta.GetGeneration()
+			if !equality.Semantic.DeepEqual(oldContent, newContent) {
+				generation++
+			}
+			objMeta.SetGeneration(generation)
+			return nil
+		},
+	}
+}
+
+// specContent returns the content of obj without its type, metadata and
+// status.
+func specContent(obj runtime.Object) (map[string]interface{}, error) {
+	content, err := toUnstructuredContent(obj)
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range []string{"apiVersion", "kind", "metadata", "status"} {
+		delete(content, field)
+	}
+	return content, nil
+}
+
+// FinalizerBehavior emulates graceful deletion of objects with finalizers:
+// deleting such an object only sets its deletion timestamp, the timestamp
+// cannot be cleared, and the object is deleted once an update removes its
+// last finalizer.
+func FinalizerBehavior() TrackerBehavior {
+	return TrackerBehavior{
+		OnWrite: func(action Action, old, obj runtime.Object) error {
+			if old == nil {
+				return nil
+			}
+			oldMeta, err := meta.Accessor(old)
+			if err != nil {
+				return err
+			}
+			objMeta, err := meta.Accessor(obj)
+			if err != nil {
+				return err
+			}
+			if oldMeta.GetDeletionTimestamp() != nil {
+				objMeta.SetDeletionTimestamp(oldMeta.GetDeletionTimestamp())
+			}
+			return nil
+		},
+		OnDelete: func(obj runtime.Object) (bool, error) {
+			objMeta, err := meta.Accessor(obj)
+			if err != nil {
+				return false, err
+			}
+			if len(objMeta.GetFinalizers()) == 0 {
+				return false, nil
+			}
+			if objMeta.GetDeletionTimestamp() == nil {
+				now := metav1.Now()
+				objMeta.SetDeletionTimestamp(&now)
+			}
+			return true, nil
+		},
+		Remove: func(obj runtime.Object) bool {
+			objMeta, err := meta.Accessor(obj)
+			if err != nil {
+				return false
+			}
+			return objMeta.GetDeletionTimestamp() != nil && len(objMeta.GetFinalizers()) == 0
+		},
+	}
+}
+
+// StatusSubresourceBehavior isolates the status of the given resources like
+// the status subresource does: updates and patches of the resource keep the
+// stored status, and updates and patches of the status subresource change
+// only the status.
+func StatusSubresourceBehavior(resources ...string) TrackerBehavior {
+	withStatus := sets.NewString(resources...)
+	return TrackerBehavior{
+		OnWrite: func(action Action, old, obj runtime.Object) error {
+			if old == nil || !withStatus.Has(action.GetResource().Resource) {
+				return nil
+			}
+			oldContent, err := toUnstructuredContent(old)
+			if err != nil {
+				return err
+			}
+			newContent, err := toUnstructuredContent(obj)
+			if err != nil {
+				return err
+			}
+
+			var content map[string]interface{}
+			switch action.GetSubresource() {
+			case "":
+				content = newContent
+				setOrDelete(content, "status", oldContent["status"])
+			case "status":
+				content = oldContent
+				setOrDelete(content, "status", newContent["status"])
+				// Keep the resource version the update was made against.
+				if rv, found, _ := unstructured.NestedString(newContent, "metadata", "resourceVersion"); found {
+					unstructured.SetNestedField(content, rv, "metadata", "resourceVersion")
+				}
+			default:
+				return nil
+			}
+			return fromUnstructuredContent(content, obj)
+		},
+	}
+}
+
+func setOrDelete(content map[string]interface{}, field string, value interface{}) {
+	if value == nil {
+		delete(content, field)
+		return
+	}
+	content[field] = value
+}
+
+func toUnstructuredContent(obj runtime.Object) (map[string]interface{}, error) {
+	if u, ok := obj.(runtime.Unstructured); ok {
+		return runtime.DeepCopyJSON(u.UnstructuredContent()), nil
+	}
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+}
+
+// fromUnstructuredContent replaces the content of obj.
+func fromUnstructuredContent(content map[string]interface{}, obj runtime.Object) error {
+	if u, ok := obj.(runtime.Unstructured); ok {
+		u.SetUnstructuredContent(content)
+		return nil
+	}
+	value := reflect.ValueOf(obj)
+	value.Elem().Set(reflect.New(value.Type().Elem()).Elem())
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(content, obj)
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	serializer "k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func newBehaviorTestFake(behaviors ...TrackerBehavior) (*Fake, ObjectTracker) {
+	scheme := runtime.NewScheme()
+	codecs := serializer.NewCodecFactory(scheme)
+	tracker := NewObjectTracker(scheme, codecs.UniversalDecoder())
+	f := &Fake{}
+	f.AddReactor("*", "*", ObjectReactionWithBehaviors(tracker, behaviors...))
+	return f, tracker
+}
+
+func newBehaviorTestObject(name string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Widget",
+		"metadata":   map[string]interface{}{"name": name, "namespace": "ns"},
+		"spec":       map[string]interface{}{"size": "small"},
+	}}
+}
+
+var behaviorTestResource = schema.GroupVersionResource{Version: "v1", Resource: "widgets"}
+
+func TestResourceVersionAndGenerationBehaviors(t *testing.T) {
+	f, _ := newBehaviorTestFake(ResourceVersionBehavior(), GenerationBehavior())
+
+	obj, err := f.Invokes(NewCreateAction(behaviorTestResource, "ns", newBehaviorTestObject("widget")), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	created := obj.(*unstructured.Unstructured)
+	if created.GetResourceVersion() != "1" || created.GetGeneration() != 1 {
+		t.Errorf("unexpected resource version %q and generation %d", created.GetResourceVersion(), created.GetGeneration())
+	}
+
+	labeled := created.DeepCopy()
+	labeled.SetLabels(map[string]string{"a": "b"})
+	obj, err = f.Invokes(NewUpdateAction(behaviorTestResource, "ns", labeled), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u := obj.(*unstructured.Unstructured); u.GetResourceVersion() != "2" || u.GetGeneration() != 1 {
+		t.Errorf("expected a metadata change to keep the generation, got %q and %d", u.GetResourceVersion(), u.GetGeneration())
+	}
+
+	if _, err := f.Invokes(NewUpdateAction(behaviorTestResource, "ns", labeled), nil); !errors.IsConflict(err) {
+		t.Errorf("expected an update with a stale resource version to conflict, got %v", err)
+	}
+
+	obj, err = f.Invokes(NewPatchAction(behaviorTestResource, "ns", "widget", types.MergePatchType, []byte(`{"spec":{"size":"large"}}`)), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u := obj.(*unstructured.Unstructured); u.GetResourceVersion() != "3" || u.GetGeneration() != 2 {
+		t.Errorf("expected a spec change to bump the generation, got %q and %d", u.GetResourceVersion(), u.GetGeneration())
+	}
+}
+
+func TestFinalizerBehavior(t *testing.T) {
+	f, tracker := newBehaviorTestFake(ResourceVersionBehavior(), FinalizerBehavior())
+
+	obj := newBehaviorTestObject("widget")
+	obj.SetFinalizers([]string{"example.com/cleanup"})
+	if _, err := f.Invokes(NewCreateAction(behaviorTestResource, "ns", obj), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := f.Invokes(NewDeleteAction(behaviorTestResource, "ns", "widget"), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stored, err := tracker.Get(behaviorTestResource, "ns", "widget")
+	if err != nil {
+		t.Fatalf("expected the object to be kept until finalized: %v", err)
+	}
+	terminating := stored.(*unstructured.Unstructured)
+	if terminating.GetDeletionTimestamp() == nil {
+		t.Fatalf("expected the deletion timestamp to be set")
+	}
+
+	terminating.SetFinalizers(nil)
+	obj2, err := f.Invokes(NewUpdateAction(behaviorTestResource, "ns", terminating), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if obj2.(*unstructured.Unstructured).GetName() != "widget" {
+		t.Errorf("expected the final state to be returned, got %v", obj2)
+	}
+	if _, err := tracker.Get(behaviorTestResource, "ns", "widget"); !errors.IsNotFound(err) {
+		t.Errorf("expected the object to be deleted once finalized, got %v", err)
+	}
+}
+
+func TestStatusSubresourceBehavior(t *testing.T) {
+	f, tracker := newBehaviorTestFake(StatusSubresourceBehavior("widgets"))
+
+	obj := newBehaviorTestObject("widget")
+	obj.Object["status"] = map[string]interface{}{"ready": false}
+	if _, err := f.Invokes(NewCreateAction(behaviorTestResource, "ns", obj), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	update := obj.DeepCopy()
+	update.Object["spec"] = map[string]interface{}{"size": "large"}
+	update.Object["status"] = map[string]interface{}{"ready": true}
+	if _, err := f.Invokes(NewUpdateAction(behaviorTestResource, "ns", update), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stored, _ := tracker.Get(behaviorTestResource, "ns", "widget")
+	if ready, _, _ := unstructured.NestedBool(stored.(*unstructured.Unstructured).Object, "status", "ready"); ready {
+		t.Errorf("expected an update of the resource to keep the status")
+	}
+
+	update = obj.DeepCopy()
+	update.Object["spec"] = map[string]interface{}{"size": "tiny"}
+	update.Object["status"] = map[string]interface{}{"ready": true}
+	if _, err := f.Invokes(NewUpdateSubresourceAction(behaviorTestResource, "status", "ns", update), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stored, _ = tracker.Get(behaviorTestResource, "ns", "widget")
+	content := stored.(*unstructured.Unstructured).Object
+	if ready, _, _ := unstructured.NestedBool(content, "status", "ready"); !ready {
+		t.Errorf("expected an update of the status subresource to change the status")
+	}
+	if size, _, _ := unstructured.NestedString(content, "spec", "size"); size != "large" {
+		t.Errorf("expected an update of the status subresource to keep the spec, got %q", size)
+	}
+}
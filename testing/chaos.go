@@ -0,0 +1,250 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// LatencyFunc returns the latency to inject into a request, drawn from rng.
+type LatencyFunc func(rng *rand.Rand) time.Duration
+
+// UniformLatency returns a LatencyFunc drawing latencies uniformly from [min, max).
+func UniformLatency(min, max time.Duration) LatencyFunc {
+	return func(rng *rand.Rand) time.Duration {
+		if max <= min {
+			return min
+		}
+		return min + time.Duration(rng.Int63n(int64(max-min)))
+	}
+}
+
+// ExponentialLatency returns a LatencyFunc drawing exponentially distributed
+// latencies with the given mean, so that most requests are fast and a few are
+// very slow.
+func ExponentialLatency(mean time.Duration) LatencyFunc {
+	return func(rng *rand.Rand) time.Duration {
+		return time.Duration(rng.ExpFloat64() * float64(mean))
+	}
+}
+
+// ChaosConfig describes the faults a fake client injects into the requests
+// matching Verb and Resource.  Rates are probabilities between 0 and 1.
+type ChaosConfig struct {
+	// Verb and Resource select the affected requests like the arguments
+	// of AddReactor; "*" matches everything.  Resource also selects the
+	// affected watches.
+	Verb     string
+	Resource string
+
+	// Seed seeds the random decisions, so that a failing run can be
+	// reproduced.
+	Seed int64
+
+	// Latency, if set, delays every matching request.  The fake client
+	// serializes requests, so latency delays other requests as well.
+	Latency LatencyFunc
+
+	// ErrorRate is the probability that a matching request fails with the
+	// error returned by Error.
+	ErrorRate float64
+	// Error returns the error to inject for action.  It defaults to a
+	// ServiceUnavailable error.
+	Error func(action Action) error
+
+	// ConflictRate is the probability that a matching update or patch
+	// fails with a Conflict error, as if the object was modified
+	// concurrently.
+	ConflictRate float64
+
+	// WatchErrorRate is the probability that establishing a watch fails.
+	WatchErrorRate float64
+	// WatchHiccupRate is the probability that a watch is closed by the
+	// "server" after delivering an event, forcing the client to rewatch.
+	// The object tracker does not replay events to new watches, so
+	// changes made before the rewatch are only seen on the next relist.
+	WatchHiccupRate float64
+}
+
+// chaos injects the faults of a ChaosConfig.
+type chaos struct {
+	config ChaosConfig
+	fake   *Fake
+
+	lock sync.Mutex
+	rng  *rand.Rand
+}
+
+// AddChaos prepends reactors to c that inject the faults described by
+// config, so that tests can exercise retry and conflict handling without
+// writing a reactor for each case.  Requests that are not failed continue
+// down the reaction chain.
+func (c *Fake) AddChaos(config ChaosConfig) {
+	if len(config.Verb) == 0 {
+		config.Verb = "*"
+	}
+	if len(config.Resource) == 0 {
+		config.Resource = "*"
+	}
+	ch := &chaos{config: config, fake: c, rng: rand.New(rand.NewSource(config.Seed))}
+	c.PrependReactor(config.Verb, config.Resource, ch.react)
+	if config.WatchErrorRate > 0 || config.WatchHiccupRate > 0 {
+		c.WatchReactionChain = append([]WatchReactor{&chaosWatchReactor{chaos: ch}}, c.WatchReactionChain...)
+	}
+}
+
+// happens returns true with the given probability.
+func (ch *chaos) happens(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	ch.lock.Lock()
+	defer ch.lock.Unlock()
+	return ch.rng.Float64() < rate
+}
+
+func (ch *chaos) latency() time.Duration {
+	ch.lock.Lock()
+	defer ch.lock.Unlock()
+	return ch.config.Latency(ch.rng)
+}
+
+func (ch *chaos) react(action Action) (bool, runtime.Object, error) {
+	if ch.config.Latency != nil {
+		time.Sleep(ch.latency())
+	}
+	if ch.happens(ch.config.ErrorRate) {
+		if ch.config.Error != nil {
+			return true, nil, ch.config.Error(action)
+		}
+		return true, nil, errors.NewServiceUnavailable(fmt.Sprintf("injected failure of %s %s", action.GetVerb(), action.GetResource().Resource))
+	}
+	if (action.GetVerb() == "update" || action.GetVerb() == "patch") && ch.happens(ch.config.ConflictRate) {
+		return true, nil, errors.NewConflict(action.GetResource().GroupResource(), actionName(action), fmt.Errorf("injected conflict"))
+	}
+	return false, nil, nil
+}
+
+// actionName returns the name of the object an update or patch acts on.
+func actionName(action Action) string {
+	switch a := action.(type) {
+	case PatchAction:
+		return a.GetName()
+	case UpdateAction:
+		if objMeta, err := meta.Accessor(a.GetObject()); err == nil {
+			return objMeta.GetName()
+		}
+	}
+	return ""
+}
+
+// chaosWatchReactor fails watches or wraps the watches of the reactors
+// after it so that they close early.
+type chaosWatchReactor struct {
+	*chaos
+}
+
+func (r *chaosWatchReactor) Handles(action Action) bool {
+	resource := r.config.Resource
+	return resource == "*" || resource == action.GetResource().Resource
+}
+
+// React is called by InvokesWatch, which holds the lock of the fake, so the
+// rest of the chain can be read directly.
+func (r *chaosWatchReactor) React(action Action) (bool, watch.Interface, error) {
+	if r.happens(r.config.WatchErrorRate) {
+		return true, nil, errors.NewServiceUnavailable(fmt.Sprintf("injected failure of watch %s", action.GetResource().Resource))
+	}
+	found := false
+	for _, reactor := range r.fake.WatchReactionChain {
+		if reactor == WatchReactor(r) {
+			found = true
+			continue
+		}
+		if !found || !reactor.Handles(action) {
+			continue
+		}
+		handled, ret, err := reactor.React(action)
+		if !handled {
+			continue
+		}
+		if err != nil || r.config.WatchHiccupRate <= 0 {
+			return true, ret, err
+		}
+		return true, newChaosWatcher(ret, r.chaos), nil
+	}
+	return false, nil, nil
+}
+
+// chaosWatcher forwards the events of a watch until a hiccup closes it.
+type chaosWatcher struct {
+	source watch.Interface
+	chaos  *chaos
+	result chan watch.Event
+	done   chan struct{}
+	once   sync.Once
+}
+
+func newChaosWatcher(source watch.Interface, ch *chaos) *chaosWatcher {
+	w := &chaosWatcher{
+		source: source,
+		chaos:  ch,
+		result: make(chan watch.Event),
+		done:   make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+func (w *chaosWatcher) run() {
+	defer close(w.result)
+	defer w.source.Stop()
+	for {
+		select {
+		case event, ok := <-w.source.ResultChan():
+			if !ok {
+				return
+			}
+			select {
+			case w.result <- event:
+			case <-w.done:
+				return
+			}
+			if w.chaos.happens(w.chaos.config.WatchHiccupRate) {
+				return
+			}
+		case <-w.done:
+			return
+		}
+	}
+}
+
+func (w *chaosWatcher) Stop() {
+	w.once.Do(func() { close(w.done) })
+}
+
+func (w *chaosWatcher) ResultChan() <-chan watch.Event {
+	return w.result
+}
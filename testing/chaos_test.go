@@ -0,0 +1,116 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func TestChaosErrorsAndConflicts(t *testing.T) {
+	testResource := schema.GroupVersionResource{Version: "v1", Resource: "widgets"}
+	testObj := getArbitraryResource(testResource, "widget", "ns")
+
+	f := &Fake{}
+	f.AddReactor("*", "*", func(action Action) (bool, runtime.Object, error) {
+		return true, testObj, nil
+	})
+	f.AddChaos(ChaosConfig{Seed: 1, ErrorRate: 0.3, ConflictRate: 0.5})
+
+	var unavailable, conflicts, succeeded int
+	for i := 0; i < 200; i++ {
+		_, err := f.Invokes(NewUpdateAction(testResource, "ns", testObj), nil)
+		switch {
+		case err == nil:
+			succeeded++
+		case errors.IsServiceUnavailable(err):
+			unavailable++
+		case errors.IsConflict(err):
+			conflicts++
+			if details := err.(*errors.StatusError).ErrStatus.Details; details.Name != "widget" {
+				t.Errorf("expected the conflict to name the object, got %q", details.Name)
+			}
+		default:
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if unavailable == 0 || conflicts == 0 || succeeded == 0 {
+		t.Errorf("expected a mix of outcomes, got %d unavailable, %d conflicts, %d succeeded", unavailable, conflicts, succeeded)
+	}
+
+	for i := 0; i < 50; i++ {
+		if _, err := f.Invokes(NewGetAction(testResource, "ns", "widget"), nil); errors.IsConflict(err) {
+			t.Fatalf("expected conflicts only for updates and patches")
+		}
+	}
+}
+
+func TestChaosLatency(t *testing.T) {
+	f := &Fake{}
+	f.AddReactor("*", "*", func(action Action) (bool, runtime.Object, error) { return true, nil, nil })
+	f.AddChaos(ChaosConfig{Latency: UniformLatency(20*time.Millisecond, 30*time.Millisecond)})
+
+	start := time.Now()
+	f.Invokes(NewGetAction(schema.GroupVersionResource{Resource: "widgets"}, "ns", "widget"), nil)
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("expected the request to be delayed, took %v", elapsed)
+	}
+}
+
+func TestChaosWatchHiccups(t *testing.T) {
+	testResource := schema.GroupVersionResource{Version: "v1", Resource: "widgets"}
+	source := watch.NewFake()
+	f := &Fake{}
+	f.AddWatchReactor("*", DefaultWatchReactor(source, nil))
+	f.AddChaos(ChaosConfig{WatchHiccupRate: 1})
+
+	w, err := f.InvokesWatch(NewWatchAction(testResource, "ns", metav1.ListOptions{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	go source.Add(getArbitraryResource(testResource, "widget", "ns"))
+	select {
+	case event := <-w.ResultChan():
+		if event.Type != watch.Added {
+			t.Errorf("expected the event to be delivered, got %v", event.Type)
+		}
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatalf("timed out waiting for the event")
+	}
+	select {
+	case _, ok := <-w.ResultChan():
+		if ok {
+			t.Errorf("expected the watch to be closed after the hiccup")
+		}
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatalf("timed out waiting for the watch to close")
+	}
+
+	f = &Fake{}
+	f.AddWatchReactor("*", DefaultWatchReactor(watch.NewFake(), nil))
+	f.AddChaos(ChaosConfig{WatchErrorRate: 1})
+	if _, err := f.InvokesWatch(NewWatchAction(testResource, "ns", metav1.ListOptions{})); !errors.IsServiceUnavailable(err) {
+		t.Errorf("expected the watch to fail, got %v", err)
+	}
+}
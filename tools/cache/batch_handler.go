@@ -0,0 +1,160 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"time"
+)
+
+// defaultBatchSize is the size of the batches of a
+// BatchResourceEventHandler unless HandlerOptions.BatchSize is set.
+const defaultBatchSize = 100
+
+// ObjectUpdate is an update of a batch, see BatchResourceEventHandler.
+type ObjectUpdate struct {
+	Old interface{}
+	New interface{}
+}
+
+// BatchResourceEventHandler is implemented by the event handlers that handle
+// notifications in batches, for example to write them to a database in one
+// transaction.  The shared informers hand it consecutive notifications of
+// the same kind together, in order, up to HandlerOptions.BatchSize
+// notifications and waiting up to HandlerOptions.BatchDelay after the first
+// one.  Its ResourceEventHandler methods are not called, except by the
+// informers that do not batch.  Batches are not used for the handlers run on
+// workers, see HandlerOptions.Workers, and PanicHandler does not apply to
+// them.
+type BatchResourceEventHandler interface {
+	ResourceEventHandler
+	// OnAddBatch is called with added objects.
+	OnAddBatch(objs []interface{})
+	// OnUpdateBatch is called with updated objects.
+	OnUpdateBatch(updates []ObjectUpdate)
+	// OnDeleteBatch is called with deleted objects, which can be
+	// DeletedFinalStateUnknown.
+	OnDeleteBatch(objs []interface{})
+}
+
+// handleBatches hands the notifications of nextCh to the batch handler until
+// nextCh is closed.
+func (p *processorListener) handleBatches() {
+	var batch []interface{}
+	var timer *time.Timer
+	var timeout <-chan time.Time
+	flush := func() {
+		if timer != nil {
+			timer.Stop()
+			timer, timeout = nil, nil
+		}
+		if len(batch) == 0 {
+			return
+		}
+		deliverBatch(p.batchHandler, batch)
+		for range batch {
+			p.countHandled()
+		}
+		batch = nil
+	}
+
+	for {
+		var next interface{}
+		var ok bool
+		if len(batch) > 0 && p.batchDelay <= 0 {
+			// Only take the notifications that are ready.
+			select {
+			case next, ok = <-p.nextCh:
+			default:
+				flush()
+				continue
+			}
+		} else {
+			select {
+			case next, ok = <-p.nextCh:
+			case <-timeout:
+				flush()
+				continue
+			}
+		}
+		if !ok {
+			flush()
+			return
+		}
+		switch next.(type) {
+		case addNotification, updateNotification, deleteNotification:
+		default:
+			flush()
+			p.handle(next)
+			p.countHandled()
+			continue
+		}
+		if len(batch) > 0 && !sameNotificationKind(batch[0], next) {
+			flush()
+		}
+		batch = append(batch, next)
+		if len(batch) == 1 && p.batchDelay > 0 {
+			timer = time.NewTimer(p.batchDelay)
+			timeout = timer.C
+		}
+		if len(batch) >= p.batchSize {
+			flush()
+		}
+	}
+}
+
+// sameNotificationKind returns true if a and b are both adds, updates or
+// deletes.
+func sameNotificationKind(a, b interface{}) bool {
+	switch a.(type) {
+	case addNotification:
+		_, ok := b.(addNotification)
+		return ok
+	case updateNotification:
+		_, ok := b.(updateNotification)
+		return ok
+	case deleteNotification:
+		_, ok := b.(deleteNotification)
+		return ok
+	}
+	return false
+}
+
+// deliverBatch calls the method of handler for a batch of notifications of
+// the same kind.
+func deliverBatch(handler BatchResourceEventHandler, batch []interface{}) {
+	switch batch[0].(type) {
+	case addNotification:
+		objs := make([]interface{}, len(batch))
+		for i, n := range batch {
+			objs[i] = n.(addNotification).newObj
+		}
+		handler.OnAddBatch(objs)
+	case updateNotification:
+		updates := make([]ObjectUpdate, len(batch))
+		for i, n := range batch {
+			update := n.(updateNotification)
+			updates[i] = ObjectUpdate{Old: update.oldObj, New: update.newObj}
+		}
+		handler.OnUpdateBatch(updates)
+	case deleteNotification:
+		objs := make([]interface{}, len(batch))
+		for i, n := range batch {
+			objs[i] = n.(deleteNotification).oldObj
+		}
+		handler.OnDeleteBatch(objs)
+	}
+}
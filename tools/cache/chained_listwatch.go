@@ -0,0 +1,253 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// ChainedTransformFunc converts an object from the source informer's cache
+// into the object stored by the chained informer.  It must not modify its
+// argument, which is shared with the source informer's cache.
+type ChainedTransformFunc func(obj interface{}) (runtime.Object, error)
+
+// chainedListWatch is a ListerWatcher that serves the contents and events of
+// another informer instead of talking to the apiserver.
+type chainedListWatch struct {
+	source    SharedInformer
+	filter    func(obj interface{}) bool
+	transform ChainedTransformFunc
+
+	lock sync.Mutex
+	cond *sync.Cond
+	// listed is set once the first List has been served; events received
+	// before that are covered by the List and are dropped.
+	listed bool
+	// pending holds the events not yet consumed by a watch.  It survives
+	// watch restarts, so no event is lost between two watches.
+	pending []watch.Event
+
+	// active is the most recently started watch.
+	active *chainedWatcher
+}
+
+// NewChainedListWatch returns a ListerWatcher that seeds its initial list from
+// the cache of source, an informer for the same resource, and then follows
+// source's events instead of opening a watch of its own.  This lets several
+// differently filtered or transformed informers in one binary share a single
+// LIST and watch against the apiserver.
+//
+// Only objects for which filter returns true are served; a nil filter accepts
+// every object.  An update that moves an object out of the filter is served as
+// a deletion, and one that moves it in as an addition.  transform, if not nil,
+// converts each served object and must preserve its ObjectMeta.
+//
+// The returned ListerWatcher must be used by a single reflector, and List
+// fails until source has synced.
+func NewChainedListWatch(source SharedInformer, filter func(obj interface{}) bool, transform ChainedTransformFunc) ListerWatcher {
+	c := &chainedListWatch{
+		source:    source,
+		filter:    filter,
+		transform: transform,
+	}
+	c.cond = sync.NewCond(&c.lock)
+	source.AddEventHandler(ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if c.accepts(obj) {
+				c.enqueue(watch.Added, obj)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			wasAccepted, isAccepted := c.accepts(oldObj), c.accepts(newObj)
+			switch {
+			case wasAccepted && isAccepted:
+				c.enqueue(watch.Modified, newObj)
+			case isAccepted:
+				c.enqueue(watch.Added, newObj)
+			case wasAccepted:
+				c.enqueue(watch.Deleted, newObj)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if c.accepts(obj) {
+				c.enqueue(watch.Deleted, obj)
+			}
+		},
+	})
+	return c
+}
+
+func (c *chainedListWatch) accepts(obj interface{}) bool {
+	return c.filter == nil || c.filter(obj)
+}
+
+func (c *chainedListWatch) convert(obj interface{}) (runtime.Object, error) {
+	if c.transform != nil {
+		return c.transform(obj)
+	}
+	runtimeObj, ok := obj.(runtime.Object)
+	if !ok {
+		return nil, fmt.Errorf("expected a runtime.Object from the source informer, got %T", obj)
+	}
+	return runtimeObj, nil
+}
+
+func (c *chainedListWatch) enqueue(eventType watch.EventType, obj interface{}) {
+	converted, err := c.convert(obj)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("unable to convert %v event from source informer: %v", eventType, err))
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if !c.listed {
+		return
+	}
+	c.pending = append(c.pending, watch.Event{Type: eventType, Object: converted})
+	c.cond.Broadcast()
+}
+
+// List returns the accepted objects of the source informer's cache, at the
+// resource version the source last synced to.  Options are ignored.
+func (c *chainedListWatch) List(options metav1.ListOptions) (runtime.Object, error) {
+	if !c.source.HasSynced() {
+		return nil, fmt.Errorf("source informer has not synced yet")
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	list := &metav1.List{
+		ListMeta: metav1.ListMeta{ResourceVersion: c.source.LastSyncResourceVersion()},
+	}
+	for _, obj := range c.source.GetStore().List() {
+		if !c.accepts(obj) {
+			continue
+		}
+		converted, err := c.convert(obj)
+		if err != nil {
+			return nil, err
+		}
+		list.Items = append(list.Items, runtime.RawExtension{Object: converted})
+	}
+	// Everything queued so far is part of the list.
+	c.pending = nil
+	c.listed = true
+	return list, nil
+}
+
+// Watch streams the source informer's events received since the last List,
+// or since the previous watch stopped.  Options are ignored.
+func (c *chainedListWatch) Watch(options metav1.ListOptions) (watch.Interface, error) {
+	c.lock.Lock()
+	previous := c.active
+	c.lock.Unlock()
+	if previous != nil {
+		// Wait for the previous watch to hand back its undelivered event, so
+		// the new one starts exactly where it stopped.
+		previous.Stop()
+		<-previous.exited
+	}
+
+	w := &chainedWatcher{
+		source: c,
+		result: make(chan watch.Event),
+		done:   make(chan struct{}),
+		exited: make(chan struct{}),
+	}
+	c.lock.Lock()
+	c.active = w
+	c.lock.Unlock()
+	go w.run()
+	return w, nil
+}
+
+// next blocks until an event is pending or done is closed.
+func (c *chainedListWatch) next(done <-chan struct{}) (watch.Event, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for {
+		select {
+		case <-done:
+			return watch.Event{}, false
+		default:
+		}
+		if len(c.pending) > 0 {
+			event := c.pending[0]
+			c.pending = c.pending[1:]
+			return event, true
+		}
+		c.cond.Wait()
+	}
+}
+
+// requeue puts back an event that a stopped watch did not deliver.
+func (c *chainedListWatch) requeue(event watch.Event) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.pending = append([]watch.Event{event}, c.pending...)
+	c.cond.Broadcast()
+}
+
+type chainedWatcher struct {
+	source   *chainedListWatch
+	result   chan watch.Event
+	done     chan struct{}
+	exited   chan struct{}
+	stopOnce sync.Once
+}
+
+func (w *chainedWatcher) run() {
+	defer close(w.exited)
+	defer close(w.result)
+	for {
+		event, ok := w.source.next(w.done)
+		if !ok {
+			return
+		}
+		select {
+		case w.result <- event:
+		case <-w.done:
+			w.source.requeue(event)
+			return
+		}
+	}
+}
+
+// Stop implements watch.Interface.
+func (w *chainedWatcher) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.done)
+		w.source.lock.Lock()
+		w.source.cond.Broadcast()
+		w.source.lock.Unlock()
+	})
+}
+
+// ResultChan implements watch.Interface.
+func (w *chainedWatcher) ResultChan() <-chan watch.Event {
+	return w.result
+}
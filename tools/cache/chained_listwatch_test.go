@@ -0,0 +1,125 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	fcache "k8s.io/client-go/tools/cache/testing"
+)
+
+func TestChainedListWatch(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Labels: map[string]string{"app": "a"}}})
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod2", Labels: map[string]string{"app": "b"}}})
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	sourceInformer := NewSharedInformer(source, &v1.Pod{}, 0)
+	go sourceInformer.Run(stop)
+	if !WaitForCacheSync(stop, sourceInformer.HasSynced) {
+		t.Fatalf("source informer did not sync")
+	}
+
+	onlyA := func(obj interface{}) bool {
+		return obj.(*v1.Pod).Labels["app"] == "a"
+	}
+	chained := NewSharedInformer(NewChainedListWatch(sourceInformer, onlyA, nil), &v1.Pod{}, 0)
+	go chained.Run(stop)
+	if !WaitForCacheSync(stop, chained.HasSynced) {
+		t.Fatalf("chained informer did not sync")
+	}
+
+	names := func() sets.String {
+		result := sets.NewString()
+		for _, obj := range chained.GetStore().List() {
+			result.Insert(obj.(*v1.Pod).Name)
+		}
+		return result
+	}
+	waitForNames := func(expected ...string) {
+		err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+			return names().Equal(sets.NewString(expected...)), nil
+		})
+		if err != nil {
+			t.Fatalf("expected %v in chained informer, got %v", expected, names().List())
+		}
+	}
+	waitForNames("pod1")
+
+	// Live events of the source are followed, including objects moving in
+	// and out of the filter.
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod3", Labels: map[string]string{"app": "a"}}})
+	source.Modify(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod2", Labels: map[string]string{"app": "a"}}})
+	waitForNames("pod1", "pod2", "pod3")
+
+	source.Modify(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Labels: map[string]string{"app": "b"}}})
+	source.Delete(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod3", Labels: map[string]string{"app": "a"}}})
+	waitForNames("pod2")
+}
+
+func TestChainedListWatchResumesAcrossWatches(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	stop := make(chan struct{})
+	defer close(stop)
+	sourceInformer := NewSharedInformer(source, &v1.Pod{}, 0)
+	go sourceInformer.Run(stop)
+	if !WaitForCacheSync(stop, sourceInformer.HasSynced) {
+		t.Fatalf("source informer did not sync")
+	}
+
+	lw := NewChainedListWatch(sourceInformer, nil, nil)
+	if _, err := lw.List(metav1.ListOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	w, err := lw.Watch(metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}})
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod2"}})
+	select {
+	case event := <-w.ResultChan():
+		if name := event.Object.(*v1.Pod).Name; name != "pod1" {
+			t.Fatalf("expected pod1, got %s", name)
+		}
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatalf("timed out waiting for event")
+	}
+	w.Stop()
+
+	// The event not delivered by the stopped watch is served by the next one.
+	w, err = lw.Watch(metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer w.Stop()
+	select {
+	case event := <-w.ResultChan():
+		if name := event.Object.(*v1.Pod).Name; name != "pod2" {
+			t.Fatalf("expected pod2, got %s", name)
+		}
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatalf("timed out waiting for event")
+	}
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"strings"
+)
+
+const (
+	// compositeIndexSeparator separates the parts of a composite indexed
+	// value.
+	compositeIndexSeparator = '|'
+	// compositeIndexEscape escapes the separators and escapes found in the
+	// parts of a composite indexed value.
+	compositeIndexEscape = '\\'
+)
+
+// CompositeIndexValue returns the indexed value made of the given parts,
+// such as a namespace and a node name, which CompositeIndexFunc indexes
+// objects under.  Parts are escaped, so that different parts always make
+// different values whatever characters they hold.
+func CompositeIndexValue(parts ...string) string {
+	var b strings.Builder
+	for i, part := range parts {
+		if i > 0 {
+			b.WriteByte(compositeIndexSeparator)
+		}
+		for j := 0; j < len(part); j++ {
+			if part[j] == compositeIndexSeparator || part[j] == compositeIndexEscape {
+				b.WriteByte(compositeIndexEscape)
+			}
+			b.WriteByte(part[j])
+		}
+	}
+	return b.String()
+}
+
+// SplitCompositeIndexValue returns the parts CompositeIndexValue made value
+// of, for example to read back the values listed by ListIndexFuncValues.
+func SplitCompositeIndexValue(value string) []string {
+	var parts []string
+	var part strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case compositeIndexEscape:
+			if i+1 < len(value) {
+				i++
+			}
+			part.WriteByte(value[i])
+		case compositeIndexSeparator:
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteByte(value[i])
+		}
+	}
+	return append(parts, part.String())
+}
+
+// CompositeIndexFunc returns an index function that indexes an object under
+// the composite value of the values each of partFuncs returns for it, in
+// order, as made by CompositeIndexValue.  When a part function returns
+// several values, the object is indexed under every combination of them;
+// when one returns none, the object is not indexed.  For example
+//
+//	CompositeIndexFunc(MetaNamespaceIndexFunc, podNodeNameIndexFunc)
+//
+// indexes pods by namespace and node name, which Index2 then looks up.
+func CompositeIndexFunc(partFuncs ...IndexFunc) IndexFunc {
+	return func(obj interface{}) ([]string, error) {
+		combinations := [][]string{nil}
+		for _, partFunc := range partFuncs {
+			values, err := partFunc(obj)
+			if err != nil {
+				return nil, err
+			}
+			next := make([][]string, 0, len(combinations)*len(values))
+			for _, combination := range combinations {
+				for _, value := range values {
+					parts := make([]string, len(combination), len(combination)+1)
+					copy(parts, combination)
+					next = append(next, append(parts, value))
+				}
+			}
+			combinations = next
+		}
+		indexedValues := make([]string, 0, len(combinations))
+		for _, parts := range combinations {
+			indexedValues = append(indexedValues, CompositeIndexValue(parts...))
+		}
+		return indexedValues, nil
+	}
+}
+
+// Index2 returns the objects of indexer indexed under the composite value
+// of part1 and part2 by the named index, which is a CompositeIndexFunc of
+// two part functions.
+func Index2(indexer Indexer, indexName, part1, part2 string) ([]interface{}, error) {
+	return indexer.ByIndex(indexName, CompositeIndexValue(part1, part2))
+}
+
+// IndexN returns the objects of indexer indexed under the composite value
+// of parts by the named index, which is a CompositeIndexFunc of as many part
+// functions as there are parts.
+func IndexN(indexer Indexer, indexName string, parts ...string) ([]interface{}, error) {
+	return indexer.ByIndex(indexName, CompositeIndexValue(parts...))
+}
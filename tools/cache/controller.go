@@ -56,6 +56,38 @@ type Config struct {
 	// the resync.
 	ShouldResync ShouldResyncFunc
 
+	// ReflectorTimeouts, if set, overrides the timeouts the reflector requests
+	// for its lists and watches.
+	ReflectorTimeouts *ReflectorTimeouts
+	// RelistBackoff, if set, is the RelistBackoff of the reflector.
+	RelistBackoff *ReflectorBackoff
+
+	// StrictWatchValidation enables the StrictValidation of the reflector.
+	StrictWatchValidation bool
+
+	// WatchListPageSize, if set, is the WatchListPageSize of the reflector.
+	WatchListPageSize int64
+
+	// UseWatchList enables the UseWatchList of the reflector.
+	UseWatchList bool
+
+	// InitialResourceVersion, if set, is the resource version of the
+	// objects the Queue was filled with before Run.  The reflector starts
+	// by watching from it instead of listing, and lists if it has expired.
+	InitialResourceVersion string
+
+	// OnSyncProgress, if set, is called with the resource version of the
+	// reflector after a list, a bookmark or a resync, once every delta
+	// queued until then has been processed.
+	OnSyncProgress func(resourceVersion string)
+
+	// WatchErrorHandler, if set, is called with the list and watch errors
+	// of the reflector instead of DefaultWatchErrorHandler.
+	WatchErrorHandler WatchErrorHandler
+
+	// Hooks are invoked at fixed points of Run.
+	Hooks ControllerHooks
+
 	// If true, when Process() returns an error, re-enqueue the object.
 	// TODO: add interface to let you inject a delay/backoff or drop
 	//       the object completely if desired. Pass the object in
@@ -63,6 +95,22 @@ type Config struct {
 	RetryOnError bool
 }
 
+// ControllerHooks are functions invoked at well-defined points of a
+// controller's Run, for example to register metrics, log how long the initial
+// sync took, or flush state before shutting down.  Hooks run synchronously and
+// should return quickly.
+type ControllerHooks struct {
+	// PreStart hooks are called by Run before anything is listed.
+	PreStart []func()
+	// PostSync hooks are called once the initial list has been processed,
+	// with the time elapsed since Run was called.  They are not called if
+	// the controller stops before syncing.
+	PostSync []func(syncDuration time.Duration)
+	// PreStop hooks are called once the stop channel is closed, before the
+	// queue is closed.
+	PreStop []func()
+}
+
 // ShouldResyncFunc is a type of function that indicates if a reflector should perform a
 // resync or not. It can be used by a shared informer to support multiple event handlers with custom
 // resync periods.
@@ -77,6 +125,11 @@ type controller struct {
 	reflector      *Reflector
 	reflectorMutex sync.RWMutex
 	clock          clock.Clock
+
+	progressLock sync.Mutex
+	// pendingProgress is the resource version to pass to OnSyncProgress
+	// once the queue is empty.
+	pendingProgress string
 }
 
 // Controller is a generic controller framework.
@@ -100,27 +153,68 @@ func New(c *Config) Controller {
 // Run blocks; call via go.
 func (c *controller) Run(stopCh <-chan struct{}) {
 	defer utilruntime.HandleCrash()
+	start := c.clock.Now()
+	for _, hook := range c.config.Hooks.PreStart {
+		hook()
+	}
 	go func() {
 		<-stopCh
+		for _, hook := range c.config.Hooks.PreStop {
+			hook()
+		}
 		c.config.Queue.Close()
 	}()
+	c.reflectorMutex.RLock()
+	fullResyncPeriod := c.config.FullResyncPeriod
+	c.reflectorMutex.RUnlock()
 	r := NewReflector(
 		c.config.ListerWatcher,
 		c.config.ObjectType,
 		c.config.Queue,
-		c.config.FullResyncPeriod,
+		fullResyncPeriod,
 	)
 	r.ShouldResync = c.config.ShouldResync
 	r.clock = c.clock
+	if c.config.ReflectorTimeouts != nil {
+		r.Timeouts = *c.config.ReflectorTimeouts
+	}
+	if c.config.RelistBackoff != nil {
+		r.RelistBackoff = *c.config.RelistBackoff
+	}
+	r.StrictValidation = r.StrictValidation || c.config.StrictWatchValidation
+	r.WatchErrorHandler = c.config.WatchErrorHandler
+	r.WatchListPageSize = c.config.WatchListPageSize
+	r.UseWatchList = r.UseWatchList || c.config.UseWatchList
+	if c.config.OnSyncProgress != nil {
+		r.onSyncProgress = c.syncProgress
+	}
+	r.initialResourceVersion = c.config.InitialResourceVersion
 
 	c.reflectorMutex.Lock()
 	c.reflector = r
+	if c.config.FullResyncPeriod != fullResyncPeriod {
+		r.SetResyncPeriod(c.config.FullResyncPeriod)
+	}
 	c.reflectorMutex.Unlock()
 
 	var wg wait.Group
 	defer wg.Wait()
 
 	wg.StartWithChannel(stopCh, r.Run)
+	if len(c.config.Hooks.PostSync) > 0 {
+		wg.StartWithChannel(stopCh, func(stopCh <-chan struct{}) {
+			err := wait.PollUntil(syncedPollPeriod, func() (bool, error) {
+				return c.HasSynced(), nil
+			}, stopCh)
+			if err != nil {
+				return
+			}
+			syncDuration := c.clock.Since(start)
+			for _, hook := range c.config.Hooks.PostSync {
+				hook(syncDuration)
+			}
+		})
+	}
 
 	wait.Until(c.processLoop, time.Second, stopCh)
 }
@@ -130,6 +224,17 @@ func (c *controller) HasSynced() bool {
 	return c.config.Queue.HasSynced()
 }
 
+// setResyncPeriod changes the FullResyncPeriod of the controller, and of its
+// reflector if it is running.
+func (c *controller) setResyncPeriod(resyncPeriod time.Duration) {
+	c.reflectorMutex.Lock()
+	defer c.reflectorMutex.Unlock()
+	c.config.FullResyncPeriod = resyncPeriod
+	if c.reflector != nil {
+		c.reflector.SetResyncPeriod(resyncPeriod)
+	}
+}
+
 func (c *controller) LastSyncResourceVersion() string {
 	c.reflectorMutex.RLock()
 	defer c.reflectorMutex.RUnlock()
@@ -139,6 +244,27 @@ func (c *controller) LastSyncResourceVersion() string {
 	return c.reflector.LastSyncResourceVersion()
 }
 
+// lastRelistTime returns the LastRelistTime of the reflector, if it is
+// running.
+func (c *controller) lastRelistTime() time.Time {
+	c.reflectorMutex.RLock()
+	defer c.reflectorMutex.RUnlock()
+	if c.reflector == nil {
+		return time.Time{}
+	}
+	return c.reflector.LastRelistTime()
+}
+
+// health returns the Health of the reflector, if it is running.
+func (c *controller) health() ReflectorHealth {
+	c.reflectorMutex.RLock()
+	defer c.reflectorMutex.RUnlock()
+	if c.reflector == nil {
+		return ReflectorHealth{}
+	}
+	return c.reflector.Health()
+}
+
 // processLoop drains the work queue.
 // TODO: Consider doing the processing in parallel. This will require a little thought
 // to make sure that we don't end up processing the same object multiple times
@@ -160,7 +286,42 @@ func (c *controller) processLoop() {
 				c.config.Queue.AddIfNotPresent(obj)
 			}
 		}
+		if c.config.OnSyncProgress != nil {
+			c.deliverProgress()
+		}
+	}
+}
+
+// syncProgress records that the reflector's store has caught up with
+// resourceVersion, to be passed on to OnSyncProgress once the deltas queued
+// until then have been processed.
+func (c *controller) syncProgress(resourceVersion string) {
+	c.progressLock.Lock()
+	c.pendingProgress = resourceVersion
+	c.progressLock.Unlock()
+	c.deliverProgress()
+}
+
+// deliverProgress calls OnSyncProgress with the pending resource version, if
+// any, once the queue is empty.  Looking at the queue waits for the delta
+// being processed, if any, since Pop holds the queue's lock meanwhile.
+func (c *controller) deliverProgress() {
+	c.progressLock.Lock()
+	defer c.progressLock.Unlock()
+
+	if len(c.pendingProgress) == 0 || !queueIsEmpty(c.config.Queue) {
+		return
+	}
+	resourceVersion := c.pendingProgress
+	c.pendingProgress = ""
+	c.config.OnSyncProgress(resourceVersion)
+}
+
+func queueIsEmpty(queue Queue) bool {
+	if f, ok := queue.(*DeltaFIFO); ok {
+		return f.isEmpty()
 	}
+	return len(queue.ListKeys()) == 0
 }
 
 // ResourceEventHandler can handle notifications for events that happen to a
@@ -183,6 +344,182 @@ type ResourceEventHandler interface {
 	OnDelete(obj interface{})
 }
 
+// SyncProgressHandler may be implemented by a ResourceEventHandler added to a
+// shared informer to learn how far the informer's cache has progressed, for
+// instance to checkpoint it.  OnSyncProgress is called with the resource
+// version the cache has caught up with after a list, a watch bookmark or a
+// resync, once the handler has been notified of every change up to it.
+type SyncProgressHandler interface {
+	OnSyncProgress(resourceVersion string)
+}
+
+// InitialSyncHandler may be implemented by a ResourceEventHandler added to a
+// shared informer to tell the replayed state of the cache from live changes,
+// for instance to batch the work of the initial flood of additions.
+// OnInitialSyncDone is called once the handler has been notified of every
+// object of the informer's initial list, or, for a handler added later, of
+// every object in the cache when it was added.
+type InitialSyncHandler interface {
+	OnInitialSyncDone()
+}
+
+// UpdateOrigin tells what an update notification comes from.
+type UpdateOrigin int
+
+const (
+	// UpdateFromWatch is the origin of the updates watched.
+	UpdateFromWatch UpdateOrigin = iota
+	// UpdateFromRelist is the origin of the updates a relist found, when
+	// the change itself was not watched.  Only informers using Replaced
+	// deltas tell them apart, see DeltaFIFOOptions.EmitDeltaTypeReplaced;
+	// the others notify them as resyncs.
+	UpdateFromRelist
+	// UpdateFromResync is the origin of the updates of periodic resyncs, and
+	// of the objects a relist found unchanged.  The old and new objects are
+	// then the same.
+	UpdateFromResync
+)
+
+// UpdateOriginHandler may be implemented by a ResourceEventHandler added to a
+// shared informer to tell genuine updates from those of relists and resyncs.
+// OnUpdateWithOrigin is then called instead of OnUpdate.
+type UpdateOriginHandler interface {
+	OnUpdateWithOrigin(oldObj, newObj interface{}, origin UpdateOrigin)
+}
+
+// EventDetails describes the change a notification is about, see
+// DetailedResourceEventHandler.
+type EventDetails struct {
+	// Type is the type of the delta the notification comes from: Added,
+	// Updated or Deleted for the changes seen by the watch, Sync for resyncs
+	// and for the objects of lists, and Replaced for the objects of relists
+	// with DeltaFIFOOptions.EmitDeltaTypeReplaced.  The objects already in
+	// the cache when a handler is added are notified to it as Sync adds.
+	Type DeltaType
+	// OldResourceVersion is the resource version of the old object of an
+	// update or of the deleted object.
+	OldResourceVersion string
+	// NewResourceVersion is the resource version of the new object of an
+	// add or an update.
+	NewResourceVersion string
+	// Tombstone is true for the deletions the informer missed, whose object
+	// is the last state the informer knew, unwrapped from its
+	// DeletedFinalStateUnknown.
+	Tombstone bool
+}
+
+// DetailedResourceEventHandler may be implemented by a ResourceEventHandler
+// added to a shared informer to be told the details of every change.  Its
+// methods are then called instead of those of ResourceEventHandler and
+// UpdateOriginHandler.
+type DetailedResourceEventHandler interface {
+	OnAddDetailed(obj interface{}, details EventDetails)
+	OnUpdateDetailed(oldObj, newObj interface{}, details EventDetails)
+	OnDeleteDetailed(obj interface{}, details EventDetails)
+}
+
+// ResourceEventHandlerDetailedFuncs is an adaptor to let you easily specify as
+// many or as few of the notification functions as you want while still
+// implementing DetailedResourceEventHandler and ResourceEventHandler.  Called
+// as a ResourceEventHandler, it passes on the details it can tell from the
+// objects, with an Added, Updated or Deleted type.
+type ResourceEventHandlerDetailedFuncs struct {
+	AddFunc    func(obj interface{}, details EventDetails)
+	UpdateFunc func(oldObj, newObj interface{}, details EventDetails)
+	DeleteFunc func(obj interface{}, details EventDetails)
+}
+
+// OnAdd calls AddFunc if it's not nil.
+func (r ResourceEventHandlerDetailedFuncs) OnAdd(obj interface{}) {
+	r.OnAddDetailed(obj, addDetails(Added, obj))
+}
+
+// OnUpdate calls UpdateFunc if it's not nil.
+func (r ResourceEventHandlerDetailedFuncs) OnUpdate(oldObj, newObj interface{}) {
+	r.OnUpdateDetailed(oldObj, newObj, updateDetails(UpdateFromWatch, oldObj, newObj))
+}
+
+// OnDelete calls DeleteFunc if it's not nil.
+func (r ResourceEventHandlerDetailedFuncs) OnDelete(obj interface{}) {
+	obj, details := deleteDetails(obj)
+	r.OnDeleteDetailed(obj, details)
+}
+
+// OnAddDetailed calls AddFunc if it's not nil.
+func (r ResourceEventHandlerDetailedFuncs) OnAddDetailed(obj interface{}, details EventDetails) {
+	if r.AddFunc != nil {
+		r.AddFunc(obj, details)
+	}
+}
+
+// OnUpdateDetailed calls UpdateFunc if it's not nil.
+func (r ResourceEventHandlerDetailedFuncs) OnUpdateDetailed(oldObj, newObj interface{}, details EventDetails) {
+	if r.UpdateFunc != nil {
+		r.UpdateFunc(oldObj, newObj, details)
+	}
+}
+
+// OnDeleteDetailed calls DeleteFunc if it's not nil.
+func (r ResourceEventHandlerDetailedFuncs) OnDeleteDetailed(obj interface{}, details EventDetails) {
+	if r.DeleteFunc != nil {
+		r.DeleteFunc(obj, details)
+	}
+}
+
+// addDetails returns the details of the add of obj from a delta of type
+// deltaType, Added if empty.
+func addDetails(deltaType DeltaType, obj interface{}) EventDetails {
+	if len(deltaType) == 0 {
+		deltaType = Added
+	}
+	return EventDetails{Type: deltaType, NewResourceVersion: resourceVersionOf(obj)}
+}
+
+// updateDetails returns the details of an update of the given origin.
+func updateDetails(origin UpdateOrigin, oldObj, newObj interface{}) EventDetails {
+	return EventDetails{
+		Type:               updateDeltaType(origin),
+		OldResourceVersion: resourceVersionOf(oldObj),
+		NewResourceVersion: resourceVersionOf(newObj),
+	}
+}
+
+// updateDeltaType returns the type of the delta an update of the given origin
+// comes from.
+func updateDeltaType(origin UpdateOrigin) DeltaType {
+	switch origin {
+	case UpdateFromRelist:
+		return Replaced
+	case UpdateFromResync:
+		return Sync
+	}
+	return Updated
+}
+
+// updateOrigin returns the origin of an update from a delta of type
+// deltaType.
+func updateOrigin(deltaType DeltaType) UpdateOrigin {
+	switch deltaType {
+	case Replaced:
+		return UpdateFromRelist
+	case Sync:
+		return UpdateFromResync
+	}
+	return UpdateFromWatch
+}
+
+// deleteDetails returns the object of the deletion of obj, unwrapped from its
+// tombstone if any, and its details.
+func deleteDetails(obj interface{}) (interface{}, EventDetails) {
+	details := EventDetails{Type: Deleted}
+	if tombstone, ok := obj.(DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+		details.Tombstone = true
+	}
+	details.OldResourceVersion = resourceVersionOf(obj)
+	return obj, details
+}
+
 // ResourceEventHandlerFuncs is an adaptor to let you easily specify as many or
 // as few of the notification functions as you want while still implementing
 // ResourceEventHandler.
@@ -357,7 +694,7 @@ func newInformer(
 			// from oldest to newest
 			for _, d := range obj.(Deltas) {
 				switch d.Type {
-				case Sync, Added, Updated:
+				case Sync, Replaced, Added, Updated:
 					if old, exists, err := clientState.Get(d.Object); err == nil && exists {
 						if err := clientState.Update(d.Object); err != nil {
 							return err
@@ -19,6 +19,7 @@ package cache
 import (
 	"fmt"
 	"math/rand"
+	"reflect"
 	"sync"
 	"testing"
 	"time"
@@ -402,3 +403,51 @@ func TestUpdate(t *testing.T) {
 	testDoneWG.Wait()
 	close(stop)
 }
+
+func TestControllerHooks(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}})
+
+	var lock sync.Mutex
+	var calls []string
+	record := func(call string) {
+		lock.Lock()
+		defer lock.Unlock()
+		calls = append(calls, call)
+	}
+	synced := make(chan struct{})
+
+	informer := NewSharedInformer(source, &v1.Pod{}, 0, WithLifecycleHooks(ControllerHooks{
+		PreStart: []func(){func() { record("preStart") }},
+		PostSync: []func(time.Duration){func(time.Duration) {
+			record("postSync")
+			close(synced)
+		}},
+		PreStop: []func(){func() { record("preStop") }},
+	}))
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		informer.Run(stop)
+		close(done)
+	}()
+	select {
+	case <-synced:
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatalf("PostSync hook was not called")
+	}
+	close(stop)
+	<-done
+
+	if err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(calls) == 3, nil
+	}); err != nil {
+		t.Fatalf("expected three hook calls, got %v", calls)
+	}
+	if e, a := []string{"preStart", "postSync", "preStop"}, calls; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+}
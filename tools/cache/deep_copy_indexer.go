@@ -0,0 +1,150 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// deepCopyIndexer is an Indexer handing out deep copies of the objects of an
+// underlying Indexer, see NewDeepCopyIndexer.
+type deepCopyIndexer struct {
+	Indexer
+}
+
+var _ SelectorLister = &deepCopyIndexer{}
+
+// NewDeepCopyIndexer returns an Indexer over indexer whose Get, GetByKey,
+// List, Index, ByIndex, ForEachByIndex and ByIndexes return deep copies of
+// the runtime.Objects of indexer, so that callers mutating them cannot
+// corrupt it.  Other objects are returned as is.  The remaining methods go to indexer unchanged.
+//
+// If indexer already returns fresh objects, because it keeps them encoded
+// (see WithStoreCodec), indexer itself is returned and no copy is made.
+func NewDeepCopyIndexer(indexer Indexer) Indexer {
+	if returnsFreshObjects(indexer) {
+		return indexer
+	}
+	return &deepCopyIndexer{Indexer: indexer}
+}
+
+// returnsFreshObjects returns true if every read of indexer decodes new
+// objects.
+func returnsFreshObjects(indexer Indexer) bool {
+	c, ok := indexer.(*cache)
+	if !ok {
+		return false
+	}
+	storage, ok := c.cacheStorage.(*threadSafeMap)
+	if !ok {
+		return false
+	}
+	storage.lock.RLock()
+	defer storage.lock.RUnlock()
+	return storage.codec != nil
+}
+
+func (d *deepCopyIndexer) Get(obj interface{}) (interface{}, bool, error) {
+	item, exists, err := d.Indexer.Get(obj)
+	return deepCopyOf(item), exists, err
+}
+
+func (d *deepCopyIndexer) GetByKey(key string) (interface{}, bool, error) {
+	item, exists, err := d.Indexer.GetByKey(key)
+	return deepCopyOf(item), exists, err
+}
+
+func (d *deepCopyIndexer) List() []interface{} {
+	return deepCopyAll(d.Indexer.List())
+}
+
+func (d *deepCopyIndexer) Index(indexName string, obj interface{}) ([]interface{}, error) {
+	items, err := d.Indexer.Index(indexName, obj)
+	return deepCopyAll(items), err
+}
+
+func (d *deepCopyIndexer) ByIndex(indexName, indexedValue string) ([]interface{}, error) {
+	items, err := d.Indexer.ByIndex(indexName, indexedValue)
+	return deepCopyAll(items), err
+}
+
+func (d *deepCopyIndexer) ForEachByIndex(indexName, indexedValue string, fn func(obj interface{}) bool) error {
+	return d.Indexer.ForEachByIndex(indexName, indexedValue, func(obj interface{}) bool {
+		return fn(deepCopyOf(obj))
+	})
+}
+
+func (d *deepCopyIndexer) ByIndexes(mode IndexQueryMode, queries ...IndexQuery) ([]interface{}, error) {
+	items, err := d.Indexer.ByIndexes(mode, queries...)
+	return deepCopyAll(items), err
+}
+
+// ListWithSelector copies only the objects matching selector.
+func (d *deepCopyIndexer) ListWithSelector(selector labels.Selector) ([]interface{}, error) {
+	lister, ok := d.Indexer.(SelectorLister)
+	if !ok {
+		var items []interface{}
+		err := filterBySelector(d.Indexer.List(), selector, func(m interface{}) {
+			items = append(items, m)
+		})
+		return deepCopyAll(items), err
+	}
+	items, err := lister.ListWithSelector(selector)
+	return deepCopyAll(items), err
+}
+
+func (d *deepCopyIndexer) ListKeysWithSelector(selector labels.Selector) ([]string, error) {
+	lister, ok := d.Indexer.(SelectorLister)
+	if !ok {
+		var keys []string
+		for _, key := range d.Indexer.ListKeys() {
+			item, exists, err := d.Indexer.GetByKey(key)
+			if err != nil || !exists {
+				continue
+			}
+			matches, err := matchesSelector(item, selector)
+			if err != nil {
+				return nil, err
+			}
+			if matches {
+				keys = append(keys, key)
+			}
+		}
+		return keys, nil
+	}
+	return lister.ListKeysWithSelector(selector)
+}
+
+// deepCopyOf returns a deep copy of obj if it is a runtime.Object, and obj
+// otherwise.
+func deepCopyOf(obj interface{}) interface{} {
+	if object, ok := obj.(runtime.Object); ok {
+		return object.DeepCopyObject()
+	}
+	return obj
+}
+
+// deepCopyAll replaces the objects of items by their deep copies.  The slices
+// returned by the reads of an Indexer are built for the caller, so they are
+// reused rather than reallocated.
+func deepCopyAll(items []interface{}) []interface{} {
+	for i := range items {
+		items[i] = deepCopyOf(items[i])
+	}
+	return items
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	fcache "k8s.io/client-go/tools/cache/testing"
+)
+
+func TestDeepCopyIndexer(t *testing.T) {
+	nodeIndex := func(obj interface{}) ([]string, error) {
+		return []string{obj.(*v1.Pod).Spec.NodeName}, nil
+	}
+	store := NewIndexer(MetaNamespaceKeyFunc, Indexers{"node": nodeIndex})
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod", Labels: map[string]string{"app": "a"}},
+		Spec:       v1.PodSpec{NodeName: "node"},
+	}
+	store.Add(pod)
+	indexer := NewDeepCopyIndexer(store)
+
+	mutate := func(method string, obj interface{}) {
+		if obj == pod {
+			t.Errorf("%s: expected a copy of the cached pod", method)
+		}
+		obj.(*v1.Pod).Labels["app"] = "mutated"
+	}
+	obj, _, _ := indexer.GetByKey("ns/pod")
+	mutate("GetByKey", obj)
+	obj, _, _ = indexer.Get(pod)
+	mutate("Get", obj)
+	mutate("List", indexer.List()[0])
+	items, _ := indexer.ByIndex("node", "node")
+	mutate("ByIndex", items[0])
+	items, _ = indexer.Index("node", pod)
+	mutate("Index", items[0])
+	err := ListAll(indexer, labels.Everything(), func(m interface{}) {
+		mutate("ListAll", m)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := "a", pod.Labels["app"]; e != a {
+		t.Errorf("expected the cached pod to keep label %q, got %q", e, a)
+	}
+
+	codecStore := NewIndexer(MetaNamespaceKeyFunc, Indexers{})
+	codecStore.(*cache).cacheStorage.(*threadSafeMap).setCodec(NewCompressingStoreCodec(spillTestCodec()))
+	if NewDeepCopyIndexer(codecStore) != codecStore {
+		t.Errorf("expected an encoding store to be returned as is")
+	}
+}
+
+func TestSharedInformerDeepCopyOnRead(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod", Labels: map[string]string{"app": "a"}}})
+	informer := NewSharedIndexInformer(source, &v1.Pod{}, 0, Indexers{}, WithDeepCopyOnRead()).(*sharedIndexInformer)
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	if !WaitForCacheSync(stop, informer.HasSynced) {
+		t.Fatal("informer did not sync")
+	}
+
+	obj, exists, err := informer.GetStore().GetByKey("ns/pod")
+	if err != nil || !exists {
+		t.Fatalf("expected ns/pod to exist, got %v, %v", exists, err)
+	}
+	obj.(*v1.Pod).Labels["app"] = "mutated"
+	cached, _, _ := informer.indexer.GetByKey("ns/pod")
+	if e, a := "a", cached.(*v1.Pod).Labels["app"]; e != a {
+		t.Errorf("expected the cached pod to keep label %q, got %q", e, a)
+	}
+}
+
+func benchmarkDeepCopyIndexerStore(b *testing.B) Indexer {
+	store := NewIndexer(MetaNamespaceKeyFunc, Indexers{})
+	for i := 0; i < 1000; i++ {
+		store.Add(&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: fmt.Sprintf("pod-%d", i), Labels: map[string]string{"app": "a"}},
+			Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "c", Image: "image"}}},
+		})
+	}
+	return store
+}
+
+// BenchmarkDeepCopyIndexerList, BenchmarkDeepCopyIndexerListEncoded and
+// BenchmarkIndexerList compare listing copies, listing from an encoding store
+// which NewDeepCopyIndexer does not wrap, and listing the cached objects.
+func BenchmarkDeepCopyIndexerList(b *testing.B) {
+	indexer := NewDeepCopyIndexer(benchmarkDeepCopyIndexerStore(b))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		indexer.List()
+	}
+}
+
+func BenchmarkDeepCopyIndexerListEncoded(b *testing.B) {
+	store := benchmarkDeepCopyIndexerStore(b)
+	store.(*cache).cacheStorage.(*threadSafeMap).setCodec(NewCompressingStoreCodec(spillTestCodec()))
+	indexer := NewDeepCopyIndexer(store)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		indexer.List()
+	}
+}
+
+func BenchmarkIndexerList(b *testing.B) {
+	indexer := benchmarkDeepCopyIndexerStore(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		indexer.List()
+	}
+}
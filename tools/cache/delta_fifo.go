@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"sync"
 
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/util/sets"
 
 	"k8s.io/klog"
@@ -57,11 +58,49 @@ import (
 //
 // Also see the comment on DeltaFIFO.
 func NewDeltaFIFO(keyFunc KeyFunc, knownObjects KeyListerGetter) *DeltaFIFO {
+	return NewDeltaFIFOWithOptions(DeltaFIFOOptions{
+		KeyFunction:  keyFunc,
+		KnownObjects: knownObjects,
+	})
+}
+
+// DeltaFIFOOptions is the configuration parameters for DeltaFIFO. All are
+// optional.
+type DeltaFIFOOptions struct {
+	// KeyFunction is used to figure out what key an object should have,
+	// MetaNamespaceKeyFunc by default.
+	KeyFunction KeyFunc
+
+	// KnownObjects is expected to return a list of keys that the consumer of
+	// this queue "knows about", see NewDeltaFIFO.
+	KnownObjects KeyListerGetter
+
+	// EmitDeltaTypeReplaced makes Replace queue Replaced deltas instead of
+	// Sync deltas, so that consumers can tell the objects of a relist from
+	// those of a periodic resync.
+	EmitDeltaTypeReplaced bool
+
+	// CompactUpdates makes the queue keep only the newest of consecutive
+	// Updated, Replaced and Sync deltas of an object, which bounds the
+	// deltas queued for an object that changes faster than it is
+	// processed.  The delta kept has the newest object and the type of the
+	// most significant change: Updated, then Replaced, then Sync.
+	CompactUpdates bool
+}
+
+// NewDeltaFIFOWithOptions returns a Store which can be used process changes to
+// items. See also the comment on DeltaFIFO.
+func NewDeltaFIFOWithOptions(opts DeltaFIFOOptions) *DeltaFIFO {
+	if opts.KeyFunction == nil {
+		opts.KeyFunction = MetaNamespaceKeyFunc
+	}
 	f := &DeltaFIFO{
-		items:        map[string]Deltas{},
-		queue:        []string{},
-		keyFunc:      keyFunc,
-		knownObjects: knownObjects,
+		items:                 map[string]Deltas{},
+		queue:                 []string{},
+		keyFunc:               opts.KeyFunction,
+		knownObjects:          opts.KnownObjects,
+		emitDeltaTypeReplaced: opts.EmitDeltaTypeReplaced,
+		compactUpdates:        opts.CompactUpdates,
 	}
 	f.cond.L = &f.lock
 	return f
@@ -119,6 +158,14 @@ type DeltaFIFO struct {
 	// when Replace() or Delete() is called.
 	knownObjects KeyListerGetter
 
+	// resyncExclusion, if set, lists the keys Resync skips.
+	resyncExclusion ResyncExclusionFunc
+
+	// emitDeltaTypeReplaced and compactUpdates are set from the
+	// DeltaFIFOOptions of the same name.
+	emitDeltaTypeReplaced bool
+	compactUpdates        bool
+
 	// Indication the queue is closed.
 	// Used to indicate a queue is closed so a control loop can exit when a queue is empty.
 	// Currently, not used to gate any of CRED operations.
@@ -295,6 +342,28 @@ func isDeletionDup(a, b *Delta) *Delta {
 	return b
 }
 
+// updateSignificance orders the types of deltas compactUpdates merges, or is
+// zero for the others.
+var updateSignificance = map[DeltaType]int{Sync: 1, Replaced: 2, Updated: 3}
+
+// compactUpdates merges the last two deltas if both are updates, keeping the
+// newest object and the most significant type.
+func compactUpdates(deltas Deltas) Deltas {
+	n := len(deltas)
+	if n < 2 {
+		return deltas
+	}
+	a, b := deltas[n-1], deltas[n-2]
+	if updateSignificance[a.Type] == 0 || updateSignificance[b.Type] == 0 {
+		return deltas
+	}
+	if updateSignificance[b.Type] > updateSignificance[a.Type] {
+		a.Type = b.Type
+	}
+	d := append(Deltas{}, deltas[:n-2]...)
+	return append(d, a)
+}
+
 // willObjectBeDeletedLocked returns true only if the last delta for the
 // given object is Delete. Caller must lock first.
 func (f *DeltaFIFO) willObjectBeDeletedLocked(id string) bool {
@@ -319,6 +388,9 @@ func (f *DeltaFIFO) queueActionLocked(actionType DeltaType, obj interface{}) err
 
 	newDeltas := append(f.items[id], Delta{actionType, obj})
 	newDeltas = dedupDeltas(newDeltas)
+	if f.compactUpdates {
+		newDeltas = compactUpdates(newDeltas)
+	}
 
 	if len(newDeltas) > 0 {
 		if _, exists := f.items[id]; !exists {
@@ -389,6 +461,13 @@ func (f *DeltaFIFO) GetByKey(key string) (item interface{}, exists bool, err err
 	return d, exists, nil
 }
 
+// isEmpty returns whether no object is queued.
+func (f *DeltaFIFO) isEmpty() bool {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	return len(f.queue) == 0
+}
+
 // IsClosed checks if the queue is closed
 func (f *DeltaFIFO) IsClosed() bool {
 	f.closedLock.Lock()
@@ -448,6 +527,11 @@ func (f *DeltaFIFO) Pop(process PopProcessFunc) (interface{}, error) {
 // 'f' takes ownership of the map, you should not reference the map again
 // after calling this function. f's queue is reset, too; upon return, it
 // will contain the items in the map, in no particular order.
+//
+// An item whose UID differs from the UID of the object last seen under the
+// same key was deleted and recreated while it was not being watched.  Such an
+// item is queued as a Deleted delta carrying the old object, followed by an
+// Added delta, instead of a Sync.
 func (f *DeltaFIFO) Replace(list []interface{}, resourceVersion string) error {
 	f.lock.Lock()
 	defer f.lock.Unlock()
@@ -459,7 +543,22 @@ func (f *DeltaFIFO) Replace(list []interface{}, resourceVersion string) error {
 			return KeyError{item, err}
 		}
 		keys.Insert(key)
-		if err := f.queueActionLocked(Sync, item); err != nil {
+		if oldObj, resurrected := f.resurrectedLocked(key, item); resurrected {
+			// Both deltas are queued under the same key, so this does not
+			// change the number of items to pop.
+			if err := f.queueActionLocked(Deleted, DeletedFinalStateUnknown{key, oldObj}); err != nil {
+				return err
+			}
+			if err := f.queueActionLocked(Added, item); err != nil {
+				return fmt.Errorf("couldn't enqueue object: %v", err)
+			}
+			continue
+		}
+		action := Sync
+		if f.emitDeltaTypeReplaced {
+			action = Replaced
+		}
+		if err := f.queueActionLocked(action, item); err != nil {
 			return fmt.Errorf("couldn't enqueue object: %v", err)
 		}
 	}
@@ -521,7 +620,56 @@ func (f *DeltaFIFO) Replace(list []interface{}, resourceVersion string) error {
 	return nil
 }
 
-// Resync will send a sync event for each item
+// resurrectedLocked returns the last known object stored under key and true
+// if its UID differs from the UID of obj.  The newest queued delta is
+// preferred over the known objects, since it has not been processed yet.
+func (f *DeltaFIFO) resurrectedLocked(key string, obj interface{}) (interface{}, bool) {
+	var oldObj interface{}
+	if newest := f.items[key].Newest(); newest != nil {
+		if newest.Type == Deleted {
+			return nil, false
+		}
+		oldObj = newest.Object
+	} else if f.knownObjects != nil {
+		known, exists, err := f.knownObjects.GetByKey(key)
+		if err != nil || !exists {
+			return nil, false
+		}
+		oldObj = known
+	} else {
+		return nil, false
+	}
+
+	oldMeta, err := meta.Accessor(oldObj)
+	if err != nil {
+		return nil, false
+	}
+	newMeta, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, false
+	}
+	if len(oldMeta.GetUID()) == 0 || len(newMeta.GetUID()) == 0 || oldMeta.GetUID() == newMeta.GetUID() {
+		return nil, false
+	}
+	return oldObj, true
+}
+
+// ResyncExclusionFunc returns the keys of the objects a resync should not
+// queue Sync deltas for, such as the keys of objects that are being processed
+// and may have been deleted already.  It is called once per resync, with the
+// lock of the DeltaFIFO held, and must not call the DeltaFIFO.
+type ResyncExclusionFunc func() sets.String
+
+// SetResyncExclusion makes Resync skip the keys returned by exclude, or none if
+// exclude is nil.
+func (f *DeltaFIFO) SetResyncExclusion(exclude ResyncExclusionFunc) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.resyncExclusion = exclude
+}
+
+// Resync will send a sync event for each item, except those excluded by the
+// ResyncExclusionFunc, if any.
 func (f *DeltaFIFO) Resync() error {
 	f.lock.Lock()
 	defer f.lock.Unlock()
@@ -530,8 +678,15 @@ func (f *DeltaFIFO) Resync() error {
 		return nil
 	}
 
+	var excluded sets.String
+	if f.resyncExclusion != nil {
+		excluded = f.resyncExclusion()
+	}
 	keys := f.knownObjects.ListKeys()
 	for _, k := range keys {
+		if excluded.Has(k) {
+			continue
+		}
 		if err := f.syncKeyLocked(k); err != nil {
 			return err
 		}
@@ -603,6 +758,9 @@ const (
 	//  * You've turned on periodic syncs.
 	// (Anything that trigger's DeltaFIFO's Replace() method.)
 	Sync DeltaType = "Sync"
+	// Replaced is emitted instead of Sync for the objects of a relist by a
+	// DeltaFIFO created with EmitDeltaTypeReplaced.
+	Replaced DeltaType = "Replaced"
 )
 
 // Delta is the type stored by a DeltaFIFO. It tells you what change
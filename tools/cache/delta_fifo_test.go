@@ -21,6 +21,11 @@ import (
 	"reflect"
 	"testing"
 	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 )
 
 // helper function to reduce stuttering
@@ -257,6 +262,25 @@ func TestDeltaFIFO_ResyncNonExisting(t *testing.T) {
 	}
 }
 
+func TestDeltaFIFO_ResyncExclusion(t *testing.T) {
+	f := NewDeltaFIFO(
+		testFifoObjectKeyFunc,
+		keyLookupFunc(func() []testFifoObject {
+			return []testFifoObject{mkFifoObj("foo", 5), mkFifoObj("bar", 6)}
+		}),
+	)
+	f.SetResyncExclusion(func() sets.String { return sets.NewString("foo") })
+	f.Resync()
+
+	if deltas := f.items["foo"]; len(deltas) != 0 {
+		t.Errorf("expected no deltas for the excluded key, got %v", deltas)
+	}
+	deltas := f.items["bar"]
+	if len(deltas) != 1 || deltas[0].Type != Sync {
+		t.Errorf("unexpected deltas: %v", deltas)
+	}
+}
+
 func TestDeltaFIFO_DeleteExistingNonPropagated(t *testing.T) {
 	f := NewDeltaFIFO(
 		testFifoObjectKeyFunc,
@@ -302,6 +326,84 @@ func TestDeltaFIFO_ReplaceMakesDeletions(t *testing.T) {
 	}
 }
 
+func TestDeltaFIFO_ReplaceMakesDeletionsReplaced(t *testing.T) {
+	f := NewDeltaFIFOWithOptions(DeltaFIFOOptions{
+		KeyFunction: testFifoObjectKeyFunc,
+		KnownObjects: keyLookupFunc(func() []testFifoObject {
+			return []testFifoObject{mkFifoObj("foo", 5), mkFifoObj("bar", 6)}
+		}),
+		EmitDeltaTypeReplaced: true,
+	})
+	f.Replace([]interface{}{mkFifoObj("foo", 5)}, "0")
+
+	expectedList := []Deltas{
+		{{Replaced, mkFifoObj("foo", 5)}},
+		{{Deleted, DeletedFinalStateUnknown{Key: "bar", Obj: mkFifoObj("bar", 6)}}},
+	}
+	for _, expected := range expectedList {
+		cur := Pop(f).(Deltas)
+		if e, a := expected, cur; !reflect.DeepEqual(e, a) {
+			t.Errorf("Expected %#v, got %#v", e, a)
+		}
+	}
+}
+
+func TestDeltaFIFO_CompactUpdates(t *testing.T) {
+	f := NewDeltaFIFOWithOptions(DeltaFIFOOptions{
+		KeyFunction:    testFifoObjectKeyFunc,
+		CompactUpdates: true,
+	})
+	f.Add(mkFifoObj("foo", 1))
+	f.Update(mkFifoObj("foo", 2))
+	f.Update(mkFifoObj("foo", 3))
+	f.Delete(mkFifoObj("foo", 4))
+	f.Replace([]interface{}{mkFifoObj("bar", 1)}, "0")
+	f.Update(mkFifoObj("bar", 2))
+	f.Resync()
+
+	expectedList := []Deltas{
+		{{Added, mkFifoObj("foo", 1)}, {Updated, mkFifoObj("foo", 3)}, {Deleted, mkFifoObj("foo", 4)}},
+		// The update is kept over the sync of the relist.
+		{{Updated, mkFifoObj("bar", 2)}},
+	}
+	for _, expected := range expectedList {
+		cur := Pop(f).(Deltas)
+		if e, a := expected, cur; !reflect.DeepEqual(e, a) {
+			t.Errorf("Expected %#v, got %#v", e, a)
+		}
+	}
+}
+
+func TestDeltaFIFO_ReplaceDetectsResurrectedObjects(t *testing.T) {
+	pod := func(name string, uid types.UID) *v1.Pod {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name, UID: uid}}
+	}
+	known := NewStore(MetaNamespaceKeyFunc)
+	known.Add(pod("same", "uid-1"))
+	known.Add(pod("recreated", "uid-2"))
+	f := NewDeltaFIFO(MetaNamespaceKeyFunc, known)
+
+	f.Replace([]interface{}{pod("same", "uid-1"), pod("recreated", "uid-3")}, "0")
+
+	expected := map[string]Deltas{
+		"ns/same": {{Sync, pod("same", "uid-1")}},
+		"ns/recreated": {
+			{Deleted, DeletedFinalStateUnknown{Key: "ns/recreated", Obj: pod("recreated", "uid-2")}},
+			{Added, pod("recreated", "uid-3")},
+		},
+	}
+	for range expected {
+		cur := Pop(f).(Deltas)
+		key, _ := f.KeyOf(cur.Newest().Object)
+		if e, a := expected[key], cur; !reflect.DeepEqual(e, a) {
+			t.Errorf("%s: expected %#v, got %#v", key, e, a)
+		}
+	}
+	if !f.HasSynced() {
+		t.Errorf("expected the queue to be synced once every listed key was popped")
+	}
+}
+
 func TestDeltaFIFO_UpdateResyncRace(t *testing.T) {
 	f := NewDeltaFIFO(
 		testFifoObjectKeyFunc,
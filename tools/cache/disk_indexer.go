@@ -0,0 +1,555 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/peterbourgon/diskv"
+
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// DiskIndexer is an Indexer keeping its objects on disk, see NewDiskIndexer.
+type DiskIndexer interface {
+	Indexer
+	// Close removes the files of the indexer, which must not be used
+	// afterwards.
+	Close() error
+}
+
+// NewDiskIndexer returns an Indexer like NewIndexer which keeps its objects
+// on disk, encoded by codec, and only their keys and indices in memory, so
+// that very large collections can be cached by processes with little memory,
+// for example with WithIndexer.  Its files are kept in a new directory
+// created in dir, or in the default directory for temporary files if dir is
+// empty, until it is closed.  Every read decodes a new copy of the objects
+// from disk.  An object that cannot be encoded or written is kept in memory.
+func NewDiskIndexer(dir string, codec StoreCodec, keyFunc KeyFunc, indexers Indexers) (DiskIndexer, error) {
+	storage, err := newDiskThreadSafeStore(dir, codec, indexers)
+	if err != nil {
+		return nil, err
+	}
+	return &diskIndexer{
+		cache: &cache{
+			cacheStorage: storage,
+			keyFunc:      keyFunc,
+		},
+		storage: storage,
+	}, nil
+}
+
+type diskIndexer struct {
+	*cache
+	storage *diskThreadSafeStore
+}
+
+func (d *diskIndexer) Close() error {
+	return d.storage.close()
+}
+
+// diskThreadSafeStore implements ThreadSafeStore keeping the items on disk.
+// The keys of the items, their indices and the values each index indexes
+// them under stay in memory, so that writes do not read the replaced items
+// back.
+type diskThreadSafeStore struct {
+	lock  sync.RWMutex
+	dir   string
+	disk  *diskv.Diskv
+	codec StoreCodec
+
+	// items maps the key of every item to the item itself if it could not
+	// be written to disk, or to nil if it is on disk.
+	items map[string]interface{}
+	// indexedValues maps the key of every item to the values each index
+	// indexes it under.
+	indexedValues map[string]map[string][]string
+
+	indexers Indexers
+	indices  Indices
+}
+
+var _ ThreadSafeStore = &diskThreadSafeStore{}
+
+func newDiskThreadSafeStore(dir string, codec StoreCodec, indexers Indexers) (*diskThreadSafeStore, error) {
+	if codec == nil {
+		return nil, fmt.Errorf("a disk indexer requires a codec")
+	}
+	dir, err := ioutil.TempDir(dir, "informer-cache-")
+	if err != nil {
+		return nil, fmt.Errorf("unable to create the directory of a disk indexer: %v", err)
+	}
+	ownIndexers := make(Indexers, len(indexers))
+	for name, indexFunc := range indexers {
+		ownIndexers[name] = indexFunc
+	}
+	return &diskThreadSafeStore{
+		dir: dir,
+		disk: diskv.New(diskv.Options{
+			BasePath: dir,
+			TempDir:  filepath.Join(dir, ".diskv-temp"),
+			// Spread the files over 256 directories.
+			Transform: func(fileKey string) []string { return []string{fileKey[:2]} },
+			PathPerm:  os.FileMode(0700),
+			FilePerm:  os.FileMode(0600),
+		}),
+		codec:         codec,
+		items:         map[string]interface{}{},
+		indexedValues: map[string]map[string][]string{},
+		indexers:      ownIndexers,
+		indices:       Indices{},
+	}, nil
+}
+
+// fileKey returns the name of the file of the item of key, which is safe
+// whatever characters key holds.
+func fileKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *diskThreadSafeStore) close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.items = map[string]interface{}{}
+	c.indexedValues = map[string]map[string][]string{}
+	c.indices = Indices{}
+	return os.RemoveAll(c.dir)
+}
+
+func (c *diskThreadSafeStore) Add(key string, obj interface{}) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.setLocked(key, obj)
+}
+
+func (c *diskThreadSafeStore) Update(key string, obj interface{}) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.setLocked(key, obj)
+}
+
+func (c *diskThreadSafeStore) setLocked(key string, obj interface{}) {
+	indexedValues := make(map[string][]string, len(c.indexers))
+	for name, indexFunc := range c.indexers {
+		values, err := indexFunc(obj)
+		if err != nil {
+			panic(fmt.Errorf("unable to calculate an index entry for key %q on index %q: %v", key, name, err))
+		}
+		indexedValues[name] = values
+	}
+
+	c.deleteFromIndicesLocked(key)
+	data, err := c.codec.Encode(obj)
+	if err == nil {
+		err = c.disk.Write(fileKey(key), data)
+	}
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("unable to write object %q to disk, keeping it in memory: %v", key, err))
+		if old, exists := c.items[key]; exists && old == nil {
+			// Do not leave the replaced object behind on disk.
+			c.disk.Erase(fileKey(key))
+		}
+		c.items[key] = obj
+	} else {
+		c.items[key] = nil
+	}
+	c.indexedValues[key] = indexedValues
+	c.addToIndicesLocked(key)
+}
+
+// addToIndicesLocked adds key to the indices under its indexed values.
+func (c *diskThreadSafeStore) addToIndicesLocked(key string) {
+	for name, values := range c.indexedValues[key] {
+		index := c.indices[name]
+		if index == nil {
+			index = Index{}
+			c.indices[name] = index
+		}
+		for _, value := range values {
+			set := index[value]
+			if set == nil {
+				set = sets.String{}
+				index[value] = set
+			}
+			set.Insert(key)
+		}
+	}
+}
+
+// deleteFromIndicesLocked removes key from the indices, if it is there.
+func (c *diskThreadSafeStore) deleteFromIndicesLocked(key string) {
+	for name, values := range c.indexedValues[key] {
+		index := c.indices[name]
+		for _, value := range values {
+			if set := index[value]; set != nil {
+				set.Delete(key)
+				if len(set) == 0 {
+					delete(index, value)
+				}
+			}
+		}
+	}
+	delete(c.indexedValues, key)
+}
+
+func (c *diskThreadSafeStore) Delete(key string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.deleteLocked(key)
+}
+
+func (c *diskThreadSafeStore) deleteLocked(key string) {
+	item, exists := c.items[key]
+	if !exists {
+		return
+	}
+	c.deleteFromIndicesLocked(key)
+	delete(c.items, key)
+	if item == nil {
+		if err := c.disk.Erase(fileKey(key)); err != nil {
+			utilruntime.HandleError(fmt.Errorf("unable to erase object %q from disk: %v", key, err))
+		}
+	}
+}
+
+// readLocked returns the item of key, read from disk if it is there.
+func (c *diskThreadSafeStore) readLocked(key string) (interface{}, bool) {
+	item, exists := c.items[key]
+	if !exists || item != nil {
+		return item, exists
+	}
+	data, err := c.disk.Read(fileKey(key))
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("unable to read object %q from disk: %v", key, err))
+		return nil, false
+	}
+	item, err = c.codec.Decode(data)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("unable to decode object %q: %v", key, err))
+		return nil, false
+	}
+	return item, true
+}
+
+// readAllLocked returns the items of keys which could be read.
+func (c *diskThreadSafeStore) readAllLocked(keys sets.String) []interface{} {
+	list := make([]interface{}, 0, len(keys))
+	for key := range keys {
+		if item, exists := c.readLocked(key); exists {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func (c *diskThreadSafeStore) Get(key string) (item interface{}, exists bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.readLocked(key)
+}
+
+func (c *diskThreadSafeStore) List() []interface{} {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	list := make([]interface{}, 0, len(c.items))
+	for key := range c.items {
+		if item, exists := c.readLocked(key); exists {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func (c *diskThreadSafeStore) ListKeys() []string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	list := make([]string, 0, len(c.items))
+	for key := range c.items {
+		list = append(list, key)
+	}
+	return list
+}
+
+// ListKeysWithPrefix returns the keys starting with prefix, sorted.
+func (c *diskThreadSafeStore) ListKeysWithPrefix(prefix string) []string {
+	keys := []string{}
+	c.RangeKeys(prefix, prefixEnd(prefix), func(key string) bool {
+		if !strings.HasPrefix(key, prefix) {
+			return false
+		}
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+// RangeKeys calls fn in ascending order with each key from from, included,
+// to to, excluded, until fn returns false.  An empty to does not bound the
+// range.
+func (c *diskThreadSafeStore) RangeKeys(from, to string, fn func(key string) bool) {
+	var keys []string
+	c.lock.RLock()
+	for key := range c.items {
+		if key >= from && (to == "" || key < to) {
+			keys = append(keys, key)
+		}
+	}
+	c.lock.RUnlock()
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !fn(key) {
+			return
+		}
+	}
+}
+
+func (c *diskThreadSafeStore) Replace(items map[string]interface{}, resourceVersion string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for key := range c.items {
+		if _, exists := items[key]; !exists {
+			c.deleteLocked(key)
+		}
+	}
+	for key, item := range items {
+		c.setLocked(key, item)
+	}
+}
+
+func (c *diskThreadSafeStore) Index(indexName string, obj interface{}) ([]interface{}, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	indexFunc := c.indexers[indexName]
+	if indexFunc == nil {
+		return nil, fmt.Errorf("Index with name %s does not exist", indexName)
+	}
+	indexKeys, err := indexFunc(obj)
+	if err != nil {
+		return nil, err
+	}
+	index := c.indices[indexName]
+	keys := sets.String{}
+	for _, indexKey := range indexKeys {
+		keys = keys.Union(index[indexKey])
+	}
+	return c.readAllLocked(keys), nil
+}
+
+func (c *diskThreadSafeStore) IndexKeys(indexName, indexKey string) ([]string, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.indexers[indexName] == nil {
+		return nil, fmt.Errorf("Index with name %s does not exist", indexName)
+	}
+	return c.indices[indexName][indexKey].List(), nil
+}
+
+func (c *diskThreadSafeStore) ListIndexFuncValues(indexName string) []string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	index := c.indices[indexName]
+	names := make([]string, 0, len(index))
+	for key := range index {
+		names = append(names, key)
+	}
+	return names
+}
+
+func (c *diskThreadSafeStore) ByIndex(indexName, indexKey string) ([]interface{}, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.indexers[indexName] == nil {
+		return nil, fmt.Errorf("Index with name %s does not exist", indexName)
+	}
+	return c.readAllLocked(c.indices[indexName][indexKey]), nil
+}
+
+// ForEachByIndex calls fn with each item that matches an exact value on the
+// index function, until fn returns false.  fn is called with the store
+// read-locked and must not write to it.
+func (c *diskThreadSafeStore) ForEachByIndex(indexName, indexKey string, fn func(obj interface{}) bool) error {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.indexers[indexName] == nil {
+		return fmt.Errorf("Index with name %s does not exist", indexName)
+	}
+	for key := range c.indices[indexName][indexKey] {
+		item, exists := c.readLocked(key)
+		if !exists {
+			continue
+		}
+		if !fn(item) {
+			break
+		}
+	}
+	return nil
+}
+
+func (c *diskThreadSafeStore) ByIndexes(mode IndexQueryMode, queries ...IndexQuery) ([]interface{}, error) {
+	if mode != IndexUnion && mode != IndexIntersect {
+		return nil, fmt.Errorf("unknown index query mode %d", mode)
+	}
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	var keys sets.String
+	for i, query := range queries {
+		if c.indexers[query.IndexName] == nil {
+			return nil, fmt.Errorf("Index with name %s does not exist", query.IndexName)
+		}
+		set := c.indices[query.IndexName][query.IndexedValue]
+		switch {
+		case i == 0:
+			keys = sets.NewString().Union(set)
+		case mode == IndexUnion:
+			keys = keys.Union(set)
+		default:
+			keys = keys.Intersection(set)
+		}
+	}
+	return c.readAllLocked(keys), nil
+}
+
+func (c *diskThreadSafeStore) GetIndexStats(indexName string) (IndexStats, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.indexers[indexName] == nil {
+		return IndexStats{}, fmt.Errorf("Index with name %s does not exist", indexName)
+	}
+	index := c.indices[indexName]
+	sizes := make(map[string]int, len(index))
+	for value, keys := range index {
+		sizes[value] = len(keys)
+	}
+	return newIndexStats(sizes), nil
+}
+
+func (c *diskThreadSafeStore) GetIndexers() Indexers {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	indexers := make(Indexers, len(c.indexers))
+	for name, indexFunc := range c.indexers {
+		indexers[name] = indexFunc
+	}
+	return indexers
+}
+
+func (c *diskThreadSafeStore) AddIndexers(newIndexers Indexers) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if len(c.items) > 0 {
+		return fmt.Errorf("cannot add indexers to running index")
+	}
+	oldKeys := sets.StringKeySet(c.indexers)
+	newKeys := sets.StringKeySet(newIndexers)
+	if oldKeys.HasAny(newKeys.List()...) {
+		return fmt.Errorf("indexer conflict: %v", oldKeys.Intersection(newKeys))
+	}
+	for k, v := range newIndexers {
+		c.indexers[k] = v
+	}
+	return nil
+}
+
+// AddIndexersAfterStart adds more indexers to the store, reading every item
+// back from disk to index it.
+func (c *diskThreadSafeStore) AddIndexersAfterStart(newIndexers Indexers) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	oldKeys := sets.StringKeySet(c.indexers)
+	newKeys := sets.StringKeySet(newIndexers)
+	if oldKeys.HasAny(newKeys.List()...) {
+		return fmt.Errorf("indexer conflict: %v", oldKeys.Intersection(newKeys))
+	}
+
+	// Compute the new indexed values aside, so that a failing index
+	// function leaves the store unchanged.
+	indexedValues := make(map[string]map[string][]string, len(c.items))
+	for key := range c.items {
+		item, exists := c.readLocked(key)
+		if !exists {
+			continue
+		}
+		values := make(map[string][]string, len(newIndexers))
+		for name, indexFunc := range newIndexers {
+			itemValues, err := indexFunc(item)
+			if err != nil {
+				return fmt.Errorf("unable to calculate an index entry for key %q on index %q: %v", key, name, err)
+			}
+			values[name] = itemValues
+		}
+		indexedValues[key] = values
+	}
+
+	for name, indexFunc := range newIndexers {
+		c.indexers[name] = indexFunc
+		c.indices[name] = Index{}
+	}
+	for key, values := range indexedValues {
+		for name, itemValues := range values {
+			c.indexedValues[key][name] = itemValues
+			for _, value := range itemValues {
+				set := c.indices[name][value]
+				if set == nil {
+					set = sets.String{}
+					c.indices[name][value] = set
+				}
+				set.Insert(key)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *diskThreadSafeStore) RemoveIndexer(name string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if _, exists := c.indexers[name]; !exists {
+		return fmt.Errorf("Index with name %s does not exist", name)
+	}
+	delete(c.indexers, name)
+	delete(c.indices, name)
+	for _, values := range c.indexedValues {
+		delete(values, name)
+	}
+	return nil
+}
+
+func (c *diskThreadSafeStore) Resync() error {
+	// Nothing to do
+	return nil
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+func TestDiskIndexer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "disk-indexer-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	mkPod := func(name, image string) *v1.Pod {
+		return &v1.Pod{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name},
+			Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "c", Image: image}}},
+		}
+	}
+	imageIndex := func(obj interface{}) ([]string, error) {
+		return []string{obj.(*v1.Pod).Spec.Containers[0].Image}, nil
+	}
+	names := func(objs []interface{}) sets.String {
+		result := sets.NewString()
+		for _, obj := range objs {
+			result.Insert(obj.(*v1.Pod).Name)
+		}
+		return result
+	}
+
+	store, err := NewDiskIndexer(dir, NewCompressingStoreCodec(spillTestCodec()), MetaNamespaceKeyFunc, Indexers{"image": imageIndex})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	store.Add(mkPod("one", "a"))
+	store.Add(mkPod("two", "a"))
+
+	obj, exists, err := store.GetByKey("ns/one")
+	if err != nil || !exists {
+		t.Fatalf("expected ns/one to exist, got %v, %v", exists, err)
+	}
+	if e, a := mkPod("one", "a"), obj.(*v1.Pod); !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %#v, got %#v", e, a)
+	}
+	if again, _, _ := store.GetByKey("ns/one"); again == obj {
+		t.Errorf("expected every read to decode a new object")
+	}
+
+	store.Update(mkPod("one", "b"))
+	if pods, _ := store.ByIndex("image", "a"); !names(pods).Equal(sets.NewString("two")) {
+		t.Errorf("expected only two to use image a, got %v", names(pods).List())
+	}
+	if err := store.AddIndexersAfterStart(Indexers{NamespaceIndex: MetaNamespaceIndexFunc}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pods, _ := store.ByIndex(NamespaceIndex, "ns"); !names(pods).Equal(sets.NewString("one", "two")) {
+		t.Errorf("expected one and two in ns, got %v", names(pods).List())
+	}
+	store.Delete(mkPod("one", "b"))
+	if keys, _ := store.IndexKeys("image", "b"); len(keys) != 0 {
+		t.Errorf("expected no pod to use image b, got %v", keys)
+	}
+
+	store.Replace([]interface{}{mkPod("replaced", "c")}, "2")
+	if found := names(store.List()); !found.Equal(sets.NewString("replaced")) {
+		t.Errorf("expected only replaced, got %v", found.List())
+	}
+	if keys := store.ListKeysWithPrefix("ns/"); !reflect.DeepEqual(keys, []string{"ns/replaced"}) {
+		t.Errorf("expected ns/replaced, got %v", keys)
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+		t.Errorf("expected the files of the indexer to be removed, found %d", len(files))
+	}
+}
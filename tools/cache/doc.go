@@ -21,4 +21,9 @@ limitations under the License.
 // list currently available nodes), and one that additionally acts as
 // a FIFO queue (for example, to allow a scheduler to process incoming
 // pods).
+//
+// The informers and listers of this package handle interface{} values.  Typed
+// event handlers, whose functions take for example a *v1.Pod, can be added to
+// a TypedSharedIndexInformer, or to any informer through
+// NewTypedResourceEventHandler.
 package cache // import "k8s.io/client-go/tools/cache"
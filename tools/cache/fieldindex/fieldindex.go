@@ -0,0 +1,127 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fieldindex provides index functions over the fields the apiserver
+// supports in field selectors, so that informer caches can be queried with
+// the filters commonly used on the server.  Every Preset is indexed under
+// the name of its field selector:
+//
+//	fieldindex.Enable(podInformer, fieldindex.PodNodeName, fieldindex.PodPhase)
+//	pods, err := podInformer.GetIndexer().ByIndex("spec.nodeName", nodeName)
+package fieldindex // import "k8s.io/client-go/tools/cache/fieldindex"
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Preset is an index function mirroring a field selector.
+type Preset struct {
+	// Field is the field selector the preset mirrors, such as
+	// "spec.nodeName".  It is also the name of the index.
+	Field string
+	// IndexFunc returns the value of the field.
+	IndexFunc cache.IndexFunc
+}
+
+var (
+	// MetadataName indexes any object by metadata.name.
+	MetadataName = Preset{"metadata.name", metaField(metav1.Object.GetName)}
+	// MetadataNamespace indexes any object by metadata.namespace.
+	MetadataNamespace = Preset{"metadata.namespace", metaField(metav1.Object.GetNamespace)}
+
+	// PodNodeName indexes pods by spec.nodeName.  Pods that are not
+	// scheduled yet are indexed under "".  It is the index PodLister's
+	// ByNodeName uses.
+	PodNodeName = Preset{corelisters.PodNodeNameIndex, corelisters.PodNodeNameIndexFunc}
+	// PodPhase indexes pods by status.phase.
+	PodPhase = Preset{"status.phase", podField(func(pod *v1.Pod) string { return string(pod.Status.Phase) })}
+	// PodSchedulerName indexes pods by spec.schedulerName.
+	PodSchedulerName = Preset{"spec.schedulerName", podField(func(pod *v1.Pod) string { return pod.Spec.SchedulerName })}
+	// PodServiceAccountName indexes pods by spec.serviceAccountName.
+	PodServiceAccountName = Preset{"spec.serviceAccountName", podField(func(pod *v1.Pod) string { return pod.Spec.ServiceAccountName })}
+	// PodIP indexes pods by status.podIP.
+	PodIP = Preset{"status.podIP", podField(func(pod *v1.Pod) string { return pod.Status.PodIP })}
+
+	// EventInvolvedObjectKind indexes events by involvedObject.kind.
+	EventInvolvedObjectKind = Preset{"involvedObject.kind", eventField(func(event *v1.Event) string { return event.InvolvedObject.Kind })}
+	// EventInvolvedObjectNamespace indexes events by involvedObject.namespace.
+	EventInvolvedObjectNamespace = Preset{"involvedObject.namespace", eventField(func(event *v1.Event) string { return event.InvolvedObject.Namespace })}
+	// EventInvolvedObjectName indexes events by involvedObject.name.
+	EventInvolvedObjectName = Preset{"involvedObject.name", eventField(func(event *v1.Event) string { return event.InvolvedObject.Name })}
+	// EventInvolvedObjectUID indexes events by involvedObject.uid.
+	EventInvolvedObjectUID = Preset{"involvedObject.uid", eventField(func(event *v1.Event) string { return string(event.InvolvedObject.UID) })}
+	// EventReason indexes events by reason.
+	EventReason = Preset{"reason", eventField(func(event *v1.Event) string { return event.Reason })}
+	// EventType indexes events by type.
+	EventType = Preset{"type", eventField(func(event *v1.Event) string { return event.Type })}
+)
+
+// Indexers returns the Indexers of presets.
+func Indexers(presets ...Preset) cache.Indexers {
+	indexers := cache.Indexers{}
+	for _, preset := range presets {
+		indexers[preset.Field] = preset.IndexFunc
+	}
+	return indexers
+}
+
+// Enable adds the indexes of presets to informer, which must not have been
+// started yet.
+func Enable(informer cache.SharedIndexInformer, presets ...Preset) error {
+	return informer.AddIndexers(Indexers(presets...))
+}
+
+// ByField returns the objects of indexer whose field, indexed by preset, has
+// the given value.
+func ByField(indexer cache.Indexer, preset Preset, value string) ([]interface{}, error) {
+	return indexer.ByIndex(preset.Field, value)
+}
+
+func metaField(field func(metav1.Object) string) cache.IndexFunc {
+	return func(obj interface{}) ([]string, error) {
+		objMeta, err := meta.Accessor(obj)
+		if err != nil {
+			return nil, fmt.Errorf("object has no meta: %v", err)
+		}
+		return []string{field(objMeta)}, nil
+	}
+}
+
+func podField(field func(*v1.Pod) string) cache.IndexFunc {
+	return func(obj interface{}) ([]string, error) {
+		pod, ok := obj.(*v1.Pod)
+		if !ok {
+			return nil, fmt.Errorf("expected a *v1.Pod, got %T", obj)
+		}
+		return []string{field(pod)}, nil
+	}
+}
+
+func eventField(field func(*v1.Event) string) cache.IndexFunc {
+	return func(obj interface{}) ([]string, error) {
+		event, ok := obj.(*v1.Event)
+		if !ok {
+			return nil, fmt.Errorf("expected a *v1.Event, got %T", obj)
+		}
+		return []string{field(event)}, nil
+	}
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fieldindex
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+	fcache "k8s.io/client-go/tools/cache/testing"
+)
+
+func names(objs []interface{}) sets.String {
+	result := sets.NewString()
+	for _, obj := range objs {
+		result.Insert(obj.(metav1.Object).GetName())
+	}
+	return result
+}
+
+func TestPodPresets(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, Indexers(PodNodeName, PodPhase, MetadataNamespace))
+	for _, pod := range []*v1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "running"}, Spec: v1.PodSpec{NodeName: "node1"}, Status: v1.PodStatus{Phase: v1.PodRunning}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "b", Name: "failed"}, Spec: v1.PodSpec{NodeName: "node1"}, Status: v1.PodStatus{Phase: v1.PodFailed}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "pending"}, Status: v1.PodStatus{Phase: v1.PodPending}},
+	} {
+		if err := indexer.Add(pod); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	tests := []struct {
+		preset   Preset
+		value    string
+		expected sets.String
+	}{
+		{PodNodeName, "node1", sets.NewString("running", "failed")},
+		{PodNodeName, "", sets.NewString("pending")},
+		{PodPhase, string(v1.PodFailed), sets.NewString("failed")},
+		{MetadataNamespace, "a", sets.NewString("running", "pending")},
+	}
+	for _, test := range tests {
+		objs, err := ByField(indexer, test.preset, test.value)
+		if err != nil {
+			t.Errorf("%s=%q: unexpected error: %v", test.preset.Field, test.value, err)
+			continue
+		}
+		if got := names(objs); !got.Equal(test.expected) {
+			t.Errorf("%s=%q: expected %v, got %v", test.preset.Field, test.value, test.expected.List(), got.List())
+		}
+	}
+
+	if _, err := PodNodeName.IndexFunc(&v1.Event{}); err == nil {
+		t.Errorf("expected an error indexing an event with a pod preset")
+	}
+}
+
+func TestEnable(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Namespace: "ns", Name: "scheduled"},
+		InvolvedObject: v1.ObjectReference{Kind: "Pod", Namespace: "ns", Name: "pod1"},
+		Reason:         "Scheduled",
+	})
+	source.Add(&v1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Namespace: "ns", Name: "pulled"},
+		InvolvedObject: v1.ObjectReference{Kind: "Pod", Namespace: "ns", Name: "pod2"},
+		Reason:         "Pulled",
+	})
+	informer := cache.NewSharedIndexInformer(source, &v1.Event{}, 0, cache.Indexers{})
+	if err := Enable(informer, EventInvolvedObjectName, EventReason); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	if !cache.WaitForCacheSync(stop, informer.HasSynced) {
+		t.Fatalf("informer did not sync")
+	}
+	objs, err := informer.GetIndexer().ByIndex("involvedObject.name", "pod2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := names(objs); !got.Equal(sets.NewString("pulled")) {
+		t.Errorf("expected the event of pod2, got %v", got.List())
+	}
+	if err := Enable(informer, EventType); err == nil {
+		t.Errorf("expected an error enabling presets on a started informer")
+	}
+}
@@ -31,6 +31,13 @@ import (
 // IndexFunc and can be a field value or any other string computed from the object.
 type Indexer interface {
 	Store
+	// ListKeysWithPrefix returns the storage keys starting with prefix,
+	// sorted, such as those of a namespace with a "namespace/" prefix
+	ListKeysWithPrefix(prefix string) []string
+	// RangeKeys calls fn in ascending order with each storage key from
+	// from, included, to to, excluded, until fn returns false.  An empty
+	// to does not bound the range.  fn must not write to the store
+	RangeKeys(from, to string, fn func(key string) bool)
 	// Index returns the stored objects whose set of indexed values
 	// intersects the set of indexed values of the given object, for
 	// the named index
@@ -44,14 +51,49 @@ type Indexer interface {
 	// ByIndex returns the stored objects whose set of indexed values
 	// for the named index includes the given indexed value
 	ByIndex(indexName, indexedValue string) ([]interface{}, error)
+	// ForEachByIndex calls fn with each stored object whose set of indexed
+	// values for the named index includes the given indexed value, until fn
+	// returns false, without building a list of them.  fn must not write
+	// to the store
+	ForEachByIndex(indexName, indexedValue string, fn func(obj interface{}) bool) error
+	// ByIndexes returns the stored objects matching the union or the
+	// intersection, depending on mode, of the given index queries
+	ByIndexes(mode IndexQueryMode, queries ...IndexQuery) ([]interface{}, error)
+	// GetIndexStats returns the number of indexed values, the average
+	// number of objects per indexed value and the indexed values with the
+	// most objects of the named index
+	GetIndexStats(indexName string) (IndexStats, error)
 	// GetIndexer return the indexers
 	GetIndexers() Indexers
 
 	// AddIndexers adds more indexers to this store.  If you call this after you already have data
 	// in the store, the results are undefined.
 	AddIndexers(newIndexers Indexers) error
+	// AddIndexersAfterStart adds more indexers to this store, indexing the
+	// objects it already holds.  It fails if an index function fails for
+	// one of them, without adding any indexer.
+	AddIndexersAfterStart(newIndexers Indexers) error
+	// RemoveIndexer removes the named indexer and its index.
+	RemoveIndexer(name string) error
 }
 
+// IndexQuery selects the stored objects whose set of indexed values for the
+// named index includes the given indexed value.
+type IndexQuery struct {
+	IndexName    string
+	IndexedValue string
+}
+
+// IndexQueryMode says how ByIndexes combines the results of its queries.
+type IndexQueryMode int
+
+const (
+	// IndexUnion matches the objects matching any of the queries.
+	IndexUnion IndexQueryMode = iota
+	// IndexIntersect matches the objects matching all of the queries.
+	IndexIntersect
+)
+
 // IndexFunc knows how to compute the set of indexed values for an object.
 type IndexFunc func(obj interface{}) ([]string, error)
 
@@ -77,8 +119,81 @@ func IndexFuncToKeyFuncAdapter(indexFunc IndexFunc) KeyFunc {
 const (
 	// NamespaceIndex is the lookup name for the most comment index function, which is to index by the namespace field.
 	NamespaceIndex string = "namespace"
+	// ClusterIndex is the lookup name of MetaClusterIndexFunc.
+	ClusterIndex string = "cluster"
+	// ClusterNamespaceIndex is the lookup name of MetaClusterNamespaceIndexFunc.
+	ClusterNamespaceIndex string = "cluster-namespace"
+	// LabelIndex is the lookup name of LabelIndexFunc.  The stores returned
+	// by NewIndexer having an index of that name use it to list the objects
+	// matching a label selector, so it must not be used for another index
+	// function.
+	LabelIndex string = "label"
+	// OwnerReferenceIndex is the lookup name of OwnerReferenceIndexFunc.
+	OwnerReferenceIndex string = "owner"
 )
 
+// OwnerReferenceIndexFunc is an index function that indexes an object under
+// the UID of each of its owners.
+func OwnerReferenceIndexFunc(obj interface{}) ([]string, error) {
+	meta, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, fmt.Errorf("object has no meta: %v", err)
+	}
+	ownerReferences := meta.GetOwnerReferences()
+	uids := make([]string, 0, len(ownerReferences))
+	for _, ownerReference := range ownerReferences {
+		uids = append(uids, string(ownerReference.UID))
+	}
+	return uids, nil
+}
+
+// LabelIndexFunc is an index function that indexes an object under each of
+// its labels, as returned by LabelIndexValue, such as "app=frontend".
+func LabelIndexFunc(obj interface{}) ([]string, error) {
+	meta, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, fmt.Errorf("object has no meta: %v", err)
+	}
+	objLabels := meta.GetLabels()
+	values := make([]string, 0, len(objLabels))
+	for key, value := range objLabels {
+		values = append(values, LabelIndexValue(key, value))
+	}
+	return values, nil
+}
+
+// LabelIndexValue returns the value LabelIndexFunc indexes the objects with
+// the given label under.
+func LabelIndexValue(key, value string) string {
+	return key + "=" + value
+}
+
+// MetaClusterIndexFunc is an index function that indexes based on an object's cluster
+func MetaClusterIndexFunc(obj interface{}) ([]string, error) {
+	meta, err := meta.Accessor(obj)
+	if err != nil {
+		return []string{""}, fmt.Errorf("object has no meta: %v", err)
+	}
+	return []string{meta.GetClusterName()}, nil
+}
+
+// MetaClusterNamespaceIndexFunc is an index function that indexes based on an
+// object's cluster and namespace, as returned by ClusterNamespaceIndexValue.
+func MetaClusterNamespaceIndexFunc(obj interface{}) ([]string, error) {
+	meta, err := meta.Accessor(obj)
+	if err != nil {
+		return []string{""}, fmt.Errorf("object has no meta: %v", err)
+	}
+	return []string{ClusterNamespaceIndexValue(meta.GetClusterName(), meta.GetNamespace())}, nil
+}
+
+// ClusterNamespaceIndexValue returns the value MetaClusterNamespaceIndexFunc
+// indexes the objects of the given cluster and namespace under, which is their
+// ClusterObjectKey without the name, such as "cluster|namespace/".
+func ClusterNamespaceIndexValue(cluster, namespace string) string {
+	return ClusterObjectKey(cluster, namespace, "")
+}
+
 // MetaNamespaceIndexFunc is a default index function that indexes based on an object's namespace
 func MetaNamespaceIndexFunc(obj interface{}) ([]string, error) {
 	meta, err := meta.Accessor(obj)
@@ -0,0 +1,90 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"sort"
+)
+
+// maxLargestIndexBuckets is the number of buckets IndexStats.LargestBuckets
+// holds at most.
+const maxLargestIndexBuckets = 10
+
+// IndexStats describes how the objects of an indexer spread over the indexed
+// values of one of its indexes.  An index whose largest buckets hold most of
+// the objects, for example one on a field with a single value, makes ByIndex
+// as slow as List.
+type IndexStats struct {
+	// IndexedValues is the number of indexed values, or buckets, of the
+	// index.
+	IndexedValues int
+	// AverageBucketSize is the average number of objects per indexed value.
+	AverageBucketSize float64
+	// LargestBuckets are the largest buckets of the index, largest first.
+	LargestBuckets []IndexBucket
+}
+
+// IndexBucket is the set of objects of an index having an indexed value.
+type IndexBucket struct {
+	IndexedValue string
+	Size         int
+}
+
+// newIndexStats returns the stats of an index whose buckets have the given
+// sizes.
+func newIndexStats(sizes map[string]int) IndexStats {
+	stats := IndexStats{IndexedValues: len(sizes)}
+	total := 0
+	buckets := make([]IndexBucket, 0, len(sizes))
+	for value, size := range sizes {
+		total += size
+		buckets = append(buckets, IndexBucket{IndexedValue: value, Size: size})
+	}
+	if len(sizes) > 0 {
+		stats.AverageBucketSize = float64(total) / float64(len(sizes))
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		if buckets[i].Size != buckets[j].Size {
+			return buckets[i].Size > buckets[j].Size
+		}
+		return buckets[i].IndexedValue < buckets[j].IndexedValue
+	})
+	if len(buckets) > maxLargestIndexBuckets {
+		buckets = buckets[:maxLargestIndexBuckets]
+	}
+	stats.LargestBuckets = buckets
+	return stats
+}
+
+// GetIndexStats returns the stats of the named index.
+func (c *threadSafeMap) GetIndexStats(indexName string) (IndexStats, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.indexers[indexName] == nil {
+		return IndexStats{}, fmt.Errorf("Index with name %s does not exist", indexName)
+	}
+	index := c.indices[indexName]
+	sizes := make(map[string]int, len(index))
+	for value, keys := range index {
+		if len(keys) > 0 {
+			sizes[value] = len(keys)
+		}
+	}
+	return newIndexStats(sizes), nil
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetIndexStats(t *testing.T) {
+	index := NewIndexer(MetaNamespaceKeyFunc, Indexers{"testmodes": testIndexFunc})
+	pod := func(name, foo string) *v1.Pod {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"foo": foo}}}
+	}
+	for i := 0; i < 5; i++ {
+		index.Add(pod(fmt.Sprintf("hot-%d", i), "hot"))
+	}
+	index.Add(pod("warm-0", "warm"))
+	index.Add(pod("warm-1", "warm"))
+	for i := 0; i < 12; i++ {
+		index.Add(pod(fmt.Sprintf("cold-%d", i), fmt.Sprintf("cold-%02d", i)))
+	}
+	index.Add(pod("gone", "gone"))
+	index.Delete(pod("gone", "gone"))
+
+	stats, err := index.GetIndexStats("testmodes")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.IndexedValues != 14 {
+		t.Errorf("expected 14 indexed values, got %d", stats.IndexedValues)
+	}
+	if stats.AverageBucketSize != 19.0/14 {
+		t.Errorf("expected an average bucket size of %v, got %v", 19.0/14, stats.AverageBucketSize)
+	}
+	if len(stats.LargestBuckets) != maxLargestIndexBuckets {
+		t.Fatalf("expected %d largest buckets, got %v", maxLargestIndexBuckets, stats.LargestBuckets)
+	}
+	expected := []IndexBucket{{"hot", 5}, {"warm", 2}, {"cold-00", 1}}
+	for i, bucket := range expected {
+		if stats.LargestBuckets[i] != bucket {
+			t.Errorf("expected bucket %d to be %v, got %v", i, bucket, stats.LargestBuckets[i])
+		}
+	}
+
+	if _, err := index.GetIndexStats("missing"); err == nil {
+		t.Errorf("expected an error for a missing index")
+	}
+	empty, err := NewIndexer(MetaNamespaceKeyFunc, Indexers{"testmodes": testIndexFunc}).GetIndexStats("testmodes")
+	if err != nil || empty.IndexedValues != 0 || empty.AverageBucketSize != 0 || len(empty.LargestBuckets) != 0 {
+		t.Errorf("expected empty stats, got %v, %v", empty, err)
+	}
+}
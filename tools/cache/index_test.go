@@ -17,6 +17,9 @@ limitations under the License.
 package cache
 
 import (
+	"fmt"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"strings"
 	"testing"
@@ -132,3 +135,257 @@ func TestMultiIndexKeys(t *testing.T) {
 		}
 	}
 }
+
+func TestAddIndexersAfterStartAndRemoveIndexer(t *testing.T) {
+	index := NewIndexer(MetaNamespaceKeyFunc, Indexers{})
+	index.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "one", Labels: map[string]string{"foo": "bar"}}})
+	index.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "two", Labels: map[string]string{"foo": "biz"}}})
+
+	failing := func(obj interface{}) ([]string, error) {
+		return nil, fmt.Errorf("cannot index")
+	}
+	if err := index.AddIndexersAfterStart(Indexers{"testmodes": testIndexFunc, "failing": failing}); err == nil {
+		t.Errorf("expected a failing index function to fail")
+	}
+	if indexers := index.GetIndexers(); len(indexers) != 0 {
+		t.Errorf("expected no indexer to be added, got %v", indexers)
+	}
+
+	if err := index.AddIndexersAfterStart(Indexers{"testmodes": testIndexFunc}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if keys, _ := index.IndexKeys("testmodes", "bar"); !sets.NewString("one").Equal(sets.NewString(keys...)) {
+		t.Errorf("expected the existing pod one to be indexed, got %v", keys)
+	}
+	index.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "tre", Labels: map[string]string{"foo": "bar"}}})
+	if keys, _ := index.IndexKeys("testmodes", "bar"); !sets.NewString("one", "tre").Equal(sets.NewString(keys...)) {
+		t.Errorf("expected pods one and tre to be indexed, got %v", keys)
+	}
+	if err := index.AddIndexersAfterStart(Indexers{"testmodes": testIndexFunc}); err == nil {
+		t.Errorf("expected an error adding an existing indexer")
+	}
+
+	if err := index.RemoveIndexer("testmodes"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := index.ByIndex("testmodes", "bar"); err == nil {
+		t.Errorf("expected the removed index not to exist")
+	}
+	if err := index.RemoveIndexer("testmodes"); err == nil {
+		t.Errorf("expected an error removing a missing indexer")
+	}
+	index.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "four", Labels: map[string]string{"foo": "bar"}}})
+}
+
+func TestByIndexes(t *testing.T) {
+	index := NewIndexer(MetaNamespaceKeyFunc, Indexers{NamespaceIndex: MetaNamespaceIndexFunc, "testmodes": testIndexFunc})
+	index.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "one", Namespace: "a", Labels: map[string]string{"foo": "bar"}}})
+	index.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "two", Namespace: "a", Labels: map[string]string{"foo": "biz"}}})
+	index.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "tre", Namespace: "b", Labels: map[string]string{"foo": "bar"}}})
+
+	names := func(objs []interface{}) sets.String {
+		result := sets.NewString()
+		for _, obj := range objs {
+			result.Insert(obj.(*v1.Pod).Name)
+		}
+		return result
+	}
+	inA := IndexQuery{IndexName: NamespaceIndex, IndexedValue: "a"}
+	bar := IndexQuery{IndexName: "testmodes", IndexedValue: "bar"}
+
+	tests := []struct {
+		name     string
+		mode     IndexQueryMode
+		queries  []IndexQuery
+		expected sets.String
+	}{
+		{"union", IndexUnion, []IndexQuery{inA, bar}, sets.NewString("one", "two", "tre")},
+		{"intersection", IndexIntersect, []IndexQuery{inA, bar}, sets.NewString("one")},
+		{"single query", IndexIntersect, []IndexQuery{bar}, sets.NewString("one", "tre")},
+		{"unknown value", IndexIntersect, []IndexQuery{inA, {IndexName: "testmodes", IndexedValue: "none"}}, sets.NewString()},
+		{"no query", IndexUnion, nil, sets.NewString()},
+	}
+	for _, test := range tests {
+		objs, err := index.ByIndexes(test.mode, test.queries...)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if found := names(objs); !found.Equal(test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected.List(), found.List())
+		}
+	}
+
+	if _, err := index.ByIndexes(IndexUnion, inA, IndexQuery{IndexName: "missing"}); err == nil {
+		t.Errorf("expected an error querying a missing index")
+	}
+	if _, err := index.ByIndexes(IndexQueryMode(7), inA); err == nil {
+		t.Errorf("expected an error for an unknown mode")
+	}
+}
+
+func TestForEachByIndex(t *testing.T) {
+	index := NewIndexer(MetaNamespaceKeyFunc, Indexers{"testmodes": testIndexFunc})
+	index.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "one", Labels: map[string]string{"foo": "bar"}}})
+	index.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "two", Labels: map[string]string{"foo": "bar"}}})
+	index.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "tre", Labels: map[string]string{"foo": "biz"}}})
+
+	found := sets.NewString()
+	err := index.ForEachByIndex("testmodes", "bar", func(obj interface{}) bool {
+		found.Insert(obj.(*v1.Pod).Name)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := sets.NewString("one", "two"); !found.Equal(expected) {
+		t.Errorf("expected %v, got %v", expected.List(), found.List())
+	}
+
+	calls := 0
+	index.ForEachByIndex("testmodes", "bar", func(obj interface{}) bool {
+		calls++
+		return false
+	})
+	if calls != 1 {
+		t.Errorf("expected iteration to stop after 1 call, got %d", calls)
+	}
+
+	if err := index.ForEachByIndex("missing", "bar", func(interface{}) bool { return true }); err == nil {
+		t.Errorf("expected an error iterating a missing index")
+	}
+}
+
+func TestLabelIndex(t *testing.T) {
+	index := NewIndexer(MetaNamespaceKeyFunc, Indexers{LabelIndex: LabelIndexFunc})
+	index.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "one", Labels: map[string]string{"app": "frontend", "tier": "web"}}})
+	index.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "two", Labels: map[string]string{"app": "frontend", "tier": "cache"}}})
+	index.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "tre", Labels: map[string]string{"app": "backend"}}})
+
+	list := func(selector string) sets.String {
+		names := sets.NewString()
+		parsed, err := labels.Parse(selector)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := ListAll(index, parsed, func(obj interface{}) { names.Insert(obj.(*v1.Pod).Name) }); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return names
+	}
+	byIndex := func(value string) sets.String {
+		objs, err := index.ByIndex(LabelIndex, value)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		names := sets.NewString()
+		for _, obj := range objs {
+			names.Insert(obj.(*v1.Pod).Name)
+		}
+		return names
+	}
+
+	if found := byIndex(LabelIndexValue("app", "frontend")); !found.Equal(sets.NewString("one", "two")) {
+		t.Errorf("expected one and two, got %v", found.List())
+	}
+	tests := map[string]sets.String{
+		"app=frontend":               sets.NewString("one", "two"),
+		"app=frontend,tier!=web":     sets.NewString("two"),
+		"app in (frontend, backend)": sets.NewString("one", "two", "tre"),
+		"app=frontend,tier=none":     sets.NewString(),
+		"tier":                       sets.NewString("one", "two"),
+		"app!=frontend":              sets.NewString("tre"),
+	}
+	for selector, expected := range tests {
+		if found := list(selector); !found.Equal(expected) {
+			t.Errorf("%s: expected %v, got %v", selector, expected.List(), found.List())
+		}
+	}
+
+	index.Update(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "one", Labels: map[string]string{"app": "backend"}}})
+	if found := byIndex(LabelIndexValue("app", "frontend")); !found.Equal(sets.NewString("two")) {
+		t.Errorf("expected two after relabeling one, got %v", found.List())
+	}
+	if found := list("app=backend"); !found.Equal(sets.NewString("one", "tre")) {
+		t.Errorf("expected one and tre after relabeling one, got %v", found.List())
+	}
+	index.Delete(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "tre"}})
+	if found := list("app=backend"); !found.Equal(sets.NewString("one")) {
+		t.Errorf("expected one after deleting tre, got %v", found.List())
+	}
+}
+
+func TestGetObjectsOwnedBy(t *testing.T) {
+	owned := func(name string, owners ...types.UID) *v1.Pod {
+		pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		for _, owner := range owners {
+			pod.OwnerReferences = append(pod.OwnerReferences, metav1.OwnerReference{UID: owner})
+		}
+		return pod
+	}
+	for _, indexers := range []Indexers{{OwnerReferenceIndex: OwnerReferenceIndexFunc}, {}} {
+		index := NewIndexer(MetaNamespaceKeyFunc, indexers)
+		index.Add(owned("one", "rs-1"))
+		index.Add(owned("two", "rs-1", "rs-2"))
+		index.Add(owned("tre"))
+
+		for owner, expected := range map[types.UID]sets.String{
+			"rs-1": sets.NewString("one", "two"),
+			"rs-2": sets.NewString("two"),
+			"rs-3": sets.NewString(),
+		} {
+			objs, err := GetObjectsOwnedBy(index, owner)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			found := sets.NewString()
+			for _, obj := range objs {
+				found.Insert(obj.(*v1.Pod).Name)
+			}
+			if !found.Equal(expected) {
+				t.Errorf("indexers %v, owner %s: expected %v, got %v", indexers, owner, expected.List(), found.List())
+			}
+		}
+	}
+}
+
+func TestCompositeIndex(t *testing.T) {
+	nodeNameIndexFunc := func(obj interface{}) ([]string, error) {
+		return []string{obj.(*v1.Pod).Spec.NodeName}, nil
+	}
+	index := NewIndexer(MetaNamespaceKeyFunc, Indexers{"by-node": CompositeIndexFunc(MetaNamespaceIndexFunc, nodeNameIndexFunc)})
+	pod := func(namespace, name, node string) *v1.Pod {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}, Spec: v1.PodSpec{NodeName: node}}
+	}
+	index.Add(pod("a", "one", "node-1"))
+	index.Add(pod("a", "two", "node-2"))
+	index.Add(pod("b", "tre", "node-1"))
+	index.Add(pod("a|node-1", "four", ""))
+
+	tests := map[[2]string]sets.String{
+		{"a", "node-1"}:  sets.NewString("one"),
+		{"a", "node-2"}:  sets.NewString("two"),
+		{"b", "node-1"}:  sets.NewString("tre"),
+		{"b", "node-2"}:  sets.NewString(),
+		{"a|node-1", ""}: sets.NewString("four"),
+	}
+	for parts, expected := range tests {
+		objs, err := Index2(index, "by-node", parts[0], parts[1])
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		found := sets.NewString()
+		for _, obj := range objs {
+			found.Insert(obj.(*v1.Pod).Name)
+		}
+		if !found.Equal(expected) {
+			t.Errorf("%q: expected %v, got %v", parts, expected.List(), found.List())
+		}
+	}
+
+	for _, parts := range [][]string{{"a", "b"}, {"a|b", ""}, {`a\`, "|"}, {""}, {"x", "", "y"}} {
+		if split := SplitCompositeIndexValue(CompositeIndexValue(parts...)); strings.Join(split, ",") != strings.Join(parts, ",") || len(split) != len(parts) {
+			t.Errorf("expected %q back, got %q", parts, split)
+		}
+	}
+}
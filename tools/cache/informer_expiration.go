@@ -0,0 +1,110 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"time"
+
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+)
+
+// expirationChecksPerTTL is how many times per TTL an informer looks for
+// expired objects, see WithTTL.
+const expirationChecksPerTTL = 10
+
+// objectKey returns the key of obj in the informer's cache.
+func (s *sharedIndexInformer) objectKey(obj interface{}) (string, error) {
+	if keyFunc := s.deltaFIFOOptions.KeyFunction; keyFunc != nil {
+		if d, ok := obj.(DeletedFinalStateUnknown); ok {
+			return d.Key, nil
+		}
+		return keyFunc(obj)
+	}
+	return DeletionHandlingMetaNamespaceKeyFunc(obj)
+}
+
+// recordWrite records that obj was written to the cache, if the informer
+// has a TTL.  It must be called with blockDeltas held.
+func (s *sharedIndexInformer) recordWrite(obj interface{}) {
+	if s.ttl <= 0 {
+		return
+	}
+	key, err := s.objectKey(obj)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("unable to track the expiration of %#v: %v", obj, err))
+		return
+	}
+	s.writeTimes[key] = s.clock.Now()
+}
+
+// forgetWrite stops tracking the expiration of obj, deleted from the cache.
+// It must be called with blockDeltas held.
+func (s *sharedIndexInformer) forgetWrite(obj interface{}) {
+	if s.ttl <= 0 {
+		return
+	}
+	if key, err := s.objectKey(obj); err == nil {
+		delete(s.writeTimes, key)
+	}
+}
+
+// runExpiration removes the expired objects from the cache until stopCh is
+// closed.
+func (s *sharedIndexInformer) runExpiration(stopCh <-chan struct{}) {
+	ticker := s.clock.NewTicker(expirationCheckPeriod(s.ttl))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C():
+			s.expire()
+		}
+	}
+}
+
+// expire removes the objects that were last written to the cache more than
+// a TTL ago, notifying the handlers of their deletion.
+func (s *sharedIndexInformer) expire() {
+	s.blockDeltas.Lock()
+	defer s.blockDeltas.Unlock()
+
+	policy := &TTLPolicy{TTL: s.ttl, Clock: s.clock}
+	for key, written := range s.writeTimes {
+		if !policy.IsExpired(&TimestampedEntry{Timestamp: written}) {
+			continue
+		}
+		delete(s.writeTimes, key)
+		obj, exists, err := s.indexer.GetByKey(key)
+		if err != nil || !exists {
+			continue
+		}
+		if err := s.indexer.Delete(obj); err != nil {
+			utilruntime.HandleError(fmt.Errorf("unable to expire %s: %v", key, err))
+			continue
+		}
+		s.objectsChanged(-1)
+		s.processor.distribute(deleteNotification{oldObj: obj}, false)
+	}
+}
+
+// expirationCheckPeriod returns how often an informer with the given TTL
+// looks for expired objects.
+func expirationCheckPeriod(ttl time.Duration) time.Duration {
+	return ttl / expirationChecksPerTTL
+}
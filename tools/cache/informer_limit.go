@@ -0,0 +1,86 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+)
+
+// ObjectLimitFunc is called when the cache of an informer grows past its
+// limit, see WithMaxObjects.
+type ObjectLimitFunc func(objects int)
+
+// WithMaxObjects makes the informer call exceeded when an object is added to
+// its cache while it holds more than max objects, for example to alert about
+// or to stop an informer whose objects are being created without bound.  The
+// informer keeps caching the objects.  exceeded is called once each time the
+// cache goes past the limit, with the number of objects, while the informer
+// holds back the notifications, so it must not add or remove event handlers;
+// it may close the stop channel of the informer.  It panics if max is not
+// positive or exceeded is nil.
+func WithMaxObjects(max int, exceeded ObjectLimitFunc) SharedIndexInformerOption {
+	if max <= 0 {
+		panic(fmt.Errorf("max objects must be positive, got %d", max))
+	}
+	if exceeded == nil {
+		panic(fmt.Errorf("max objects callback must be set"))
+	}
+	return func(informer *sharedIndexInformer) *sharedIndexInformer {
+		informer.maxObjects = max
+		informer.objectLimitExceeded = exceeded
+		return informer
+	}
+}
+
+// objectsChanged records that delta objects were added to the cache, or
+// removed if negative, then calls the object limit callback if the cache just
+// went past the limit, and rearms it once the cache is back within it.  It
+// must be called with blockDeltas held.
+func (s *sharedIndexInformer) objectsChanged(delta int) {
+	if s.maxObjects <= 0 {
+		return
+	}
+	// The objects are counted once, which also takes the objects of a
+	// cache set with WithIndexer into account, and then kept track of.
+	if s.objectsCounted {
+		s.objects += delta
+	} else {
+		s.objects = storeLen(s.indexer)
+		s.objectsCounted = true
+	}
+	if s.objects <= s.maxObjects {
+		s.overObjectLimit = false
+		return
+	}
+	if s.overObjectLimit {
+		return
+	}
+	s.overObjectLimit = true
+	s.objectLimitExceeded(s.objects)
+}
+
+// storeLen returns the number of objects of store.
+func storeLen(store Store) int {
+	if c, ok := store.(*cache); ok {
+		if storage, ok := c.cacheStorage.(*threadSafeMap); ok {
+			storage.lock.RLock()
+			defer storage.lock.RUnlock()
+			return len(storage.items)
+		}
+	}
+	return len(store.ListKeys())
+}
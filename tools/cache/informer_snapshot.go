@@ -0,0 +1,104 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// informerSnapshot is the content of the files written by SaveSnapshot.
+type informerSnapshot struct {
+	// ResourceVersion is the resource version the items are at.
+	ResourceVersion string `json:"resourceVersion"`
+	// Items are the JSON encodings of the objects of the cache.
+	Items []json.RawMessage `json:"items"`
+}
+
+// writeSnapshot writes the objects at resourceVersion to path.  The file is
+// replaced atomically, so that a crash leaves either the previous snapshot or
+// the new one.
+func writeSnapshot(path, resourceVersion string, objs []interface{}) error {
+	snapshot := informerSnapshot{
+		ResourceVersion: resourceVersion,
+		Items:           make([]json.RawMessage, 0, len(objs)),
+	}
+	for _, obj := range objs {
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return fmt.Errorf("unable to encode %T: %v", obj, err)
+		}
+		snapshot.Items = append(snapshot.Items, data)
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// readSnapshot reads the snapshot written to path, decoding its items as
+// objects of the type of objectType.
+func readSnapshot(path string, objectType runtime.Object) (string, []interface{}, error) {
+	t := reflect.TypeOf(objectType)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return "", nil, fmt.Errorf("unable to decode objects of type %T", objectType)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", nil, err
+	}
+	var snapshot informerSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return "", nil, fmt.Errorf("unable to decode snapshot %s: %v", path, err)
+	}
+	if len(snapshot.ResourceVersion) == 0 {
+		return "", nil, fmt.Errorf("snapshot %s has no resource version", path)
+	}
+	objs := make([]interface{}, 0, len(snapshot.Items))
+	for _, item := range snapshot.Items {
+		obj := reflect.New(t.Elem()).Interface()
+		if err := json.Unmarshal(item, obj); err != nil {
+			return "", nil, fmt.Errorf("unable to decode snapshot %s: %v", path, err)
+		}
+		objs = append(objs, obj)
+	}
+	return snapshot.ResourceVersion, objs, nil
+}
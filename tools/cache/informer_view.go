@@ -0,0 +1,306 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// ViewFilterFunc decides whether an object belongs to an InformerView.
+type ViewFilterFunc func(obj interface{}) bool
+
+// NamespaceViewFilter returns a ViewFilterFunc accepting objects in any of the
+// given namespaces.
+func NamespaceViewFilter(namespaces ...string) ViewFilterFunc {
+	allowed := sets.NewString(namespaces...)
+	return func(obj interface{}) bool {
+		metadata, err := meta.Accessor(obj)
+		if err != nil {
+			return false
+		}
+		return allowed.Has(metadata.GetNamespace())
+	}
+}
+
+// LabelViewFilter returns a ViewFilterFunc accepting objects whose labels match
+// selector.
+func LabelViewFilter(selector labels.Selector) ViewFilterFunc {
+	return func(obj interface{}) bool {
+		metadata, err := meta.Accessor(obj)
+		if err != nil {
+			return false
+		}
+		return selector.Matches(labels.Set(metadata.GetLabels()))
+	}
+}
+
+// InformerView is a filtered, read-only view of a shared informer.  Several
+// views, for example one per tenant, can be layered on one informer so that
+// each sees only its own objects while a single watch feeds them all.
+type InformerView interface {
+	// AddEventHandler adds a handler that is only notified about objects of
+	// the view.  An update that moves an object into or out of the view is
+	// delivered as an add or a delete.
+	AddEventHandler(handler ResourceEventHandler)
+	// GetIndexer returns a read-only Indexer holding only the objects of the
+	// view.  Its mutating methods return errors.
+	GetIndexer() Indexer
+	// HasSynced returns true once the underlying informer has synced.
+	HasSynced() bool
+}
+
+type informerView struct {
+	informer SharedIndexInformer
+	filter   ViewFilterFunc
+	indexer  *viewIndexer
+}
+
+// NewInformerView returns a view of informer restricted to the objects
+// accepted by filter.  The view shares the informer's cache and watch; the
+// informer must still be started by its owner.
+func NewInformerView(informer SharedIndexInformer, filter ViewFilterFunc) InformerView {
+	return &informerView{
+		informer: informer,
+		filter:   filter,
+		indexer:  &viewIndexer{indexer: informer.GetIndexer(), filter: filter},
+	}
+}
+
+func (v *informerView) AddEventHandler(handler ResourceEventHandler) {
+	v.informer.AddEventHandler(FilteringResourceEventHandler{
+		FilterFunc: func(obj interface{}) bool {
+			if tombstone, ok := obj.(DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			return v.filter(obj)
+		},
+		Handler: handler,
+	})
+}
+
+func (v *informerView) GetIndexer() Indexer {
+	return v.indexer
+}
+
+func (v *informerView) HasSynced() bool {
+	return v.informer.HasSynced()
+}
+
+// viewIndexer is a read-only Indexer that hides the objects of an underlying
+// Indexer not accepted by filter.
+type viewIndexer struct {
+	indexer Indexer
+	filter  ViewFilterFunc
+}
+
+var _ Indexer = &viewIndexer{}
+
+func errReadOnlyView(operation string) error {
+	return fmt.Errorf("%s is not supported on a read-only informer view", operation)
+}
+
+func (v *viewIndexer) filterObjects(objs []interface{}) []interface{} {
+	result := make([]interface{}, 0, len(objs))
+	for _, obj := range objs {
+		if v.filter(obj) {
+			result = append(result, obj)
+		}
+	}
+	return result
+}
+
+func (v *viewIndexer) Add(obj interface{}) error {
+	return errReadOnlyView("Add")
+}
+
+func (v *viewIndexer) Update(obj interface{}) error {
+	return errReadOnlyView("Update")
+}
+
+func (v *viewIndexer) Delete(obj interface{}) error {
+	return errReadOnlyView("Delete")
+}
+
+func (v *viewIndexer) Replace(list []interface{}, resourceVersion string) error {
+	return errReadOnlyView("Replace")
+}
+
+func (v *viewIndexer) Resync() error {
+	return errReadOnlyView("Resync")
+}
+
+func (v *viewIndexer) AddIndexers(newIndexers Indexers) error {
+	return errReadOnlyView("AddIndexers")
+}
+
+func (v *viewIndexer) AddIndexersAfterStart(newIndexers Indexers) error {
+	return errReadOnlyView("AddIndexersAfterStart")
+}
+
+func (v *viewIndexer) RemoveIndexer(name string) error {
+	return errReadOnlyView("RemoveIndexer")
+}
+
+func (v *viewIndexer) List() []interface{} {
+	return v.filterObjects(v.indexer.List())
+}
+
+func (v *viewIndexer) ListKeys() []string {
+	var keys []string
+	for _, key := range v.indexer.ListKeys() {
+		if _, exists, _ := v.GetByKey(key); exists {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func (v *viewIndexer) ListKeysWithPrefix(prefix string) []string {
+	return v.visibleKeys(v.indexer.ListKeysWithPrefix(prefix))
+}
+
+// RangeKeys collects the keys in range before checking whether the view shows
+// them, since fn must not read the underlying indexer.
+func (v *viewIndexer) RangeKeys(from, to string, fn func(key string) bool) {
+	var keys []string
+	v.indexer.RangeKeys(from, to, func(key string) bool {
+		keys = append(keys, key)
+		return true
+	})
+	for _, key := range v.visibleKeys(keys) {
+		if !fn(key) {
+			return
+		}
+	}
+}
+
+// visibleKeys returns the keys of the objects in the view among keys.
+func (v *viewIndexer) visibleKeys(keys []string) []string {
+	result := []string{}
+	for _, key := range keys {
+		if _, exists, _ := v.GetByKey(key); exists {
+			result = append(result, key)
+		}
+	}
+	return result
+}
+
+func (v *viewIndexer) Get(obj interface{}) (interface{}, bool, error) {
+	item, exists, err := v.indexer.Get(obj)
+	if err != nil || !exists || !v.filter(item) {
+		return nil, false, err
+	}
+	return item, true, nil
+}
+
+func (v *viewIndexer) GetByKey(key string) (interface{}, bool, error) {
+	item, exists, err := v.indexer.GetByKey(key)
+	if err != nil || !exists || !v.filter(item) {
+		return nil, false, err
+	}
+	return item, true, nil
+}
+
+func (v *viewIndexer) Index(indexName string, obj interface{}) ([]interface{}, error) {
+	objs, err := v.indexer.Index(indexName, obj)
+	if err != nil {
+		return nil, err
+	}
+	return v.filterObjects(objs), nil
+}
+
+func (v *viewIndexer) IndexKeys(indexName, indexedValue string) ([]string, error) {
+	keys, err := v.indexer.IndexKeys(indexName, indexedValue)
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	for _, key := range keys {
+		if _, exists, _ := v.GetByKey(key); exists {
+			result = append(result, key)
+		}
+	}
+	return result, nil
+}
+
+// ListIndexFuncValues returns the indexed values of the objects in the view,
+// computed by running the index function over them.
+func (v *viewIndexer) ListIndexFuncValues(indexName string) []string {
+	indexFunc := v.indexer.GetIndexers()[indexName]
+	if indexFunc == nil {
+		return []string{}
+	}
+	values := sets.NewString()
+	for _, obj := range v.List() {
+		indexedValues, err := indexFunc(obj)
+		if err != nil {
+			continue
+		}
+		values.Insert(indexedValues...)
+	}
+	return values.List()
+}
+
+// GetIndexStats returns the stats of the index over the objects in the view,
+// computed by running the index function over them.
+func (v *viewIndexer) GetIndexStats(indexName string) (IndexStats, error) {
+	indexFunc := v.indexer.GetIndexers()[indexName]
+	if indexFunc == nil {
+		return IndexStats{}, fmt.Errorf("Index with name %s does not exist", indexName)
+	}
+	sizes := map[string]int{}
+	for _, obj := range v.List() {
+		indexedValues, err := indexFunc(obj)
+		if err != nil {
+			continue
+		}
+		for value := range sets.NewString(indexedValues...) {
+			sizes[value]++
+		}
+	}
+	return newIndexStats(sizes), nil
+}
+
+func (v *viewIndexer) ByIndex(indexName, indexedValue string) ([]interface{}, error) {
+	objs, err := v.indexer.ByIndex(indexName, indexedValue)
+	if err != nil {
+		return nil, err
+	}
+	return v.filterObjects(objs), nil
+}
+
+func (v *viewIndexer) ForEachByIndex(indexName, indexedValue string, fn func(obj interface{}) bool) error {
+	return v.indexer.ForEachByIndex(indexName, indexedValue, func(obj interface{}) bool {
+		return !v.filter(obj) || fn(obj)
+	})
+}
+
+func (v *viewIndexer) ByIndexes(mode IndexQueryMode, queries ...IndexQuery) ([]interface{}, error) {
+	objs, err := v.indexer.ByIndexes(mode, queries...)
+	if err != nil {
+		return nil, err
+	}
+	return v.filterObjects(objs), nil
+}
+
+func (v *viewIndexer) GetIndexers() Indexers {
+	return v.indexer.GetIndexers()
+}
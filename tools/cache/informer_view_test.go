@@ -0,0 +1,94 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	fcache "k8s.io/client-go/tools/cache/testing"
+)
+
+func TestInformerView(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "tenant-a", Name: "pod1", Labels: map[string]string{"tier": "web"}}})
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "tenant-a", Name: "pod2"}})
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "tenant-b", Name: "pod3", Labels: map[string]string{"tier": "web"}}})
+
+	informer := NewSharedIndexInformer(source, &v1.Pod{}, 0, Indexers{NamespaceIndex: MetaNamespaceIndexFunc})
+	viewA := NewInformerView(informer, NamespaceViewFilter("tenant-a"))
+	web := NewInformerView(informer, LabelViewFilter(labels.SelectorFromSet(labels.Set{"tier": "web"})))
+
+	added := make(chan string, 10)
+	viewA.AddEventHandler(ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { added <- obj.(*v1.Pod).Name },
+	})
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	if !WaitForCacheSync(stop, viewA.HasSynced, web.HasSynced) {
+		t.Fatalf("views did not sync")
+	}
+
+	if e, a := []string{"tenant-a/pod1", "tenant-a/pod2"}, sortedKeys(viewA.GetIndexer()); !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	if e, a := []string{"tenant-a/pod1", "tenant-b/pod3"}, sortedKeys(web.GetIndexer()); !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	if _, exists, _ := viewA.GetIndexer().GetByKey("tenant-b/pod3"); exists {
+		t.Errorf("expected objects of other tenants to be hidden")
+	}
+	if objs, _ := viewA.GetIndexer().ByIndex(NamespaceIndex, "tenant-b"); len(objs) != 0 {
+		t.Errorf("expected index lookups to be filtered, got %v", objs)
+	}
+	if e, a := []string{"tenant-a"}, viewA.GetIndexer().ListIndexFuncValues(NamespaceIndex); !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	if err := viewA.GetIndexer().Add(&v1.Pod{}); err == nil {
+		t.Errorf("expected view to be read-only")
+	}
+
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "tenant-b", Name: "pod4"}})
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "tenant-a", Name: "pod5"}})
+	var names []string
+	for len(names) < 3 {
+		select {
+		case name := <-added:
+			names = append(names, name)
+		case <-time.After(wait.ForeverTestTimeout):
+			t.Fatalf("timed out waiting for events, got %v", names)
+		}
+	}
+	sort.Strings(names)
+	if e := []string{"pod1", "pod2", "pod5"}; !reflect.DeepEqual(e, names) {
+		t.Errorf("expected handler to see %v, got %v", e, names)
+	}
+}
+
+func sortedKeys(store Store) []string {
+	keys := store.ListKeys()
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,266 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// InvariantChecker is a testing aid recording the mutations of an informer's
+// cache and the notifications of its handlers, and checking them against the
+// guarantees informers document:
+//
+//   - a handler is notified of an object only once the cache holds it, and of
+//     a deletion only once the cache no longer does;
+//   - the notifications of an object follow the order of its mutations, an
+//     add being followed by updates and finally a delete;
+//   - once the informer is idle, every handler has been notified of the last
+//     state of every object, so that no deletion is lost.
+//
+// It is meant for property tests exercising informer configurations, such as
+// custom options or handlers, against randomized sources: attach it with
+// WithInvariantChecker, add its handlers with Handler, drive the source, then
+// call WaitForConsistency.  Objects are identified by their key and resource
+// version, which must change with every mutation.
+type InvariantChecker struct {
+	lock sync.Mutex
+	// history holds the mutations of the cache, by key, oldest first.
+	history map[string][]storeMutation
+	// handlers holds the notifications recorded by each handler.
+	handlers []*invariantHandler
+	// violations holds the notifications that broke an invariant.
+	violations []error
+}
+
+// storeMutation is a mutation of the cache: a resource version stored, or a
+// deletion.
+type storeMutation struct {
+	deleted         bool
+	resourceVersion string
+}
+
+// NewInvariantChecker returns an InvariantChecker that has not recorded
+// anything yet.
+func NewInvariantChecker() *InvariantChecker {
+	return &InvariantChecker{history: map[string][]storeMutation{}}
+}
+
+// WithInvariantChecker makes checker record the mutations of the informer's
+// cache.
+func WithInvariantChecker(checker *InvariantChecker) SharedIndexInformerOption {
+	return func(informer *sharedIndexInformer) *sharedIndexInformer {
+		informer.indexer = &recordingIndexer{Indexer: informer.indexer, checker: checker}
+		return informer
+	}
+}
+
+// Handler returns a new ResourceEventHandler recording its notifications, to
+// be added to the informer.  Each handler is checked on its own.
+func (c *InvariantChecker) Handler() ResourceEventHandler {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	h := &invariantHandler{checker: c, id: len(c.handlers), cursors: map[string]int{}, present: map[string]bool{}}
+	c.handlers = append(c.handlers, h)
+	return h
+}
+
+// Violations returns the notifications recorded so far that broke an
+// invariant.
+func (c *InvariantChecker) Violations() []error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]error(nil), c.violations...)
+}
+
+// WaitForConsistency waits up to timeout for every handler to have been
+// notified of the last state of every object, and returns the violations of
+// the invariants, including the objects whose last state was not notified
+// by then.
+func (c *InvariantChecker) WaitForConsistency(timeout time.Duration) []error {
+	var inconsistencies []error
+	wait.PollImmediate(10*time.Millisecond, timeout, func() (bool, error) {
+		inconsistencies = c.inconsistencies()
+		return len(inconsistencies) == 0, nil
+	})
+	return append(c.Violations(), inconsistencies...)
+}
+
+// inconsistencies returns the objects whose last state some handler has not
+// been notified of.
+func (c *InvariantChecker) inconsistencies() []error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	keys := make([]string, 0, len(c.history))
+	for key := range c.history {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var errs []error
+	for _, h := range c.handlers {
+		for _, key := range keys {
+			history := c.history[key]
+			last := history[len(history)-1]
+			switch {
+			case last.deleted && h.present[key]:
+				errs = append(errs, fmt.Errorf("handler %d was not notified of the deletion of %s", h.id, key))
+			case !last.deleted && (!h.present[key] || h.cursors[key] != len(history)-1):
+				errs = append(errs, fmt.Errorf("handler %d was not notified of %s at resource version %s", h.id, key, last.resourceVersion))
+			}
+		}
+	}
+	return errs
+}
+
+func (c *InvariantChecker) recordMutation(obj interface{}, deleted bool) {
+	key, err := DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return
+	}
+	mutation := storeMutation{deleted: deleted}
+	if !deleted {
+		mutation.resourceVersion = resourceVersionOf(obj)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.history[key] = append(c.history[key], mutation)
+}
+
+func resourceVersionOf(obj interface{}) string {
+	if tombstone, ok := obj.(DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	metadata, err := meta.Accessor(obj)
+	if err != nil {
+		return ""
+	}
+	return metadata.GetResourceVersion()
+}
+
+// invariantHandler records the notifications of a handler.
+type invariantHandler struct {
+	checker *InvariantChecker
+	id      int
+	// cursors holds, by key, the index in the history of the cache of the
+	// last mutation notified.
+	cursors map[string]int
+	// present holds whether the handler was last notified of an object
+	// being added or updated rather than deleted.
+	present map[string]bool
+}
+
+func (h *invariantHandler) OnAdd(obj interface{}) {
+	h.notify("add", obj, false)
+}
+
+func (h *invariantHandler) OnUpdate(oldObj, newObj interface{}) {
+	h.notify("update", newObj, true)
+}
+
+func (h *invariantHandler) OnDelete(obj interface{}) {
+	h.notify("delete", obj, true)
+}
+
+// notify checks a notification against the history of the cache.  present
+// is whether the handler must have been notified of the object already.
+func (h *invariantHandler) notify(event string, obj interface{}, present bool) {
+	key, err := DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return
+	}
+	deleted := event == "delete"
+	resourceVersion := resourceVersionOf(obj)
+
+	c := h.checker
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if h.present[key] != present {
+		c.violations = append(c.violations, fmt.Errorf("handler %d: unexpected %s of %s at resource version %s, the handler was notified of it being present=%v", h.id, event, key, resourceVersion, h.present[key]))
+	}
+	h.present[key] = !deleted
+
+	// The notification must match a mutation of the object no older than
+	// the last one notified.
+	history := c.history[key]
+	start, found := h.cursors[key]
+	if !found {
+		start = 0
+	}
+	for i := start; i < len(history); i++ {
+		if history[i].deleted == deleted && (deleted || history[i].resourceVersion == resourceVersion) {
+			h.cursors[key] = i
+			return
+		}
+	}
+	c.violations = append(c.violations, fmt.Errorf("handler %d: %s of %s at resource version %s does not follow a mutation of the cache", h.id, event, key, resourceVersion))
+}
+
+// recordingIndexer records the mutations of an Indexer.
+type recordingIndexer struct {
+	Indexer
+	checker *InvariantChecker
+}
+
+func (r *recordingIndexer) Add(obj interface{}) error {
+	if err := r.Indexer.Add(obj); err != nil {
+		return err
+	}
+	r.checker.recordMutation(obj, false)
+	return nil
+}
+
+func (r *recordingIndexer) Update(obj interface{}) error {
+	if err := r.Indexer.Update(obj); err != nil {
+		return err
+	}
+	r.checker.recordMutation(obj, false)
+	return nil
+}
+
+func (r *recordingIndexer) Delete(obj interface{}) error {
+	if err := r.Indexer.Delete(obj); err != nil {
+		return err
+	}
+	r.checker.recordMutation(obj, true)
+	return nil
+}
+
+func (r *recordingIndexer) Replace(list []interface{}, resourceVersion string) error {
+	previous := r.Indexer.List()
+	if err := r.Indexer.Replace(list, resourceVersion); err != nil {
+		return err
+	}
+	kept := map[string]bool{}
+	for _, obj := range list {
+		if key, err := DeletionHandlingMetaNamespaceKeyFunc(obj); err == nil {
+			kept[key] = true
+		}
+		r.checker.recordMutation(obj, false)
+	}
+	for _, obj := range previous {
+		if key, err := DeletionHandlingMetaNamespaceKeyFunc(obj); err == nil && !kept[key] {
+			r.checker.recordMutation(obj, true)
+		}
+	}
+	return nil
+}
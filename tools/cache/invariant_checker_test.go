@@ -0,0 +1,97 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	fcache "k8s.io/client-go/tools/cache/testing"
+)
+
+func TestInvariantChecker(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	checker := NewInvariantChecker()
+	informer := NewSharedInformer(source, &v1.Pod{}, 0, WithInvariantChecker(checker))
+	informer.AddEventHandler(checker.Handler())
+	informer.AddEventHandlerWithResyncPeriod(checker.Handler(), 10*time.Millisecond)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	if !WaitForCacheSync(stop, informer.HasSynced) {
+		t.Fatalf("informer did not sync")
+	}
+
+	rand.Seed(time.Now().UnixNano())
+	exists := map[string]bool{}
+	for i := 0; i < 200; i++ {
+		pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("pod%d", rand.Intn(10))}}
+		switch {
+		case !exists[pod.Name]:
+			source.Add(pod)
+			exists[pod.Name] = true
+		case rand.Intn(3) == 0:
+			source.Delete(pod)
+			exists[pod.Name] = false
+		default:
+			source.Modify(pod)
+		}
+	}
+	// A late handler is notified of the objects already cached.
+	informer.AddEventHandler(checker.Handler())
+
+	for _, err := range checker.WaitForConsistency(wait.ForeverTestTimeout) {
+		t.Error(err)
+	}
+}
+
+func TestInvariantCheckerViolations(t *testing.T) {
+	checker := NewInvariantChecker()
+	indexer := WithInvariantChecker(checker)(&sharedIndexInformer{indexer: NewIndexer(DeletionHandlingMetaNamespaceKeyFunc, Indexers{})}).indexer
+	handler := checker.Handler()
+	pod := func(rv string) *v1.Pod {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod", ResourceVersion: rv}}
+	}
+
+	// Notified before the cache holds the object.
+	handler.OnAdd(pod("1"))
+	indexer.Add(pod("1"))
+	indexer.Update(pod("2"))
+	indexer.Update(pod("3"))
+	// Notified out of order.
+	handler.OnUpdate(pod("1"), pod("3"))
+	handler.OnUpdate(pod("3"), pod("2"))
+	if violations := checker.Violations(); len(violations) != 2 {
+		t.Errorf("expected 2 violations, got %v", violations)
+	}
+
+	// A lost deletion.
+	indexer.Delete(pod("3"))
+	if errs := checker.WaitForConsistency(50 * time.Millisecond); len(errs) != 3 {
+		t.Errorf("expected the lost deletion to be reported, got %v", errs)
+	}
+	handler.OnDelete(pod("3"))
+	if errs := checker.WaitForConsistency(50 * time.Millisecond); len(errs) != 2 {
+		t.Errorf("expected only the earlier violations, got %v", errs)
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"hash/fnv"
+	"math"
+	"sync/atomic"
+)
+
+// KeyFilter is a counting Bloom filter over the keys of a store, which answers
+// "may this key exist" and "how many objects are there" without taking the
+// lock of the store.  It lets code outside of the event handlers, which would
+// otherwise call GetByKey on a hot path, rule out absent keys cheaply.
+//
+// MayContain never returns false for a key that was in the store for the
+// whole call, and returns true for an absent key with a probability close to
+// the false positive rate the filter was sized for, as long as the store does
+// not hold many more keys than expected.  A KeyFilter is attached to the store
+// of an informer with WithKeyFilter, which keeps it up to date, rebuilding it
+// whenever the store is replaced.
+type KeyFilter struct {
+	hashes uint64
+	// counters holds a []uint32 counting, for each bucket, the keys hashed
+	// to it.  It is replaced as a whole when the filter is rebuilt, so that
+	// readers never see a partially built filter.
+	counters atomic.Value
+	count    int64
+}
+
+// NewKeyFilter returns an empty KeyFilter sized for expectedKeys keys and the
+// given false positive rate, between 0 and 1 exclusive.
+func NewKeyFilter(expectedKeys int, falsePositiveRate float64) *KeyFilter {
+	if expectedKeys < 1 {
+		expectedKeys = 1
+	}
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		falsePositiveRate = 0.01
+	}
+	// The optimal number of buckets and of hashes of a Bloom filter.
+	buckets := math.Ceil(-float64(expectedKeys) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	hashes := math.Max(1, math.Round(buckets/float64(expectedKeys)*math.Ln2))
+	f := &KeyFilter{hashes: uint64(hashes)}
+	f.counters.Store(make([]uint32, int(buckets)))
+	return f
+}
+
+// MayContain returns false if key is not in the store, and true if it may be.
+func (f *KeyFilter) MayContain(key string) bool {
+	counters := f.counters.Load().([]uint32)
+	h1, h2 := keyHashes(key)
+	for i := uint64(0); i < f.hashes; i++ {
+		if atomic.LoadUint32(&counters[(h1+i*h2)%uint64(len(counters))]) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Len returns the number of objects in the store.
+func (f *KeyFilter) Len() int {
+	return int(atomic.LoadInt64(&f.count))
+}
+
+// add records key, which must not be in the filter already.  Writers must be
+// serialized.
+func (f *KeyFilter) add(key string) {
+	f.addTo(f.counters.Load().([]uint32), key)
+	atomic.AddInt64(&f.count, 1)
+}
+
+// remove forgets key, which must be in the filter.  Writers must be
+// serialized.
+func (f *KeyFilter) remove(key string) {
+	counters := f.counters.Load().([]uint32)
+	h1, h2 := keyHashes(key)
+	for i := uint64(0); i < f.hashes; i++ {
+		atomic.AddUint32(&counters[(h1+i*h2)%uint64(len(counters))], ^uint32(0))
+	}
+	atomic.AddInt64(&f.count, -1)
+}
+
+// rebuild replaces the content of the filter with the keys of items.  Writers
+// must be serialized.
+func (f *KeyFilter) rebuild(items map[string]interface{}) {
+	counters := make([]uint32, len(f.counters.Load().([]uint32)))
+	for key := range items {
+		f.addTo(counters, key)
+	}
+	f.counters.Store(counters)
+	atomic.StoreInt64(&f.count, int64(len(items)))
+}
+
+func (f *KeyFilter) addTo(counters []uint32, key string) {
+	h1, h2 := keyHashes(key)
+	for i := uint64(0); i < f.hashes; i++ {
+		atomic.AddUint32(&counters[(h1+i*h2)%uint64(len(counters))], 1)
+	}
+}
+
+// keyHashes returns the two hashes of key from which the hashes of the filter
+// are derived by double hashing.
+func keyHashes(key string) (uint64, uint64) {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	sum := h.Sum64()
+	return sum, sum>>32 | 1
+}
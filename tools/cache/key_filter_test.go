@@ -0,0 +1,134 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fcache "k8s.io/client-go/tools/cache/testing"
+)
+
+func TestKeyFilterAccuracy(t *testing.T) {
+	const keys = 10000
+	filter := NewKeyFilter(keys, 0.01)
+	store := NewStore(MetaNamespaceKeyFunc)
+	store.(*cache).cacheStorage.(*threadSafeMap).setKeyFilter(filter)
+	for i := 0; i < keys; i++ {
+		store.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: fmt.Sprintf("pod-%d", i)}})
+	}
+	if filter.Len() != keys {
+		t.Errorf("expected %d keys, got %d", keys, filter.Len())
+	}
+	for i := 0; i < keys; i++ {
+		if key := fmt.Sprintf("ns/pod-%d", i); !filter.MayContain(key) {
+			t.Fatalf("false negative for %q", key)
+		}
+	}
+	falsePositives := 0
+	for i := 0; i < keys; i++ {
+		if filter.MayContain(fmt.Sprintf("ns/absent-%d", i)) {
+			falsePositives++
+		}
+	}
+	if rate := float64(falsePositives) / keys; rate > 0.02 {
+		t.Errorf("expected a false positive rate close to 1%%, got %v", rate)
+	}
+
+	store.Delete(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod-0"}})
+	if filter.Len() != keys-1 {
+		t.Errorf("expected %d keys, got %d", keys-1, filter.Len())
+	}
+	store.Replace([]interface{}{&v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "replaced"}}}, "2")
+	if filter.Len() != 1 || !filter.MayContain("ns/replaced") {
+		t.Errorf("expected the filter to be rebuilt, got %d keys", filter.Len())
+	}
+	if filter.MayContain("ns/pod-1") && filter.MayContain("ns/pod-2") && filter.MayContain("ns/pod-3") {
+		t.Errorf("expected the replaced keys to be forgotten")
+	}
+}
+
+func TestSharedInformerKeyFilter(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}})
+	filter := NewKeyFilter(100, 0.01)
+	informer := NewSharedInformer(source, &v1.Pod{}, 0, WithKeyFilter(filter))
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	if !WaitForCacheSync(stop, informer.HasSynced) {
+		t.Fatalf("informer did not sync")
+	}
+	if filter.Len() != 1 || !filter.MayContain("pod1") {
+		t.Errorf("expected the filter to contain pod1, got %d keys", filter.Len())
+	}
+}
+
+func benchmarkKeyFilterStore(b *testing.B) (Store, *KeyFilter) {
+	filter := NewKeyFilter(10000, 0.01)
+	store := NewStore(MetaNamespaceKeyFunc)
+	store.(*cache).cacheStorage.(*threadSafeMap).setKeyFilter(filter)
+	for i := 0; i < 10000; i++ {
+		store.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: fmt.Sprintf("pod-%d", i)}})
+	}
+	b.ResetTimer()
+	return store, filter
+}
+
+// BenchmarkKeyFilterMayContain and BenchmarkKeyFilterGetByKey compare the
+// cost of looking up absent keys with the filter and with the store, while
+// the store is being written to.
+func BenchmarkKeyFilterMayContain(b *testing.B) {
+	store, filter := benchmarkKeyFilterStore(b)
+	stop := startBenchmarkWriter(store)
+	defer close(stop)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			filter.MayContain("ns/absent")
+		}
+	})
+}
+
+func BenchmarkKeyFilterGetByKey(b *testing.B) {
+	store, _ := benchmarkKeyFilterStore(b)
+	stop := startBenchmarkWriter(store)
+	defer close(stop)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			store.GetByKey("ns/absent")
+		}
+	})
+}
+
+func startBenchmarkWriter(store Store) chan struct{} {
+	stop := make(chan struct{})
+	go func() {
+		pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod-0"}}
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				store.Update(pod)
+			}
+		}
+	}()
+	return stop
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"sync"
+
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// KeyOrderedHandler is a ResourceEventHandler that runs the callbacks of
+// another handler on a pool of goroutines, with a barrier per object: the
+// callbacks for an object run one at a time, in the order they were received,
+// so that for instance an OnUpdate never runs concurrently with, or after, a
+// later OnDelete of the same object.  Callbacks for different objects run
+// concurrently and in no particular order.
+//
+// Objects are identified by DeletionHandlingMetaNamespaceKeyFunc, or by the
+// key function of the informer when it runs a handler with
+// HandlerOptions.Workers; those without a key are all handled as one object.
+// The callbacks of KeyOrderedHandler only queue the notification, so the
+// HasSynced of its registration does not wait for the pool.  A callback that
+// panics is reported, and the next notifications of its object are still
+// delivered.  The notifications received once Run is stopping are dropped.
+type KeyOrderedHandler struct {
+	handler ResourceEventHandler
+	workers int
+	// keyFunc identifies the objects.
+	keyFunc KeyFunc
+	// queue holds the keys with pending notifications.  It never hands out
+	// a key that is being processed, which serializes each object.
+	queue workqueue.Interface
+
+	lock sync.Mutex
+	// pending holds the notifications of every key, oldest first.
+	pending map[string][]interface{}
+	// stopped is set once Run is stopping, after which nothing would
+	// handle new notifications.
+	stopped bool
+}
+
+var _ ResourceEventHandler = &KeyOrderedHandler{}
+var _ DetailedResourceEventHandler = &KeyOrderedHandler{}
+
+// NewKeyOrderedHandler returns a KeyOrderedHandler running the callbacks of
+// handler on up to workers goroutines once it is started with Run.
+func NewKeyOrderedHandler(handler ResourceEventHandler, workers int) *KeyOrderedHandler {
+	return newKeyOrderedHandler(handler, workers, DeletionHandlingMetaNamespaceKeyFunc)
+}
+
+// newKeyOrderedHandler returns a KeyOrderedHandler identifying objects with
+// keyFunc, for the informers keying their objects with another function.
+func newKeyOrderedHandler(handler ResourceEventHandler, workers int, keyFunc KeyFunc) *KeyOrderedHandler {
+	if workers < 1 {
+		workers = 1
+	}
+	return &KeyOrderedHandler{
+		handler: handler,
+		workers: workers,
+		keyFunc: keyFunc,
+		queue:   workqueue.New(),
+		pending: map[string][]interface{}{},
+	}
+}
+
+func (h *KeyOrderedHandler) OnAdd(obj interface{}) {
+	h.enqueue(obj, addNotification{newObj: obj})
+}
+
+func (h *KeyOrderedHandler) OnUpdate(oldObj, newObj interface{}) {
+	h.enqueue(newObj, updateNotification{oldObj: oldObj, newObj: newObj})
+}
+
+// OnUpdateWithOrigin implements UpdateOriginHandler, passing origin on to the
+// handler if it implements UpdateOriginHandler too.
+func (h *KeyOrderedHandler) OnUpdateWithOrigin(oldObj, newObj interface{}, origin UpdateOrigin) {
+	h.enqueue(newObj, updateNotification{oldObj: oldObj, newObj: newObj, origin: origin})
+}
+
+func (h *KeyOrderedHandler) OnDelete(obj interface{}) {
+	h.enqueue(obj, deleteNotification{oldObj: obj})
+}
+
+// OnAddDetailed implements DetailedResourceEventHandler, passing the details
+// on to the handler if it implements DetailedResourceEventHandler too.
+func (h *KeyOrderedHandler) OnAddDetailed(obj interface{}, details EventDetails) {
+	h.enqueue(obj, addNotification{newObj: obj, deltaType: details.Type})
+}
+
+// OnUpdateDetailed implements DetailedResourceEventHandler, passing the
+// details on to the handler if it implements DetailedResourceEventHandler
+// too, or their origin if it implements UpdateOriginHandler.
+func (h *KeyOrderedHandler) OnUpdateDetailed(oldObj, newObj interface{}, details EventDetails) {
+	h.enqueue(newObj, updateNotification{oldObj: oldObj, newObj: newObj, origin: updateOrigin(details.Type)})
+}
+
+// OnDeleteDetailed implements DetailedResourceEventHandler, passing the
+// details on to the handler if it implements DetailedResourceEventHandler
+// too.
+func (h *KeyOrderedHandler) OnDeleteDetailed(obj interface{}, details EventDetails) {
+	if details.Tombstone {
+		key, _ := h.keyFunc(obj)
+		obj = DeletedFinalStateUnknown{Key: key, Obj: obj}
+	}
+	h.enqueue(obj, deleteNotification{oldObj: obj})
+}
+
+func (h *KeyOrderedHandler) enqueue(obj interface{}, notification interface{}) {
+	key, _ := h.keyFunc(obj)
+	h.lock.Lock()
+	if h.stopped {
+		h.lock.Unlock()
+		return
+	}
+	h.pending[key] = append(h.pending[key], notification)
+	h.lock.Unlock()
+	h.queue.Add(key)
+}
+
+// Run runs the callbacks until stopCh is closed, then waits for the
+// notifications already received to be handled.
+func (h *KeyOrderedHandler) Run(stopCh <-chan struct{}) {
+	var wg wait.Group
+	for i := 0; i < h.workers; i++ {
+		wg.Start(func() {
+			for h.processNext() {
+			}
+		})
+	}
+	<-stopCh
+	h.lock.Lock()
+	h.stopped = true
+	h.lock.Unlock()
+	h.queue.ShutDown()
+	wg.Wait()
+}
+
+// processNext handles the notifications of the next key, returning false
+// once the queue is shut down and drained.
+func (h *KeyOrderedHandler) processNext() bool {
+	item, shutdown := h.queue.Get()
+	if shutdown {
+		return false
+	}
+	key := item.(string)
+	defer h.queue.Done(key)
+
+	// The notifications received meanwhile are handled here as well: the
+	// queue no longer accepts the key once it is shutting down.
+	for {
+		h.lock.Lock()
+		pending := h.pending[key]
+		if len(pending) == 0 {
+			delete(h.pending, key)
+			h.lock.Unlock()
+			return true
+		}
+		notification := pending[0]
+		h.pending[key] = pending[1:]
+		h.lock.Unlock()
+
+		h.deliver(key, notification)
+	}
+}
+
+// deliver hands notification to the handler.  A panicking callback is
+// reported and the next notifications are still delivered: crashing the
+// worker would leave the key marked as being processed forever.
+func (h *KeyOrderedHandler) deliver(key string, notification interface{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			utilruntime.HandleError(fmt.Errorf("event handler panicked handling a notification of %q: %v", key, r))
+		}
+	}()
+	deliverNotification(h.handler, notification)
+}
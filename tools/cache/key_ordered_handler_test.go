@@ -0,0 +1,152 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// orderCheckingHandler records the callbacks of every object, and fails the
+// test if callbacks for the same object overlap.
+type orderCheckingHandler struct {
+	t *testing.T
+
+	lock          sync.Mutex
+	active        map[string]bool
+	running       int
+	maxConcurrent int
+	events        map[string][]string
+}
+
+func (h *orderCheckingHandler) handle(event string, obj interface{}) {
+	name := obj.(*v1.Pod).Name
+	h.lock.Lock()
+	if h.active[name] {
+		h.t.Errorf("concurrent callbacks for %s", name)
+	}
+	h.active[name] = true
+	h.running++
+	if h.running > h.maxConcurrent {
+		h.maxConcurrent = h.running
+	}
+	h.lock.Unlock()
+
+	time.Sleep(time.Millisecond)
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.active[name] = false
+	h.running--
+	h.events[name] = append(h.events[name], event+" "+obj.(*v1.Pod).ResourceVersion)
+}
+
+func (h *orderCheckingHandler) OnAdd(obj interface{})               { h.handle("add", obj) }
+func (h *orderCheckingHandler) OnUpdate(oldObj, newObj interface{}) { h.handle("update", newObj) }
+func (h *orderCheckingHandler) OnDelete(obj interface{})            { h.handle("delete", obj) }
+
+func TestKeyOrderedHandler(t *testing.T) {
+	recorder := &orderCheckingHandler{t: t, active: map[string]bool{}, events: map[string][]string{}}
+	handler := NewKeyOrderedHandler(recorder, 4)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.Run(stop)
+	}()
+
+	pod := func(name string, rv int) *v1.Pod {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name, ResourceVersion: fmt.Sprint(rv)}}
+	}
+	const objects = 10
+	for i := 0; i < objects; i++ {
+		handler.OnAdd(pod(fmt.Sprint("pod", i), 1))
+	}
+	for i := 0; i < objects; i++ {
+		name := fmt.Sprint("pod", i)
+		handler.OnUpdate(pod(name, 1), pod(name, 2))
+		handler.OnUpdate(pod(name, 2), pod(name, 3))
+	}
+	for i := 0; i < objects; i++ {
+		handler.OnDelete(pod(fmt.Sprint("pod", i), 4))
+	}
+	// Run handles the notifications received before it is stopped.
+	close(stop)
+	<-done
+
+	expected := []string{"add 1", "update 2", "update 3", "delete 4"}
+	for i := 0; i < objects; i++ {
+		name := fmt.Sprint("pod", i)
+		if events := recorder.events[name]; !reflect.DeepEqual(events, expected) {
+			t.Errorf("%s: expected %v, got %v", name, expected, events)
+		}
+	}
+	if recorder.maxConcurrent < 2 {
+		t.Errorf("expected different objects to be handled concurrently")
+	}
+}
+
+func TestKeyOrderedHandlerPanic(t *testing.T) {
+	var lock sync.Mutex
+	var handled []string
+	handler := NewKeyOrderedHandler(ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			pod := obj.(*v1.Pod)
+			if pod.ResourceVersion == "1" {
+				panic("handler failure")
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			handled = append(handled, pod.ResourceVersion)
+		},
+	}, 1)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.Run(stop)
+	}()
+
+	for rv := 1; rv <= 3; rv++ {
+		handler.OnAdd(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod", ResourceVersion: fmt.Sprint(rv)}})
+	}
+	close(stop)
+	<-done
+
+	// The worker survives the panic and delivers the later notifications.
+	if expected := []string{"2", "3"}; !reflect.DeepEqual(handled, expected) {
+		t.Errorf("expected %v to be handled, got %v", expected, handled)
+	}
+}
+
+func TestKeyOrderedHandlerDropsNotificationsAfterStop(t *testing.T) {
+	handler := NewKeyOrderedHandler(ResourceEventHandlerFuncs{}, 1)
+	stop := make(chan struct{})
+	close(stop)
+	handler.Run(stop)
+
+	handler.OnAdd(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod"}})
+	if len(handler.pending) != 0 {
+		t.Errorf("expected the notifications received after Run returned to be dropped, got %v", handler.pending)
+	}
+}
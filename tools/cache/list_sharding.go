@@ -0,0 +1,294 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/pager"
+	"k8s.io/klog"
+)
+
+// ListShardResult is the outcome of listing one shard of a collection.
+type ListShardResult struct {
+	// ResourceVersion is the resource version the shard was listed at.
+	ResourceVersion string
+	// Items are the objects of the shard.
+	Items []runtime.Object
+}
+
+// ListShardExchange shares the results of shard LISTs between cooperating
+// replicas, so that each replica lists only some shards and fetches the rest
+// from its peers.  The transport between replicas is up to the implementation.
+type ListShardExchange interface {
+	// Publish makes the result of a shard listed by this replica available
+	// to its peers.
+	Publish(shard int, result ListShardResult) error
+	// Fetch waits up to timeout for a peer to publish the result of shard.
+	Fetch(shard int, timeout time.Duration) (ListShardResult, error)
+}
+
+// ListShardingConfig describes how the initial LIST of a collection is split
+// between replicas.
+type ListShardingConfig struct {
+	// Shards restrict the list options to one shard each, for example with
+	// a label or field selector.  Together they must select every object
+	// of the collection, and no object may be selected by two shards.
+	// The other shards are listed at the resource version of the first.  If
+	// that resource version has expired, the first shard is listed again at
+	// the latest resource version.
+	Shards []func(options *metav1.ListOptions)
+
+	// LocalShards are the indexes of the shards this replica lists itself,
+	// concurrently.
+	LocalShards []int
+
+	// Exchange publishes the local shards and fetches the others.
+	Exchange ListShardExchange
+
+	// FetchTimeout bounds how long to wait for a peer's shard before
+	// listing it locally.
+	FetchTimeout time.Duration
+}
+
+// shardedListWatch splits the first LIST of a ListerWatcher into shards.
+type shardedListWatch struct {
+	lw     ListerWatcher
+	config ListShardingConfig
+
+	lock sync.Mutex
+	// listed is set once a sharded list succeeded.
+	listed bool
+}
+
+// NewShardedListWatch returns a ListerWatcher whose first successful List is
+// split into the shards of config: the local shards are listed at the
+// resource version of the first shard and published, the others are fetched
+// from peers, falling back to listing them locally when a peer does not
+// deliver in time, and the results are merged.  This lets the replicas of a
+// controller warm their caches in parallel on cold start.  Later lists and
+// all watches go directly to lw.
+func NewShardedListWatch(lw ListerWatcher, config ListShardingConfig) ListerWatcher {
+	return &shardedListWatch{lw: lw, config: config}
+}
+
+func (s *shardedListWatch) List(options metav1.ListOptions) (runtime.Object, error) {
+	s.lock.Lock()
+	listed := s.listed
+	s.lock.Unlock()
+	if listed || len(s.config.Shards) == 0 {
+		return s.lw.List(options)
+	}
+
+	list, err := s.listShards(options, false)
+	if apierrs.IsResourceExpired(err) {
+		klog.V(2).Infof("The resource version of list shard 0 expired, listing it again: %v", err)
+		list, err = s.listShards(options, true)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.lock.Lock()
+	s.listed = true
+	s.lock.Unlock()
+	return list, nil
+}
+
+// listShards lists or fetches every shard and merges them.  If latest is
+// set, the first shard is listed locally at the latest resource version
+// rather than at the one options asks for.
+func (s *shardedListWatch) listShards(options metav1.ListOptions, latest bool) (*metav1.List, error) {
+	local := map[int]bool{}
+	for _, shard := range s.config.LocalShards {
+		local[shard] = true
+	}
+	results := make([]ListShardResult, len(s.config.Shards))
+	// The other shards are listed at the resource version of the first one,
+	// so that the merged list is a consistent snapshot to watch from.
+	var err error
+	switch {
+	case local[0]:
+		if latest {
+			options.ResourceVersion = ""
+		}
+		results[0], err = s.listLocalShard(0, options)
+	case latest:
+		options.ResourceVersion = ""
+		results[0], err = s.listShard(0, options)
+	default:
+		results[0], err = s.fetchShard(0, options)
+	}
+	if err != nil {
+		return nil, err
+	}
+	options.ResourceVersion = results[0].ResourceVersion
+
+	errs := make([]error, len(s.config.Shards))
+	var wg sync.WaitGroup
+	for shard := 1; shard < len(s.config.Shards); shard++ {
+		if !local[shard] {
+			continue
+		}
+		wg.Add(1)
+		go func(shard int) {
+			defer wg.Done()
+			results[shard], errs[shard] = s.listLocalShard(shard, options)
+		}(shard)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	for shard := 1; shard < len(s.config.Shards); shard++ {
+		if local[shard] {
+			continue
+		}
+		if results[shard], err = s.fetchShard(shard, options); err != nil {
+			return nil, err
+		}
+	}
+	return MergeListShards(results)
+}
+
+// listLocalShard lists shard and publishes it to the peers.
+func (s *shardedListWatch) listLocalShard(shard int, options metav1.ListOptions) (ListShardResult, error) {
+	result, err := s.listShard(shard, options)
+	if err != nil {
+		return ListShardResult{}, err
+	}
+	if err := s.config.Exchange.Publish(shard, result); err != nil {
+		utilruntime.HandleError(fmt.Errorf("unable to publish list shard %d: %v", shard, err))
+	}
+	return result, nil
+}
+
+// fetchShard fetches shard from a peer, or lists it locally when no peer
+// delivers it in time or a peer listed it at another resource version than
+// options asks for.
+func (s *shardedListWatch) fetchShard(shard int, options metav1.ListOptions) (ListShardResult, error) {
+	result, err := s.config.Exchange.Fetch(shard, s.config.FetchTimeout)
+	if err == nil && options.ResourceVersion != "" && result.ResourceVersion != options.ResourceVersion {
+		err = fmt.Errorf("listed at resource version %q instead of %q", result.ResourceVersion, options.ResourceVersion)
+	}
+	if err != nil {
+		klog.V(2).Infof("Unable to fetch list shard %d from a peer, listing it locally: %v", shard, err)
+		return s.listShard(shard, options)
+	}
+	return result, nil
+}
+
+func (s *shardedListWatch) listShard(shard int, options metav1.ListOptions) (ListShardResult, error) {
+	options.Continue = ""
+	s.config.Shards[shard](&options)
+	list, err := pager.New(pager.SimplePageFunc(s.lw.List)).List(context.Background(), options)
+	if err != nil {
+		return ListShardResult{}, err
+	}
+	listMetaInterface, err := meta.ListAccessor(list)
+	if err != nil {
+		return ListShardResult{}, err
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return ListShardResult{}, err
+	}
+	return ListShardResult{ResourceVersion: listMetaInterface.GetResourceVersion(), Items: items}, nil
+}
+
+func (s *shardedListWatch) Watch(options metav1.ListOptions) (watch.Interface, error) {
+	return s.lw.Watch(options)
+}
+
+// MergeListShards merges shard results into a single list.  The shards must
+// have been listed at the same resource version, which the list carries.
+func MergeListShards(results []ListShardResult) (*metav1.List, error) {
+	list := &metav1.List{}
+	for i, result := range results {
+		if i == 0 {
+			list.ResourceVersion = result.ResourceVersion
+		} else if result.ResourceVersion != list.ResourceVersion {
+			return nil, fmt.Errorf("unable to merge list shard %d with resource version %q into list shard 0 with resource version %q", i, result.ResourceVersion, list.ResourceVersion)
+		}
+		for _, item := range result.Items {
+			list.Items = append(list.Items, runtime.RawExtension{Object: item})
+		}
+	}
+	return list, nil
+}
+
+// inMemoryListShardExchange is a ListShardExchange for replicas running in
+// the same process.
+type inMemoryListShardExchange struct {
+	lock    sync.Mutex
+	results map[int]ListShardResult
+	ready   map[int]chan struct{}
+}
+
+// NewInMemoryListShardExchange returns a ListShardExchange that shares shard
+// results between informers of the same process.
+func NewInMemoryListShardExchange() ListShardExchange {
+	return &inMemoryListShardExchange{
+		results: map[int]ListShardResult{},
+		ready:   map[int]chan struct{}{},
+	}
+}
+
+func (e *inMemoryListShardExchange) readyLocked(shard int) chan struct{} {
+	ch, ok := e.ready[shard]
+	if !ok {
+		ch = make(chan struct{})
+		e.ready[shard] = ch
+	}
+	return ch
+}
+
+func (e *inMemoryListShardExchange) Publish(shard int, result ListShardResult) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if _, ok := e.results[shard]; ok {
+		return fmt.Errorf("list shard %d was already published", shard)
+	}
+	e.results[shard] = result
+	close(e.readyLocked(shard))
+	return nil
+}
+
+func (e *inMemoryListShardExchange) Fetch(shard int, timeout time.Duration) (ListShardResult, error) {
+	e.lock.Lock()
+	ready := e.readyLocked(shard)
+	e.lock.Unlock()
+
+	select {
+	case <-ready:
+	case <-time.After(timeout):
+		return ListShardResult{}, fmt.Errorf("timed out waiting for list shard %d", shard)
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.results[shard], nil
+}
@@ -0,0 +1,284 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func shardedTestListWatch(lock *sync.Mutex, selectors *[]string) ListerWatcher {
+	pods := []v1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Labels: map[string]string{"shard": "0"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "pod2", Labels: map[string]string{"shard": "1"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "pod3", Labels: map[string]string{"shard": "1"}}},
+	}
+	resourceVersion := map[string]string{"shard=0": "10", "shard=1": "7"}
+	return &ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			lock.Lock()
+			*selectors = append(*selectors, options.LabelSelector)
+			lock.Unlock()
+			selector, err := labels.Parse(options.LabelSelector)
+			if err != nil {
+				return nil, err
+			}
+			list := &v1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: resourceVersion[options.LabelSelector]}}
+			if options.ResourceVersion != "" && options.ResourceVersion != "0" {
+				list.ResourceVersion = options.ResourceVersion
+			}
+			for _, pod := range pods {
+				if selector.Matches(labels.Set(pod.Labels)) {
+					list.Items = append(list.Items, pod)
+				}
+			}
+			return list, nil
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return watch.NewFake(), nil
+		},
+	}
+}
+
+func listedNames(t *testing.T, list runtime.Object) []string {
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	for _, item := range items {
+		names = append(names, item.(*v1.Pod).Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestShardedListWatch(t *testing.T) {
+	shards := []func(*metav1.ListOptions){
+		func(options *metav1.ListOptions) { options.LabelSelector = "shard=0" },
+		func(options *metav1.ListOptions) { options.LabelSelector = "shard=1" },
+	}
+	exchange := NewInMemoryListShardExchange()
+
+	var lock sync.Mutex
+	var selectors [][]string
+	var lists []runtime.Object
+	var wg sync.WaitGroup
+	for replica := 0; replica < 2; replica++ {
+		replicaSelectors := &[]string{}
+		lw := NewShardedListWatch(shardedTestListWatch(&lock, replicaSelectors), ListShardingConfig{
+			Shards:       shards,
+			LocalShards:  []int{replica},
+			Exchange:     exchange,
+			FetchTimeout: time.Minute,
+		})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			list, err := lw.List(metav1.ListOptions{})
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			lists = append(lists, list)
+			selectors = append(selectors, *replicaSelectors)
+		}()
+	}
+	wg.Wait()
+
+	for i, list := range lists {
+		if e, a := []string{"pod1", "pod2", "pod3"}, listedNames(t, list); !reflect.DeepEqual(e, a) {
+			t.Errorf("%d: expected %v, got %v", i, e, a)
+		}
+		if rv := list.(*metav1.List).ResourceVersion; rv != "10" {
+			t.Errorf("%d: expected the resource version of the first shard, got %s", i, rv)
+		}
+		if len(selectors[i]) != 1 {
+			t.Errorf("%d: expected each replica to list one shard, got %v", i, selectors[i])
+		}
+	}
+}
+
+func TestShardedListWatchFallsBackToLocalList(t *testing.T) {
+	var lock sync.Mutex
+	var selectors []string
+	lw := NewShardedListWatch(shardedTestListWatch(&lock, &selectors), ListShardingConfig{
+		Shards: []func(*metav1.ListOptions){
+			func(options *metav1.ListOptions) { options.LabelSelector = "shard=0" },
+			func(options *metav1.ListOptions) { options.LabelSelector = "shard=1" },
+		},
+		LocalShards:  []int{0},
+		Exchange:     NewInMemoryListShardExchange(),
+		FetchTimeout: 10 * time.Millisecond,
+	})
+	list, err := lw.List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := []string{"pod1", "pod2", "pod3"}, listedNames(t, list); !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	if e := []string{"shard=0", "shard=1"}; !reflect.DeepEqual(e, selectors) {
+		t.Errorf("expected the missing shard to be listed locally, got %v", selectors)
+	}
+
+	// Only the first list is sharded.
+	if _, err := lw.List(metav1.ListOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e := []string{"shard=0", "shard=1", ""}; !reflect.DeepEqual(e, selectors) {
+		t.Errorf("expected a plain relist, got %v", selectors)
+	}
+}
+
+func TestShardedListWatchRelistsInconsistentShards(t *testing.T) {
+	exchange := NewInMemoryListShardExchange()
+	// A peer listed the second shard at another resource version.
+	exchange.Publish(1, ListShardResult{ResourceVersion: "7"})
+
+	var lock sync.Mutex
+	var selectors []string
+	lw := NewShardedListWatch(shardedTestListWatch(&lock, &selectors), ListShardingConfig{
+		Shards: []func(*metav1.ListOptions){
+			func(options *metav1.ListOptions) { options.LabelSelector = "shard=0" },
+			func(options *metav1.ListOptions) { options.LabelSelector = "shard=1" },
+		},
+		LocalShards:  []int{0},
+		Exchange:     exchange,
+		FetchTimeout: time.Minute,
+	})
+	list, err := lw.List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := []string{"pod1", "pod2", "pod3"}, listedNames(t, list); !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	if rv := list.(*metav1.List).ResourceVersion; rv != "10" {
+		t.Errorf("expected the resource version of the first shard, got %s", rv)
+	}
+	if e := []string{"shard=0", "shard=1"}; !reflect.DeepEqual(e, selectors) {
+		t.Errorf("expected the inconsistent shard to be listed locally, got %v", selectors)
+	}
+}
+
+func TestShardedListWatchRelistsExpiredShards(t *testing.T) {
+	var selectors []string
+	lw := NewShardedListWatch(&ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			// The pagers retry expired lists, record each list once.
+			if listed := options.LabelSelector + "@" + options.ResourceVersion; len(selectors) == 0 || selectors[len(selectors)-1] != listed {
+				selectors = append(selectors, listed)
+			}
+			switch {
+			case options.LabelSelector == "shard=0" && options.ResourceVersion == "0":
+				// A stale resource version served from a cache.
+				return &v1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: "3"}}, nil
+			case options.ResourceVersion == "3":
+				return nil, apierrs.NewResourceExpired("too old resource version: 3")
+			}
+			return &v1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: "10"}}, nil
+		},
+	}, ListShardingConfig{
+		Shards: []func(*metav1.ListOptions){
+			func(options *metav1.ListOptions) { options.LabelSelector = "shard=0" },
+			func(options *metav1.ListOptions) { options.LabelSelector = "shard=1" },
+		},
+		LocalShards: []int{0, 1},
+		Exchange:    NewInMemoryListShardExchange(),
+	})
+	list, err := lw.List(metav1.ListOptions{ResourceVersion: "0"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rv := list.(*metav1.List).ResourceVersion; rv != "10" {
+		t.Errorf("expected the latest resource version, got %s", rv)
+	}
+	if e := []string{"shard=0@0", "shard=1@3", "shard=0@", "shard=1@10"}; !reflect.DeepEqual(e, selectors) {
+		t.Errorf("expected the first shard to be listed again, got %v", selectors)
+	}
+}
+
+func TestShardedListWatchListsLocalShardsConcurrently(t *testing.T) {
+	var lock sync.Mutex
+	var selectors []string
+	lw := shardedTestListWatch(&lock, &selectors)
+	shard2Listed := make(chan struct{})
+	failed := true
+	sharded := NewShardedListWatch(&ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			switch options.LabelSelector {
+			case "shard=1":
+				// Fails unless shard 2 is listed meanwhile.
+				select {
+				case <-shard2Listed:
+				case <-time.After(wait.ForeverTestTimeout):
+					return nil, fmt.Errorf("the shards were not listed concurrently")
+				}
+			case "shard=2":
+				close(shard2Listed)
+			}
+			if failed && options.LabelSelector == "shard=0" {
+				failed = false
+				return nil, fmt.Errorf("unavailable")
+			}
+			return lw.List(options)
+		},
+	}, ListShardingConfig{
+		Shards: []func(*metav1.ListOptions){
+			func(options *metav1.ListOptions) { options.LabelSelector = "shard=0" },
+			func(options *metav1.ListOptions) { options.LabelSelector = "shard=1" },
+			func(options *metav1.ListOptions) { options.LabelSelector = "shard=2" },
+		},
+		LocalShards: []int{0, 1, 2},
+		Exchange:    NewInMemoryListShardExchange(),
+	})
+
+	if _, err := sharded.List(metav1.ListOptions{}); err == nil {
+		t.Fatalf("expected the first list to fail")
+	}
+	// A failed list does not count as the sharded one.
+	list, err := sharded.List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := []string{"pod1", "pod2", "pod3"}, listedNames(t, list); !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+}
+
+func TestMergeListShardsRejectsInconsistentShards(t *testing.T) {
+	_, err := MergeListShards([]ListShardResult{{ResourceVersion: "10"}, {ResourceVersion: "7"}})
+	if err == nil {
+		t.Errorf("expected an error merging shards listed at different resource versions")
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 // AppendFunc is used to add a matching item to whatever list the caller is using
@@ -32,45 +33,53 @@ type AppendFunc func(interface{})
 
 // ListAll calls appendFn with each value retrieved from store which matches the selector.
 func ListAll(store Store, selector labels.Selector, appendFn AppendFunc) error {
-	selectAll := selector.Empty()
-	for _, m := range store.List() {
-		if selectAll {
-			// Avoid computing labels of the objects to speed up common flows
-			// of listing all objects.
+	if lister, ok := store.(SelectorLister); ok && !selector.Empty() {
+		list, err := lister.ListWithSelector(selector)
+		if err != nil {
+			return err
+		}
+		for _, m := range list {
 			appendFn(m)
-			continue
 		}
-		metadata, err := meta.Accessor(m)
+		return nil
+	}
+	return filterBySelector(store.List(), selector, appendFn)
+}
+
+// filterBySelector calls appendFn with each of objs whose labels match the
+// selector.
+func filterBySelector(objs []interface{}, selector labels.Selector, appendFn AppendFunc) error {
+	for _, m := range objs {
+		matches, err := matchesSelector(m, selector)
 		if err != nil {
 			return err
 		}
-		if selector.Matches(labels.Set(metadata.GetLabels())) {
+		if matches {
 			appendFn(m)
 		}
 	}
 	return nil
 }
 
+// matchesSelector returns whether the labels of obj match selector.
+func matchesSelector(obj interface{}, selector labels.Selector) (bool, error) {
+	if selector.Empty() {
+		// Avoid computing labels of the objects to speed up common flows
+		// of listing all objects.
+		return true, nil
+	}
+	metadata, err := meta.Accessor(obj)
+	if err != nil {
+		return false, err
+	}
+	return selector.Matches(labels.Set(metadata.GetLabels())), nil
+}
+
 // ListAllByNamespace used to list items belongs to namespace from Indexer.
 func ListAllByNamespace(indexer Indexer, namespace string, selector labels.Selector, appendFn AppendFunc) error {
 	selectAll := selector.Empty()
 	if namespace == metav1.NamespaceAll {
-		for _, m := range indexer.List() {
-			if selectAll {
-				// Avoid computing labels of the objects to speed up common flows
-				// of listing all objects.
-				appendFn(m)
-				continue
-			}
-			metadata, err := meta.Accessor(m)
-			if err != nil {
-				return err
-			}
-			if selector.Matches(labels.Set(metadata.GetLabels())) {
-				appendFn(m)
-			}
-		}
-		return nil
+		return ListAll(indexer, selector, appendFn)
 	}
 
 	items, err := indexer.Index(NamespaceIndex, &metav1.ObjectMeta{Namespace: namespace})
@@ -108,6 +117,51 @@ func ListAllByNamespace(indexer Indexer, namespace string, selector labels.Selec
 	return nil
 }
 
+// ListByIndex calls appendFn with each value retrieved from indexer whose indexed values for
+// indexName include indexedValue and which matches the selector. If the indexer was not given
+// an index named indexName, the matching values are found by evaluating indexFunc over every
+// object in the indexer instead, so typed listers can offer index-backed accessors that keep
+// working whether or not the informer registered the index.
+func ListByIndex(indexer Indexer, indexName, indexedValue string, indexFunc IndexFunc, selector labels.Selector, appendFn AppendFunc) error {
+	var items []interface{}
+	if _, exists := indexer.GetIndexers()[indexName]; exists {
+		var err error
+		items, err = indexer.ByIndex(indexName, indexedValue)
+		if err != nil {
+			return err
+		}
+	} else {
+		klog.V(5).Infof("index %q is not registered, falling back to a full scan", indexName)
+		for _, m := range indexer.List() {
+			values, err := indexFunc(m)
+			if err != nil {
+				return err
+			}
+			for _, value := range values {
+				if value == indexedValue {
+					items = append(items, m)
+					break
+				}
+			}
+		}
+	}
+	return filterBySelector(items, selector, appendFn)
+}
+
+// GetObjectsOwnedBy returns the objects of indexer having an owner reference
+// to ownerUID, using its OwnerReferenceIndex if it has one and checking every
+// object otherwise.
+func GetObjectsOwnedBy(indexer Indexer, ownerUID types.UID) ([]interface{}, error) {
+	var owned []interface{}
+	err := ListByIndex(indexer, OwnerReferenceIndex, string(ownerUID), OwnerReferenceIndexFunc, labels.Everything(), func(m interface{}) {
+		owned = append(owned, m)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return owned, nil
+}
+
 // GenericLister is a lister skin on a generic Indexer
 type GenericLister interface {
 	// List will return all objects across namespaces
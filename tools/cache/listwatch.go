@@ -18,6 +18,7 @@ package cache
 
 import (
 	"context"
+	"fmt"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
@@ -46,6 +47,18 @@ type ListerWatcher interface {
 	Watcher
 }
 
+// InitialEventsAnnotationKey is the annotation of the bookmark that ends the
+// initial events of a watch started with sendInitialEvents.
+const InitialEventsAnnotationKey = "k8s.io/initial-events-end"
+
+// InitialEventsWatcher is implemented by ListerWatchers that can start a
+// watch sending the current state of every object as Added events first,
+// followed by a bookmark annotated with InitialEventsAnnotationKey, as servers
+// supporting the watch-list protocol do when sendInitialEvents is requested.
+type InitialEventsWatcher interface {
+	WatchWithInitialEvents(options metav1.ListOptions) (watch.Interface, error)
+}
+
 // ListFunc knows how to list resources
 type ListFunc func(options metav1.ListOptions) (runtime.Object, error)
 
@@ -58,6 +71,8 @@ type WatchFunc func(options metav1.ListOptions) (watch.Interface, error)
 type ListWatch struct {
 	ListFunc  ListFunc
 	WatchFunc WatchFunc
+	// InitialEventsWatchFunc, if set, implements WatchWithInitialEvents.
+	InitialEventsWatchFunc WatchFunc
 	// DisableChunking requests no chunking for this list watcher.
 	DisableChunking bool
 }
@@ -97,7 +112,18 @@ func NewFilteredListWatchFromClient(c Getter, resource string, namespace string,
 			VersionedParams(&options, metav1.ParameterCodec).
 			Watch()
 	}
-	return &ListWatch{ListFunc: listFunc, WatchFunc: watchFunc}
+	initialEventsWatchFunc := func(options metav1.ListOptions) (watch.Interface, error) {
+		options.Watch = true
+		optionsModifier(&options)
+		return c.Get().
+			Namespace(namespace).
+			Resource(resource).
+			VersionedParams(&options, metav1.ParameterCodec).
+			Param("sendInitialEvents", "true").
+			Param("resourceVersionMatch", "NotOlderThan").
+			Watch()
+	}
+	return &ListWatch{ListFunc: listFunc, WatchFunc: watchFunc, InitialEventsWatchFunc: initialEventsWatchFunc}
 }
 
 // List a set of apiserver resources
@@ -112,3 +138,12 @@ func (lw *ListWatch) List(options metav1.ListOptions) (runtime.Object, error) {
 func (lw *ListWatch) Watch(options metav1.ListOptions) (watch.Interface, error) {
 	return lw.WatchFunc(options)
 }
+
+// WatchWithInitialEvents implements InitialEventsWatcher, failing if
+// InitialEventsWatchFunc is not set.
+func (lw *ListWatch) WatchWithInitialEvents(options metav1.ListOptions) (watch.Interface, error) {
+	if lw.InitialEventsWatchFunc == nil {
+		return nil, fmt.Errorf("watching with initial events is not supported")
+	}
+	return lw.InitialEventsWatchFunc(options)
+}
@@ -0,0 +1,315 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+)
+
+// ClusterResourceEventHandler is notified of the changes of the objects of
+// several clusters, see MultiClusterSharedInformer.  Like a
+// ResourceEventHandler, OnDelete may get a DeletedFinalStateUnknown.
+type ClusterResourceEventHandler interface {
+	OnAdd(cluster string, obj interface{})
+	OnUpdate(cluster string, oldObj, newObj interface{})
+	OnDelete(cluster string, obj interface{})
+}
+
+// ClusterResourceEventHandlerFuncs is an adaptor to let you easily specify as
+// many or as few of the notification functions as you want while still
+// implementing ClusterResourceEventHandler.
+type ClusterResourceEventHandlerFuncs struct {
+	AddFunc    func(cluster string, obj interface{})
+	UpdateFunc func(cluster string, oldObj, newObj interface{})
+	DeleteFunc func(cluster string, obj interface{})
+}
+
+// OnAdd calls AddFunc if it's not nil.
+func (r ClusterResourceEventHandlerFuncs) OnAdd(cluster string, obj interface{}) {
+	if r.AddFunc != nil {
+		r.AddFunc(cluster, obj)
+	}
+}
+
+// OnUpdate calls UpdateFunc if it's not nil.
+func (r ClusterResourceEventHandlerFuncs) OnUpdate(cluster string, oldObj, newObj interface{}) {
+	if r.UpdateFunc != nil {
+		r.UpdateFunc(cluster, oldObj, newObj)
+	}
+}
+
+// OnDelete calls DeleteFunc if it's not nil.
+func (r ClusterResourceEventHandlerFuncs) OnDelete(cluster string, obj interface{}) {
+	if r.DeleteFunc != nil {
+		r.DeleteFunc(cluster, obj)
+	}
+}
+
+// MultiClusterSharedInformer aggregates one SharedIndexInformer per cluster,
+// each identified by a cluster name, for controllers managing a fleet of
+// clusters.  Its store holds the objects of every cluster under the keys of
+// ClusterObjectKey, and its handlers are told which cluster each
+// notification comes from.
+type MultiClusterSharedInformer interface {
+	// AddCluster adds the informer of the named cluster, starting it if the
+	// multi-cluster informer is running.  The name must be non-empty and
+	// unique, and must not contain "|".
+	AddCluster(cluster string, informer SharedIndexInformer) error
+	// RemoveCluster stops the informer of the named cluster and removes its
+	// objects from the store, without notifying the handlers.  Removing an
+	// unknown cluster is not an error.
+	RemoveCluster(cluster string)
+	// GetInformer returns the informer of the named cluster.
+	GetInformer(cluster string) (SharedIndexInformer, bool)
+	// AddEventHandler adds a handler notified of the changes of every
+	// cluster, including those added later.
+	AddEventHandler(handler ClusterResourceEventHandler) error
+	// GetStore returns a read-only Store holding the objects of every
+	// cluster, keyed by ClusterObjectKey.  Its mutating methods return
+	// errors.
+	GetStore() Store
+	// HasSynced returns true once the informer of every cluster has synced.
+	HasSynced() bool
+	// Run runs the informers of the clusters until stopCh is closed, then
+	// waits for them to stop.  The informers must not be run by anyone
+	// else.
+	Run(stopCh <-chan struct{})
+}
+
+type clusterInformer struct {
+	informer SharedIndexInformer
+	// removed is closed by RemoveCluster to stop the informer.
+	removed chan struct{}
+}
+
+type multiClusterInformer struct {
+	lock     sync.RWMutex
+	clusters map[string]*clusterInformer
+	handlers []ClusterResourceEventHandler
+	// stopCh is the stop channel of Run, nil until it is called.
+	stopCh <-chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewMultiClusterSharedInformer returns a MultiClusterSharedInformer without
+// clusters.
+func NewMultiClusterSharedInformer() MultiClusterSharedInformer {
+	return &multiClusterInformer{clusters: map[string]*clusterInformer{}}
+}
+
+func (m *multiClusterInformer) AddCluster(cluster string, informer SharedIndexInformer) error {
+	if len(cluster) == 0 || strings.Contains(cluster, clusterKeySeparator) {
+		return fmt.Errorf("invalid cluster name %q", cluster)
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if _, exists := m.clusters[cluster]; exists {
+		return fmt.Errorf("cluster %q already exists", cluster)
+	}
+	for _, handler := range m.handlers {
+		if _, err := informer.AddEventHandler(clusterHandler(cluster, handler)); err != nil {
+			return fmt.Errorf("unable to add the handlers to cluster %q: %v", cluster, err)
+		}
+	}
+	c := &clusterInformer{informer: informer, removed: make(chan struct{})}
+	m.clusters[cluster] = c
+	if m.stopCh != nil {
+		m.startLocked(c)
+	}
+	return nil
+}
+
+func (m *multiClusterInformer) RemoveCluster(cluster string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if c, exists := m.clusters[cluster]; exists {
+		close(c.removed)
+		delete(m.clusters, cluster)
+	}
+}
+
+func (m *multiClusterInformer) GetInformer(cluster string) (SharedIndexInformer, bool) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	c, exists := m.clusters[cluster]
+	if !exists {
+		return nil, false
+	}
+	return c.informer, true
+}
+
+func (m *multiClusterInformer) AddEventHandler(handler ClusterResourceEventHandler) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for cluster, c := range m.clusters {
+		if _, err := c.informer.AddEventHandler(clusterHandler(cluster, handler)); err != nil {
+			return fmt.Errorf("unable to add the handler to cluster %q: %v", cluster, err)
+		}
+	}
+	m.handlers = append(m.handlers, handler)
+	return nil
+}
+
+// clusterHandler returns a ResourceEventHandler passing the notifications of
+// cluster to handler.
+func clusterHandler(cluster string, handler ClusterResourceEventHandler) ResourceEventHandler {
+	return ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			handler.OnAdd(cluster, obj)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			handler.OnUpdate(cluster, oldObj, newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			handler.OnDelete(cluster, obj)
+		},
+	}
+}
+
+func (m *multiClusterInformer) GetStore() Store {
+	return &multiClusterStore{informer: m}
+}
+
+func (m *multiClusterInformer) HasSynced() bool {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	for _, c := range m.clusters {
+		if !c.informer.HasSynced() {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *multiClusterInformer) Run(stopCh <-chan struct{}) {
+	defer utilruntime.HandleCrash()
+	func() {
+		m.lock.Lock()
+		defer m.lock.Unlock()
+		m.stopCh = stopCh
+		for _, c := range m.clusters {
+			m.startLocked(c)
+		}
+	}()
+	<-stopCh
+	m.wg.Wait()
+}
+
+// startLocked runs the informer of c until the multi-cluster informer stops
+// or c is removed.  It must be called with lock held.
+func (m *multiClusterInformer) startLocked(c *clusterInformer) {
+	stop := make(chan struct{})
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		c.informer.Run(stop)
+	}()
+	go func() {
+		select {
+		case <-m.stopCh:
+		case <-c.removed:
+		}
+		close(stop)
+	}()
+}
+
+// snapshot returns the stores of the clusters by name.
+func (m *multiClusterInformer) snapshot() map[string]Store {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	stores := make(map[string]Store, len(m.clusters))
+	for cluster, c := range m.clusters {
+		stores[cluster] = c.informer.GetStore()
+	}
+	return stores
+}
+
+// multiClusterStore is the read-only Store of a multiClusterInformer.
+type multiClusterStore struct {
+	informer *multiClusterInformer
+}
+
+var _ Store = &multiClusterStore{}
+
+func errReadOnlyMultiClusterStore(operation string) error {
+	return fmt.Errorf("%s is not supported on a multi-cluster store", operation)
+}
+
+func (s *multiClusterStore) Add(obj interface{}) error {
+	return errReadOnlyMultiClusterStore("Add")
+}
+
+func (s *multiClusterStore) Update(obj interface{}) error {
+	return errReadOnlyMultiClusterStore("Update")
+}
+
+func (s *multiClusterStore) Delete(obj interface{}) error {
+	return errReadOnlyMultiClusterStore("Delete")
+}
+
+func (s *multiClusterStore) Replace(list []interface{}, resourceVersion string) error {
+	return errReadOnlyMultiClusterStore("Replace")
+}
+
+func (s *multiClusterStore) Resync() error {
+	return errReadOnlyMultiClusterStore("Resync")
+}
+
+func (s *multiClusterStore) List() []interface{} {
+	var list []interface{}
+	for _, store := range s.informer.snapshot() {
+		list = append(list, store.List()...)
+	}
+	return list
+}
+
+func (s *multiClusterStore) ListKeys() []string {
+	var keys []string
+	for cluster, store := range s.informer.snapshot() {
+		for _, key := range store.ListKeys() {
+			keys = append(keys, cluster+clusterKeySeparator+key)
+		}
+	}
+	return keys
+}
+
+// Get returns the object with the key ClusterObjectKeyFunc makes for obj,
+// which must be an ExplicitKey or have a cluster name.
+func (s *multiClusterStore) Get(obj interface{}) (interface{}, bool, error) {
+	key, err := ClusterObjectKeyFunc(obj)
+	if err != nil {
+		return nil, false, KeyError{obj, err}
+	}
+	return s.GetByKey(key)
+}
+
+func (s *multiClusterStore) GetByKey(key string) (interface{}, bool, error) {
+	i := strings.Index(key, clusterKeySeparator)
+	if i < 0 {
+		return nil, false, fmt.Errorf("key %q has no cluster", key)
+	}
+	informer, exists := s.informer.GetInformer(key[:i])
+	if !exists {
+		return nil, false, nil
+	}
+	return informer.GetStore().GetByKey(key[i+1:])
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	fcache "k8s.io/client-go/tools/cache/testing"
+)
+
+func TestMultiClusterSharedInformer(t *testing.T) {
+	newCluster := func(names ...string) (*fcache.FakeControllerSource, SharedIndexInformer) {
+		source := fcache.NewFakeControllerSource()
+		for _, name := range names {
+			source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name}})
+		}
+		return source, NewSharedIndexInformer(source, &v1.Pod{}, 0, Indexers{})
+	}
+	multi := NewMultiClusterSharedInformer()
+	_, east := newCluster("pod1", "pod2")
+	if err := multi.AddCluster("east", east); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := multi.AddCluster("east", east); err == nil {
+		t.Error("expected an error adding a cluster twice")
+	}
+	if err := multi.AddCluster("a|b", east); err == nil {
+		t.Error("expected an error adding a cluster with an invalid name")
+	}
+
+	handler := &recordingHandler{}
+	multi.AddEventHandler(ClusterResourceEventHandlerFuncs{
+		AddFunc: func(cluster string, obj interface{}) {
+			handler.record("add "+cluster, obj)
+		},
+	})
+	stop := make(chan struct{})
+	defer close(stop)
+	go multi.Run(stop)
+
+	_, west := newCluster("pod1")
+	if err := multi.AddCluster("west", west); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !WaitForCacheSync(stop, multi.HasSynced) {
+		t.Fatal("the clusters did not sync")
+	}
+	handler.waitFor(t, "add east ns/pod1", "add east ns/pod2", "add west ns/pod1")
+
+	store := multi.GetStore()
+	if e, a := sets.NewString("east|ns/pod1", "east|ns/pod2", "west|ns/pod1"), sets.NewString(store.ListKeys()...); !e.Equal(a) {
+		t.Errorf("expected keys %v, got %v", e.List(), a.List())
+	}
+	if len(store.List()) != 3 {
+		t.Errorf("expected 3 objects, got %v", store.List())
+	}
+	if _, exists, err := store.GetByKey(ClusterObjectKey("west", "ns", "pod1")); err != nil || !exists {
+		t.Errorf("expected west|ns/pod1 to exist, got %v, %v", exists, err)
+	}
+	if _, exists, _ := store.GetByKey(ClusterObjectKey("west", "ns", "pod2")); exists {
+		t.Error("expected west|ns/pod2 not to exist")
+	}
+	if err := store.Add(&v1.Pod{}); err == nil {
+		t.Error("expected the store to be read-only")
+	}
+
+	multi.RemoveCluster("west")
+	if e, a := sets.NewString("east|ns/pod1", "east|ns/pod2"), sets.NewString(store.ListKeys()...); !e.Equal(a) {
+		t.Errorf("expected keys %v after removing a cluster, got %v", e.List(), a.List())
+	}
+	select {
+	case <-west.(ContextRunner).Done():
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Error("expected the informer of a removed cluster to stop")
+	}
+}
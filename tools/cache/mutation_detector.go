@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"sort"
 	"strconv"
 	"sync"
 	"time"
@@ -36,6 +37,47 @@ func init() {
 	mutationDetectionEnabled, _ = strconv.ParseBool(os.Getenv("KUBE_CACHE_MUTATION_DETECTOR"))
 }
 
+// FieldMutation is a field of a cached object that was mutated.
+type FieldMutation struct {
+	// Path is the path of the field in the object, such as
+	// ".Spec.Containers[0].Image" or `.ObjectMeta.Labels["app"]`.
+	Path string
+	// Cached is the value of the field when the object was cached, and
+	// Mutated its value now.  They are nil for missing slice elements or map
+	// entries.
+	Cached, Mutated interface{}
+}
+
+// CacheMutation describes an object of a cache that was mutated.
+type CacheMutation struct {
+	// Cache is the name of the cache, that of the type of its objects.
+	Cache string
+	// Key is the key of the object.
+	Key string
+	// Fields are the mutated fields of the object.
+	Fields []FieldMutation
+}
+
+// MutationReporter is called with the objects mutated since the previous
+// check of a mutation detector, see SetMutationReporter.
+type MutationReporter func(mutations []CacheMutation)
+
+var mutationReporter struct {
+	once     sync.Once
+	reporter MutationReporter
+}
+
+// SetMutationReporter makes the mutation detectors enabled by the
+// KUBE_CACHE_MUTATION_DETECTOR environment variable report the fields of the
+// mutated objects to reporter, instead of panicking.  Each mutation is
+// reported once.  Only the first call has an effect, and only detectors
+// created after it use the reporter.
+func SetMutationReporter(reporter MutationReporter) {
+	mutationReporter.once.Do(func() {
+		mutationReporter.reporter = reporter
+	})
+}
+
 // MutationDetector is able to monitor if the object be modified outside.
 type MutationDetector interface {
 	AddObject(obj interface{})
@@ -48,7 +90,7 @@ func NewCacheMutationDetector(name string) MutationDetector {
 		return dummyMutationDetector{}
 	}
 	klog.Warningln("Mutation detector is enabled, this will result in memory leakage.")
-	return &defaultCacheMutationDetector{name: name, period: 1 * time.Second}
+	return &defaultCacheMutationDetector{name: name, period: 1 * time.Second, reporter: mutationReporter.reporter}
 }
 
 type dummyMutationDetector struct{}
@@ -73,6 +115,8 @@ type defaultCacheMutationDetector struct {
 	// failure signal.  This failure is effectively a p0 bug and you can't trust process results
 	// after a mutation anyway.
 	failureFunc func(message string)
+	// reporter, if set, is called with the mutations instead of failing.
+	reporter MutationReporter
 }
 
 // cacheObj holds the actual object and a copy
@@ -113,6 +157,11 @@ func (d *defaultCacheMutationDetector) CompareObjects() {
 	d.lock.Lock()
 	defer d.lock.Unlock()
 
+	if d.reporter != nil {
+		d.reportMutations()
+		return
+	}
+
 	altered := false
 	for i, obj := range d.cachedObjs {
 		if !reflect.DeepEqual(obj.cached, obj.copied) {
@@ -130,3 +179,114 @@ func (d *defaultCacheMutationDetector) CompareObjects() {
 		panic(msg)
 	}
 }
+
+// reportMutations reports the mutated objects to the reporter, then copies
+// them again so that their mutations are only reported once.
+func (d *defaultCacheMutationDetector) reportMutations() {
+	var mutations []CacheMutation
+	for i, obj := range d.cachedObjs {
+		if reflect.DeepEqual(obj.cached, obj.copied) {
+			continue
+		}
+		key, _ := DeletionHandlingMetaNamespaceKeyFunc(obj.cached)
+		mutations = append(mutations, CacheMutation{
+			Cache:  d.name,
+			Key:    key,
+			Fields: diffFields(reflect.ValueOf(obj.copied), reflect.ValueOf(obj.cached)),
+		})
+		d.cachedObjs[i].copied = obj.cached.(runtime.Object).DeepCopyObject()
+	}
+	if len(mutations) > 0 {
+		d.reporter(mutations)
+	}
+}
+
+// diffFields returns the fields that differ between cached and mutated, two
+// values of the same type.
+func diffFields(cached, mutated reflect.Value) []FieldMutation {
+	var fields []FieldMutation
+	appendFieldDiffs(&fields, "", cached, mutated)
+	return fields
+}
+
+func appendFieldDiffs(fields *[]FieldMutation, path string, cached, mutated reflect.Value) {
+	if !cached.IsValid() || !mutated.IsValid() {
+		if cached.IsValid() != mutated.IsValid() {
+			*fields = append(*fields, FieldMutation{Path: path, Cached: valueInterface(cached), Mutated: valueInterface(mutated)})
+		}
+		return
+	}
+	if reflect.DeepEqual(cached.Interface(), mutated.Interface()) {
+		return
+	}
+	switch cached.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if cached.IsNil() || mutated.IsNil() || cached.Elem().Type() != mutated.Elem().Type() {
+			break
+		}
+		appendFieldDiffs(fields, path, cached.Elem(), mutated.Elem())
+		return
+	case reflect.Struct:
+		if !hasOnlyExportedFields(cached.Type()) {
+			// Unexported fields, such as those of times or quantities,
+			// cannot be compared one by one.
+			break
+		}
+		for i := 0; i < cached.NumField(); i++ {
+			appendFieldDiffs(fields, path+"."+cached.Type().Field(i).Name, cached.Field(i), mutated.Field(i))
+		}
+		return
+	case reflect.Slice, reflect.Array:
+		if cached.Kind() == reflect.Slice && (cached.IsNil() || mutated.IsNil()) {
+			break
+		}
+		for i := 0; i < cached.Len() || i < mutated.Len(); i++ {
+			appendFieldDiffs(fields, fmt.Sprintf("%s[%d]", path, i), indexValue(cached, i), indexValue(mutated, i))
+		}
+		return
+	case reflect.Map:
+		if cached.IsNil() || mutated.IsNil() {
+			break
+		}
+		keys := map[string]reflect.Value{}
+		for _, key := range append(cached.MapKeys(), mutated.MapKeys()...) {
+			keys[fmt.Sprintf("%#v", key.Interface())] = key
+		}
+		names := make([]string, 0, len(keys))
+		for name := range keys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			key := keys[name]
+			appendFieldDiffs(fields, fmt.Sprintf("%s[%s]", path, name), cached.MapIndex(key), mutated.MapIndex(key))
+		}
+		return
+	}
+	*fields = append(*fields, FieldMutation{Path: path, Cached: cached.Interface(), Mutated: mutated.Interface()})
+}
+
+// indexValue returns the element i of v, or the zero Value if v is shorter.
+func indexValue(v reflect.Value, i int) reflect.Value {
+	if i >= v.Len() {
+		return reflect.Value{}
+	}
+	return v.Index(i)
+}
+
+// valueInterface returns the value held by v, or nil for the zero Value.
+func valueInterface(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Interface()
+}
+
+func hasOnlyExportedFields(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if len(t.Field(i).PkgPath) > 0 {
+			return false
+		}
+	}
+	return true
+}
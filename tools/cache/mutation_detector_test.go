@@ -19,6 +19,7 @@ limitations under the License.
 package cache
 
 import (
+	"reflect"
 	"testing"
 	"time"
 
@@ -79,3 +80,49 @@ func TestMutationDetector(t *testing.T) {
 	}
 
 }
+
+func TestMutationDetectorReporter(t *testing.T) {
+	var reported [][]CacheMutation
+	detector := &defaultCacheMutationDetector{
+		name: "pods",
+		reporter: func(mutations []CacheMutation) {
+			reported = append(reported, mutations)
+		},
+		failureFunc: func(message string) {
+			t.Errorf("unexpected failure: %s", message)
+		},
+	}
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod", Labels: map[string]string{"app": "a"}},
+		Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "c", Image: "image:1"}}},
+	}
+	detector.AddObject(pod)
+	detector.AddObject(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "other"}})
+	detector.CompareObjects()
+	if len(reported) != 0 {
+		t.Fatalf("expected no mutation, got %v", reported)
+	}
+
+	pod.Labels["app"] = "b"
+	pod.Spec.Containers[0].Image = "image:2"
+	pod.Spec.Containers = append(pod.Spec.Containers, v1.Container{Name: "sidecar"})
+	detector.CompareObjects()
+	expected := []CacheMutation{{
+		Cache: "pods",
+		Key:   "ns/pod",
+		Fields: []FieldMutation{
+			{Path: `.ObjectMeta.Labels["app"]`, Cached: "a", Mutated: "b"},
+			{Path: ".Spec.Containers[0].Image", Cached: "image:1", Mutated: "image:2"},
+			{Path: ".Spec.Containers[1]", Cached: nil, Mutated: v1.Container{Name: "sidecar"}},
+		},
+	}}
+	if len(reported) != 1 || !reflect.DeepEqual(expected, reported[0]) {
+		t.Fatalf("expected mutations %#v, got %#v", expected, reported)
+	}
+
+	// A mutation is only reported once.
+	detector.CompareObjects()
+	if len(reported) != 1 {
+		t.Errorf("expected the mutation to be reported once, got %v", reported)
+	}
+}
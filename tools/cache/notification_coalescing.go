@@ -0,0 +1,140 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"container/list"
+)
+
+// coalescingBuffer is a notificationBuffer that merges a notification into
+// the pending notification of the same object, so that a handler that falls
+// behind is only handed the latest state of objects that changed several
+// times.  Notifications of different objects may be reordered, but those of
+// a single object are delivered in order.
+type coalescingBuffer struct {
+	// pending holds the *pendingNotification values in delivery order.
+	pending *list.List
+	// latest maps the key of an object to its last pending notification.
+	latest map[string]*list.Element
+	// keyFunc returns the keys of the objects.
+	keyFunc KeyFunc
+	// coalesced is called with the number of notifications merged away.
+	coalesced func(n int)
+}
+
+type pendingNotification struct {
+	key          string
+	notification interface{}
+}
+
+var _ notificationBuffer = &coalescingBuffer{}
+
+func newCoalescingBuffer(keyFunc KeyFunc, coalesced func(n int)) *coalescingBuffer {
+	return &coalescingBuffer{
+		pending:   list.New(),
+		latest:    map[string]*list.Element{},
+		keyFunc:   keyFunc,
+		coalesced: coalesced,
+	}
+}
+
+func (b *coalescingBuffer) WriteOne(data interface{}) {
+	key, ok := notificationKey(b.keyFunc, data)
+	if !ok {
+		b.pending.PushBack(&pendingNotification{notification: data})
+		return
+	}
+	if element, exists := b.latest[key]; exists {
+		pending := element.Value.(*pendingNotification)
+		if merged, ok := coalesceNotifications(pending.notification, data); ok {
+			if merged == nil {
+				// The handler never learned about the object.
+				b.pending.Remove(element)
+				delete(b.latest, key)
+				b.coalesced(2)
+				return
+			}
+			pending.notification = merged
+			b.coalesced(1)
+			return
+		}
+	}
+	b.latest[key] = b.pending.PushBack(&pendingNotification{key: key, notification: data})
+}
+
+func (b *coalescingBuffer) ReadOne() (interface{}, bool) {
+	element := b.pending.Front()
+	if element == nil {
+		return nil, false
+	}
+	b.pending.Remove(element)
+	pending := element.Value.(*pendingNotification)
+	if len(pending.key) > 0 && b.latest[pending.key] == element {
+		delete(b.latest, pending.key)
+	}
+	return pending.notification, true
+}
+
+// notificationKey returns the key keyFunc gives the object a notification is
+// about.
+func notificationKey(keyFunc KeyFunc, notification interface{}) (string, bool) {
+	var obj interface{}
+	switch n := notification.(type) {
+	case addNotification:
+		obj = n.newObj
+	case updateNotification:
+		obj = n.newObj
+	case deleteNotification:
+		obj = n.oldObj
+	default:
+		return "", false
+	}
+	key, err := keyFunc(obj)
+	if err != nil || len(key) == 0 {
+		return "", false
+	}
+	return key, true
+}
+
+// coalesceNotifications returns the notification equivalent to pending
+// followed by next, nil if they cancel out, and false if they cannot be
+// merged.  A merged notification keeps the distributed time of pending.
+func coalesceNotifications(pending, next interface{}) (interface{}, bool) {
+	switch p := pending.(type) {
+	case addNotification:
+		switch n := next.(type) {
+		case updateNotification:
+			return addNotification{newObj: n.newObj, deltaType: p.deltaType, distributed: p.distributed}, true
+		case deleteNotification:
+			return nil, true
+		}
+	case updateNotification:
+		switch n := next.(type) {
+		case updateNotification:
+			origin := p.origin
+			if n.origin < origin {
+				// The merged update is as genuine as the most genuine
+				// of the two.
+				origin = n.origin
+			}
+			return updateNotification{oldObj: p.oldObj, newObj: n.newObj, origin: origin, distributed: p.distributed}, true
+		case deleteNotification:
+			return deleteNotification{oldObj: n.oldObj, distributed: p.distributed}, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	fcache "k8s.io/client-go/tools/cache/testing"
+)
+
+func TestCoalescingBuffer(t *testing.T) {
+	pod := func(name, rv string) *v1.Pod {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name, ResourceVersion: rv}}
+	}
+	coalesced := 0
+	b := newCoalescingBuffer(DeletionHandlingMetaNamespaceKeyFunc, func(n int) { coalesced += n })
+
+	b.WriteOne(updateNotification{oldObj: pod("a", "1"), newObj: pod("a", "2")})
+	b.WriteOne(addNotification{newObj: pod("b", "3")})
+	b.WriteOne(updateNotification{oldObj: pod("a", "2"), newObj: pod("a", "4")})
+	b.WriteOne(updateNotification{oldObj: pod("b", "3"), newObj: pod("b", "5")})
+	b.WriteOne(addNotification{newObj: pod("c", "6")})
+	b.WriteOne(deleteNotification{oldObj: pod("c", "7")})
+	b.WriteOne(deleteNotification{oldObj: pod("b", "8")})
+	b.WriteOne(addNotification{newObj: pod("b", "9")})
+	b.WriteOne(updateNotification{oldObj: pod("b", "9"), newObj: pod("b", "10")})
+	// A delete followed by an add cannot be merged.
+	b.WriteOne(deleteNotification{oldObj: pod("d", "11")})
+	b.WriteOne(addNotification{newObj: pod("d", "12")})
+
+	expected := []interface{}{
+		updateNotification{oldObj: pod("a", "1"), newObj: pod("a", "4")},
+		addNotification{newObj: pod("b", "10")},
+		deleteNotification{oldObj: pod("d", "11")},
+		addNotification{newObj: pod("d", "12")},
+	}
+	var got []interface{}
+	for {
+		notification, ok := b.ReadOne()
+		if !ok {
+			break
+		}
+		got = append(got, notification)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if e, a := 11-len(expected), coalesced; e != a {
+		t.Errorf("expected %d coalesced notifications, got %d", e, a)
+	}
+}
+
+func TestCoalesceNotifications(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}})
+	informer := NewSharedInformer(source, &v1.Pod{}, 0).(*sharedIndexInformer)
+
+	release := make(chan struct{})
+	handler := &recordingHandler{}
+	var lastUpdate *v1.Pod
+	handle, err := informer.AddEventHandlerWithOptions(ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			<-release
+			handler.OnAdd(obj)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			lastUpdate = newObj.(*v1.Pod)
+			handler.OnUpdate(oldObj, newObj)
+		},
+	}, HandlerOptions{CoalesceNotifications: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	if !WaitForCacheSync(stop, informer.HasSynced) {
+		t.Fatalf("informer did not sync")
+	}
+	for i := 1; i <= 10; i++ {
+		source.Modify(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Labels: map[string]string{"i": strconv.Itoa(i)}}})
+	}
+	// Wait for the updates to queue up behind the blocked handler.
+	err = wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		obj, _, _ := informer.GetStore().GetByKey("pod1")
+		return obj.(*v1.Pod).Labels["i"] == "10", nil
+	})
+	if err != nil {
+		t.Fatalf("the updates were not received")
+	}
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+
+	if !WaitForCacheSync(stop, handle.HasSynced) {
+		t.Fatalf("handler did not sync")
+	}
+	// The first update is already waiting to be dispatched when the others
+	// arrive, so only the nine others are merged.
+	handler.waitFor(t, "add pod1", "update pod1", "update pod1")
+	handler.lock.Lock()
+	defer handler.lock.Unlock()
+	if lastUpdate == nil || lastUpdate.Labels["i"] != "10" {
+		t.Errorf("expected the last update to carry the latest state, got %v", lastUpdate)
+	}
+}
@@ -0,0 +1,405 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/utils/buffer"
+)
+
+// notificationBuffer holds the notifications that a processorListener has
+// accepted but not yet handed to its handler.  Implementations are not
+// required to be thread safe; only the listener's pop goroutine uses them.
+type notificationBuffer interface {
+	WriteOne(data interface{})
+	ReadOne() (data interface{}, ok bool)
+}
+
+// NotificationSpillConfig configures a listener's pending notification
+// buffer to spill to a temporary file once it holds MemoryLimit
+// notifications, instead of growing the in-memory ring without bound.
+type NotificationSpillConfig struct {
+	// MemoryLimit is the number of pending notifications kept in memory
+	// before further notifications are written to disk. Must be positive.
+	MemoryLimit int
+
+	// Dir is the directory the spill file is created in. If empty, the
+	// default directory for temporary files is used.
+	Dir string
+
+	// Codec encodes and decodes the objects carried by spilled
+	// notifications. It must be able to round trip the informer's object
+	// type.
+	Codec runtime.Codec
+
+	// HighWatermark is the number of spilled notifications at which
+	// OnBackPressure is called with true. If zero, MemoryLimit is used.
+	HighWatermark int
+
+	// OnBackPressure, if set, is called with true when the number of
+	// spilled notifications of a handler reaches HighWatermark, and with
+	// false once that handler has caught up and nothing is spilled anymore.
+	// It is called from the listener's delivery goroutine and must not block.
+	OnBackPressure func(handler ResourceEventHandler, engaged bool)
+}
+
+func (c *NotificationSpillConfig) validate() error {
+	if c.MemoryLimit <= 0 {
+		return fmt.Errorf("notification spill memory limit must be positive, got %d", c.MemoryLimit)
+	}
+	if c.Codec == nil {
+		return fmt.Errorf("notification spill requires a codec")
+	}
+	return nil
+}
+
+// spillingBuffer is a notificationBuffer that keeps up to MemoryLimit
+// notifications in memory and appends the rest to a file.  Notifications
+// are always returned in the order they were written: the in-memory ring
+// holds the oldest ones, followed by the spill file, followed by an overflow
+// ring used only when the spill file cannot be written.
+type spillingBuffer struct {
+	config  NotificationSpillConfig
+	handler ResourceEventHandler
+
+	memory      *buffer.RingGrowing
+	memoryCount int
+
+	file      *os.File
+	readOff   int64
+	writeOff  int64
+	fileCount int
+
+	overflow      *buffer.RingGrowing
+	overflowCount int
+
+	pressured bool
+}
+
+var _ notificationBuffer = &spillingBuffer{}
+
+func newSpillingBuffer(config NotificationSpillConfig, handler ResourceEventHandler) *spillingBuffer {
+	return &spillingBuffer{
+		config:   config,
+		handler:  handler,
+		memory:   buffer.NewRingGrowing(config.MemoryLimit),
+		overflow: buffer.NewRingGrowing(1),
+	}
+}
+
+// spilled returns the number of notifications not held in the in-memory ring.
+func (b *spillingBuffer) spilled() int {
+	return b.fileCount + b.overflowCount
+}
+
+func (b *spillingBuffer) WriteOne(data interface{}) {
+	if b.spilled() == 0 && b.memoryCount < b.config.MemoryLimit {
+		b.memory.WriteOne(data)
+		b.memoryCount++
+		return
+	}
+
+	written := false
+	if b.overflowCount == 0 {
+		if err := b.writeToFile(data); err != nil {
+			utilruntime.HandleError(fmt.Errorf("unable to spill notification to disk, keeping it in memory: %v", err))
+		} else {
+			b.fileCount++
+			written = true
+		}
+	}
+	if !written {
+		b.overflow.WriteOne(data)
+		b.overflowCount++
+	}
+
+	highWatermark := b.config.HighWatermark
+	if highWatermark == 0 {
+		highWatermark = b.config.MemoryLimit
+	}
+	if !b.pressured && b.spilled() >= highWatermark {
+		b.setPressure(true)
+	}
+}
+
+func (b *spillingBuffer) ReadOne() (interface{}, bool) {
+	if b.memoryCount > 0 {
+		b.memoryCount--
+		return b.memory.ReadOne()
+	}
+	for b.fileCount > 0 {
+		record, err := b.readRecord()
+		if err != nil {
+			// The file is unreadable from here on; drop what is left in it
+			// rather than blocking delivery forever.
+			utilruntime.HandleError(fmt.Errorf("unable to read spilled notifications, dropping %d of them: %v", b.fileCount, err))
+			b.fileCount = 0
+			b.resetFile()
+			break
+		}
+		b.fileCount--
+		if b.fileCount == 0 {
+			b.resetFile()
+		}
+		data, err := decodeNotification(b.config.Codec, record)
+		if err != nil {
+			utilruntime.HandleError(fmt.Errorf("unable to decode spilled notification, dropping it: %v", err))
+			continue
+		}
+		b.maybeReleasePressure()
+		return data, true
+	}
+	if b.overflowCount > 0 {
+		b.overflowCount--
+		b.maybeReleasePressure()
+		return b.overflow.ReadOne()
+	}
+	b.maybeReleasePressure()
+	return nil, false
+}
+
+// Close removes the spill file, discarding any notifications still in it.
+func (b *spillingBuffer) Close() error {
+	if b.file == nil {
+		return nil
+	}
+	name := b.file.Name()
+	err := b.file.Close()
+	b.file = nil
+	if rmErr := os.Remove(name); err == nil {
+		err = rmErr
+	}
+	return err
+}
+
+func (b *spillingBuffer) maybeReleasePressure() {
+	if b.pressured && b.spilled() == 0 {
+		b.setPressure(false)
+	}
+}
+
+func (b *spillingBuffer) setPressure(engaged bool) {
+	b.pressured = engaged
+	if b.config.OnBackPressure != nil {
+		b.config.OnBackPressure(b.handler, engaged)
+	}
+}
+
+// resetFile truncates the spill file once everything in it has been read, so
+// that disk usage does not keep growing across bursts.
+func (b *spillingBuffer) resetFile() {
+	b.readOff, b.writeOff = 0, 0
+	if b.file == nil {
+		return
+	}
+	if err := b.file.Truncate(0); err != nil {
+		utilruntime.HandleError(fmt.Errorf("unable to truncate notification spill file %s: %v", b.file.Name(), err))
+	}
+}
+
+func (b *spillingBuffer) writeToFile(data interface{}) error {
+	record, err := encodeNotification(b.config.Codec, data)
+	if err != nil {
+		return err
+	}
+	if b.file == nil {
+		f, err := ioutil.TempFile(b.config.Dir, "informer-notifications-")
+		if err != nil {
+			return err
+		}
+		b.file = f
+	}
+	var header [4]byte
+	binary.BigEndian.PutUint32(header[:], uint32(len(record)))
+	if _, err := b.file.WriteAt(append(header[:], record...), b.writeOff); err != nil {
+		return err
+	}
+	b.writeOff += int64(len(header) + len(record))
+	return nil
+}
+
+func (b *spillingBuffer) readRecord() ([]byte, error) {
+	var header [4]byte
+	if _, err := b.file.ReadAt(header[:], b.readOff); err != nil {
+		return nil, err
+	}
+	record := make([]byte, binary.BigEndian.Uint32(header[:]))
+	if _, err := b.file.ReadAt(record, b.readOff+int64(len(header))); err != nil {
+		return nil, err
+	}
+	b.readOff += int64(len(header) + len(record))
+	return record, nil
+}
+
+const (
+	spilledAdd byte = iota + 1
+	spilledUpdate
+	spilledDelete
+	spilledProgress
+	spilledInitialSync
+)
+
+const (
+	spilledNil byte = iota
+	spilledObject
+	spilledTombstone
+)
+
+func encodeNotification(codec runtime.Codec, notification interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	var objs []interface{}
+	switch n := notification.(type) {
+	case addNotification:
+		buf.WriteByte(spilledAdd)
+		writeSpilledBytes(buf, []byte(n.deltaType))
+		objs = []interface{}{n.newObj}
+	case updateNotification:
+		buf.WriteByte(spilledUpdate)
+		buf.WriteByte(byte(n.origin))
+		objs = []interface{}{n.oldObj, n.newObj}
+	case deleteNotification:
+		buf.WriteByte(spilledDelete)
+		objs = []interface{}{n.oldObj}
+	case progressNotification:
+		buf.WriteByte(spilledProgress)
+		writeSpilledBytes(buf, []byte(n.resourceVersion))
+	case initialSyncNotification:
+		buf.WriteByte(spilledInitialSync)
+	default:
+		return nil, fmt.Errorf("unrecognized notification: %T", notification)
+	}
+	for _, obj := range objs {
+		if err := encodeSpilledObject(codec, buf, obj); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeSpilledObject(codec runtime.Codec, buf *bytes.Buffer, obj interface{}) error {
+	switch o := obj.(type) {
+	case nil:
+		return buf.WriteByte(spilledNil)
+	case DeletedFinalStateUnknown:
+		buf.WriteByte(spilledTombstone)
+		writeSpilledBytes(buf, []byte(o.Key))
+		return encodeSpilledObject(codec, buf, o.Obj)
+	case runtime.Object:
+		data, err := runtime.Encode(codec, o)
+		if err != nil {
+			return err
+		}
+		buf.WriteByte(spilledObject)
+		writeSpilledBytes(buf, data)
+		return nil
+	default:
+		return fmt.Errorf("unable to spill object of type %T", obj)
+	}
+}
+
+func writeSpilledBytes(buf *bytes.Buffer, data []byte) {
+	var length [binary.MaxVarintLen64]byte
+	buf.Write(length[:binary.PutUvarint(length[:], uint64(len(data)))])
+	buf.Write(data)
+}
+
+func readSpilledBytes(r *bytes.Reader) ([]byte, error) {
+	length, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func decodeNotification(codec runtime.Codec, record []byte) (interface{}, error) {
+	r := bytes.NewReader(record)
+	kind, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case spilledAdd:
+		deltaType, err := readSpilledBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		obj, err := decodeSpilledObject(codec, r)
+		return addNotification{newObj: obj, deltaType: DeltaType(deltaType)}, err
+	case spilledUpdate:
+		origin, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		oldObj, err := decodeSpilledObject(codec, r)
+		if err != nil {
+			return nil, err
+		}
+		newObj, err := decodeSpilledObject(codec, r)
+		return updateNotification{oldObj: oldObj, newObj: newObj, origin: UpdateOrigin(origin)}, err
+	case spilledDelete:
+		obj, err := decodeSpilledObject(codec, r)
+		return deleteNotification{oldObj: obj}, err
+	case spilledProgress:
+		resourceVersion, err := readSpilledBytes(r)
+		return progressNotification{resourceVersion: string(resourceVersion)}, err
+	case spilledInitialSync:
+		return initialSyncNotification{}, nil
+	default:
+		return nil, fmt.Errorf("unrecognized spilled notification kind %d", kind)
+	}
+}
+
+func decodeSpilledObject(codec runtime.Codec, r *bytes.Reader) (interface{}, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch tag {
+	case spilledNil:
+		return nil, nil
+	case spilledTombstone:
+		key, err := readSpilledBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		obj, err := decodeSpilledObject(codec, r)
+		if err != nil {
+			return nil, err
+		}
+		return DeletedFinalStateUnknown{Key: string(key), Obj: obj}, nil
+	case spilledObject:
+		data, err := readSpilledBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		return runtime.Decode(codec, data)
+	default:
+		return nil, fmt.Errorf("unrecognized spilled object tag %d", tag)
+	}
+}
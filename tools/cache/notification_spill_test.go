@@ -0,0 +1,142 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+func spillTestPod(name string) *v1.Pod {
+	return &v1.Pod{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name},
+	}
+}
+
+func spillTestCodec() runtime.Codec {
+	info, _ := runtime.SerializerInfoForMediaType(scheme.Codecs.SupportedMediaTypes(), runtime.ContentTypeJSON)
+	return scheme.Codecs.CodecForVersions(info.Serializer, info.Serializer, v1.SchemeGroupVersion, v1.SchemeGroupVersion)
+}
+
+func TestSpillingBufferOrdering(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spill-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var pressure []bool
+	b := newSpillingBuffer(NotificationSpillConfig{
+		MemoryLimit:   2,
+		Dir:           dir,
+		Codec:         spillTestCodec(),
+		HighWatermark: 3,
+		OnBackPressure: func(_ ResourceEventHandler, engaged bool) {
+			pressure = append(pressure, engaged)
+		},
+	}, nil)
+	defer b.Close()
+
+	var expected []interface{}
+	for i := 0; i < 5; i++ {
+		n := addNotification{newObj: spillTestPod(fmt.Sprintf("pod%d", i))}
+		expected = append(expected, n)
+		b.WriteOne(n)
+	}
+	update := updateNotification{oldObj: spillTestPod("pod0"), newObj: spillTestPod("pod0"), origin: UpdateFromRelist}
+	tombstone := deleteNotification{oldObj: DeletedFinalStateUnknown{Key: "ns/pod1", Obj: spillTestPod("pod1")}}
+	progress := progressNotification{resourceVersion: "7"}
+	expected = append(expected, update, tombstone, progress)
+	b.WriteOne(update)
+	b.WriteOne(tombstone)
+	b.WriteOne(progress)
+
+	if e, a := []bool{true}, pressure; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected back-pressure signals %v, got %v", e, a)
+	}
+	if b.fileCount != 6 || b.memoryCount != 2 {
+		t.Errorf("expected 2 notifications in memory and 6 on disk, got %d and %d", b.memoryCount, b.fileCount)
+	}
+
+	for i, e := range expected {
+		a, ok := b.ReadOne()
+		if !ok {
+			t.Fatalf("%d: expected a notification", i)
+		}
+		if !reflect.DeepEqual(e, a) {
+			t.Errorf("%d: expected %#v, got %#v", i, e, a)
+		}
+	}
+	if _, ok := b.ReadOne(); ok {
+		t.Errorf("expected buffer to be empty")
+	}
+	if e, a := []bool{true, false}, pressure; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected back-pressure signals %v, got %v", e, a)
+	}
+
+	// Once drained, the buffer uses memory again before spilling.
+	b.WriteOne(expected[0])
+	if b.memoryCount != 1 || b.fileCount != 0 {
+		t.Errorf("expected drained buffer to write to memory first")
+	}
+
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 0 {
+		t.Errorf("expected spill file to be removed, found %d files", len(files))
+	}
+}
+
+func TestSpillingBufferOverflowsWhenUnencodable(t *testing.T) {
+	b := newSpillingBuffer(NotificationSpillConfig{
+		MemoryLimit: 1,
+		Codec:       spillTestCodec(),
+	}, nil)
+	defer b.Close()
+
+	expected := []interface{}{
+		addNotification{newObj: "first"},
+		addNotification{newObj: "second"},
+		addNotification{newObj: spillTestPod("third")},
+	}
+	for _, n := range expected {
+		b.WriteOne(n)
+	}
+	if b.fileCount != 0 || b.overflowCount != 2 {
+		t.Errorf("expected notifications after an unencodable one to stay in memory, got %d on disk and %d in overflow", b.fileCount, b.overflowCount)
+	}
+	for i, e := range expected {
+		a, ok := b.ReadOne()
+		if !ok || !reflect.DeepEqual(e, a) {
+			t.Errorf("%d: expected %#v, got %#v", i, e, a)
+		}
+	}
+}
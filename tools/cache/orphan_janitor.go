@@ -0,0 +1,184 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// OrphanFunc is called with a likely orphaned object and the owner
+// references it has whose owners are missing.
+type OrphanFunc func(obj interface{}, missingOwners []metav1.OwnerReference)
+
+// OrphanJanitor watches the cache of an informer for objects whose owners
+// are missing from the caches of their kinds for longer than a threshold,
+// and reports them once as likely orphaned.  It is a building block for
+// custom garbage collectors: it only reports objects and never deletes them.
+//
+// Only the owner references of kinds added with AddOwnerInformer are
+// checked.  An owner is missing if its cache holds no object with its
+// namespace, or no namespace, its name and its UID.
+type OrphanJanitor struct {
+	informer  SharedInformer
+	threshold time.Duration
+	onOrphan  OrphanFunc
+	clock     clock.Clock
+
+	lock   sync.Mutex
+	owners map[schema.GroupKind]SharedInformer
+	// missingSince holds when the owners of an object were first seen
+	// missing, by UID of the object.
+	missingSince map[types.UID]time.Time
+	// reported holds the UIDs of the objects already reported.
+	reported map[types.UID]bool
+}
+
+// NewOrphanJanitor returns an OrphanJanitor reporting the objects of
+// informer that are missing an owner for longer than threshold to onOrphan.
+func NewOrphanJanitor(informer SharedInformer, threshold time.Duration, onOrphan OrphanFunc) *OrphanJanitor {
+	return &OrphanJanitor{
+		informer:     informer,
+		threshold:    threshold,
+		onOrphan:     onOrphan,
+		clock:        clock.RealClock{},
+		owners:       map[schema.GroupKind]SharedInformer{},
+		missingSince: map[types.UID]time.Time{},
+		reported:     map[types.UID]bool{},
+	}
+}
+
+// AddOwnerInformer makes the janitor look up the owners of kind in the
+// cache of informer.
+func (j *OrphanJanitor) AddOwnerInformer(kind schema.GroupKind, informer SharedInformer) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	j.owners[kind] = informer
+}
+
+// Run checks the objects every half threshold until stopCh is closed.
+// Nothing is checked until the informer and the owner informers have synced.
+func (j *OrphanJanitor) Run(stopCh <-chan struct{}) {
+	wait.Until(j.check, j.threshold/2, stopCh)
+}
+
+// check looks for the objects missing an owner and reports those missing it
+// for longer than the threshold.
+func (j *OrphanJanitor) check() {
+	for _, orphan := range j.findOrphans() {
+		j.onOrphan(orphan.obj, orphan.missingOwners)
+	}
+}
+
+type orphan struct {
+	obj           interface{}
+	missingOwners []metav1.OwnerReference
+}
+
+// findOrphans returns the objects to report.
+func (j *OrphanJanitor) findOrphans() []orphan {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	if !j.informer.HasSynced() {
+		return nil
+	}
+	for _, owner := range j.owners {
+		if !owner.HasSynced() {
+			return nil
+		}
+	}
+
+	var orphans []orphan
+	now := j.clock.Now()
+	seen := map[types.UID]bool{}
+	for _, obj := range j.informer.GetStore().List() {
+		metadata, err := meta.Accessor(obj)
+		if err != nil {
+			continue
+		}
+		uid := metadata.GetUID()
+		missing := j.missingOwners(metadata)
+		if len(missing) == 0 {
+			continue
+		}
+		seen[uid] = true
+		since, found := j.missingSince[uid]
+		if !found {
+			j.missingSince[uid] = now
+			continue
+		}
+		if !j.reported[uid] && now.Sub(since) >= j.threshold {
+			j.reported[uid] = true
+			orphans = append(orphans, orphan{obj: obj, missingOwners: missing})
+		}
+	}
+	// Forget the objects that are gone or found their owners again.
+	for uid := range j.missingSince {
+		if !seen[uid] {
+			delete(j.missingSince, uid)
+			delete(j.reported, uid)
+		}
+	}
+	return orphans
+}
+
+// missingOwners returns the owner references of object whose owners are
+// missing from the owner caches.
+func (j *OrphanJanitor) missingOwners(object metav1.Object) []metav1.OwnerReference {
+	var missing []metav1.OwnerReference
+	for _, ref := range object.GetOwnerReferences() {
+		gv, err := schema.ParseGroupVersion(ref.APIVersion)
+		if err != nil {
+			continue
+		}
+		owners, found := j.owners[gv.WithKind(ref.Kind).GroupKind()]
+		if !found {
+			continue
+		}
+		if !hasOwner(owners.GetStore(), object.GetNamespace(), ref) {
+			missing = append(missing, ref)
+		}
+	}
+	return missing
+}
+
+// hasOwner returns whether store holds the owner referenced by ref, which is
+// either in namespace or cluster-scoped.
+func hasOwner(store Store, namespace string, ref metav1.OwnerReference) bool {
+	keys := []string{ref.Name}
+	if namespace != "" {
+		keys = append(keys, namespace+"/"+ref.Name)
+	}
+	for _, key := range keys {
+		obj, exists, err := store.GetByKey(key)
+		if err != nil || !exists {
+			continue
+		}
+		if owner, err := meta.Accessor(obj); err == nil && owner.GetUID() == ref.UID {
+			return true
+		}
+	}
+	return false
+}
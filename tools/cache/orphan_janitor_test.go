@@ -0,0 +1,105 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/wait"
+	fcache "k8s.io/client-go/tools/cache/testing"
+)
+
+func TestOrphanJanitor(t *testing.T) {
+	ownedBy := func(name, kind, owner string, uid types.UID) *v1.Pod {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns",
+			Name:      name,
+			UID:       types.UID(name),
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "apps/v1", Kind: kind, Name: owner, UID: uid},
+			},
+		}}
+	}
+	pods := fcache.NewFakeControllerSource()
+	pods.Add(ownedBy("owned", "ReplicaSet", "rs1", "rs1"))
+	pods.Add(ownedBy("orphan", "ReplicaSet", "rs2", "rs2"))
+	pods.Add(ownedBy("recreated-owner", "ReplicaSet", "rs1", "old-rs1"))
+	pods.Add(ownedBy("unknown-kind", "StatefulSet", "ss1", "ss1"))
+	replicaSets := fcache.NewFakeControllerSource()
+	replicaSets.Add(&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "rs1", UID: "rs1"}})
+
+	podInformer := NewSharedInformer(pods, &v1.Pod{}, 0)
+	replicaSetInformer := NewSharedInformer(replicaSets, &appsv1.ReplicaSet{}, 0)
+	stop := make(chan struct{})
+	defer close(stop)
+	go podInformer.Run(stop)
+	go replicaSetInformer.Run(stop)
+	if !WaitForCacheSync(stop, podInformer.HasSynced, replicaSetInformer.HasSynced) {
+		t.Fatalf("informers did not sync")
+	}
+
+	var reported []string
+	janitor := NewOrphanJanitor(podInformer, time.Minute, func(obj interface{}, missingOwners []metav1.OwnerReference) {
+		if len(missingOwners) != 1 {
+			t.Errorf("expected one missing owner, got %v", missingOwners)
+		}
+		reported = append(reported, obj.(*v1.Pod).Name)
+	})
+	fakeClock := clock.NewFakeClock(time.Now())
+	janitor.clock = fakeClock
+	janitor.AddOwnerInformer(schema.GroupKind{Group: "apps", Kind: "ReplicaSet"}, replicaSetInformer)
+
+	janitor.check()
+	if len(reported) != 0 {
+		t.Errorf("expected objects not to be reported before the threshold, got %v", reported)
+	}
+	fakeClock.Step(time.Minute)
+	janitor.check()
+	sort.Strings(reported)
+	if expected := []string{"orphan", "recreated-owner"}; !reflect.DeepEqual(reported, expected) {
+		t.Errorf("expected %v to be reported, got %v", expected, reported)
+	}
+	reported = nil
+	fakeClock.Step(time.Minute)
+	janitor.check()
+	if len(reported) != 0 {
+		t.Errorf("expected objects to be reported once, got %v", reported)
+	}
+
+	// An owner showing up again resets the object.
+	replicaSets.Add(&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "rs2", UID: "rs2"}})
+	err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		_, exists, _ := replicaSetInformer.GetStore().GetByKey("ns/rs2")
+		return exists, nil
+	})
+	if err != nil {
+		t.Fatalf("the owner was not cached")
+	}
+	janitor.check()
+	if _, found := janitor.missingSince["orphan"]; found {
+		t.Errorf("expected an object whose owner showed up to be forgotten")
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package cache
 
 import (
+	"reflect"
 	"sync"
 	"testing"
 	"time"
@@ -56,3 +57,49 @@ func BenchmarkListener(b *testing.B) {
 	swg.Wait() // Block until all notifications have been received
 	b.StopTimer()
 }
+
+func TestListenerSlowOverflow(t *testing.T) {
+	var lock sync.Mutex
+	var added []interface{}
+	pl := newProcessListener(&ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			lock.Lock()
+			defer lock.Unlock()
+			added = append(added, obj)
+		},
+	}, 0, 0, time.Now(), 16)
+	pl.slowListener = &SlowListenerConfig{Threshold: time.Millisecond, Overflow: true}
+
+	// Nothing accepts the notifications before pop starts, so they overflow
+	// rather than block.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 3; i++ {
+			pl.add(addNotification{newObj: i})
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("expected add not to block past the threshold")
+	}
+
+	var wg wait.Group
+	defer wg.Wait()
+	defer close(pl.addCh)
+	wg.Start(pl.run)
+	wg.Start(pl.pop)
+	pl.add(addNotification{newObj: 3})
+
+	err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(added) == 4, nil
+	})
+	lock.Lock()
+	defer lock.Unlock()
+	if e, a := []interface{}{0, 1, 2, 3}, added; err != nil || !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v to be added in order, got %v", e, a)
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"io"
 	"math/rand"
 	"reflect"
+	"strconv"
 	"sync"
 	"time"
 
@@ -36,6 +37,7 @@ import (
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/features"
 	"k8s.io/client-go/tools/pager"
 	"k8s.io/klog"
 	"k8s.io/utils/trace"
@@ -58,7 +60,12 @@ type Reflector struct {
 	// the beginning of the next one.
 	period       time.Duration
 	resyncPeriod time.Duration
-	ShouldResync func() bool
+	// resyncPeriodLock guards resyncPeriod, which SetResyncPeriod changes
+	// while the reflector runs.
+	resyncPeriodLock sync.Mutex
+	// resyncPeriodChanged tells the resync loop to restart its timer.
+	resyncPeriodChanged chan struct{}
+	ShouldResync        func() bool
 	// clock allows tests to manipulate time
 	clock clock.Clock
 	// lastSyncResourceVersion is the resource version token last
@@ -66,18 +73,241 @@ type Reflector struct {
 	// it is thread safe, but not synchronized with the underlying store
 	lastSyncResourceVersion string
 	// lastSyncResourceVersionMutex guards read/write access to lastSyncResourceVersion
+	// and lastRelistTime
 	lastSyncResourceVersionMutex sync.RWMutex
+	// lastRelistTime is when the store was last replaced by a list
+	lastRelistTime time.Time
 	// WatchListPageSize is the requested chunk size of initial and resync watch lists.
 	// Defaults to pager.PageSize.
 	WatchListPageSize int64
+	// Timeouts controls the timeouts requested for lists and watches.
+	// Defaults to DefaultReflectorTimeouts().
+	Timeouts ReflectorTimeouts
+	// StrictValidation makes the reflector check every watch event for signs
+	// of a broken intermediary such as a caching proxy: resource versions must
+	// be numeric and must not go backwards, and objects must have a name that
+	// yields a decodable key. An event failing a check is not applied to the
+	// store: it is counted by the watch anomalies metric and ends the watch
+	// with an error, which goes to the WatchErrorHandler, so that the
+	// reflector relists rather than miss the change.
+	StrictValidation bool
+	// watchAnomalies counts the events rejected by StrictValidation.
+	watchAnomalies CounterMetric
+	// WatchBackoff controls how long the reflector waits before re-establishing
+	// a watch that failed. Defaults to DefaultReflectorBackoff().
+	WatchBackoff ReflectorBackoff
+	// watchFailures is the number of consecutive watch failures. It is only
+	// accessed by the goroutine running ListAndWatch.
+	watchFailures int
+	// watchFailuresMetric and watchBackoffMetric expose the backoff state.
+	watchFailuresMetric GaugeMetric
+	watchBackoffMetric  GaugeMetric
+	// RelistBackoff controls how long the reflector waits before listing
+	// again soon after its last list, for example when its watches keep
+	// expiring, so that relists do not overload the server.  Lists started
+	// less than RelistBackoff.ResetAfter apart count as consecutive.
+	// Defaults to no backoff.
+	RelistBackoff ReflectorBackoff
+	// relists is the number of consecutive relists, and lastListTime when
+	// the last list started.  They are only accessed by the goroutine
+	// running ListAndWatch.
+	relists      int
+	lastListTime time.Time
+	// WatchErrorHandler is called with every error that ends a list or a
+	// watch. Defaults to DefaultWatchErrorHandler.
+	WatchErrorHandler WatchErrorHandler
+	// UseWatchList makes the reflector fill its store from a watch sending
+	// the initial state of the objects, which the server streams instead of
+	// holding a whole list in memory, if its ListerWatcher is an
+	// InitialEventsWatcher.  The reflector waits for the initial events for
+	// at most Timeouts.ListTimeout, or a minute if unset.  Once that fails,
+	// such as when the server does not support it, the reflector lists
+	// instead for the rest of its life.  Defaults to the WatchListClient
+	// feature.
+	UseWatchList bool
+	// watchListFailed is set once watching with initial events failed, so
+	// that the reflector no longer attempts it.  It is only accessed by the
+	// goroutine running ListAndWatch.
+	watchListFailed bool
+	// onSyncProgress, if set, is called with the resource version the store
+	// has caught up with after a list, a bookmark or a resync.
+	onSyncProgress func(resourceVersion string)
+	// initialResourceVersion, if set, is the resource version of the objects
+	// the store already holds.  The first ListAndWatch watches from it
+	// instead of listing.  It is only accessed by the goroutine running
+	// ListAndWatch.
+	initialResourceVersion string
+	// health describes the connection to the server, healthLock guards it.
+	health     ReflectorHealth
+	healthLock sync.Mutex
+}
+
+// WatchErrorHandler is called with the errors that end the lists and watches
+// of a Reflector, which keeps retrying them after the handler returns. It can
+// be used to surface errors such as a missing permission or resource, for
+// example to stop the reflector. Failed lists are reported as a *ListError,
+// failed watches with the error returned by the ListerWatcher or the server.
+type WatchErrorHandler func(r *Reflector, err error)
+
+// DefaultWatchErrorHandler logs err.
+func DefaultWatchErrorHandler(r *Reflector, err error) {
+	switch {
+	case err == io.EOF:
+		// watch closed normally
+	case err == io.ErrUnexpectedEOF:
+		klog.V(1).Infof("%s: Watch for %v closed with unexpected EOF: %v", r.name, r.expectedType, err)
+	case apierrs.IsResourceExpired(err):
+		klog.V(4).Infof("%s: watch of %v ended with: %v", r.name, r.expectedType, err)
+	default:
+		if _, ok := err.(*ListError); ok {
+			utilruntime.HandleError(err)
+			return
+		}
+		utilruntime.HandleError(fmt.Errorf("%s: Failed to watch %v: %v", r.name, r.expectedType, err))
+	}
+}
+
+// ListError is returned by ListAndWatch when the ListerWatcher failed to list.
+type ListError struct {
+	// Reflector is the name of the reflector.
+	Reflector string
+	// Type is the type of the listed objects.
+	Type reflect.Type
+	// Err is the error returned by the ListerWatcher.
+	Err error
+}
+
+func (e *ListError) Error() string {
+	return fmt.Sprintf("%s: Failed to list %v: %v", e.Reflector, e.Type, e.Err)
+}
+
+// Name returns the name identifying the reflector in logs and metrics.
+func (r *Reflector) Name() string {
+	return r.name
+}
+
+func (r *Reflector) handleWatchError(err error) {
+	r.healthFailed(err)
+	if r.WatchErrorHandler != nil {
+		r.WatchErrorHandler(r, err)
+		return
+	}
+	DefaultWatchErrorHandler(r, err)
 }
 
 var (
 	// We try to spread the load on apiserver by setting timeouts for
 	// watch requests - it is random in [minWatchTimeout, 2*minWatchTimeout].
 	minWatchTimeout = 5 * time.Minute
+	// defaultInitialEventsTimeout bounds the wait for the initial events of a
+	// watch-list when no ListTimeout is set.
+	defaultInitialEventsTimeout = time.Minute
 )
 
+// ReflectorTimeouts configures the timeouts a Reflector asks the server to apply
+// to its list and watch requests.
+type ReflectorTimeouts struct {
+	// ListTimeout, if non-zero, is sent as the timeoutSeconds of every LIST request
+	// and also bounds the total time spent paging through a single list.
+	ListTimeout time.Duration
+	// MinWatchTimeout is the shortest timeout requested for a watch. The server closes
+	// the watch once it expires and the reflector starts a new one. If zero, no timeout
+	// is requested and the server default applies.
+	MinWatchTimeout time.Duration
+	// WatchTimeoutJitter spreads watch timeouts across reflectors: each watch requests
+	// a random timeout in [MinWatchTimeout, MinWatchTimeout*(1+WatchTimeoutJitter)].
+	WatchTimeoutJitter float64
+}
+
+// DefaultReflectorTimeouts returns the timeouts used by a Reflector unless
+// configured otherwise: no list timeout, and watch timeouts spread between five
+// and ten minutes.
+func DefaultReflectorTimeouts() ReflectorTimeouts {
+	return ReflectorTimeouts{
+		MinWatchTimeout:    minWatchTimeout,
+		WatchTimeoutJitter: 1.0,
+	}
+}
+
+// listTimeoutSeconds returns the timeoutSeconds to send with a LIST request, or nil.
+func (t ReflectorTimeouts) listTimeoutSeconds() *int64 {
+	if t.ListTimeout <= 0 {
+		return nil
+	}
+	return timeoutSeconds(t.ListTimeout)
+}
+
+// watchTimeoutSeconds returns a jittered timeoutSeconds to send with a watch request, or nil.
+func (t ReflectorTimeouts) watchTimeoutSeconds() *int64 {
+	if t.MinWatchTimeout <= 0 {
+		return nil
+	}
+	jitter := t.WatchTimeoutJitter
+	if jitter < 0 {
+		jitter = 0
+	}
+	return timeoutSeconds(time.Duration(float64(t.MinWatchTimeout) * (rand.Float64()*jitter + 1.0)))
+}
+
+// timeoutSeconds rounds d up to whole seconds, so short timeouts are not lost.
+func timeoutSeconds(d time.Duration) *int64 {
+	seconds := int64((d + time.Second - 1) / time.Second)
+	return &seconds
+}
+
+// ReflectorBackoff configures the exponential backoff a Reflector applies
+// between consecutive failed attempts to watch, or between consecutive
+// relists, so that a struggling server is not hammered with requests.
+type ReflectorBackoff struct {
+	// Initial is the wait after the first failure. If zero, failed watches
+	// are re-established without waiting.
+	Initial time.Duration
+	// Max caps the wait, before jitter is added.
+	Max time.Duration
+	// Factor multiplies the wait after every further consecutive failure.
+	Factor float64
+	// Jitter adds a random wait of up to Jitter times the wait.
+	Jitter float64
+	// ResetAfter is how long a watch must have lasted for its normal end to
+	// reset the count of consecutive failures, or how long after a list the
+	// next one no longer counts as a consecutive relist.
+	ResetAfter time.Duration
+}
+
+// DefaultReflectorBackoff returns the backoff used by a Reflector unless
+// configured otherwise: starting at 800ms and doubling up to 30s, with up to
+// 100% jitter, reset by a watch lasting two minutes.
+func DefaultReflectorBackoff() ReflectorBackoff {
+	return ReflectorBackoff{
+		Initial:    800 * time.Millisecond,
+		Max:        30 * time.Second,
+		Factor:     2.0,
+		Jitter:     1.0,
+		ResetAfter: 2 * time.Minute,
+	}
+}
+
+// delay returns the wait after the given number of consecutive failures.
+func (b ReflectorBackoff) delay(failures int) time.Duration {
+	if b.Initial <= 0 || failures <= 0 {
+		return 0
+	}
+	delay := b.Initial
+	for i := 1; i < failures && (b.Max <= 0 || delay < b.Max); i++ {
+		if b.Factor <= 1 {
+			break
+		}
+		delay = time.Duration(float64(delay) * b.Factor)
+	}
+	if b.Max > 0 && delay > b.Max {
+		delay = b.Max
+	}
+	if b.Jitter > 0 {
+		delay = wait.Jitter(delay, b.Jitter)
+	}
+	return delay
+}
+
 // NewNamespaceKeyedIndexerAndReflector creates an Indexer and a Reflector
 // The indexer is configured to key on namespace
 func NewNamespaceKeyedIndexerAndReflector(lw ListerWatcher, expectedType interface{}, resyncPeriod time.Duration) (indexer Indexer, reflector *Reflector) {
@@ -106,7 +336,16 @@ func NewNamedReflector(name string, lw ListerWatcher, expectedType interface{},
 		period:        time.Second,
 		resyncPeriod:  resyncPeriod,
 		clock:         &clock.RealClock{},
+		Timeouts:      DefaultReflectorTimeouts(),
+		WatchBackoff:  DefaultReflectorBackoff(),
+
+		resyncPeriodChanged: make(chan struct{}, 1),
+
+		StrictValidation: features.Enabled(features.StrictReflectorValidation),
+		UseWatchList:     features.Enabled(features.WatchListClient),
 	}
+	r.watchAnomalies = newWatchAnomaliesMetric(name)
+	r.watchFailuresMetric, r.watchBackoffMetric = newWatchBackoffMetrics(name)
 	return r
 }
 
@@ -117,10 +356,10 @@ var internalPackages = []string{"client-go/tools/cache/"}
 // Run starts a watch and handles watch events. Will restart the watch if it is closed.
 // Run will exit when stopCh is closed.
 func (r *Reflector) Run(stopCh <-chan struct{}) {
-	klog.V(3).Infof("Starting reflector %v (%s) from %s", r.expectedType, r.resyncPeriod, r.name)
+	klog.V(3).Infof("Starting reflector %v (%s) from %s", r.expectedType, r.getResyncPeriod(), r.name)
 	wait.Until(func() {
 		if err := r.ListAndWatch(stopCh); err != nil {
-			utilruntime.HandleError(err)
+			r.handleWatchError(err)
 		}
 	}, r.period, stopCh)
 }
@@ -140,85 +379,147 @@ var (
 // resyncChan returns a channel which will receive something when a resync is
 // required, and a cleanup function.
 func (r *Reflector) resyncChan() (<-chan time.Time, func() bool) {
-	if r.resyncPeriod == 0 {
+	resyncPeriod := r.getResyncPeriod()
+	if resyncPeriod == 0 {
 		return neverExitWatch, func() bool { return false }
 	}
 	// The cleanup function is required: imagine the scenario where watches
 	// always fail so we end up listing frequently. Then, if we don't
 	// manually stop the timer, we could end up with many timers active
 	// concurrently.
-	t := r.clock.NewTimer(r.resyncPeriod)
+	t := r.clock.NewTimer(resyncPeriod)
 	return t.C(), t.Stop
 }
 
+// SetResyncPeriod changes the resync period of a reflector, running or not.
+// A running reflector restarts its resync timer with the new period, zero
+// disabling resyncs.
+func (r *Reflector) SetResyncPeriod(resyncPeriod time.Duration) {
+	r.resyncPeriodLock.Lock()
+	r.resyncPeriod = resyncPeriod
+	r.resyncPeriodLock.Unlock()
+
+	if r.resyncPeriodChanged == nil {
+		return
+	}
+	select {
+	case r.resyncPeriodChanged <- struct{}{}:
+	default:
+	}
+}
+
+func (r *Reflector) getResyncPeriod() time.Duration {
+	r.resyncPeriodLock.Lock()
+	defer r.resyncPeriodLock.Unlock()
+	return r.resyncPeriod
+}
+
 // ListAndWatch first lists all items and get the resource version at the moment of call,
 // and then use the resource version to watch.
 // It returns error if ListAndWatch didn't even try to initialize watch.
 func (r *Reflector) ListAndWatch(stopCh <-chan struct{}) error {
 	klog.V(3).Infof("Listing and watching %v from %s", r.expectedType, r.name)
+	r.healthStarted()
 	var resourceVersion string
 
 	// Explicitly set "0" as resource version - it's fine for the List()
 	// to be served from cache and potentially be delayed relative to
 	// etcd contents. Reflector framework will catch up via Watch() eventually.
-	options := metav1.ListOptions{ResourceVersion: "0"}
+	options := metav1.ListOptions{ResourceVersion: "0", TimeoutSeconds: r.Timeouts.listTimeoutSeconds()}
 
-	if err := func() error {
-		initTrace := trace.New("Reflector ListAndWatch", trace.Field{"name", r.name})
-		defer initTrace.LogIfLong(10 * time.Second)
-		var list runtime.Object
+	if len(r.initialResourceVersion) == 0 && !r.waitToRelist(stopCh) {
+		return nil
+	}
+
+	var w watch.Interface
+	skipList := false
+	if len(r.initialResourceVersion) > 0 {
+		// The store was restored at this resource version.  If it has
+		// expired, the watch fails and the next ListAndWatch lists.
+		resourceVersion, r.initialResourceVersion = r.initialResourceVersion, ""
+		r.setLastSyncResourceVersion(resourceVersion)
+		r.syncProgress(resourceVersion)
+		skipList = true
+	} else if r.UseWatchList && !r.watchListFailed {
 		var err error
-		listCh := make(chan struct{}, 1)
-		panicCh := make(chan interface{}, 1)
-		go func() {
-			defer func() {
-				if r := recover(); r != nil {
-					panicCh <- r
-				}
-			}()
-			// Attempt to gather list in chunks, if supported by listerWatcher, if not, the first
-			// list request will return the full response.
-			pager := pager.New(pager.SimplePageFunc(func(opts metav1.ListOptions) (runtime.Object, error) {
-				return r.listerWatcher.List(opts)
-			}))
-			if r.WatchListPageSize != 0 {
-				pager.PageSize = r.WatchListPageSize
-			}
-			// Pager falls back to full list if paginated list calls fail due to an "Expired" error.
-			list, err = pager.List(context.Background(), options)
-			close(listCh)
-		}()
-		select {
-		case <-stopCh:
+		w, resourceVersion, err = r.watchList(stopCh)
+		if err == errorStopRequested {
 			return nil
-		case r := <-panicCh:
-			panic(r)
-		case <-listCh:
 		}
 		if err != nil {
-			return fmt.Errorf("%s: Failed to list %v: %v", r.name, r.expectedType, err)
+			klog.Warningf("%s: falling back to listing %v: %v", r.name, r.expectedType, err)
+			r.watchListFailed = true
+			w = nil
 		}
-		initTrace.Step("Objects listed")
-		listMetaInterface, err := meta.ListAccessor(list)
-		if err != nil {
-			return fmt.Errorf("%s: Unable to understand list result %#v: %v", r.name, list, err)
-		}
-		resourceVersion = listMetaInterface.GetResourceVersion()
-		initTrace.Step("Resource version extracted")
-		items, err := meta.ExtractList(list)
-		if err != nil {
-			return fmt.Errorf("%s: Unable to understand list result %#v (%v)", r.name, list, err)
-		}
-		initTrace.Step("Objects extracted")
-		if err := r.syncWith(items, resourceVersion); err != nil {
-			return fmt.Errorf("%s: Unable to sync list result: %v", r.name, err)
+	}
+
+	if w == nil && !skipList {
+		if err := func() error {
+			initTrace := trace.New("Reflector ListAndWatch", trace.Field{"name", r.name})
+			defer initTrace.LogIfLong(10 * time.Second)
+			var list runtime.Object
+			var err error
+			listCh := make(chan struct{}, 1)
+			panicCh := make(chan interface{}, 1)
+			go func() {
+				defer func() {
+					if r := recover(); r != nil {
+						panicCh <- r
+					}
+				}()
+				// Attempt to gather list in chunks, if supported by listerWatcher, if not, the first
+				// list request will return the full response.
+				pager := pager.New(pager.SimplePageFunc(func(opts metav1.ListOptions) (runtime.Object, error) {
+					return r.listerWatcher.List(opts)
+				}))
+				if r.WatchListPageSize != 0 {
+					pager.PageSize = r.WatchListPageSize
+				}
+				ctx := context.Background()
+				if r.Timeouts.ListTimeout > 0 {
+					var cancel context.CancelFunc
+					ctx, cancel = context.WithTimeout(ctx, r.Timeouts.ListTimeout)
+					defer cancel()
+				}
+				// Pager falls back to full list if paginated list calls fail due to an "Expired" error.
+				list, err = pager.List(ctx, options)
+				close(listCh)
+			}()
+			select {
+			case <-stopCh:
+				return nil
+			case r := <-panicCh:
+				panic(r)
+			case <-listCh:
+			}
+			if err != nil {
+				return &ListError{Reflector: r.name, Type: r.expectedType, Err: err}
+			}
+			initTrace.Step("Objects listed")
+			listMetaInterface, err := meta.ListAccessor(list)
+			if err != nil {
+				return fmt.Errorf("%s: Unable to understand list result %#v: %v", r.name, list, err)
+			}
+			resourceVersion = listMetaInterface.GetResourceVersion()
+			initTrace.Step("Resource version extracted")
+			items, err := meta.ExtractList(list)
+			if err != nil {
+				return fmt.Errorf("%s: Unable to understand list result %#v (%v)", r.name, list, err)
+			}
+			initTrace.Step("Objects extracted")
+			if err := r.syncWith(items, resourceVersion); err != nil {
+				return fmt.Errorf("%s: Unable to sync list result: %v", r.name, err)
+			}
+			initTrace.Step("SyncWith done")
+			r.setLastSyncResourceVersion(resourceVersion)
+			r.setLastRelistTime(r.clock.Now())
+			r.healthListed()
+			r.syncProgress(resourceVersion)
+			initTrace.Step("Resource version updated")
+			return nil
+		}(); err != nil {
+			return err
 		}
-		initTrace.Step("SyncWith done")
-		r.setLastSyncResourceVersion(resourceVersion)
-		initTrace.Step("Resource version updated")
-		return nil
-	}(); err != nil {
-		return err
 	}
 
 	resyncerrc := make(chan error, 1)
@@ -232,6 +533,10 @@ func (r *Reflector) ListAndWatch(stopCh <-chan struct{}) error {
 		for {
 			select {
 			case <-resyncCh:
+			case <-r.resyncPeriodChanged:
+				cleanup()
+				resyncCh, cleanup = r.resyncChan()
+				continue
 			case <-stopCh:
 				return
 			case <-cancelCh:
@@ -243,6 +548,7 @@ func (r *Reflector) ListAndWatch(stopCh <-chan struct{}) error {
 					resyncerrc <- err
 					return
 				}
+				r.syncProgress(r.LastSyncResourceVersion())
 			}
 			cleanup()
 			resyncCh, cleanup = r.resyncChan()
@@ -257,50 +563,191 @@ func (r *Reflector) ListAndWatch(stopCh <-chan struct{}) error {
 		default:
 		}
 
-		timeoutSeconds := int64(minWatchTimeout.Seconds() * (rand.Float64() + 1.0))
 		options = metav1.ListOptions{
 			ResourceVersion: resourceVersion,
 			// We want to avoid situations of hanging watchers. Stop any wachers that do not
 			// receive any events within the timeout window.
-			TimeoutSeconds: &timeoutSeconds,
+			TimeoutSeconds: r.Timeouts.watchTimeoutSeconds(),
 			// To reduce load on kube-apiserver on watch restarts, you may enable watch bookmarks.
 			// Reflector doesn't assume bookmarks are returned at all (if the server do not support
 			// watch bookmarks, it will ignore this field).
 			AllowWatchBookmarks: true,
 		}
 
-		w, err := r.listerWatcher.Watch(options)
+		var err error
+		if w == nil {
+			w, err = r.listerWatcher.Watch(options)
+		}
 		if err != nil {
-			switch err {
-			case io.EOF:
-				// watch closed normally
-			case io.ErrUnexpectedEOF:
-				klog.V(1).Infof("%s: Watch for %v closed with unexpected EOF: %v", r.name, r.expectedType, err)
-			default:
-				utilruntime.HandleError(fmt.Errorf("%s: Failed to watch %v: %v", r.name, r.expectedType, err))
+			r.handleWatchError(err)
+			if !r.watchFailed(stopCh) {
+				return nil
 			}
 			// If this is "connection refused" error, it means that most likely apiserver is not responsive.
 			// It doesn't make sense to re-list all objects because most likely we will be able to restart
 			// watch where we ended.
 			// If that's the case wait and resend watch request.
 			if utilnet.IsConnectionRefused(err) {
-				time.Sleep(time.Second)
 				continue
 			}
 			return nil
 		}
 
-		if err := r.watchHandler(w, &resourceVersion, resyncerrc, stopCh); err != nil {
+		start := r.clock.Now()
+		r.healthWatching()
+		err = r.watchHandler(w, &resourceVersion, resyncerrc, stopCh)
+		r.healthWatchEnded()
+		// The next iteration starts a new watch.
+		w = nil
+		if err != nil {
 			if err != errorStopRequested {
-				switch {
-				case apierrs.IsResourceExpired(err):
-					klog.V(4).Infof("%s: watch of %v ended with: %v", r.name, r.expectedType, err)
-				default:
-					klog.Warningf("%s: watch of %v ended with: %v", r.name, r.expectedType, err)
+				r.handleWatchError(err)
+				if !apierrs.IsResourceExpired(err) {
+					r.watchFailed(stopCh)
 				}
 			}
 			return nil
 		}
+		r.watchEnded(r.clock.Since(start))
+	}
+}
+
+// watchList starts a watch sending the initial state of the objects and
+// fills the store with it.  It returns the watch, to be resumed once the
+// initial events have ended, and their resource version.  Servers that do
+// not support the watch-list protocol either reject the watch or send a
+// regular bookmark instead of the one ending the initial events.
+func (r *Reflector) watchList(stopCh <-chan struct{}) (watch.Interface, string, error) {
+	lw, ok := r.listerWatcher.(InitialEventsWatcher)
+	if !ok {
+		return nil, "", fmt.Errorf("%T does not support watching with initial events", r.listerWatcher)
+	}
+	w, err := lw.WatchWithInitialEvents(metav1.ListOptions{
+		AllowWatchBookmarks: true,
+		TimeoutSeconds:      r.Timeouts.watchTimeoutSeconds(),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	// Without a bound, a server ignoring the initial events request would
+	// hold the reflector until the watch times out.
+	initialEventsTimeout := r.Timeouts.ListTimeout
+	if initialEventsTimeout <= 0 {
+		initialEventsTimeout = defaultInitialEventsTimeout
+	}
+	timer := r.clock.NewTimer(initialEventsTimeout)
+	defer timer.Stop()
+
+	initial := NewStore(DeletionHandlingMetaNamespaceKeyFunc)
+	for {
+		var event watch.Event
+		select {
+		case <-stopCh:
+			w.Stop()
+			return nil, "", errorStopRequested
+		case <-timer.C():
+			w.Stop()
+			return nil, "", fmt.Errorf("the initial events did not end within %v", initialEventsTimeout)
+		case event, ok = <-w.ResultChan():
+		}
+		if !ok {
+			return nil, "", fmt.Errorf("the watch ended before the initial events")
+		}
+		if event.Type == watch.Error {
+			w.Stop()
+			return nil, "", apierrs.FromObject(event.Object)
+		}
+		if e, a := r.expectedType, reflect.TypeOf(event.Object); e != nil && e != a {
+			utilruntime.HandleError(fmt.Errorf("%s: expected type %v, but watch event object had type %v", r.name, e, a))
+			continue
+		}
+		switch event.Type {
+		case watch.Added, watch.Modified:
+			err = initial.Update(event.Object)
+		case watch.Deleted:
+			err = initial.Delete(event.Object)
+		case watch.Bookmark:
+			accessor, err := meta.Accessor(event.Object)
+			if err != nil || accessor.GetAnnotations()[InitialEventsAnnotationKey] != "true" {
+				w.Stop()
+				return nil, "", fmt.Errorf("the server sent a bookmark before the end of the initial events")
+			}
+			resourceVersion := accessor.GetResourceVersion()
+			if err := r.store.Replace(initial.List(), resourceVersion); err != nil {
+				w.Stop()
+				return nil, "", fmt.Errorf("%s: Unable to sync the initial events: %v", r.name, err)
+			}
+			r.setLastSyncResourceVersion(resourceVersion)
+			r.setLastRelistTime(r.clock.Now())
+			r.healthListed()
+			r.syncProgress(resourceVersion)
+			return w, resourceVersion, nil
+		}
+		if err != nil {
+			utilruntime.HandleError(fmt.Errorf("%s: unable to apply initial event %#v: %v", r.name, event, err))
+		}
+	}
+}
+
+// watchFailed records a failed watch and waits for the backoff it earned,
+// returning false if stopCh was closed in the meantime.
+func (r *Reflector) watchFailed(stopCh <-chan struct{}) bool {
+	r.watchFailures++
+	delay := r.WatchBackoff.delay(r.watchFailures)
+	r.setWatchBackoffMetrics(delay)
+	if delay <= 0 {
+		return true
+	}
+	klog.V(2).Infof("%s: backing off %v after %d consecutive watch failures of %v", r.name, delay, r.watchFailures, r.expectedType)
+	t := r.clock.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C():
+		return true
+	case <-stopCh:
+		return false
+	}
+}
+
+// waitToRelist records a list about to start and waits for the backoff it
+// earned if it follows the last list closely, returning false if stopCh was
+// closed in the meantime.
+func (r *Reflector) waitToRelist(stopCh <-chan struct{}) bool {
+	if r.lastListTime.IsZero() || r.clock.Since(r.lastListTime) >= r.RelistBackoff.ResetAfter {
+		r.relists = 0
+	} else {
+		r.relists++
+	}
+	if delay := r.RelistBackoff.delay(r.relists); delay > 0 {
+		klog.V(2).Infof("%s: backing off %v after %d consecutive relists of %v", r.name, delay, r.relists, r.expectedType)
+		t := r.clock.NewTimer(delay)
+		defer t.Stop()
+		select {
+		case <-t.C():
+		case <-stopCh:
+			return false
+		}
+	}
+	r.lastListTime = r.clock.Now()
+	return true
+}
+
+// watchEnded resets the consecutive watch failures once a watch that lasted
+// long enough ended normally.
+func (r *Reflector) watchEnded(duration time.Duration) {
+	if r.watchFailures == 0 || duration < r.WatchBackoff.ResetAfter {
+		return
+	}
+	r.watchFailures = 0
+	r.setWatchBackoffMetrics(0)
+}
+
+func (r *Reflector) setWatchBackoffMetrics(delay time.Duration) {
+	if r.watchFailuresMetric != nil {
+		r.watchFailuresMetric.Set(float64(r.watchFailures))
+	}
+	if r.watchBackoffMetric != nil {
+		r.watchBackoffMetric.Set(delay.Seconds())
 	}
 }
 
@@ -340,12 +787,27 @@ loop:
 				utilruntime.HandleError(fmt.Errorf("%s: expected type %v, but watch event object had type %v", r.name, e, a))
 				continue
 			}
-			meta, err := meta.Accessor(event.Object)
+			accessor, err := meta.Accessor(event.Object)
 			if err != nil {
 				utilruntime.HandleError(fmt.Errorf("%s: unable to understand watch event %#v", r.name, event))
 				continue
 			}
-			newResourceVersion := meta.GetResourceVersion()
+			r.healthEvent()
+			newResourceVersion := accessor.GetResourceVersion()
+			if event.Type == watch.Bookmark && len(newResourceVersion) == 0 {
+				// Resuming from an empty resource version would watch from
+				// an arbitrary point, missing events.
+				utilruntime.HandleError(fmt.Errorf("%s: ignoring bookmark without a resource version", r.name))
+				continue
+			}
+			if r.StrictValidation {
+				if err := validateWatchEvent(event, newResourceVersion, *resourceVersion); err != nil {
+					if r.watchAnomalies != nil {
+						r.watchAnomalies.Inc()
+					}
+					return fmt.Errorf("%s: rejected watch event: %v", r.name, err)
+				}
+			}
 			switch event.Type {
 			case watch.Added:
 				err := r.store.Add(event.Object)
@@ -366,12 +828,17 @@ loop:
 					utilruntime.HandleError(fmt.Errorf("%s: unable to delete watch event object (%#v) from store: %v", r.name, event.Object, err))
 				}
 			case watch.Bookmark:
-				// A `Bookmark` means watch has synced here, just update the resourceVersion
+				// A `Bookmark` means watch has synced here, just update the resourceVersion,
+				// so that the next watch resumes from it instead of from the last change
+				// this watch delivered, which may have expired by then.
 			default:
 				utilruntime.HandleError(fmt.Errorf("%s: unable to understand watch event %#v", r.name, event))
 			}
 			*resourceVersion = newResourceVersion
 			r.setLastSyncResourceVersion(newResourceVersion)
+			if event.Type == watch.Bookmark {
+				r.syncProgress(newResourceVersion)
+			}
 			eventCount++
 		}
 	}
@@ -384,6 +851,29 @@ loop:
 	return nil
 }
 
+// validateWatchEvent checks that the resource version of a watch event does not
+// go back from the last one seen, and that the key of its object can be decoded.
+func validateWatchEvent(event watch.Event, newResourceVersion, lastResourceVersion string) error {
+	newRV, err := strconv.ParseUint(newResourceVersion, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%s event has a non-numeric resource version %q", event.Type, newResourceVersion)
+	}
+	if lastRV, err := strconv.ParseUint(lastResourceVersion, 10, 64); err == nil && newRV < lastRV {
+		return fmt.Errorf("%s event has resource version %d, older than the last seen %d", event.Type, newRV, lastRV)
+	}
+	if event.Type == watch.Bookmark {
+		return nil
+	}
+	key, err := MetaNamespaceKeyFunc(event.Object)
+	if err != nil {
+		return fmt.Errorf("%s event object has no key: %v", event.Type, err)
+	}
+	if _, name, err := SplitMetaNamespaceKey(key); err != nil || len(name) == 0 {
+		return fmt.Errorf("%s event object has an undecodable key %q", event.Type, key)
+	}
+	return nil
+}
+
 // LastSyncResourceVersion is the resource version observed when last sync with the underlying store
 // The value returned is not synchronized with access to the underlying store and is not thread-safe
 func (r *Reflector) LastSyncResourceVersion() string {
@@ -397,3 +887,23 @@ func (r *Reflector) setLastSyncResourceVersion(v string) {
 	defer r.lastSyncResourceVersionMutex.Unlock()
 	r.lastSyncResourceVersion = v
 }
+
+// LastRelistTime returns when the reflector last replaced the contents of its
+// store with a list, or the zero time if it has not yet.
+func (r *Reflector) LastRelistTime() time.Time {
+	r.lastSyncResourceVersionMutex.RLock()
+	defer r.lastSyncResourceVersionMutex.RUnlock()
+	return r.lastRelistTime
+}
+
+func (r *Reflector) setLastRelistTime(t time.Time) {
+	r.lastSyncResourceVersionMutex.Lock()
+	defer r.lastSyncResourceVersionMutex.Unlock()
+	r.lastRelistTime = t
+}
+
+func (r *Reflector) syncProgress(resourceVersion string) {
+	if r.onSyncProgress != nil && len(resourceVersion) > 0 {
+		r.onSyncProgress(resourceVersion)
+	}
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// ReflectorHealth describes the connection of a Reflector to the server.
+type ReflectorHealth struct {
+	// WatchConnected is true while a watch is open.
+	WatchConnected bool
+	// LastEventTime is when the reflector last listed or received a watch
+	// event, bookmarks included, or the zero time if it has not yet.
+	LastEventTime time.Time
+	// DisconnectedSince is when the reflector started, or its last watch
+	// ended or failed, while no watch is open, and the zero time otherwise.
+	DisconnectedSince time.Time
+	// ConsecutiveFailures is the number of lists and watches that failed
+	// since the last one that succeeded.  Watches ending normally do not
+	// count.
+	ConsecutiveFailures int
+}
+
+// HealthChecker reports the health of the connection of an informer to the
+// server, for example to serve a readiness probe.  It is implemented by the
+// SharedInformers returned by NewSharedIndexInformer.
+type HealthChecker interface {
+	// Health returns the health of the informer's reflector.  Informers that
+	// have not started report the zero ReflectorHealth.
+	Health() ReflectorHealth
+}
+
+var _ HealthChecker = &sharedIndexInformer{}
+
+// Check returns an error if the reflector has been without a watch for longer
+// than maxDisconnected at now.
+func (h ReflectorHealth) Check(now time.Time, maxDisconnected time.Duration) error {
+	if h.WatchConnected || h.DisconnectedSince.IsZero() {
+		return nil
+	}
+	if disconnected := now.Sub(h.DisconnectedSince); disconnected > maxDisconnected {
+		return fmt.Errorf("watch disconnected for %v, after %d consecutive failures", disconnected, h.ConsecutiveFailures)
+	}
+	return nil
+}
+
+// Health returns the health of the connection of the reflector.
+func (r *Reflector) Health() ReflectorHealth {
+	r.healthLock.Lock()
+	defer r.healthLock.Unlock()
+	return r.health
+}
+
+// healthStarted records that the reflector starts listing and watching
+// without a watch.
+func (r *Reflector) healthStarted() {
+	r.healthLock.Lock()
+	defer r.healthLock.Unlock()
+	if !r.health.WatchConnected && r.health.DisconnectedSince.IsZero() {
+		r.health.DisconnectedSince = r.clock.Now()
+	}
+}
+
+// healthListed records a successful list.
+func (r *Reflector) healthListed() {
+	r.healthLock.Lock()
+	defer r.healthLock.Unlock()
+	r.health.LastEventTime = r.clock.Now()
+	r.health.ConsecutiveFailures = 0
+}
+
+// healthWatching records that a watch was opened.
+func (r *Reflector) healthWatching() {
+	r.healthLock.Lock()
+	defer r.healthLock.Unlock()
+	r.health.WatchConnected = true
+	r.health.DisconnectedSince = time.Time{}
+	r.health.ConsecutiveFailures = 0
+}
+
+// healthEvent records a watch event.
+func (r *Reflector) healthEvent() {
+	r.healthLock.Lock()
+	defer r.healthLock.Unlock()
+	r.health.LastEventTime = r.clock.Now()
+}
+
+// healthWatchEnded records that the open watch ended.
+func (r *Reflector) healthWatchEnded() {
+	r.healthLock.Lock()
+	defer r.healthLock.Unlock()
+	r.health.WatchConnected = false
+	r.health.DisconnectedSince = r.clock.Now()
+}
+
+// healthFailed records a list or watch ending with err.
+func (r *Reflector) healthFailed(err error) {
+	if err == io.EOF {
+		return
+	}
+	r.healthLock.Lock()
+	defer r.healthLock.Unlock()
+	r.health.ConsecutiveFailures++
+}
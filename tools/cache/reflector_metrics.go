@@ -87,6 +87,39 @@ func (noopMetricsProvider) NewLastResourceVersionMetric(name string) GaugeMetric
 	return noopMetric{}
 }
 
+// WatchAnomaliesMetricsProvider is optionally implemented by a MetricsProvider
+// to count the watch events a reflector rejects in strict validation mode.
+type WatchAnomaliesMetricsProvider interface {
+	NewWatchAnomaliesMetric(name string) CounterMetric
+}
+
+// newWatchAnomaliesMetric returns the watch anomalies counter of the named
+// reflector, or a no-op counter if the metrics provider does not supply one.
+func newWatchAnomaliesMetric(name string) CounterMetric {
+	if provider, ok := metricsFactory.metricsProvider.(WatchAnomaliesMetricsProvider); ok {
+		return provider.NewWatchAnomaliesMetric(name)
+	}
+	return noopMetric{}
+}
+
+// WatchBackoffMetricsProvider is optionally implemented by a MetricsProvider
+// to expose the state of the backoff a reflector applies to failing watches.
+type WatchBackoffMetricsProvider interface {
+	// NewWatchFailuresMetric returns a gauge of the consecutive watch failures.
+	NewWatchFailuresMetric(name string) GaugeMetric
+	// NewWatchBackoffMetric returns a gauge of the last backoff in seconds.
+	NewWatchBackoffMetric(name string) GaugeMetric
+}
+
+// newWatchBackoffMetrics returns the watch backoff gauges of the named
+// reflector, or no-op gauges if the metrics provider does not supply them.
+func newWatchBackoffMetrics(name string) (failures, backoff GaugeMetric) {
+	if provider, ok := metricsFactory.metricsProvider.(WatchBackoffMetricsProvider); ok {
+		return provider.NewWatchFailuresMetric(name), provider.NewWatchBackoffMetric(name)
+	}
+	return noopMetric{}, noopMetric{}
+}
+
 var metricsFactory = struct {
 	metricsProvider MetricsProvider
 	setProviders    sync.Once
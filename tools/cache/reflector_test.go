@@ -20,13 +20,17 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"reflect"
 	"strconv"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/clock"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apimachinery/pkg/watch"
 )
@@ -194,6 +198,181 @@ func TestReflectorWatchHandler(t *testing.T) {
 	}
 }
 
+func TestReflectorWatchBookmarks(t *testing.T) {
+	s := NewStore(MetaNamespaceKeyFunc)
+	r := NewReflector(&testLW{}, &v1.Pod{}, s, 0)
+	fw := watch.NewFakeWithChanSize(4, false)
+	fw.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo", ResourceVersion: "5"}})
+	fw.Action(watch.Bookmark, &v1.Pod{ObjectMeta: metav1.ObjectMeta{ResourceVersion: "9"}})
+	fw.Action(watch.Bookmark, &v1.Pod{})
+	fw.Stop()
+
+	var resumeRV string
+	if err := r.watchHandler(fw, &resumeRV, nevererrc, wait.NeverStop); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if keys := s.ListKeys(); len(keys) != 1 {
+		t.Errorf("expected bookmarks not to change the store, got %v", keys)
+	}
+	if resumeRV != "9" || r.LastSyncResourceVersion() != "9" {
+		t.Errorf("expected to resume from the bookmark, got %q and %q", resumeRV, r.LastSyncResourceVersion())
+	}
+}
+
+func TestReflectorWatchList(t *testing.T) {
+	initialEnd := &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		ResourceVersion: "7",
+		Annotations:     map[string]string{InitialEventsAnnotationKey: "true"},
+	}}
+	table := []struct {
+		name string
+		// events are sent by the initial events watch.
+		events []watch.Event
+		// listed is whether the reflector is expected to fall back to a list.
+		listed bool
+	}{
+		{
+			name: "initial events",
+			events: []watch.Event{
+				{Type: watch.Added, Object: &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo", ResourceVersion: "5"}}},
+				{Type: watch.Added, Object: &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "bar", ResourceVersion: "6"}}},
+				{Type: watch.Bookmark, Object: initialEnd},
+			},
+		},
+		{
+			name: "unsupported by the server",
+			events: []watch.Event{
+				{Type: watch.Bookmark, Object: &v1.Pod{ObjectMeta: metav1.ObjectMeta{ResourceVersion: "7"}}},
+			},
+			listed: true,
+		},
+		{
+			name: "error",
+			events: []watch.Event{
+				{Type: watch.Added, Object: &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo", ResourceVersion: "5"}}},
+				{Type: watch.Error, Object: &metav1.Status{Status: metav1.StatusFailure, Reason: metav1.StatusReasonBadRequest}},
+			},
+			listed: true,
+		},
+	}
+	for _, item := range table {
+		t.Run(item.name, func(t *testing.T) {
+			stopCh := make(chan struct{})
+			initialWatch := watch.NewFakeWithChanSize(len(item.events), false)
+			for _, event := range item.events {
+				initialWatch.Action(event.Type, event.Object)
+			}
+			listed := false
+			resumed := make(chan string, 1)
+			lw := &ListWatch{
+				ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+					listed = true
+					return &v1.PodList{
+						ListMeta: metav1.ListMeta{ResourceVersion: "7"},
+						Items:    []v1.Pod{{ObjectMeta: metav1.ObjectMeta{Name: "foo", ResourceVersion: "5"}}, {ObjectMeta: metav1.ObjectMeta{Name: "bar", ResourceVersion: "6"}}},
+					}, nil
+				},
+				WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+					resumed <- options.ResourceVersion
+					return watch.NewFake(), nil
+				},
+				InitialEventsWatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+					return initialWatch, nil
+				},
+			}
+			s := NewStore(MetaNamespaceKeyFunc)
+			r := NewReflector(lw, &v1.Pod{}, s, 0)
+			r.UseWatchList = true
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				if err := r.ListAndWatch(stopCh); err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			}()
+			err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+				return r.LastSyncResourceVersion() == "7", nil
+			})
+			if err != nil {
+				t.Fatalf("the reflector did not sync")
+			}
+
+			// A new watch is only started when falling back to a list.
+			var rv string
+			select {
+			case rv = <-resumed:
+			case <-time.After(100 * time.Millisecond):
+			}
+			close(stopCh)
+			<-done
+
+			if listed != item.listed {
+				t.Errorf("expected listed=%v, got %v", item.listed, listed)
+			}
+			if item.listed && rv != "7" {
+				t.Errorf("expected to watch from the list, got %q", rv)
+			}
+			if !item.listed && rv != "" {
+				t.Errorf("expected the initial events watch to be resumed, got a watch from %q", rv)
+			}
+			if keys := s.ListKeys(); len(keys) != 2 {
+				t.Errorf("expected the store to hold both pods, got %v", keys)
+			}
+		})
+	}
+}
+
+func TestReflectorWatchListFallback(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Now())
+	var watchLists int32
+	listed := make(chan struct{}, 2)
+	lw := &ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			listed <- struct{}{}
+			return &v1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: "7"}}, nil
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return watch.NewFake(), nil
+		},
+		InitialEventsWatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			atomic.AddInt32(&watchLists, 1)
+			// The server never ends the initial events.
+			return watch.NewFake(), nil
+		},
+	}
+	r := NewReflector(lw, &v1.Pod{}, NewStore(MetaNamespaceKeyFunc), 0)
+	r.clock = fakeClock
+	r.RelistBackoff = ReflectorBackoff{}
+	r.UseWatchList = true
+
+	for i := 0; i < 2; i++ {
+		stopCh := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			r.ListAndWatch(stopCh)
+		}()
+		if i == 0 {
+			if err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+				return fakeClock.HasWaiters(), nil
+			}); err != nil {
+				t.Fatalf("the reflector did not wait for the initial events")
+			}
+			fakeClock.Step(defaultInitialEventsTimeout)
+		}
+		select {
+		case <-listed:
+		case <-time.After(wait.ForeverTestTimeout):
+			t.Fatalf("the reflector did not fall back to a list")
+		}
+		close(stopCh)
+		<-done
+	}
+	if n := atomic.LoadInt32(&watchLists); n != 1 {
+		t.Errorf("expected the fallback to be remembered, got %d initial events watches", n)
+	}
+}
+
 func TestReflectorStopWatch(t *testing.T) {
 	s := NewStore(MetaNamespaceKeyFunc)
 	g := NewReflector(&testLW{}, &v1.Pod{}, s, 0)
@@ -350,6 +529,8 @@ func TestReflectorListAndWatchWithErrors(t *testing.T) {
 			},
 		}
 		r := NewReflector(lw, &v1.Pod{}, s, 0)
+		// The watch failures are expected, there is no point in backing off.
+		r.WatchBackoff = ReflectorBackoff{}
 		r.ListAndWatch(wait.NeverStop)
 	}
 }
@@ -430,3 +611,303 @@ func TestReflectorWatchListPageSize(t *testing.T) {
 		t.Errorf("Expected 10 results, got %d", len(results))
 	}
 }
+
+func TestReflectorTimeouts(t *testing.T) {
+	stopCh := make(chan struct{})
+	s := NewStore(MetaNamespaceKeyFunc)
+
+	var listTimeout, watchTimeout *int64
+	lw := &testLW{
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			// Stop once the reflector begins watching since we're only interested in the options.
+			watchTimeout = options.TimeoutSeconds
+			close(stopCh)
+			return watch.NewFake(), nil
+		},
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			listTimeout = options.TimeoutSeconds
+			return &v1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: "1"}}, nil
+		},
+	}
+	r := NewReflector(lw, &v1.Pod{}, s, 0)
+	r.Timeouts = ReflectorTimeouts{
+		ListTimeout:        1500 * time.Millisecond,
+		MinWatchTimeout:    30 * time.Second,
+		WatchTimeoutJitter: 0.5,
+	}
+	r.ListAndWatch(stopCh)
+
+	if listTimeout == nil || *listTimeout != 2 {
+		t.Errorf("expected list timeout of 2 seconds, got %v", listTimeout)
+	}
+	if watchTimeout == nil || *watchTimeout < 30 || *watchTimeout > 45 {
+		t.Errorf("expected watch timeout in [30, 45] seconds, got %v", watchTimeout)
+	}
+
+	defaults := DefaultReflectorTimeouts()
+	if defaults.listTimeoutSeconds() != nil {
+		t.Errorf("expected no list timeout by default")
+	}
+	defaults.MinWatchTimeout = 0
+	if defaults.watchTimeoutSeconds() != nil {
+		t.Errorf("expected no watch timeout when MinWatchTimeout is zero")
+	}
+}
+
+type countingMetric struct {
+	count int
+}
+
+func (m *countingMetric) Inc() { m.count++ }
+
+func TestReflectorStrictValidation(t *testing.T) {
+	rejected := []*v1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "stale", ResourceVersion: "9"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "opaque", ResourceVersion: "abc"}},
+		{ObjectMeta: metav1.ObjectMeta{ResourceVersion: "11"}},
+	}
+	for _, pod := range rejected {
+		s := NewStore(MetaNamespaceKeyFunc)
+		g := NewReflector(&testLW{}, &v1.Pod{}, s, 0)
+		g.StrictValidation = true
+		anomalies := &countingMetric{}
+		g.watchAnomalies = anomalies
+		fw := watch.NewFakeWithChanSize(3, false)
+		fw.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo", ResourceVersion: "10"}})
+		fw.Add(pod)
+		fw.Modify(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo", ResourceVersion: "12"}})
+		resumeRV := "5"
+		if err := g.watchHandler(fw, &resumeRV, nevererrc, wait.NeverStop); err == nil {
+			t.Errorf("%s: expected the rejected event to end the watch", pod.Name)
+		}
+
+		if e, a := []string{"foo"}, s.ListKeys(); !reflect.DeepEqual(e, a) {
+			t.Errorf("%s: expected only the events before the rejected one to be applied, got %v", pod.Name, a)
+		}
+		if anomalies.count != 1 {
+			t.Errorf("%s: expected 1 anomaly, got %d", pod.Name, anomalies.count)
+		}
+		if resumeRV != "10" {
+			t.Errorf("%s: expected the rejected event not to move the resource version, got %s", pod.Name, resumeRV)
+		}
+	}
+}
+
+func TestReflectorBackoffDelay(t *testing.T) {
+	b := ReflectorBackoff{Initial: time.Second, Max: 5 * time.Second, Factor: 2}
+	for failures, expected := range []time.Duration{0, time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		if delay := b.delay(failures); delay != expected {
+			t.Errorf("expected a delay of %v after %d failures, got %v", expected, failures, delay)
+		}
+	}
+
+	b.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if delay := b.delay(2); delay < 2*time.Second || delay > 3*time.Second {
+			t.Fatalf("expected a jittered delay in [2s, 3s], got %v", delay)
+		}
+	}
+
+	if delay := (ReflectorBackoff{}).delay(3); delay != 0 {
+		t.Errorf("expected no delay without an initial backoff, got %v", delay)
+	}
+}
+
+type gaugeMetric struct {
+	value float64
+}
+
+func (m *gaugeMetric) Set(value float64) { m.value = value }
+
+func TestReflectorWatchBackoff(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Now())
+	lw := &testLW{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return &v1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: "1"}}, nil
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return nil, errors.New("watch failed")
+		},
+	}
+	r := NewReflector(lw, &v1.Pod{}, NewStore(MetaNamespaceKeyFunc), 0)
+	r.clock = fakeClock
+	r.WatchBackoff = ReflectorBackoff{Initial: time.Second, Max: 3 * time.Second, Factor: 2, ResetAfter: time.Minute}
+	failures, backoff := &gaugeMetric{}, &gaugeMetric{}
+	r.watchFailuresMetric, r.watchBackoffMetric = failures, backoff
+
+	for i, expected := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second} {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			r.ListAndWatch(wait.NeverStop)
+		}()
+		if err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+			return fakeClock.HasWaiters(), nil
+		}); err != nil {
+			t.Fatalf("%d: expected the reflector to back off", i)
+		}
+		fakeClock.Step(expected - time.Millisecond)
+		select {
+		case <-done:
+			t.Fatalf("%d: expected the reflector to back off for %v", i, expected)
+		case <-time.After(50 * time.Millisecond):
+		}
+		fakeClock.Step(time.Millisecond)
+		<-done
+		if failures.value != float64(i+1) || backoff.value != expected.Seconds() {
+			t.Errorf("%d: expected metrics of %d failures and %vs backoff, got %v and %v", i, i+1, expected.Seconds(), failures.value, backoff.value)
+		}
+	}
+
+	r.watchEnded(time.Second)
+	if r.watchFailures != 3 {
+		t.Errorf("expected a short watch not to reset the backoff, got %d failures", r.watchFailures)
+	}
+	r.watchEnded(time.Minute)
+	if r.watchFailures != 0 || failures.value != 0 || backoff.value != 0 {
+		t.Errorf("expected a long watch to reset the backoff, got %d failures and metrics %v and %v", r.watchFailures, failures.value, backoff.value)
+	}
+}
+
+func TestReflectorWatchErrorHandler(t *testing.T) {
+	forbidden := apierrors.NewForbidden(v1.Resource("pods"), "", fmt.Errorf("no access"))
+	r := NewReflector(&testLW{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return &v1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: "1"}}, nil
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return nil, forbidden
+		},
+	}, &v1.Pod{}, NewStore(MetaNamespaceKeyFunc), 0)
+	r.WatchBackoff = ReflectorBackoff{}
+	stopCh := make(chan struct{})
+	var handled []error
+	r.WatchErrorHandler = func(reflector *Reflector, err error) {
+		if reflector != r {
+			t.Errorf("expected the handler to be passed the reflector")
+		}
+		handled = append(handled, err)
+		close(stopCh)
+	}
+	r.Run(stopCh)
+	if len(handled) != 1 || !apierrors.IsForbidden(handled[0]) {
+		t.Errorf("expected the Forbidden error of the watch to be handled, got %v", handled)
+	}
+}
+
+func TestReflectorHealth(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Now())
+	fw := watch.NewFake()
+	watches := 0
+	lw := &testLW{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return &v1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: "1"}}, nil
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			watches++
+			if watches == 2 {
+				return fw, nil
+			}
+			return nil, errors.New("watch failed")
+		},
+	}
+	r := NewReflector(lw, &v1.Pod{}, NewStore(MetaNamespaceKeyFunc), 0)
+	r.clock = fakeClock
+	r.WatchBackoff = ReflectorBackoff{}
+	r.WatchErrorHandler = func(r *Reflector, err error) {}
+
+	started := fakeClock.Now()
+	r.ListAndWatch(wait.NeverStop)
+	expected := ReflectorHealth{LastEventTime: started, DisconnectedSince: started, ConsecutiveFailures: 1}
+	if health := r.Health(); health != expected {
+		t.Errorf("expected %+v after a failed watch, got %+v", expected, health)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.ListAndWatch(wait.NeverStop)
+	}()
+	if err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		return r.Health().WatchConnected, nil
+	}); err != nil {
+		t.Fatal("expected the watch to connect")
+	}
+	fakeClock.Step(time.Minute)
+	fw.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", ResourceVersion: "2"}})
+	if err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		return r.Health().LastEventTime.Equal(fakeClock.Now()), nil
+	}); err != nil {
+		t.Fatalf("expected the event to be recorded, got %+v", r.Health())
+	}
+	expected = ReflectorHealth{WatchConnected: true, LastEventTime: fakeClock.Now()}
+	if health := r.Health(); health != expected {
+		t.Errorf("expected %+v while watching, got %+v", expected, health)
+	}
+
+	fakeClock.Step(time.Minute)
+	fw.Stop()
+	<-done
+	health := r.Health()
+	expected = ReflectorHealth{LastEventTime: fakeClock.Now().Add(-time.Minute), DisconnectedSince: fakeClock.Now(), ConsecutiveFailures: 1}
+	if health != expected {
+		t.Errorf("expected %+v after the watch ended, got %+v", expected, health)
+	}
+	if err := health.Check(fakeClock.Now().Add(time.Minute), time.Minute); err != nil {
+		t.Errorf("expected a minute without a watch to pass, got %v", err)
+	}
+	if err := health.Check(fakeClock.Now().Add(2*time.Minute), time.Minute); err == nil {
+		t.Errorf("expected two minutes without a watch to fail")
+	}
+}
+
+func TestReflectorRelistBackoff(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Now())
+	lists := 0
+	lw := &testLW{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			lists++
+			return &v1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: "1"}}, nil
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return nil, apierrors.NewResourceExpired("expired")
+		},
+	}
+	r := NewReflector(lw, &v1.Pod{}, NewStore(MetaNamespaceKeyFunc), 0)
+	r.clock = fakeClock
+	r.WatchBackoff = ReflectorBackoff{}
+	r.WatchErrorHandler = func(r *Reflector, err error) {}
+	r.RelistBackoff = ReflectorBackoff{Initial: time.Second, Factor: 2, ResetAfter: time.Minute}
+
+	r.ListAndWatch(wait.NeverStop)
+	for i, expected := range []time.Duration{time.Second, 2 * time.Second} {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			r.ListAndWatch(wait.NeverStop)
+		}()
+		if err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+			return fakeClock.HasWaiters(), nil
+		}); err != nil {
+			t.Fatalf("%d: expected the reflector to back off before relisting", i)
+		}
+		fakeClock.Step(expected - time.Millisecond)
+		select {
+		case <-done:
+			t.Fatalf("%d: expected the reflector to back off for %v", i, expected)
+		case <-time.After(50 * time.Millisecond):
+		}
+		fakeClock.Step(time.Millisecond)
+		<-done
+		if e, a := i+2, lists; e != a {
+			t.Errorf("%d: expected %d lists, got %d", i, e, a)
+		}
+	}
+
+	// A relist long after the last list does not wait.
+	fakeClock.Step(time.Minute)
+	r.ListAndWatch(wait.NeverStop)
+	if e, a := 4, lists; e != a {
+		t.Errorf("expected %d lists, got %d", e, a)
+	}
+}
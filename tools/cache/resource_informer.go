@@ -0,0 +1,120 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	restclient "k8s.io/client-go/rest"
+)
+
+// resourceInformerConfig collects the ResourceInformerOptions of an informer.
+type resourceInformerConfig struct {
+	namespace        string
+	resyncPeriod     time.Duration
+	indexers         Indexers
+	tweakListOptions func(*metav1.ListOptions)
+	informerOptions  []SharedIndexInformerOption
+}
+
+// ResourceInformerOption configures an informer built by NewInformerForResource.
+type ResourceInformerOption func(*resourceInformerConfig)
+
+// WithResourceNamespace limits the informer to one namespace.  By default all
+// namespaces are watched.
+func WithResourceNamespace(namespace string) ResourceInformerOption {
+	return func(config *resourceInformerConfig) {
+		config.namespace = namespace
+	}
+}
+
+// WithResourceResync sets the default resync period of the informer's event
+// handlers.  By default handlers are not resynced.
+func WithResourceResync(resyncPeriod time.Duration) ResourceInformerOption {
+	return func(config *resourceInformerConfig) {
+		config.resyncPeriod = resyncPeriod
+	}
+}
+
+// WithResourceIndexers sets the indexers of the informer, replacing the
+// default namespace index.
+func WithResourceIndexers(indexers Indexers) ResourceInformerOption {
+	return func(config *resourceInformerConfig) {
+		config.indexers = indexers
+	}
+}
+
+// WithResourceTweakListOptions modifies the options of every list and watch,
+// for example to set a label or field selector.
+func WithResourceTweakListOptions(tweakListOptions func(*metav1.ListOptions)) ResourceInformerOption {
+	return func(config *resourceInformerConfig) {
+		config.tweakListOptions = tweakListOptions
+	}
+}
+
+// WithResourceInformerOptions passes options to the underlying shared informer.
+func WithResourceInformerOptions(options ...SharedIndexInformerOption) ResourceInformerOption {
+	return func(config *resourceInformerConfig) {
+		config.informerOptions = append(config.informerOptions, options...)
+	}
+}
+
+// NewInformerForResource returns a shared informer for the resource gvr of the
+// cluster described by config, holding *unstructured.Unstructured objects.
+// The informer is indexed by namespace unless other indexers are given, and
+// must be started by the caller.
+func NewInformerForResource(config *restclient.Config, gvr schema.GroupVersionResource, options ...ResourceInformerOption) (SharedIndexInformer, error) {
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return NewInformerForDynamicResource(client, gvr, options...), nil
+}
+
+// NewInformerForDynamicResource is like NewInformerForResource, but uses an
+// existing dynamic client.
+func NewInformerForDynamicResource(client dynamic.Interface, gvr schema.GroupVersionResource, options ...ResourceInformerOption) SharedIndexInformer {
+	config := &resourceInformerConfig{
+		indexers: Indexers{NamespaceIndex: MetaNamespaceIndexFunc},
+	}
+	for _, opt := range options {
+		opt(config)
+	}
+
+	resourceClient := client.Resource(gvr).Namespace(config.namespace)
+	lw := &ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			if config.tweakListOptions != nil {
+				config.tweakListOptions(&options)
+			}
+			return resourceClient.List(options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			if config.tweakListOptions != nil {
+				config.tweakListOptions(&options)
+			}
+			return resourceClient.Watch(options)
+		},
+	}
+	return NewSharedIndexInformer(lw, &unstructured.Unstructured{}, config.resyncPeriod, config.indexers, config.informerOptions...)
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+	restclient "k8s.io/client-go/rest"
+)
+
+func newResourceInformerTestObject(namespace, name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("example.com/v1")
+	obj.SetKind("Widget")
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	return obj
+}
+
+func TestNewInformerForDynamicResource(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+	client := fake.NewSimpleDynamicClient(runtime.NewScheme(),
+		newResourceInformerTestObject("ns1", "widget1"),
+		newResourceInformerTestObject("ns2", "widget2"),
+	)
+
+	var tweaked int32
+	informer := NewInformerForDynamicResource(client, gvr,
+		WithResourceNamespace("ns1"),
+		WithResourceTweakListOptions(func(options *metav1.ListOptions) { atomic.AddInt32(&tweaked, 1) }),
+	)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	if !WaitForCacheSync(stop, informer.HasSynced) {
+		t.Fatalf("informer did not sync")
+	}
+
+	if e, a := []string{"ns1/widget1"}, sortedKeys(informer.GetIndexer()); !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	if objs, err := informer.GetIndexer().ByIndex(NamespaceIndex, "ns1"); err != nil || len(objs) != 1 {
+		t.Errorf("expected the namespace index to be set up, got %v, %v", objs, err)
+	}
+	if atomic.LoadInt32(&tweaked) == 0 {
+		t.Errorf("expected list options to be tweaked")
+	}
+}
+
+func TestNewInformerForResource(t *testing.T) {
+	config := &restclient.Config{Host: "127.0.0.1"}
+	informer, err := NewInformerForResource(config, schema.GroupVersionResource{Version: "v1", Resource: "pods"}, WithResourceIndexers(Indexers{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if indexers := informer.GetIndexer().GetIndexers(); len(indexers) != 0 {
+		t.Errorf("expected the given indexers to replace the defaults, got %v", indexers)
+	}
+}
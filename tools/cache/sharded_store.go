@@ -0,0 +1,436 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// shardedThreadSafeStore implements ThreadSafeStore with several
+// threadSafeMaps, the shards, each holding the items whose keys hash to it
+// and indexing them.  Writes only lock the shard of their key.  Reads over
+// several keys read-lock every shard at once, so that they see the same
+// state a single threadSafeMap would.
+type shardedThreadSafeStore struct {
+	shards []*threadSafeMap
+}
+
+var _ ThreadSafeStore = &shardedThreadSafeStore{}
+
+// newShardedThreadSafeStore returns a store of the given number of shards
+// indexing their items with indexers.
+func newShardedThreadSafeStore(indexers Indexers, shards int) *shardedThreadSafeStore {
+	if shards < 1 {
+		panic(fmt.Errorf("a sharded store needs at least 1 shard, got %d", shards))
+	}
+	s := &shardedThreadSafeStore{shards: make([]*threadSafeMap, shards)}
+	for i := range s.shards {
+		// Every shard adds to its own indexers.
+		shardIndexers := make(Indexers, len(indexers))
+		for name, indexFunc := range indexers {
+			shardIndexers[name] = indexFunc
+		}
+		s.shards[i] = &threadSafeMap{
+			items:    map[string]interface{}{},
+			indexers: shardIndexers,
+			indices:  Indices{},
+		}
+	}
+	return s
+}
+
+// shardIndex returns the index of the shard holding the item of key.
+func (s *shardedThreadSafeStore) shardIndex(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(len(s.shards)))
+}
+
+func (s *shardedThreadSafeStore) shardFor(key string) *threadSafeMap {
+	return s.shards[s.shardIndex(key)]
+}
+
+// rlockAll read-locks every shard, always in the same order.
+func (s *shardedThreadSafeStore) rlockAll() {
+	for _, shard := range s.shards {
+		shard.lock.RLock()
+	}
+}
+
+func (s *shardedThreadSafeStore) runlockAll() {
+	for _, shard := range s.shards {
+		shard.lock.RUnlock()
+	}
+}
+
+// lockAll locks every shard, always in the same order.
+func (s *shardedThreadSafeStore) lockAll() {
+	for _, shard := range s.shards {
+		shard.lock.Lock()
+	}
+}
+
+func (s *shardedThreadSafeStore) unlockAll() {
+	for _, shard := range s.shards {
+		shard.lock.Unlock()
+	}
+}
+
+func (s *shardedThreadSafeStore) Add(key string, obj interface{}) {
+	s.shardFor(key).Add(key, obj)
+}
+
+func (s *shardedThreadSafeStore) Update(key string, obj interface{}) {
+	s.shardFor(key).Update(key, obj)
+}
+
+func (s *shardedThreadSafeStore) Delete(key string) {
+	s.shardFor(key).Delete(key)
+}
+
+func (s *shardedThreadSafeStore) Get(key string) (item interface{}, exists bool) {
+	return s.shardFor(key).Get(key)
+}
+
+func (s *shardedThreadSafeStore) List() []interface{} {
+	s.rlockAll()
+	defer s.runlockAll()
+	list := []interface{}{}
+	for _, shard := range s.shards {
+		for _, item := range shard.items {
+			if item = shard.decode(item); item != nil {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
+
+func (s *shardedThreadSafeStore) ListKeys() []string {
+	s.rlockAll()
+	defer s.runlockAll()
+	list := []string{}
+	for _, shard := range s.shards {
+		for key := range shard.items {
+			list = append(list, key)
+		}
+	}
+	return list
+}
+
+// ListKeysWithPrefix returns the keys starting with prefix, sorted.
+func (s *shardedThreadSafeStore) ListKeysWithPrefix(prefix string) []string {
+	keys := []string{}
+	s.RangeKeys(prefix, prefixEnd(prefix), func(key string) bool {
+		if !strings.HasPrefix(key, prefix) {
+			return false
+		}
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+// RangeKeys calls fn in ascending order with each key from from, included,
+// to to, excluded, until fn returns false.  An empty to does not bound the
+// range.
+func (s *shardedThreadSafeStore) RangeKeys(from, to string, fn func(key string) bool) {
+	var keys []string
+	s.rlockAll()
+	for _, shard := range s.shards {
+		for key := range shard.items {
+			if key >= from && (to == "" || key < to) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	s.runlockAll()
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !fn(key) {
+			return
+		}
+	}
+}
+
+// Replace replaces the items of every shard at once.
+func (s *shardedThreadSafeStore) Replace(items map[string]interface{}, resourceVersion string) {
+	shardItems := make([]map[string]interface{}, len(s.shards))
+	for i := range shardItems {
+		shardItems[i] = map[string]interface{}{}
+	}
+	for key, item := range items {
+		shardItems[s.shardIndex(key)][key] = item
+	}
+	s.lockAll()
+	defer s.unlockAll()
+	for i, shard := range s.shards {
+		shard.swapLocked(shardItems[i])
+	}
+}
+
+// indexerLocked returns the named IndexFunc, which every shard has.
+func (s *shardedThreadSafeStore) indexerLocked(indexName string) (IndexFunc, error) {
+	indexFunc := s.shards[0].indexers[indexName]
+	if indexFunc == nil {
+		return nil, fmt.Errorf("Index with name %s does not exist", indexName)
+	}
+	return indexFunc, nil
+}
+
+func (s *shardedThreadSafeStore) Index(indexName string, obj interface{}) ([]interface{}, error) {
+	s.rlockAll()
+	defer s.runlockAll()
+
+	indexFunc, err := s.indexerLocked(indexName)
+	if err != nil {
+		return nil, err
+	}
+	indexKeys, err := indexFunc(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	list := []interface{}{}
+	for _, shard := range s.shards {
+		index := shard.indices[indexName]
+		keys := sets.String{}
+		for _, indexKey := range indexKeys {
+			keys = keys.Union(index[indexKey])
+		}
+		for key := range keys {
+			if item := shard.decode(shard.items[key]); item != nil {
+				list = append(list, item)
+			}
+		}
+	}
+	return list, nil
+}
+
+func (s *shardedThreadSafeStore) IndexKeys(indexName, indexKey string) ([]string, error) {
+	s.rlockAll()
+	defer s.runlockAll()
+
+	if _, err := s.indexerLocked(indexName); err != nil {
+		return nil, err
+	}
+	keys := sets.String{}
+	for _, shard := range s.shards {
+		keys = keys.Union(shard.indices[indexName][indexKey])
+	}
+	return keys.List(), nil
+}
+
+func (s *shardedThreadSafeStore) ListIndexFuncValues(indexName string) []string {
+	s.rlockAll()
+	defer s.runlockAll()
+
+	values := sets.String{}
+	for _, shard := range s.shards {
+		for value := range shard.indices[indexName] {
+			values.Insert(value)
+		}
+	}
+	return values.UnsortedList()
+}
+
+func (s *shardedThreadSafeStore) ByIndex(indexName, indexKey string) ([]interface{}, error) {
+	list := []interface{}{}
+	err := s.ForEachByIndex(indexName, indexKey, func(obj interface{}) bool {
+		list = append(list, obj)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// ForEachByIndex calls fn with each item that matches an exact value on the
+// index function, until fn returns false.  fn is called with the store
+// read-locked and must not write to it.
+func (s *shardedThreadSafeStore) ForEachByIndex(indexName, indexKey string, fn func(obj interface{}) bool) error {
+	s.rlockAll()
+	defer s.runlockAll()
+
+	if _, err := s.indexerLocked(indexName); err != nil {
+		return err
+	}
+	for _, shard := range s.shards {
+		for key := range shard.indices[indexName][indexKey] {
+			item := shard.decode(shard.items[key])
+			if item == nil {
+				continue
+			}
+			if !fn(item) {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// ByIndexes returns a list of items matching the union or the intersection
+// of the given queries.  As a key is in a single shard, the queries are
+// computed shard by shard.
+func (s *shardedThreadSafeStore) ByIndexes(mode IndexQueryMode, queries ...IndexQuery) ([]interface{}, error) {
+	if mode != IndexUnion && mode != IndexIntersect {
+		return nil, fmt.Errorf("unknown index query mode %d", mode)
+	}
+
+	s.rlockAll()
+	defer s.runlockAll()
+
+	for _, query := range queries {
+		if _, err := s.indexerLocked(query.IndexName); err != nil {
+			return nil, err
+		}
+	}
+	list := []interface{}{}
+	for _, shard := range s.shards {
+		var keys sets.String
+		for i, query := range queries {
+			set := shard.indices[query.IndexName][query.IndexedValue]
+			switch {
+			case i == 0:
+				keys = sets.NewString().Union(set)
+			case mode == IndexUnion:
+				keys = keys.Union(set)
+			default:
+				keys = keys.Intersection(set)
+			}
+		}
+		for key := range keys {
+			if item := shard.decode(shard.items[key]); item != nil {
+				list = append(list, item)
+			}
+		}
+	}
+	return list, nil
+}
+
+// GetIndexStats returns the stats of the named index over every shard.
+func (s *shardedThreadSafeStore) GetIndexStats(indexName string) (IndexStats, error) {
+	s.rlockAll()
+	defer s.runlockAll()
+
+	if _, err := s.indexerLocked(indexName); err != nil {
+		return IndexStats{}, err
+	}
+	sizes := map[string]int{}
+	for _, shard := range s.shards {
+		for value, keys := range shard.indices[indexName] {
+			if len(keys) > 0 {
+				sizes[value] += len(keys)
+			}
+		}
+	}
+	return newIndexStats(sizes), nil
+}
+
+func (s *shardedThreadSafeStore) GetIndexers() Indexers {
+	return s.shards[0].GetIndexers()
+}
+
+func (s *shardedThreadSafeStore) AddIndexers(newIndexers Indexers) error {
+	s.lockAll()
+	defer s.unlockAll()
+
+	for _, shard := range s.shards {
+		if len(shard.items) > 0 {
+			return fmt.Errorf("cannot add indexers to running index")
+		}
+	}
+	oldKeys := sets.StringKeySet(s.shards[0].indexers)
+	newKeys := sets.StringKeySet(newIndexers)
+	if oldKeys.HasAny(newKeys.List()...) {
+		return fmt.Errorf("indexer conflict: %v", oldKeys.Intersection(newKeys))
+	}
+
+	for _, shard := range s.shards {
+		for k, v := range newIndexers {
+			shard.indexers[k] = v
+		}
+	}
+	return nil
+}
+
+// AddIndexersAfterStart adds more indexers to every shard and indexes the
+// items they already hold.  A failing index function leaves every shard
+// unchanged.
+func (s *shardedThreadSafeStore) AddIndexersAfterStart(newIndexers Indexers) error {
+	s.lockAll()
+	defer s.unlockAll()
+
+	oldKeys := sets.StringKeySet(s.shards[0].indexers)
+	newKeys := sets.StringKeySet(newIndexers)
+	if oldKeys.HasAny(newKeys.List()...) {
+		return fmt.Errorf("indexer conflict: %v", oldKeys.Intersection(newKeys))
+	}
+
+	indices := make([]Indices, len(s.shards))
+	for i, shard := range s.shards {
+		shardIndices, err := shard.buildIndicesLocked(newIndexers)
+		if err != nil {
+			return err
+		}
+		indices[i] = shardIndices
+	}
+	for i, shard := range s.shards {
+		shard.setIndicesLocked(newIndexers, indices[i])
+	}
+	return nil
+}
+
+func (s *shardedThreadSafeStore) RemoveIndexer(name string) error {
+	s.lockAll()
+	defer s.unlockAll()
+
+	if _, err := s.indexerLocked(name); err != nil {
+		return err
+	}
+	for _, shard := range s.shards {
+		delete(shard.indexers, name)
+		delete(shard.indices, name)
+	}
+	return nil
+}
+
+func (s *shardedThreadSafeStore) Resync() error {
+	// Nothing to do
+	return nil
+}
+
+// NewShardedIndexer returns an Indexer like NewIndexer which spreads its
+// objects over the given number of shards by the hash of their keys, each
+// with its own lock and indices, so that concurrent writes to different
+// shards, and reads of single objects, do not wait for each other.  Reads
+// over several objects, such as List and ByIndex, lock every shard and see
+// the same state a single store would.  It suits stores with many
+// concurrent writers and readers; the KeyFilter, sorted keys, StoreCodec and
+// SnapshotGroup features are not available on it.
+func NewShardedIndexer(keyFunc KeyFunc, indexers Indexers, shards int) Indexer {
+	return &cache{
+		cacheStorage: newShardedThreadSafeStore(indexers, shards),
+		keyFunc:      keyFunc,
+	}
+}
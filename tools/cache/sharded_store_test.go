@@ -0,0 +1,131 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+func TestShardedIndexer(t *testing.T) {
+	index := NewShardedIndexer(MetaNamespaceKeyFunc, Indexers{"testmodes": testIndexFunc}, 4)
+	pod := func(name, foo string) *v1.Pod {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns", Labels: map[string]string{"foo": foo}}}
+	}
+	names := func(objs []interface{}) sets.String {
+		result := sets.NewString()
+		for _, obj := range objs {
+			result.Insert(obj.(*v1.Pod).Name)
+		}
+		return result
+	}
+	for i := 0; i < 20; i++ {
+		foo := "bar"
+		if i%2 == 1 {
+			foo = "biz"
+		}
+		index.Add(pod(fmt.Sprintf("pod-%d", i), foo))
+	}
+
+	if found := len(index.ListKeys()); found != 20 {
+		t.Errorf("expected 20 keys, got %d", found)
+	}
+	if _, exists, _ := index.GetByKey("ns/pod-3"); !exists {
+		t.Errorf("expected ns/pod-3 to exist")
+	}
+	bars, err := index.ByIndex("testmodes", "bar")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(bars) != 10 {
+		t.Errorf("expected 10 bars, got %v", names(bars).List())
+	}
+	keys, _ := index.IndexKeys("testmodes", "biz")
+	if len(keys) != 10 {
+		t.Errorf("expected 10 biz keys, got %v", keys)
+	}
+	if values := sets.NewString(index.ListIndexFuncValues("testmodes")...); !values.Equal(sets.NewString("bar", "biz")) {
+		t.Errorf("expected bar and biz, got %v", values.List())
+	}
+	stats, _ := index.GetIndexStats("testmodes")
+	if stats.IndexedValues != 2 || stats.AverageBucketSize != 10 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	index.Update(pod("pod-0", "biz"))
+	index.Delete(pod("pod-2", ""))
+	objs, _ := index.ByIndexes(IndexIntersect,
+		IndexQuery{IndexName: "testmodes", IndexedValue: "biz"},
+		IndexQuery{IndexName: "testmodes", IndexedValue: "biz"})
+	if len(objs) != 11 || !names(objs).Has("pod-0") {
+		t.Errorf("expected 11 biz including pod-0, got %v", names(objs).List())
+	}
+
+	if err := index.AddIndexers(Indexers{NamespaceIndex: MetaNamespaceIndexFunc}); err == nil {
+		t.Errorf("expected an error adding indexers to a non-empty store")
+	}
+	if err := index.AddIndexersAfterStart(Indexers{NamespaceIndex: MetaNamespaceIndexFunc}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if objs, _ := index.ByIndex(NamespaceIndex, "ns"); len(objs) != 19 {
+		t.Errorf("expected 19 objects in ns, got %d", len(objs))
+	}
+	if err := index.RemoveIndexer(NamespaceIndex); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := index.ByIndex(NamespaceIndex, "ns"); err == nil {
+		t.Errorf("expected an error querying a removed index")
+	}
+
+	index.Replace([]interface{}{pod("one", "bar"), pod("two", "biz")}, "1")
+	if found := names(index.List()); !found.Equal(sets.NewString("one", "two")) {
+		t.Errorf("expected one and two after replace, got %v", found.List())
+	}
+	if found, _ := index.ByIndex("testmodes", "bar"); !names(found).Equal(sets.NewString("one")) {
+		t.Errorf("expected one after replace, got %v", names(found).List())
+	}
+}
+
+func TestShardedIndexerConcurrentAccess(t *testing.T) {
+	index := NewShardedIndexer(MetaNamespaceKeyFunc, Indexers{"testmodes": testIndexFunc}, 8)
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(2)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				index.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("pod-%d-%d", w, i), Labels: map[string]string{"foo": "bar"}}})
+			}
+		}(w)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				index.List()
+				index.ByIndex("testmodes", "bar")
+			}
+		}()
+	}
+	wg.Wait()
+	if objs, _ := index.ByIndex("testmodes", "bar"); len(objs) != 400 {
+		t.Errorf("expected 400 objects, got %d", len(objs))
+	}
+}
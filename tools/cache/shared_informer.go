@@ -17,13 +17,23 @@ limitations under the License.
 package cache
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"math/rand"
+	"runtime/debug"
+	"runtime/pprof"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/clock"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/util/retry"
 	"k8s.io/utils/buffer"
@@ -125,14 +135,19 @@ import (
 type SharedInformer interface {
 	// AddEventHandler adds an event handler to the shared informer using the shared informer's resync
 	// period.  Events to a single handler are delivered sequentially, but there is no coordination
-	// between different handlers.
-	AddEventHandler(handler ResourceEventHandler)
+	// between different handlers.  The returned registration can be passed to RemoveEventHandler.
+	// It returns an error if the informer has already stopped.
+	AddEventHandler(handler ResourceEventHandler) (ResourceEventHandlerRegistration, error)
 	// AddEventHandlerWithResyncPeriod adds an event handler to the
 	// shared informer using the specified resync period.  The resync
 	// operation consists of delivering to the handler a create
 	// notification for every object in the informer's local cache; it
 	// does not add any interactions with the authoritative storage.
-	AddEventHandlerWithResyncPeriod(handler ResourceEventHandler, resyncPeriod time.Duration)
+	AddEventHandlerWithResyncPeriod(handler ResourceEventHandler, resyncPeriod time.Duration) (ResourceEventHandlerRegistration, error)
+	// RemoveEventHandler removes the handler registered as handle and stops
+	// delivering notifications to it.  Notifications the handler has not
+	// received yet are dropped.  Removing a handler twice is not an error.
+	RemoveEventHandler(handle ResourceEventHandlerRegistration) error
 	// GetStore returns the informer's local cache as a Store.
 	GetStore() Store
 	// GetController gives back a synthetic interface that "votes" to start the informer
@@ -150,21 +165,609 @@ type SharedInformer interface {
 	LastSyncResourceVersion() string
 }
 
+// HandlerOptionsAdder is implemented by the SharedInformers whose event
+// handlers can be configured with HandlerOptions, such as those returned by
+// NewSharedIndexInformer.
+type HandlerOptionsAdder interface {
+	// AddEventHandlerWithOptions adds an event handler to the shared informer
+	// like AddEventHandlerWithResyncPeriod, configured by options.
+	AddEventHandlerWithOptions(handler ResourceEventHandler, options HandlerOptions) (ResourceEventHandlerRegistration, error)
+}
+
+var _ HandlerOptionsAdder = &sharedIndexInformer{}
+
+// WatchErrorHandlerSetter is implemented by the SharedInformers whose list and
+// watch errors can be handled by a WatchErrorHandler, such as those returned by
+// NewSharedIndexInformer.
+type WatchErrorHandlerSetter interface {
+	// SetWatchErrorHandler sets the function called with the errors that end
+	// the informer's lists and watches, instead of DefaultWatchErrorHandler.
+	// The informer keeps retrying after the handler returns; the handler
+	// can stop it by closing the stop channel passed to Run.  It must be
+	// called before the informer starts.
+	SetWatchErrorHandler(handler WatchErrorHandler) error
+}
+
+var _ WatchErrorHandlerSetter = &sharedIndexInformer{}
+
+// ContextRunner is implemented by the SharedInformers that can be run with a
+// context and tell when they have stopped, such as those returned by
+// NewSharedIndexInformer.
+type ContextRunner interface {
+	// RunWithContext is like Run, but stops the informer when ctx is done.
+	// It returns the StopReason of the informer.
+	RunWithContext(ctx context.Context) error
+	// Done returns a channel that is closed once Run has returned, that is
+	// once the informer has stopped and every event handler has returned
+	// from its last notification.
+	Done() <-chan struct{}
+}
+
+var _ ContextRunner = &sharedIndexInformer{}
+
+// ResyncPeriodSetter is implemented by the SharedInformers whose resync periods
+// can be changed after their handlers are added, such as those returned by
+// NewSharedIndexInformer.
+type ResyncPeriodSetter interface {
+	// SetResyncCheckPeriod changes how often the informer checks whether
+	// its handlers need a resync, running or not.  A running informer
+	// restarts its resync timer with the new period.  Handlers whose resync
+	// period is shorter are resynced at every check instead, and zero
+	// disables resyncs.
+	SetResyncCheckPeriod(resyncCheckPeriod time.Duration)
+	// SetHandlerResyncPeriod changes the resync period of the handler
+	// registered as handle, running or not, zero disabling its resyncs.  Its
+	// next resync is due a period from now.  Like when the handler is added
+	// before the informer starts, a period shorter than the informer's
+	// resync check period shortens the check period.
+	SetHandlerResyncPeriod(handle ResourceEventHandlerRegistration, resyncPeriod time.Duration) error
+}
+
+var _ ResyncPeriodSetter = &sharedIndexInformer{}
+
+// Pauser is implemented by the SharedInformers whose notifications can be held
+// back, such as those returned by NewSharedIndexInformer.
+type Pauser interface {
+	// Pause stops delivering notifications to the event handlers, without
+	// stopping the informer: its cache stays up to date, and the
+	// notifications are buffered, or coalesced if the handlers coalesce
+	// updates, until Resume is called.  Handlers added meanwhile start
+	// paused.  Pausing a paused informer has no effect.
+	Pause()
+	// Resume delivers the notifications buffered since Pause to the event
+	// handlers, and the following ones as they come.
+	Resume()
+}
+
+var _ Pauser = &sharedIndexInformer{}
+
+// HandlerOptions configures an event handler added with
+// AddEventHandlerWithOptions.
+type HandlerOptions struct {
+	// ResyncPeriod, if set, is the resync period of the handler instead of
+	// the informer's default.
+	ResyncPeriod *time.Duration
+	// Filter, if set, selects the objects the handler is notified about.  It
+	// is evaluated when notifications are distributed, so filtered out
+	// notifications are never buffered for the handler.  Like with a
+	// FilteringResourceEventHandler, an object that starts passing the filter
+	// after an update is notified as an add, and an object that stops passing
+	// it as a delete.  Filter is passed a DeletedFinalStateUnknown for objects
+	// whose deletion was missed.
+	Filter func(obj interface{}) bool
+	// Name, if set, identifies the handler in logs, and in CPU and heap
+	// profiles and goroutine dumps through the HandlerProfileLabel pprof
+	// label of the goroutines delivering its notifications.  By default
+	// handlers are identified by their type.
+	Name string
+	// CoalesceNotifications makes the handler's pending notifications keep
+	// only the latest state of every object: an update of an object with a
+	// pending add or update is merged into it, and a delete replaces a
+	// pending update or cancels a pending add.  This bounds the memory a
+	// slow handler uses during update storms by the number of objects, at
+	// the price of the intermediate states.  It replaces the informer's
+	// notification spill for the handler.
+	CoalesceNotifications bool
+	// Priority orders the handler among the handlers of the informer: each
+	// notification is handed to the handlers of higher priority first, so
+	// that critical handlers do not wait for the others to accept it.
+	// Every handler has its own buffer, so a slow handler only delays
+	// its own notifications.  Handlers of the same priority are handed
+	// notifications in the order they were added.
+	Priority HandlerPriority
+	// PanicHandler, if set, is called when the handler panics handling a
+	// notification, which is then skipped, and the next notification is
+	// delivered right away.  It can log or alert, or stop the informer,
+	// for instance when the handler keeps failing on the same object.
+	// Without a PanicHandler, a panic is logged, and crashes the process
+	// unless utilruntime.ReallyCrash is false, in which case delivery
+	// resumes after a delay.
+	PanicHandler HandlerPanicFunc
+	// SkipResyncs keeps the updates of resyncs, whose origin is
+	// UpdateFromResync, from the handler: the periodic resyncs and the
+	// objects a relist found unchanged.  The handler still gets the adds,
+	// the other updates and the deletes.  ResyncPeriod is ignored.
+	SkipResyncs bool
+	// Workers, if greater than 1, runs the add, update and delete callbacks
+	// of the handler on up to that many goroutines, one object at a time and
+	// in order for each object, like a KeyOrderedHandler does.  Slow
+	// callbacks then only delay the other notifications of their object.
+	// The callbacks of SyncProgressHandler and InitialSyncHandler, and
+	// HasSynced of the registration, do not wait for the callbacks in
+	// progress, and PanicHandler does not apply to them: their panics are
+	// reported and the next notifications still delivered.
+	Workers int
+	// PriorState, if set, holds the objects the handler knew about before it
+	// was added, by key, for example those of a controller being reloaded.
+	// A handler added to a running informer then gets the cached objects
+	// whose key is in PriorState as updates from their prior state, with
+	// origin UpdateFromRelist like the changes a relist finds, instead of
+	// adds, and the prior objects no longer cached as deletes of a
+	// DeletedFinalStateUnknown.  It is ignored by informers that have not
+	// started, whose handlers get the initial list as adds.
+	PriorState map[string]interface{}
+	// BatchSize is the largest batch of notifications handed to a
+	// BatchResourceEventHandler, 100 by default.
+	BatchSize int
+	// BatchDelay is how long a batch of a BatchResourceEventHandler waits
+	// for more notifications after its first one.  By default batches only
+	// hold the notifications pending when they start.
+	BatchDelay time.Duration
+}
+
+// HandlerPanic describes the panic of an event handler handling a
+// notification.
+type HandlerPanic struct {
+	// Handler identifies the handler, see HandlerOptions.Name.
+	Handler string
+	// Object is the object of the notification, the new one for an update,
+	// or nil for notifications without an object.
+	Object interface{}
+	// Key is the key of Object, or empty if it has none.
+	Key string
+	// Value is the value the handler panicked with.
+	Value interface{}
+	// Stack is the stack trace of the panic.
+	Stack []byte
+	// Count is the number of consecutive panics of the handler handling
+	// Object, including this one.  It is reset once the handler handles a
+	// notification of the object without panicking.
+	Count int
+}
+
+// HandlerPanicFunc handles the panics of an event handler, see
+// HandlerOptions.PanicHandler.
+type HandlerPanicFunc func(panic HandlerPanic)
+
+// HandlerPriority is the priority of an event handler, see
+// HandlerOptions.Priority.  Higher values are served first.
+type HandlerPriority int
+
+const (
+	// HandlerPriorityLow is for handlers that can tolerate delays, such as
+	// the ones exporting metrics or audit logs.
+	HandlerPriorityLow HandlerPriority = -100
+	// HandlerPriorityNormal is the priority of handlers by default.
+	HandlerPriorityNormal HandlerPriority = 0
+	// HandlerPriorityHigh is for handlers critical to the controller.
+	HandlerPriorityHigh HandlerPriority = 100
+)
+
+// HandlerProfileLabel is the pprof label that carries the name of the event
+// handler whose notifications a goroutine delivers.
+const HandlerProfileLabel = "informer-handler"
+
+// ResourceEventHandlerRegistration is a handle for an event handler added to
+// a SharedInformer.
+type ResourceEventHandlerRegistration interface {
+	// HasSynced returns true once the informer has synced and the handler
+	// has been notified of every object of the informer's initial list, or,
+	// for a handler added later, of every object in the cache when it was
+	// added.  Unlike the informer's HasSynced, it can be passed to
+	// WaitForCacheSync to wait for a single handler.
+	HasSynced() bool
+}
+
 // SharedIndexInformer provides add and get Indexers ability based on SharedInformer.
 type SharedIndexInformer interface {
 	SharedInformer
 	// AddIndexers add indexers to the informer before it starts.
 	AddIndexers(indexers Indexers) error
 	GetIndexer() Indexer
+	// SetTransform sets a function that transforms every object received
+	// from the server before it is stored in the indexer and distributed to
+	// the event handlers, for example to drop fields that are never read.
+	// It must be called before the informer starts.
+	SetTransform(transform TransformFunc) error
+	// GetStoreStats returns the number of objects in the informer's cache,
+	// an estimate of their size, the cardinality of its indexes and the
+	// time of the last relist, for example to monitor its memory usage.
+	GetStoreStats() StoreStats
+}
+
+// TransformFunc transforms an object before a SharedIndexInformer stores it.
+// It may modify the object it is passed, which is not shared with anything
+// else yet, and must return an object of the same type.
+type TransformFunc func(obj interface{}) (interface{}, error)
+
+// Snapshotter is implemented by the SharedIndexInformers whose cache can be
+// saved to a file and warm-started from it, such as those returned by
+// NewSharedIndexInformer.
+type Snapshotter interface {
+	// SaveSnapshot writes the objects of the informer's cache to path, with
+	// the resource version the informer last caught up with after a list, a
+	// bookmark or a resync.  It fails until the informer has synced.
+	SaveSnapshot(path string) error
+	// LoadSnapshot makes the informer start from the objects saved to path
+	// by SaveSnapshot instead of listing them: handlers are notified of them
+	// like of a list, and the informer watches from the resource version of
+	// the snapshot, falling back to a list if it has expired.  Objects
+	// changed after the snapshot was saved may be notified again.  It must
+	// be called before the informer starts.
+	LoadSnapshot(path string) error
+}
+
+var _ Snapshotter = &sharedIndexInformer{}
+
+// StopReasonReporter is implemented by the SharedInformers reporting why they
+// stopped, such as those returned by NewSharedIndexInformer.
+type StopReasonReporter interface {
+	// StopReason returns why the informer stopped: ErrInformerStopRequested
+	// once Run returned because its stop channel was closed, or an
+	// *InformerPanicError if Run panicked.  It returns nil while the
+	// informer has not been started or is still running.
+	StopReason() error
+}
+
+var _ StopReasonReporter = &sharedIndexInformer{}
+
+// ErrInformerStopRequested is the StopReason of an informer whose stop channel
+// was closed.
+var ErrInformerStopRequested = errors.New("informer stopped because its stop channel was closed")
+
+// InformerPanicError is the StopReason of an informer whose Run panicked.
+type InformerPanicError struct {
+	// Value is the value the informer panicked with.
+	Value interface{}
+}
+
+func (e *InformerPanicError) Error() string {
+	return fmt.Sprintf("informer stopped after a panic: %v", e.Value)
+}
+
+// SharedIndexInformerOption defines the functional option type for SharedIndexInformer.
+type SharedIndexInformerOption func(*sharedIndexInformer) *sharedIndexInformer
+
+// WithNotificationSpill makes every event handler of the informer keep at most
+// config.MemoryLimit pending notifications in memory, writing the rest to a
+// temporary file until the handler catches up.  This bounds the memory used by
+// a stalled handler at the cost of encoding and decoding the spilled objects.
+// It panics if the config is invalid.
+func WithNotificationSpill(config NotificationSpillConfig) SharedIndexInformerOption {
+	if err := config.validate(); err != nil {
+		panic(err)
+	}
+	return func(informer *sharedIndexInformer) *sharedIndexInformer {
+		informer.notificationSpill = &config
+		return informer
+	}
+}
+
+// WithReflectorTimeouts sets the timeouts the informer requests for its list and
+// watch calls, instead of DefaultReflectorTimeouts().
+func WithReflectorTimeouts(timeouts ReflectorTimeouts) SharedIndexInformerOption {
+	return func(informer *sharedIndexInformer) *sharedIndexInformer {
+		informer.reflectorTimeouts = &timeouts
+		return informer
+	}
+}
+
+// WithRelistBackoff makes the informer wait before listing again soon after
+// its last list, see Reflector.RelistBackoff.  This keeps a server whose
+// watches keep expiring from being flooded with full relists, at the cost of
+// delaying the informer's recovery.
+func WithRelistBackoff(backoff ReflectorBackoff) SharedIndexInformerOption {
+	return func(informer *sharedIndexInformer) *sharedIndexInformer {
+		informer.relistBackoff = &backoff
+		return informer
+	}
+}
+
+// WithStrictWatchValidation makes the informer's reflector validate every watch
+// event and reject those with resource versions going backwards or undecodable
+// keys; see Reflector.StrictValidation.
+func WithStrictWatchValidation() SharedIndexInformerOption {
+	return func(informer *sharedIndexInformer) *sharedIndexInformer {
+		informer.strictWatchValidation = true
+		return informer
+	}
+}
+
+// WithStrictDeliveryOrdering makes the informer wait, for every notification,
+// until each event handler has accepted it into its pending buffer before
+// applying the next change, waiting no longer than timeout for each handler.
+// A handler that does not accept a notification in time does not receive it,
+// and an error naming the handler is reported through
+// utilruntime.HandleError.  Without this option, the informer waits for each
+// handler indefinitely.
+func WithStrictDeliveryOrdering(timeout time.Duration) SharedIndexInformerOption {
+	return func(informer *sharedIndexInformer) *sharedIndexInformer {
+		informer.processor.strictDeliveryTimeout = timeout
+		return informer
+	}
+}
+
+// WithLifecycleHooks registers hooks invoked when the informer starts, once it
+// has synced, and when it is stopped.  It can be passed several times; hooks
+// run in the order they were registered.
+func WithLifecycleHooks(hooks ControllerHooks) SharedIndexInformerOption {
+	return func(informer *sharedIndexInformer) *sharedIndexInformer {
+		informer.hooks.PreStart = append(informer.hooks.PreStart, hooks.PreStart...)
+		informer.hooks.PostSync = append(informer.hooks.PostSync, hooks.PostSync...)
+		informer.hooks.PreStop = append(informer.hooks.PreStop, hooks.PreStop...)
+		return informer
+	}
+}
+
+// WithPageSize makes the informer request its lists in chunks of pageSize,
+// bounding the memory the server and the informer need for a single response
+// on large collections; see Reflector.WatchListPageSize.  The lists are still
+// made at resource version "0", which a server serving them from its watch
+// cache may answer whole.
+func WithPageSize(pageSize int64) SharedIndexInformerOption {
+	return func(informer *sharedIndexInformer) *sharedIndexInformer {
+		informer.pageSize = pageSize
+		return informer
+	}
+}
+
+// WithObjectFreezing makes the informer store a deep copy of every object, so
+// that the objects passed to the event handlers are never those of the cache
+// and handlers mutating them by mistake cannot corrupt it.  The handlers
+// still share the objects of a notification, and the objects returned by the
+// informer's indexer and listers are those of the cache and must not be
+// mutated.  It costs a deep copy of every object received.
+func WithObjectFreezing() SharedIndexInformerOption {
+	return func(informer *sharedIndexInformer) *sharedIndexInformer {
+		informer.freezeObjects = true
+		return informer
+	}
+}
+
+// WithDeepCopyOnRead makes the indexer returned by GetStore and GetIndexer,
+// and so the listers built on it, hand out deep copies of the cached objects;
+// see NewDeepCopyIndexer.  Together with WithObjectFreezing it keeps handlers
+// mutating the objects they read from corrupting the cache, at the cost of a
+// deep copy of every object read.
+func WithDeepCopyOnRead() SharedIndexInformerOption {
+	return func(informer *sharedIndexInformer) *sharedIndexInformer {
+		informer.deepCopyOnRead = true
+		return informer
+	}
+}
+
+// WithWatchList makes the informer fill its cache from a watch streaming the
+// initial state of the objects rather than from a list, falling back to a list
+// if the server or the ListerWatcher do not support it; see
+// Reflector.UseWatchList.
+func WithWatchList() SharedIndexInformerOption {
+	return func(informer *sharedIndexInformer) *sharedIndexInformer {
+		informer.useWatchList = true
+		return informer
+	}
+}
+
+// WithResyncExclusion makes the periodic resyncs of the informer skip the keys
+// returned by exclude, typically those of the objects a controller is still
+// processing, which would otherwise be notified again as updates even if they
+// are about to be deleted.  See ResyncExclusionFunc.
+func WithResyncExclusion(exclude ResyncExclusionFunc) SharedIndexInformerOption {
+	return func(informer *sharedIndexInformer) *sharedIndexInformer {
+		informer.resyncExclusion = exclude
+		return informer
+	}
+}
+
+// WithDeltaFIFOOptions configures the queue between the reflector and the cache
+// of the informer.  With EmitDeltaTypeReplaced, the objects of a relist that
+// changed are notified as updates to every handler, not only to the handlers
+// being resynced.  A KeyFunction also becomes the key function of the
+// informer's cache.  KnownObjects is ignored: the queue always knows the
+// objects of the cache.
+func WithDeltaFIFOOptions(options DeltaFIFOOptions) SharedIndexInformerOption {
+	return func(informer *sharedIndexInformer) *sharedIndexInformer {
+		keyFunc := options.KeyFunction
+		options.KeyFunction = informer.deltaFIFOOptions.KeyFunction
+		informer.deltaFIFOOptions = options
+		if keyFunc != nil {
+			informer.setKeyFunction("WithDeltaFIFOOptions with a KeyFunction", keyFunc)
+		}
+		return informer
+	}
+}
+
+// WithKeyFunction makes the informer key its objects with keyFunc instead of
+// MetaNamespaceKeyFunc, in its queue and its cache, for objects whose
+// namespace and name are not their natural identity.  The keys of the
+// listers, of GetByKey and of HandlerOptions.PriorState are then those of
+// keyFunc.  Objects whose deletion was missed keep the key of their
+// DeletedFinalStateUnknown.  It cannot be combined with WithIndexer, in
+// either order, whose indexer has its own key function.
+func WithKeyFunction(keyFunc KeyFunc) SharedIndexInformerOption {
+	return func(informer *sharedIndexInformer) *sharedIndexInformer {
+		informer.setKeyFunction("WithKeyFunction", keyFunc)
+		return informer
+	}
+}
+
+// setKeyFunction makes keyFunc the key function of the queue and of the
+// built-in cache, on behalf of option.
+func (s *sharedIndexInformer) setKeyFunction(option string, keyFunc KeyFunc) {
+	s.deltaFIFOOptions.KeyFunction = keyFunc
+	s.builtinCache(option).keyFunc = func(obj interface{}) (string, error) {
+		if d, ok := obj.(DeletedFinalStateUnknown); ok {
+			return d.Key, nil
+		}
+		return keyFunc(obj)
+	}
+}
+
+// WithResyncJitter moves the resyncs of every event handler of the informer at
+// random, earlier or later, by up to maxFactor times its resync period, so
+// that handlers added at the same time, in this informer or in others using
+// the option, do not all resync at once.  maxFactor must be between 0 and 1,
+// for instance 0.1 for resyncs spread over ±10% of the period.  Resyncs
+// still happen at the informer's resync check period at the earliest.
+func WithResyncJitter(maxFactor float64) SharedIndexInformerOption {
+	if maxFactor < 0 || maxFactor >= 1 {
+		panic(fmt.Errorf("the resync jitter must be between 0 and 1, got %v", maxFactor))
+	}
+	return func(informer *sharedIndexInformer) *sharedIndexInformer {
+		informer.resyncJitter = maxFactor
+		return informer
+	}
+}
+
+// WithKeyFilter makes the informer keep filter up to date with the keys of its
+// cache, see KeyFilter.  A filter must not be passed to several informers.
+func WithKeyFilter(filter *KeyFilter) SharedIndexInformerOption {
+	return func(informer *sharedIndexInformer) *sharedIndexInformer {
+		informer.builtinStorage("WithKeyFilter").setKeyFilter(filter)
+		return informer
+	}
+}
+
+// WithSortedKeys makes the informer keep the keys of its cache sorted, see
+// NewSortedKeysIndexer, so that the ListKeysWithPrefix and RangeKeys of its
+// indexer do not go through all the cached keys.
+func WithSortedKeys() SharedIndexInformerOption {
+	return func(informer *sharedIndexInformer) *sharedIndexInformer {
+		informer.builtinStorage("WithSortedKeys").setSortedKeys()
+		return informer
+	}
+}
+
+// WithStoreCodec makes the informer keep the objects of its cache encoded by
+// codec, trading the CPU spent decoding them on every read for the memory
+// saved, see NewCompressingStoreCodec.  Reads return a new copy of an object
+// each time.
+func WithStoreCodec(codec StoreCodec) SharedIndexInformerOption {
+	return func(informer *sharedIndexInformer) *sharedIndexInformer {
+		informer.builtinStorage("WithStoreCodec").setCodec(codec)
+		return informer
+	}
+}
+
+// WithStoreShards makes the informer spread the objects of its cache over
+// the given number of shards, see NewShardedIndexer, so that its writes and
+// the reads of its listers contend less on a single lock.  It cannot be
+// combined with WithKeyFilter, WithSortedKeys, WithStoreCodec or
+// WithIndexer, and its indexer cannot join a SnapshotGroup.
+func WithStoreShards(shards int) SharedIndexInformerOption {
+	if shards < 1 {
+		panic(fmt.Errorf("the number of store shards must be at least 1, got %d", shards))
+	}
+	return func(informer *sharedIndexInformer) *sharedIndexInformer {
+		c := informer.builtinCache("WithStoreShards")
+		storage := informer.builtinStorage("WithStoreShards")
+		storage.lock.RLock()
+		customized := storage.keyFilter != nil || storage.sortedKeys != nil || storage.codec != nil
+		storage.lock.RUnlock()
+		if customized {
+			panic(fmt.Errorf("WithStoreShards cannot be combined with WithKeyFilter, WithSortedKeys or WithStoreCodec"))
+		}
+		c.cacheStorage = newShardedThreadSafeStore(storage.GetIndexers(), shards)
+		return informer
+	}
+}
+
+// WithIndexer makes the informer keep its objects in indexer instead of an
+// in-memory cache, for example to keep very large collections on disk, see
+// NewDiskIndexer, or to bound the memory they take.  The indexer must be
+// empty and key objects like DeletionHandlingMetaNamespaceKeyFunc does; the
+// indexers passed to NewSharedIndexInformer are added to it.  The options
+// working on the in-memory cache, such as WithKeyFunction, WithKeyFilter or
+// WithStoreCodec, cannot be combined with it, in either order.
+func WithIndexer(indexer Indexer) SharedIndexInformerOption {
+	return func(informer *sharedIndexInformer) *sharedIndexInformer {
+		if option := informer.builtinCacheOption; len(option) > 0 {
+			panic(fmt.Errorf("%s cannot be combined with WithIndexer", option))
+		}
+		if err := indexer.AddIndexers(informer.indexer.GetIndexers()); err != nil {
+			panic(fmt.Errorf("unable to add the indexers to the informer's indexer: %v", err))
+		}
+		informer.indexer = indexer
+		return informer
+	}
+}
+
+// WithLabelIndex adds a LabelIndex to the informer's indexer, which its
+// listers then use to find the objects matching a label selector instead of
+// checking every cached object.  Objects can also be looked up by label with
+// ByIndex(LabelIndex, LabelIndexValue(key, value)).  The index is updated
+// as the labels of the objects change, at the cost of the memory taken by
+// an entry per label of every object.
+func WithLabelIndex() SharedIndexInformerOption {
+	return func(informer *sharedIndexInformer) *sharedIndexInformer {
+		if err := informer.indexer.AddIndexers(Indexers{LabelIndex: LabelIndexFunc}); err != nil {
+			panic(fmt.Errorf("unable to add the label index to the informer's indexer: %v", err))
+		}
+		return informer
+	}
+}
+
+// builtinCache returns the in-memory cache of the informer, for the option
+// named option, which panics if WithIndexer replaced it.  WithIndexer panics
+// in turn if it comes after option.
+func (s *sharedIndexInformer) builtinCache(option string) *cache {
+	c, ok := s.indexer.(*cache)
+	if !ok {
+		panic(fmt.Errorf("%s cannot be combined with WithIndexer", option))
+	}
+	s.builtinCacheOption = option
+	return c
+}
+
+// builtinStorage returns the store of the in-memory cache of the informer,
+// for the option named option, which panics if WithIndexer or
+// WithStoreShards replaced it.
+func (s *sharedIndexInformer) builtinStorage(option string) *threadSafeMap {
+	storage, ok := s.builtinCache(option).cacheStorage.(*threadSafeMap)
+	if !ok {
+		panic(fmt.Errorf("%s cannot be combined with WithStoreShards", option))
+	}
+	return storage
+}
+
+// WithTTL makes the informer drop the objects of its cache that were not
+// added or updated for longer than ttl, even if they were not deleted, and
+// notify the handlers of their deletion.  An expired object comes back if it
+// changes or a relist finds it.  Objects expire up to a tenth of the TTL
+// late.
+func WithTTL(ttl time.Duration) SharedIndexInformerOption {
+	return func(informer *sharedIndexInformer) *sharedIndexInformer {
+		informer.ttl = ttl
+		informer.writeTimes = map[string]time.Time{}
+		return informer
+	}
+}
+
+// WithInformerName sets the name identifying the informer in metrics,
+// instead of its object type.
+func WithInformerName(name string) SharedIndexInformerOption {
+	return func(informer *sharedIndexInformer) *sharedIndexInformer {
+		informer.name = name
+		return informer
+	}
 }
 
 // NewSharedInformer creates a new instance for the listwatcher.
-func NewSharedInformer(lw ListerWatcher, objType runtime.Object, resyncPeriod time.Duration) SharedInformer {
-	return NewSharedIndexInformer(lw, objType, resyncPeriod, Indexers{})
+func NewSharedInformer(lw ListerWatcher, objType runtime.Object, resyncPeriod time.Duration, options ...SharedIndexInformerOption) SharedInformer {
+	return NewSharedIndexInformer(lw, objType, resyncPeriod, Indexers{}, options...)
 }
 
 // NewSharedIndexInformer creates a new instance for the listwatcher.
-func NewSharedIndexInformer(lw ListerWatcher, objType runtime.Object, defaultEventHandlerResyncPeriod time.Duration, indexers Indexers) SharedIndexInformer {
+func NewSharedIndexInformer(lw ListerWatcher, objType runtime.Object, defaultEventHandlerResyncPeriod time.Duration, indexers Indexers, options ...SharedIndexInformerOption) SharedIndexInformer {
 	realClock := &clock.RealClock{}
 	sharedIndexInformer := &sharedIndexInformer{
 		processor:                       &sharedProcessor{clock: realClock},
@@ -175,7 +778,14 @@ func NewSharedIndexInformer(lw ListerWatcher, objType runtime.Object, defaultEve
 		defaultEventHandlerResyncPeriod: defaultEventHandlerResyncPeriod,
 		cacheMutationDetector:           NewCacheMutationDetector(fmt.Sprintf("%T", objType)),
 		clock:                           realClock,
+		done:                            make(chan struct{}),
 	}
+
+	// Apply all options
+	for _, opt := range options {
+		sharedIndexInformer = opt(sharedIndexInformer)
+	}
+
 	return sharedIndexInformer
 }
 
@@ -228,9 +838,51 @@ func WaitForCacheSync(stopCh <-chan struct{}, cacheSyncs ...InformerSynced) bool
 	return true
 }
 
+// CacheSyncError is returned by WaitForCacheSyncWithContext when some caches
+// have not synced before its context is done.
+type CacheSyncError struct {
+	// Unsynced are the sorted names of the caches that have not synced.
+	Unsynced []string
+	// Err is the error of the context.
+	Err error
+}
+
+func (e *CacheSyncError) Error() string {
+	return fmt.Sprintf("caches %s have not synced: %v", strings.Join(e.Unsynced, ", "), e.Err)
+}
+
+// WaitForCacheSyncWithContext waits for the named caches to populate until ctx
+// is done, honoring its deadline.  It returns a *CacheSyncError naming the
+// caches that have not synced if ctx is done first.
+func WaitForCacheSyncWithContext(ctx context.Context, cacheSyncs map[string]InformerSynced) error {
+	synced := sets.NewString()
+	checkSynced := func() bool {
+		for name, syncFunc := range cacheSyncs {
+			if !synced.Has(name) && syncFunc() {
+				synced.Insert(name)
+			}
+		}
+		return synced.Len() == len(cacheSyncs)
+	}
+	err := wait.PollUntil(syncedPollPeriod, func() (bool, error) {
+		return checkSynced(), nil
+	}, ctx.Done())
+	if err == nil || checkSynced() {
+		klog.V(4).Infof("caches populated")
+		return nil
+	}
+	return &CacheSyncError{
+		Unsynced: sets.StringKeySet(cacheSyncs).Difference(synced).List(),
+		Err:      ctx.Err(),
+	}
+}
+
 type sharedIndexInformer struct {
 	indexer    Indexer
 	controller Controller
+	// builtinCacheOption is the name of the last option that configured
+	// the in-memory cache, which WithIndexer would discard.
+	builtinCacheOption string
 
 	processor             *sharedProcessor
 	cacheMutationDetector MutationDetector
@@ -248,13 +900,84 @@ type sharedIndexInformer struct {
 	defaultEventHandlerResyncPeriod time.Duration
 	// clock allows for testability
 	clock clock.Clock
+	// notificationSpill, if set, bounds the in-memory notification buffer of
+	// every listener and spills the excess to disk.
+	notificationSpill *NotificationSpillConfig
+	// slowListener, if set, detects the listeners slow to accept
+	// notifications, see WithSlowListenerDetection.
+	slowListener *SlowListenerConfig
+	// reflectorTimeouts, if set, overrides the reflector's default timeouts.
+	reflectorTimeouts *ReflectorTimeouts
+	// relistBackoff, if set, overrides the reflector's relist backoff.
+	relistBackoff *ReflectorBackoff
+	// strictWatchValidation enables the reflector's strict validation mode.
+	strictWatchValidation bool
+	// pageSize, if set, is the chunk size of the reflector's lists.
+	pageSize int64
+	// useWatchList makes the reflector initialize from a watch.
+	useWatchList bool
+	// freezeObjects makes the informer store deep copies of the objects it
+	// hands out, see WithObjectFreezing.
+	freezeObjects bool
+	// deepCopyOnRead makes GetStore and GetIndexer return deep copies, see
+	// WithDeepCopyOnRead.
+	deepCopyOnRead bool
+	// maxObjects, if positive, is the number of objects past which the cache
+	// calls objectLimitExceeded, see WithMaxObjects.  overObjectLimit is
+	// true while the cache is past it.  objects is the number of objects
+	// of the cache once objectsCounted is set.  They are guarded by
+	// blockDeltas.
+	maxObjects          int
+	objectLimitExceeded ObjectLimitFunc
+	overObjectLimit     bool
+	objects             int
+	objectsCounted      bool
+	// hooks are invoked at fixed points of the informer's Run.
+	hooks ControllerHooks
+	// transform, if set, is applied to every object in HandleDeltas.
+	transform TransformFunc
+	// watchErrorHandler, if set, is called with the reflector's errors.
+	watchErrorHandler WatchErrorHandler
+	// resyncExclusion, if set, lists the keys the resyncs skip.
+	resyncExclusion ResyncExclusionFunc
+	// deltaFIFOOptions configures the queue of the informer, see
+	// WithDeltaFIFOOptions.
+	deltaFIFOOptions DeltaFIFOOptions
+	// resyncJitter is the resync jitter of the listeners, see
+	// WithResyncJitter.
+	resyncJitter float64
+	// ttl, if positive, is how long objects stay in the cache without being
+	// written, see WithTTL.
+	ttl time.Duration
+	// writeTimes, guarded by blockDeltas, maps the keys of the cache to the
+	// time their object was last written, if ttl is positive.
+	writeTimes map[string]time.Time
+	// snapshotObjects and snapshotResourceVersion are the snapshot Run
+	// starts from, see LoadSnapshot.
+	snapshotObjects         []interface{}
+	snapshotResourceVersion string
+	// name identifies the informer in metrics, see WithInformerName.
+	name string
+	// metrics is nil unless an InformerMetricsProvider is set.
+	metrics *informerMetrics
 
 	started, stopped bool
-	startedLock      sync.Mutex
+	// stopReason records why Run returned.
+	stopReason  error
+	startedLock sync.Mutex
+	// done is closed once Run has returned.
+	done     chan struct{}
+	doneOnce sync.Once
 
 	// blockDeltas gives a way to stop all event distribution so that a late event handler
 	// can safely join the shared informer.
 	blockDeltas sync.Mutex
+	// initialSynced is set, with blockDeltas held, once the listeners have
+	// been told of the end of the initial sync.
+	initialSynced bool
+	// syncedResourceVersion, guarded by blockDeltas, is the resource version
+	// the indexer last caught up with.
+	syncedResourceVersion string
 }
 
 // dummyController hides the fact that a SharedInformer is different from a dedicated one
@@ -277,31 +1000,73 @@ func (v *dummyController) LastSyncResourceVersion() string {
 	return ""
 }
 
+// The distributed time of a notification is when it was handed to a
+// listener, and is only recorded when the listener has metrics.
+
 type updateNotification struct {
-	oldObj interface{}
-	newObj interface{}
+	oldObj      interface{}
+	newObj      interface{}
+	origin      UpdateOrigin
+	distributed time.Time
 }
 
 type addNotification struct {
 	newObj interface{}
+	// deltaType is the type of the delta the add comes from, Added if
+	// empty.
+	deltaType   DeltaType
+	distributed time.Time
 }
 
 type deleteNotification struct {
-	oldObj interface{}
+	oldObj      interface{}
+	distributed time.Time
+}
+
+// progressNotification is only handed to the listeners whose handler is a
+// SyncProgressHandler.
+type progressNotification struct {
+	resourceVersion string
 }
 
+// initialSyncNotification is only handed to the listeners whose handler is an
+// InitialSyncHandler.
+type initialSyncNotification struct{}
+
 func (s *sharedIndexInformer) Run(stopCh <-chan struct{}) {
 	defer utilruntime.HandleCrash()
-
-	fifo := NewDeltaFIFO(MetaNamespaceKeyFunc, s.indexer)
+	// Runs after the processor and its listeners have stopped.
+	defer s.doneOnce.Do(func() { close(s.done) })
+
+	fifoOptions := s.deltaFIFOOptions
+	fifoOptions.KnownObjects = s.indexer
+	fifo := NewDeltaFIFOWithOptions(fifoOptions)
+	fifo.SetResyncExclusion(s.resyncExclusion)
+	initialResourceVersion := ""
+	if len(s.snapshotResourceVersion) > 0 {
+		if err := fifo.Replace(s.snapshotObjects, s.snapshotResourceVersion); err != nil {
+			utilruntime.HandleError(fmt.Errorf("unable to load the snapshot: %v", err))
+		} else {
+			initialResourceVersion = s.snapshotResourceVersion
+		}
+	}
 
 	cfg := &Config{
-		Queue:            fifo,
-		ListerWatcher:    s.listerWatcher,
-		ObjectType:       s.objectType,
-		FullResyncPeriod: s.resyncCheckPeriod,
-		RetryOnError:     false,
-		ShouldResync:     s.processor.shouldResync,
+		Queue:         fifo,
+		ListerWatcher: s.listerWatcher,
+		ObjectType:    s.objectType,
+		RetryOnError:  false,
+		ShouldResync:  s.shouldResync,
+
+		ReflectorTimeouts:      s.reflectorTimeouts,
+		RelistBackoff:          s.relistBackoff,
+		StrictWatchValidation:  s.strictWatchValidation,
+		WatchListPageSize:      s.pageSize,
+		UseWatchList:           s.useWatchList,
+		WatchErrorHandler:      s.watchErrorHandler,
+		Hooks:                  s.controllerHooks(),
+		OnSyncProgress:         s.syncProgress,
+		InitialResourceVersion: initialResourceVersion,
 
 		Process: s.HandleDeltas,
 	}
@@ -310,8 +1075,14 @@ func (s *sharedIndexInformer) Run(stopCh <-chan struct{}) {
 		s.startedLock.Lock()
 		defer s.startedLock.Unlock()
 
+		// The resync check period may change until the controller exists.
+		cfg.FullResyncPeriod = s.resyncCheckPeriod
+		s.snapshotObjects = nil
 		s.controller = New(cfg)
 		s.controller.(*controller).clock = s.clock
+		if s.metrics == nil {
+			s.metrics = newInformerMetrics(s.informerName())
+		}
 		s.started = true
 	}()
 
@@ -322,15 +1093,83 @@ func (s *sharedIndexInformer) Run(stopCh <-chan struct{}) {
 	defer close(processorStopCh) // Tell Processor to stop
 	wg.StartWithChannel(processorStopCh, s.cacheMutationDetector.Run)
 	wg.StartWithChannel(processorStopCh, s.processor.run)
+	if s.ttl > 0 {
+		wg.StartWithChannel(stopCh, s.runExpiration)
+	}
 
 	defer func() {
 		s.startedLock.Lock()
 		defer s.startedLock.Unlock()
 		s.stopped = true // Don't want any new listeners
 	}()
+	defer func() {
+		if r := recover(); r != nil {
+			s.setStopReason(&InformerPanicError{Value: r})
+			panic(r)
+		}
+		s.setStopReason(ErrInformerStopRequested)
+	}()
 	s.controller.Run(stopCh)
 }
 
+func (s *sharedIndexInformer) RunWithContext(ctx context.Context) error {
+	s.Run(ctx.Done())
+	return s.StopReason()
+}
+
+func (s *sharedIndexInformer) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *sharedIndexInformer) setStopReason(reason error) {
+	s.startedLock.Lock()
+	defer s.startedLock.Unlock()
+	s.stopReason = reason
+}
+
+func (s *sharedIndexInformer) StopReason() error {
+	s.startedLock.Lock()
+	defer s.startedLock.Unlock()
+	return s.stopReason
+}
+
+// controllerHooks returns the hooks of the informer, followed by the hook
+// notifying the listeners of the end of the initial sync.
+func (s *sharedIndexInformer) controllerHooks() ControllerHooks {
+	hooks := s.hooks
+	hooks.PostSync = append(append([]func(time.Duration){}, s.hooks.PostSync...), func(time.Duration) {
+		s.initialSyncDone()
+	})
+	return hooks
+}
+
+// initialSyncDone hands an initialSyncNotification to the listeners.  The
+// notifications of the initial list have all been distributed by then, and
+// blocking deltas orders it with the additions of listeners added meanwhile.
+func (s *sharedIndexInformer) initialSyncDone() {
+	s.blockDeltas.Lock()
+	defer s.blockDeltas.Unlock()
+	s.initialSynced = true
+	s.processor.distributeInitialSync()
+}
+
+// informerName returns the name identifying the informer in metrics.
+func (s *sharedIndexInformer) informerName() string {
+	if len(s.name) > 0 {
+		return s.name
+	}
+	return fmt.Sprintf("%T", s.objectType)
+}
+
+// shouldResync tells the reflector whether any listener needs a resync.
+func (s *sharedIndexInformer) shouldResync() bool {
+	resync := s.processor.shouldResync()
+	if resync && s.metrics != nil {
+		s.metrics.resyncs.Inc()
+	}
+	return resync
+}
+
 func (s *sharedIndexInformer) HasSynced() bool {
 	s.startedLock.Lock()
 	defer s.startedLock.Unlock()
@@ -351,11 +1190,71 @@ func (s *sharedIndexInformer) LastSyncResourceVersion() string {
 	return s.controller.LastSyncResourceVersion()
 }
 
+func (s *sharedIndexInformer) Health() ReflectorHealth {
+	s.startedLock.Lock()
+	defer s.startedLock.Unlock()
+	if c, ok := s.controller.(*controller); ok {
+		return c.health()
+	}
+	return ReflectorHealth{}
+}
+
+func (s *sharedIndexInformer) GetStoreStats() StoreStats {
+	stats := indexerStats(s.indexer)
+
+	s.startedLock.Lock()
+	defer s.startedLock.Unlock()
+	if c, ok := s.controller.(*controller); ok {
+		stats.LastRelistTime = c.lastRelistTime()
+	}
+	return stats
+}
+
+// syncProgress records that the indexer has caught up with resourceVersion
+// and tells the listeners.
+func (s *sharedIndexInformer) syncProgress(resourceVersion string) {
+	s.blockDeltas.Lock()
+	s.syncedResourceVersion = resourceVersion
+	s.blockDeltas.Unlock()
+	s.processor.distributeProgress(resourceVersion)
+}
+
+func (s *sharedIndexInformer) SaveSnapshot(path string) error {
+	s.blockDeltas.Lock()
+	resourceVersion := s.syncedResourceVersion
+	objs := s.indexer.List()
+	s.blockDeltas.Unlock()
+
+	if len(resourceVersion) == 0 {
+		return fmt.Errorf("the informer has not synced yet")
+	}
+	return writeSnapshot(path, resourceVersion, objs)
+}
+
+func (s *sharedIndexInformer) LoadSnapshot(path string) error {
+	resourceVersion, objs, err := readSnapshot(path, s.objectType)
+	if err != nil {
+		return err
+	}
+
+	s.startedLock.Lock()
+	defer s.startedLock.Unlock()
+	if s.started {
+		return fmt.Errorf("informer has already started")
+	}
+	s.snapshotObjects = objs
+	s.snapshotResourceVersion = resourceVersion
+	return nil
+}
+
 func (s *sharedIndexInformer) GetStore() Store {
-	return s.indexer
+	return s.GetIndexer()
 }
 
 func (s *sharedIndexInformer) GetIndexer() Indexer {
+	if s.deepCopyOnRead {
+		return NewDeepCopyIndexer(s.indexer)
+	}
 	return s.indexer
 }
 
@@ -364,18 +1263,90 @@ func (s *sharedIndexInformer) AddIndexers(indexers Indexers) error {
 	defer s.startedLock.Unlock()
 
 	if s.started {
-		return fmt.Errorf("informer has already started")
+		return fmt.Errorf("informer has already started, its indexer's AddIndexersAfterStart can still add indexers")
 	}
 
 	return s.indexer.AddIndexers(indexers)
 }
 
+func (s *sharedIndexInformer) SetTransform(transform TransformFunc) error {
+	s.startedLock.Lock()
+	defer s.startedLock.Unlock()
+
+	if s.started {
+		return fmt.Errorf("informer has already started")
+	}
+
+	s.transform = transform
+	return nil
+}
+
+func (s *sharedIndexInformer) SetWatchErrorHandler(handler WatchErrorHandler) error {
+	s.startedLock.Lock()
+	defer s.startedLock.Unlock()
+
+	if s.started {
+		return fmt.Errorf("informer has already started")
+	}
+
+	s.watchErrorHandler = handler
+	return nil
+}
+
+func (s *sharedIndexInformer) SetResyncCheckPeriod(resyncCheckPeriod time.Duration) {
+	s.startedLock.Lock()
+	defer s.startedLock.Unlock()
+	s.setResyncCheckPeriodLocked(resyncCheckPeriod)
+}
+
+// setResyncCheckPeriodLocked must be called with startedLock held.
+func (s *sharedIndexInformer) setResyncCheckPeriodLocked(resyncCheckPeriod time.Duration) {
+	s.resyncCheckPeriod = resyncCheckPeriod
+	s.processor.resyncCheckPeriodChanged(resyncCheckPeriod)
+	if c, ok := s.controller.(*controller); ok {
+		c.setResyncPeriod(resyncCheckPeriod)
+	}
+}
+
+func (s *sharedIndexInformer) Pause() {
+	s.processor.setPaused(true)
+}
+
+func (s *sharedIndexInformer) Resume() {
+	s.processor.setPaused(false)
+}
+
+func (s *sharedIndexInformer) SetHandlerResyncPeriod(handle ResourceEventHandlerRegistration, resyncPeriod time.Duration) error {
+	listener, ok := handle.(*processorListener)
+	if !ok {
+		return fmt.Errorf("invalid event handler registration %T", handle)
+	}
+
+	s.startedLock.Lock()
+	defer s.startedLock.Unlock()
+
+	if !s.processor.hasListener(listener) {
+		return fmt.Errorf("event handler %s is not registered", listener)
+	}
+	if resyncPeriod > 0 {
+		if resyncPeriod < minimumResyncPeriod {
+			klog.Warningf("resyncPeriod %d is too small. Changing it to the minimum allowed value of %d", resyncPeriod, minimumResyncPeriod)
+			resyncPeriod = minimumResyncPeriod
+		}
+		if s.resyncCheckPeriod == 0 || resyncPeriod < s.resyncCheckPeriod {
+			s.setResyncCheckPeriodLocked(resyncPeriod)
+		}
+	}
+	listener.setRequestedResyncPeriod(resyncPeriod, determineResyncPeriod(resyncPeriod, s.resyncCheckPeriod), s.clock.Now())
+	return nil
+}
+
 func (s *sharedIndexInformer) GetController() Controller {
 	return &dummyController{informer: s}
 }
 
-func (s *sharedIndexInformer) AddEventHandler(handler ResourceEventHandler) {
-	s.AddEventHandlerWithResyncPeriod(handler, s.defaultEventHandlerResyncPeriod)
+func (s *sharedIndexInformer) AddEventHandler(handler ResourceEventHandler) (ResourceEventHandlerRegistration, error) {
+	return s.AddEventHandlerWithResyncPeriod(handler, s.defaultEventHandlerResyncPeriod)
 }
 
 func determineResyncPeriod(desired, check time.Duration) time.Duration {
@@ -395,13 +1366,28 @@ func determineResyncPeriod(desired, check time.Duration) time.Duration {
 
 const minimumResyncPeriod = 1 * time.Second
 
-func (s *sharedIndexInformer) AddEventHandlerWithResyncPeriod(handler ResourceEventHandler, resyncPeriod time.Duration) {
+func (s *sharedIndexInformer) AddEventHandlerWithResyncPeriod(handler ResourceEventHandler, resyncPeriod time.Duration) (ResourceEventHandlerRegistration, error) {
+	return s.AddEventHandlerWithOptions(handler, HandlerOptions{ResyncPeriod: &resyncPeriod})
+}
+
+func (s *sharedIndexInformer) AddEventHandlerWithOptions(handler ResourceEventHandler, options HandlerOptions) (ResourceEventHandlerRegistration, error) {
 	s.startedLock.Lock()
 	defer s.startedLock.Unlock()
 
 	if s.stopped {
-		klog.V(2).Infof("Handler %v was not added to shared informer because it has stopped already", handler)
-		return
+		name := options.Name
+		if len(name) == 0 {
+			name = fmt.Sprintf("%T", handler)
+		}
+		return nil, fmt.Errorf("handler %s was not added to shared informer because it has stopped already", name)
+	}
+
+	resyncPeriod := s.defaultEventHandlerResyncPeriod
+	if options.ResyncPeriod != nil {
+		resyncPeriod = *options.ResyncPeriod
+	}
+	if options.SkipResyncs {
+		resyncPeriod = 0
 	}
 
 	if resyncPeriod > 0 {
@@ -425,10 +1411,41 @@ func (s *sharedIndexInformer) AddEventHandlerWithResyncPeriod(handler ResourceEv
 	}
 
 	listener := newProcessListener(handler, resyncPeriod, determineResyncPeriod(resyncPeriod, s.resyncCheckPeriod), s.clock.Now(), initialBufferSize)
+	listener.upstreamHasSynced = s.HasSynced
+	listener.filter = options.Filter
+	listener.name = options.Name
+	listener.priority = options.Priority
+	listener.panicHandler = options.PanicHandler
+	listener.skipResyncs = options.SkipResyncs
+	listener.keyFunc = s.objectKey
+	if options.Workers > 1 {
+		listener.pool = newKeyOrderedHandler(handler, options.Workers, s.objectKey)
+	} else if batchHandler, ok := handler.(BatchResourceEventHandler); ok {
+		listener.batchHandler = batchHandler
+		listener.batchSize = options.BatchSize
+		if listener.batchSize <= 0 {
+			listener.batchSize = defaultBatchSize
+		}
+		listener.batchDelay = options.BatchDelay
+	}
+	if s.resyncJitter > 0 {
+		listener.setResyncJitter(s.resyncJitter, s.clock.Now())
+	}
+	listener.metrics = newListenerMetrics(s.informerName(), listener.String())
+	if s.slowListener != nil {
+		listener.slowListener = s.slowListener
+		listener.slowNotifications = newSlowNotificationsMetric(s.informerName(), listener.String())
+	}
+	if s.notificationSpill != nil {
+		listener.pendingNotifications = newSpillingBuffer(*s.notificationSpill, handler)
+	}
+	if options.CoalesceNotifications {
+		listener.pendingNotifications = newCoalescingBuffer(s.objectKey, listener.countCoalesced)
+	}
 
 	if !s.started {
 		s.processor.addListener(listener)
-		return
+		return listener, nil
 	}
 
 	// in order to safely join, we have to
@@ -440,42 +1457,157 @@ func (s *sharedIndexInformer) AddEventHandlerWithResyncPeriod(handler ResourceEv
 	defer s.blockDeltas.Unlock()
 
 	s.processor.addListener(listener)
+	s.replay(listener, options.PriorState)
+	if _, ok := handler.(InitialSyncHandler); ok && s.initialSynced {
+		listener.add(initialSyncNotification{})
+	}
+	return listener, nil
+}
+
+// replay hands the cached objects to a listener added late, as adds, or as
+// updates of the objects of prior, followed by the deletes of the objects of
+// prior no longer cached.  It must be called with blockDeltas held.
+func (s *sharedIndexInformer) replay(listener *processorListener, prior map[string]interface{}) {
+	replayed := sets.NewString()
 	for _, item := range s.indexer.List() {
-		listener.add(addNotification{newObj: item})
+		var notification interface{} = addNotification{newObj: s.frozenCopy(item), deltaType: Sync}
+		if len(prior) > 0 {
+			if key, err := s.objectKey(item); err == nil {
+				if oldObj, ok := prior[key]; ok {
+					notification = updateNotification{oldObj: oldObj, newObj: s.frozenCopy(item), origin: UpdateFromRelist}
+					replayed.Insert(key)
+				}
+			}
+		}
+		if notification, ok := listener.filtered(notification); ok {
+			listener.add(notification)
+		}
 	}
+	for key, oldObj := range prior {
+		if replayed.Has(key) {
+			continue
+		}
+		notification := deleteNotification{oldObj: DeletedFinalStateUnknown{Key: key, Obj: oldObj}}
+		if notification, ok := listener.filtered(notification); ok {
+			listener.add(notification)
+		}
+	}
+}
+
+func (s *sharedIndexInformer) RemoveEventHandler(handle ResourceEventHandlerRegistration) error {
+	s.startedLock.Lock()
+	defer s.startedLock.Unlock()
+
+	// in order to safely remove, we have to
+	// 1. stop sending add/update/delete notifications
+	// 2. remove and stop the listener
+	// 3. unblock
+	s.blockDeltas.Lock()
+	defer s.blockDeltas.Unlock()
+	return s.processor.removeListener(handle)
 }
 
 func (s *sharedIndexInformer) HandleDeltas(obj interface{}) error {
 	s.blockDeltas.Lock()
 	defer s.blockDeltas.Unlock()
 
+	if s.metrics != nil {
+		defer func(start time.Time) {
+			s.metrics.handleDeltasDuration.Observe(time.Since(start).Seconds())
+		}(time.Now())
+	}
+
 	// from oldest to newest
 	for _, d := range obj.(Deltas) {
+		if s.transform != nil {
+			// The objects of DeletedFinalStateUnknown come from the indexer
+			// and have been transformed already.
+			if _, ok := d.Object.(DeletedFinalStateUnknown); !ok {
+				transformed, err := s.transform(d.Object)
+				if err != nil {
+					return err
+				}
+				d.Object = transformed
+			}
+		}
 		switch d.Type {
-		case Sync, Added, Updated:
+		case Sync, Replaced, Added, Updated:
 			isSync := d.Type == Sync
 			s.cacheMutationDetector.AddObject(d.Object)
+			// The object replaced in the cache, if frozen, is safe to hand
+			// out.
+			stored := s.frozenCopy(d.Object)
 			if old, exists, err := s.indexer.Get(d.Object); err == nil && exists {
-				if err := s.indexer.Update(d.Object); err != nil {
+				if err := s.indexer.Update(stored); err != nil {
 					return err
 				}
-				s.processor.distribute(updateNotification{oldObj: old, newObj: d.Object}, isSync)
+				// An object a relist found unchanged is a sync.
+				if d.Type == Replaced && sameResourceVersion(old, d.Object) {
+					isSync = true
+				}
+				if !isSync {
+					s.recordWrite(d.Object)
+				}
+				origin := UpdateFromWatch
+				switch {
+				case isSync:
+					origin = UpdateFromResync
+				case d.Type == Replaced:
+					origin = UpdateFromRelist
+				}
+				s.processor.distribute(updateNotification{oldObj: old, newObj: d.Object, origin: origin}, isSync)
 			} else {
-				if err := s.indexer.Add(d.Object); err != nil {
+				if err := s.indexer.Add(stored); err != nil {
 					return err
 				}
-				s.processor.distribute(addNotification{newObj: d.Object}, isSync)
+				s.recordWrite(d.Object)
+				s.processor.distribute(addNotification{newObj: d.Object, deltaType: d.Type}, isSync)
+				s.objectsChanged(1)
 			}
 		case Deleted:
+			existed := true
+			if s.maxObjects > 0 {
+				_, existed, _ = s.indexer.Get(d.Object)
+			}
 			if err := s.indexer.Delete(d.Object); err != nil {
 				return err
 			}
+			s.forgetWrite(d.Object)
+			if existed {
+				s.objectsChanged(-1)
+			}
 			s.processor.distribute(deleteNotification{oldObj: d.Object}, false)
 		}
 	}
 	return nil
 }
 
+// sameResourceVersion returns true if both objects have the same, non-empty,
+// resource version.
+func sameResourceVersion(a, b interface{}) bool {
+	aMeta, err := meta.Accessor(a)
+	if err != nil {
+		return false
+	}
+	bMeta, err := meta.Accessor(b)
+	if err != nil {
+		return false
+	}
+	return len(aMeta.GetResourceVersion()) > 0 && aMeta.GetResourceVersion() == bMeta.GetResourceVersion()
+}
+
+// frozenCopy returns a deep copy of obj if the informer freezes objects, and
+// obj otherwise.
+func (s *sharedIndexInformer) frozenCopy(obj interface{}) interface{} {
+	if !s.freezeObjects {
+		return obj
+	}
+	if object, ok := obj.(runtime.Object); ok {
+		return object.DeepCopyObject()
+	}
+	return obj
+}
+
 type sharedProcessor struct {
 	listenersStarted bool
 	listenersLock    sync.RWMutex
@@ -483,6 +1615,12 @@ type sharedProcessor struct {
 	syncingListeners []*processorListener
 	clock            clock.Clock
 	wg               wait.Group
+
+	// strictDeliveryTimeout, if positive, bounds how long distribute waits
+	// for each listener to accept a notification.
+	strictDeliveryTimeout time.Duration
+	// paused is true while the listeners hold their notifications back.
+	paused bool
 }
 
 func (p *sharedProcessor) addListener(listener *processorListener) {
@@ -491,28 +1629,140 @@ func (p *sharedProcessor) addListener(listener *processorListener) {
 
 	p.addListenerLocked(listener)
 	if p.listenersStarted {
-		p.wg.Start(listener.run)
-		p.wg.Start(listener.pop)
+		p.wg.Start(listener.labeled(listener.run))
+		p.wg.Start(listener.labeled(listener.pop))
 	}
 }
 
+// addListenerLocked adds listener after the listeners of the same or higher
+// priority, so that distribute serves higher priorities first.
 func (p *sharedProcessor) addListenerLocked(listener *processorListener) {
-	p.listeners = append(p.listeners, listener)
-	p.syncingListeners = append(p.syncingListeners, listener)
+	listener.setPaused(p.paused)
+	p.listeners = insertByPriority(p.listeners, listener)
+	p.syncingListeners = insertByPriority(p.syncingListeners, listener)
+}
+
+func insertByPriority(listeners []*processorListener, listener *processorListener) []*processorListener {
+	i := sort.Search(len(listeners), func(i int) bool {
+		return listeners[i].priority < listener.priority
+	})
+	listeners = append(listeners, nil)
+	copy(listeners[i+1:], listeners[i:])
+	listeners[i] = listener
+	return listeners
+}
+
+// removeListener removes the listener registered as handle and, if it is
+// running, tells it to stop.
+func (p *sharedProcessor) removeListener(handle ResourceEventHandlerRegistration) error {
+	listener, ok := handle.(*processorListener)
+	if !ok {
+		return fmt.Errorf("invalid event handler registration %T", handle)
+	}
+
+	p.listenersLock.Lock()
+	defer p.listenersLock.Unlock()
+
+	i := indexOfListener(p.listeners, listener)
+	if i < 0 {
+		return nil
+	}
+	p.listeners = append(p.listeners[:i], p.listeners[i+1:]...)
+	if i := indexOfListener(p.syncingListeners, listener); i >= 0 {
+		p.syncingListeners = append(p.syncingListeners[:i], p.syncingListeners[i+1:]...)
+	}
+	if p.listenersStarted {
+		close(listener.addCh) // Tell .pop() to stop. .pop() will tell .run() to stop
+	}
+	return nil
+}
+
+// setPaused pauses or resumes the delivery of notifications by every
+// listener.
+func (p *sharedProcessor) setPaused(paused bool) {
+	p.listenersLock.Lock()
+	defer p.listenersLock.Unlock()
+	p.paused = paused
+	for _, listener := range p.listeners {
+		listener.setPaused(paused)
+	}
+}
+
+func (p *sharedProcessor) hasListener(listener *processorListener) bool {
+	p.listenersLock.RLock()
+	defer p.listenersLock.RUnlock()
+	return indexOfListener(p.listeners, listener) >= 0
+}
+
+func indexOfListener(listeners []*processorListener, listener *processorListener) int {
+	for i := range listeners {
+		if listeners[i] == listener {
+			return i
+		}
+	}
+	return -1
 }
 
 func (p *sharedProcessor) distribute(obj interface{}, sync bool) {
 	p.listenersLock.RLock()
 	defer p.listenersLock.RUnlock()
 
+	listeners := p.listeners
 	if sync {
-		for _, listener := range p.syncingListeners {
-			listener.add(obj)
+		listeners = p.syncingListeners
+	}
+	if p.strictDeliveryTimeout > 0 {
+		p.distributeWithin(obj, listeners, p.strictDeliveryTimeout)
+		return
+	}
+	for _, listener := range listeners {
+		if notification, ok := listener.filtered(obj); ok {
+			listener.add(notification)
 		}
-	} else {
-		for _, listener := range p.listeners {
-			listener.add(obj)
+	}
+}
+
+// distributeProgress hands a progressNotification to the listeners whose
+// handler is a SyncProgressHandler.
+func (p *sharedProcessor) distributeProgress(resourceVersion string) {
+	p.listenersLock.RLock()
+	defer p.listenersLock.RUnlock()
+
+	for _, listener := range p.listeners {
+		if _, ok := listener.handler.(SyncProgressHandler); ok {
+			listener.add(progressNotification{resourceVersion: resourceVersion})
+		}
+	}
+}
+
+// distributeInitialSync hands an initialSyncNotification to the listeners
+// whose handler is an InitialSyncHandler.
+func (p *sharedProcessor) distributeInitialSync() {
+	p.listenersLock.RLock()
+	defer p.listenersLock.RUnlock()
+
+	for _, listener := range p.listeners {
+		if _, ok := listener.handler.(InitialSyncHandler); ok {
+			listener.add(initialSyncNotification{})
+		}
+	}
+}
+
+// distributeWithin hands obj to every listener like distribute does, waiting
+// no longer than timeout for each of them.  The listeners that have not
+// accepted obj by then do not get it and are reported.
+func (p *sharedProcessor) distributeWithin(obj interface{}, listeners []*processorListener, timeout time.Duration) {
+	for _, listener := range listeners {
+		obj, ok := listener.filtered(obj)
+		if !ok {
+			continue
+		}
+		if !listener.addWatched(obj, timeout) {
+			utilruntime.HandleError(fmt.Errorf("event handler %s did not accept a %T within %v, dropping it",
+				listener, obj, timeout))
+			continue
 		}
+		listener.countAdded()
 	}
 }
 
@@ -521,17 +1771,26 @@ func (p *sharedProcessor) run(stopCh <-chan struct{}) {
 		p.listenersLock.RLock()
 		defer p.listenersLock.RUnlock()
 		for _, listener := range p.listeners {
-			p.wg.Start(listener.run)
-			p.wg.Start(listener.pop)
+			p.wg.Start(listener.labeled(listener.run))
+			p.wg.Start(listener.labeled(listener.pop))
 		}
 		p.listenersStarted = true
 	}()
 	<-stopCh
-	p.listenersLock.RLock()
-	defer p.listenersLock.RUnlock()
-	for _, listener := range p.listeners {
-		close(listener.addCh) // Tell .pop() to stop. .pop() will tell .run() to stop
-	}
+	func() {
+		p.listenersLock.Lock()
+		defer p.listenersLock.Unlock()
+		for _, listener := range p.listeners {
+			close(listener.addCh) // Tell .pop() to stop. .pop() will tell .run() to stop
+		}
+		// The stopped listeners cannot be started again, and forgetting them
+		// keeps a later RemoveEventHandler from stopping them twice.
+		p.listeners = nil
+		p.syncingListeners = nil
+		p.listenersStarted = false
+	}()
+	// Wait without the lock: the handlers finishing their notifications may
+	// still call RemoveEventHandler or other methods taking it.
 	p.wg.Wait() // Wait for all .pop() and .run() to stop
 }
 
@@ -562,8 +1821,7 @@ func (p *sharedProcessor) resyncCheckPeriodChanged(resyncCheckPeriod time.Durati
 	defer p.listenersLock.RUnlock()
 
 	for _, listener := range p.listeners {
-		resyncPeriod := determineResyncPeriod(listener.requestedResyncPeriod, resyncCheckPeriod)
-		listener.setResyncPeriod(resyncPeriod)
+		listener.resyncCheckPeriodChanged(resyncCheckPeriod)
 	}
 }
 
@@ -572,13 +1830,23 @@ type processorListener struct {
 	addCh  chan interface{}
 
 	handler ResourceEventHandler
-
-	// pendingNotifications is an unbounded ring buffer that holds all notifications not yet distributed.
+	// name identifies the handler, see HandlerOptions.Name.
+	name string
+	// pool, if set, runs the callbacks of handler, see
+	// HandlerOptions.Workers.
+	pool *KeyOrderedHandler
+	// batchHandler, if set, is the handler, which gets the notifications in
+	// batches of up to batchSize, waiting up to batchDelay for them.
+	batchHandler BatchResourceEventHandler
+	batchSize    int
+	batchDelay   time.Duration
+
+	// pendingNotifications is an unbounded buffer that holds all notifications not yet distributed.
 	// There is one per listener, but a failing/stalled listener will have infinite pendingNotifications
-	// added until we OOM.
+	// added until we OOM, unless the informer was configured to spill them to disk.
 	// TODO: This is no worse than before, since reflectors were backed by unbounded DeltaFIFOs, but
 	// we should try to do something better.
-	pendingNotifications buffer.RingGrowing
+	pendingNotifications notificationBuffer
 
 	// requestedResyncPeriod is how frequently the listener wants a full resync from the shared informer
 	requestedResyncPeriod time.Duration
@@ -588,8 +1856,65 @@ type processorListener struct {
 	resyncPeriod time.Duration
 	// nextResync is the earliest time the listener should get a full resync
 	nextResync time.Time
-	// resyncLock guards access to resyncPeriod and nextResync
+	// resyncJitter is the maximum fraction of the resync period by which
+	// nextResync is moved, at random, earlier or later.
+	resyncJitter float64
+	// resyncLock guards access to requestedResyncPeriod, resyncPeriod,
+	// nextResync and resyncJitter
 	resyncLock sync.Mutex
+
+	// filter, if set, selects the objects the handler is notified about.
+	filter func(obj interface{}) bool
+	// priority orders the listener in sharedProcessor.listeners.
+	priority HandlerPriority
+	// skipResyncs drops the updates from resyncs, see
+	// HandlerOptions.SkipResyncs.
+	skipResyncs bool
+	// panicHandler, if set, is called with the panics of the handler, see
+	// HandlerOptions.PanicHandler.
+	panicHandler HandlerPanicFunc
+	// panics counts the consecutive panics of the handler by object key.
+	// It is only used by run.
+	panics map[string]int
+	// keyFunc returns the keys of the objects of the notifications, those
+	// of the informer's cache.
+	keyFunc KeyFunc
+
+	// upstreamHasSynced is the HasSynced of the informer the listener was
+	// added to.
+	upstreamHasSynced func() bool
+	// syncLock guards the fields below, which track whether the handler has
+	// caught up with the informer's initial list.
+	syncLock sync.Mutex
+	// added and handled count the notifications added to the listener and
+	// delivered to the handler.
+	added, handled int
+	// syncTarget is the number of notifications added when the informer was
+	// first seen synced, or -1 before that.
+	syncTarget int
+	synced     bool
+
+	// metrics is nil unless an InformerMetricsProvider is set.
+	metrics *listenerMetrics
+
+	// paused makes pop hold the notifications back.  pauseLock guards it,
+	// and pauseChanged wakes pop up when it changes.
+	paused       bool
+	pauseLock    sync.Mutex
+	pauseChanged chan struct{}
+
+	// slowListener, if set, makes add report the listener when pop is slow
+	// to accept notifications, see WithSlowListenerDetection.
+	slowListener *SlowListenerConfig
+	// slowNotifications is nil unless the metrics provider is a
+	// SlowListenerMetricsProvider.
+	slowNotifications CounterMetric
+	// overflow holds the notifications add did not wait for pop to accept,
+	// oldest first.  overflowLock guards it, and overflowed wakes pop up
+	// when it fills.
+	overflow     []interface{}
+	overflowLock sync.Mutex
+	overflowed   chan struct{}
 }
 
 func newProcessListener(handler ResourceEventHandler, requestedResyncPeriod, resyncPeriod time.Duration, now time.Time, bufferSize int) *processorListener {
@@ -597,9 +1922,13 @@ func newProcessListener(handler ResourceEventHandler, requestedResyncPeriod, res
 		nextCh:                make(chan interface{}),
 		addCh:                 make(chan interface{}),
 		handler:               handler,
-		pendingNotifications:  *buffer.NewRingGrowing(bufferSize),
+		pendingNotifications:  buffer.NewRingGrowing(bufferSize),
 		requestedResyncPeriod: requestedResyncPeriod,
 		resyncPeriod:          resyncPeriod,
+		syncTarget:            -1,
+		pauseChanged:          make(chan struct{}, 1),
+		overflowed:            make(chan struct{}, 1),
+		keyFunc:               DeletionHandlingMetaNamespaceKeyFunc,
 	}
 
 	ret.determineNextResync(now)
@@ -608,18 +1937,163 @@ func newProcessListener(handler ResourceEventHandler, requestedResyncPeriod, res
 }
 
 func (p *processorListener) add(notification interface{}) {
-	p.addCh <- notification
+	if p.slowListener != nil {
+		p.addWatched(notification, 0)
+	} else {
+		p.addCh <- notification
+	}
+	p.countAdded()
+}
+
+// String returns the name of the listener's handler, or its type.
+func (p *processorListener) String() string {
+	if len(p.name) > 0 {
+		return p.name
+	}
+	return fmt.Sprintf("%T", p.handler)
+}
+
+// labeled returns f, running under the HandlerProfileLabel of a named
+// listener so that profiles attribute its work to the handler.
+func (p *processorListener) labeled(f func()) func() {
+	if len(p.name) == 0 {
+		return f
+	}
+	return func() {
+		pprof.Do(context.Background(), pprof.Labels(HandlerProfileLabel, p.name), func(context.Context) {
+			f()
+		})
+	}
+}
+
+// filtered applies the listener's filter to notification, returning the
+// notification to deliver instead and whether there is one.
+func (p *processorListener) filtered(notification interface{}) (interface{}, bool) {
+	if n, ok := notification.(updateNotification); ok && p.skipResyncs && n.origin == UpdateFromResync {
+		return nil, false
+	}
+	if p.filter == nil {
+		return notification, true
+	}
+	switch n := notification.(type) {
+	case addNotification:
+		return n, p.filter(n.newObj)
+	case deleteNotification:
+		return n, p.filter(n.oldObj)
+	case updateNotification:
+		newer := p.filter(n.newObj)
+		older := p.filter(n.oldObj)
+		switch {
+		case newer && older:
+			return n, true
+		case newer:
+			return addNotification{newObj: n.newObj, deltaType: updateDeltaType(n.origin)}, true
+		case older:
+			return deleteNotification{oldObj: n.oldObj}, true
+		}
+		return nil, false
+	}
+	return notification, true
+}
+
+func (p *processorListener) countAdded() {
+	p.syncLock.Lock()
+	defer p.syncLock.Unlock()
+	p.added++
+	p.setPendingMetric()
+}
+
+func (p *processorListener) countHandled() {
+	p.syncLock.Lock()
+	defer p.syncLock.Unlock()
+	p.handled++
+	p.setPendingMetric()
+}
+
+// countCoalesced counts notifications merged into others as handled.
+func (p *processorListener) countCoalesced(n int) {
+	p.syncLock.Lock()
+	defer p.syncLock.Unlock()
+	p.handled += n
+	p.setPendingMetric()
+}
+
+// setPendingMetric must be called with syncLock held.
+func (p *processorListener) setPendingMetric() {
+	if p.metrics != nil {
+		p.metrics.pendingNotifications.Set(float64(p.added - p.handled))
+	}
+}
+
+// HasSynced implements ResourceEventHandlerRegistration.  The informer's
+// HasSynced blocks while the informer distributes a batch of deltas, so once
+// it returns true every notification of the initial list, and of the cache
+// contents when the listener was added, has already been counted.
+func (p *processorListener) HasSynced() bool {
+	p.syncLock.Lock()
+	synced := p.synced
+	p.syncLock.Unlock()
+	if synced {
+		return true
+	}
+	if p.upstreamHasSynced == nil || !p.upstreamHasSynced() {
+		return false
+	}
+
+	p.syncLock.Lock()
+	defer p.syncLock.Unlock()
+	if p.syncTarget < 0 {
+		p.syncTarget = p.added
+	}
+	p.synced = p.handled >= p.syncTarget
+	return p.synced
+}
+
+// setPaused makes pop hold the notifications back, or deliver them again.
+func (p *processorListener) setPaused(paused bool) {
+	p.pauseLock.Lock()
+	p.paused = paused
+	p.pauseLock.Unlock()
+	select {
+	case p.pauseChanged <- struct{}{}:
+	default:
+	}
+}
+
+func (p *processorListener) isPaused() bool {
+	p.pauseLock.Lock()
+	defer p.pauseLock.Unlock()
+	return p.paused
 }
 
 func (p *processorListener) pop() {
 	defer utilruntime.HandleCrash()
 	defer close(p.nextCh) // Tell .run() to stop
+	defer p.releaseBuffer()
 
 	var nextCh chan<- interface{}
 	var notification interface{}
+	accept := func(notificationToAdd interface{}) {
+		if p.metrics != nil {
+			notificationToAdd = stampNotification(notificationToAdd, time.Now())
+		}
+		if notification == nil { // No notification to pop (and pendingNotifications is empty)
+			// Optimize the case - skip adding to pendingNotifications
+			notification = notificationToAdd
+			nextCh = p.nextCh
+		} else { // There is already a notification waiting to be dispatched
+			p.pendingNotifications.WriteOne(notificationToAdd)
+		}
+	}
 	for {
+		dispatchCh := nextCh
+		if p.isPaused() {
+			dispatchCh = nil
+		}
 		select {
-		case nextCh <- notification:
+		case <-p.pauseChanged:
+			// Check again whether to dispatch
+		case dispatchCh <- notification:
 			// Notification dispatched
 			var ok bool
 			notification, ok = p.pendingNotifications.ReadOne()
@@ -630,37 +2104,71 @@ func (p *processorListener) pop() {
 			if !ok {
 				return
 			}
-			if notification == nil { // No notification to pop (and pendingNotifications is empty)
-				// Optimize the case - skip adding to pendingNotifications
-				notification = notificationToAdd
-				nextCh = p.nextCh
-			} else { // There is already a notification waiting to be dispatched
-				p.pendingNotifications.WriteOne(notificationToAdd)
+			accept(notificationToAdd)
+		case <-p.overflowed:
+			for _, notificationToAdd := range p.takeOverflow() {
+				accept(notificationToAdd)
 			}
 		}
 	}
 }
 
+// releaseBuffer frees any resources held by the pending notification buffer
+// once the listener has stopped.
+func (p *processorListener) releaseBuffer() {
+	if closer, ok := p.pendingNotifications.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			utilruntime.HandleError(fmt.Errorf("unable to release notification buffer: %v", err))
+		}
+	}
+}
+
 func (p *processorListener) run() {
 	// this call blocks until the channel is closed.  When a panic happens during the notification
 	// we will catch it, **the offending item will be skipped!**, and after a short delay (one second)
 	// the next notification will be attempted.  This is usually better than the alternative of never
 	// delivering again.
+	if p.pool != nil {
+		poolStopCh := make(chan struct{})
+		var pool wait.Group
+		pool.Start(func() { p.pool.Run(poolStopCh) })
+		defer pool.Wait()
+		defer close(poolStopCh)
+	}
 	stopCh := make(chan struct{})
 	wait.Until(func() {
 		// this gives us a few quick retries before a long pause and then a few more quick retries
 		err := wait.ExponentialBackoff(retry.DefaultRetry, func() (bool, error) {
+			defer func() {
+				if r := recover(); r != nil {
+					klog.Errorf("Event handler %s panicked handling a notification", p)
+					if p.metrics != nil {
+						p.metrics.panics.Inc()
+					}
+					panic(r)
+				}
+			}()
+			if p.batchHandler != nil {
+				p.handleBatches()
+				return true, nil
+			}
 			for next := range p.nextCh {
-				switch notification := next.(type) {
-				case updateNotification:
-					p.handler.OnUpdate(notification.oldObj, notification.newObj)
-				case addNotification:
-					p.handler.OnAdd(notification.newObj)
-				case deleteNotification:
-					p.handler.OnDelete(notification.oldObj)
-				default:
-					utilruntime.HandleError(fmt.Errorf("unrecognized notification: %T", next))
+				var start time.Time
+				if p.metrics != nil {
+					start = time.Now()
+					if distributed := notificationDistributed(next); !distributed.IsZero() {
+						p.metrics.distributionLatency.Observe(start.Sub(distributed).Seconds())
+					}
+				}
+				if p.panicHandler != nil {
+					p.handleRecovering(next)
+				} else {
+					p.handle(next)
 				}
+				if p.metrics != nil {
+					p.metrics.handlerDuration.Observe(time.Since(start).Seconds())
+				}
+				p.countHandled()
 			}
 			// the only way to get here is if the p.nextCh is empty and closed
 			return true, nil
@@ -673,6 +2181,96 @@ func (p *processorListener) run() {
 	}, 1*time.Minute, stopCh)
 }
 
+// handle calls the handler with notification.
+func (p *processorListener) handle(next interface{}) {
+	switch notification := next.(type) {
+	case addNotification, updateNotification, deleteNotification:
+		if p.pool != nil {
+			deliverNotification(p.pool, notification)
+		} else {
+			deliverNotification(p.handler, notification)
+		}
+	case progressNotification:
+		p.handler.(SyncProgressHandler).OnSyncProgress(notification.resourceVersion)
+	case initialSyncNotification:
+		p.handler.(InitialSyncHandler).OnInitialSyncDone()
+	default:
+		utilruntime.HandleError(fmt.Errorf("unrecognized notification: %T", next))
+	}
+}
+
+// deliverNotification calls the method of handler for an add, update or
+// delete notification, preferring those of DetailedResourceEventHandler and
+// UpdateOriginHandler.
+func deliverNotification(handler ResourceEventHandler, notification interface{}) {
+	detailed, isDetailed := handler.(DetailedResourceEventHandler)
+	switch n := notification.(type) {
+	case addNotification:
+		if isDetailed {
+			detailed.OnAddDetailed(n.newObj, addDetails(n.deltaType, n.newObj))
+		} else {
+			handler.OnAdd(n.newObj)
+		}
+	case updateNotification:
+		if isDetailed {
+			detailed.OnUpdateDetailed(n.oldObj, n.newObj, updateDetails(n.origin, n.oldObj, n.newObj))
+		} else if originHandler, ok := handler.(UpdateOriginHandler); ok {
+			originHandler.OnUpdateWithOrigin(n.oldObj, n.newObj, n.origin)
+		} else {
+			handler.OnUpdate(n.oldObj, n.newObj)
+		}
+	case deleteNotification:
+		if isDetailed {
+			obj, details := deleteDetails(n.oldObj)
+			detailed.OnDeleteDetailed(obj, details)
+		} else {
+			handler.OnDelete(n.oldObj)
+		}
+	}
+}
+
+// handleRecovering calls the handler with notification, passing a panic of the
+// handler to the panic handler instead of propagating it.
+func (p *processorListener) handleRecovering(next interface{}) {
+	var obj interface{}
+	switch notification := next.(type) {
+	case updateNotification:
+		obj = notification.newObj
+	case addNotification:
+		obj = notification.newObj
+	case deleteNotification:
+		obj = notification.oldObj
+	}
+	var key string
+	if obj != nil {
+		key, _ = p.keyFunc(obj)
+	}
+
+	defer func() {
+		r := recover()
+		if r == nil {
+			delete(p.panics, key)
+			return
+		}
+		if p.panics == nil {
+			p.panics = map[string]int{}
+		}
+		p.panics[key]++
+		if p.metrics != nil {
+			p.metrics.panics.Inc()
+		}
+		p.panicHandler(HandlerPanic{
+			Handler: p.String(),
+			Object:  obj,
+			Key:     key,
+			Value:   r,
+			Stack:   debug.Stack(),
+			Count:   p.panics[key],
+		})
+	}()
+	p.handle(next)
+}
+
 // shouldResync deterimines if the listener needs a resync. If the listener's resyncPeriod is 0,
 // this always returns false.
 func (p *processorListener) shouldResync(now time.Time) bool {
@@ -690,12 +2288,36 @@ func (p *processorListener) determineNextResync(now time.Time) {
 	p.resyncLock.Lock()
 	defer p.resyncLock.Unlock()
 
-	p.nextResync = now.Add(p.resyncPeriod)
+	period := p.resyncPeriod
+	if p.resyncJitter > 0 {
+		period += time.Duration((rand.Float64()*2 - 1) * p.resyncJitter * float64(period))
+	}
+	p.nextResync = now.Add(period)
+}
+
+// setResyncJitter sets the resync jitter of the listener, see
+// WithResyncJitter.
+func (p *processorListener) setResyncJitter(jitter float64, now time.Time) {
+	p.resyncLock.Lock()
+	p.resyncJitter = jitter
+	p.resyncLock.Unlock()
+	p.determineNextResync(now)
+}
+
+func (p *processorListener) resyncCheckPeriodChanged(resyncCheckPeriod time.Duration) {
+	p.resyncLock.Lock()
+	defer p.resyncLock.Unlock()
+
+	p.resyncPeriod = determineResyncPeriod(p.requestedResyncPeriod, resyncCheckPeriod)
 }
 
-func (p *processorListener) setResyncPeriod(resyncPeriod time.Duration) {
+// setRequestedResyncPeriod changes the resync period of the listener, its
+// next resync being due a period from now.
+func (p *processorListener) setRequestedResyncPeriod(requestedResyncPeriod, resyncPeriod time.Duration, now time.Time) {
 	p.resyncLock.Lock()
 	defer p.resyncLock.Unlock()
 
+	p.requestedResyncPeriod = requestedResyncPeriod
 	p.resyncPeriod = resyncPeriod
+	p.nextResync = now.Add(resyncPeriod)
 }
@@ -0,0 +1,165 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// InformerMetricsProvider generates the metrics of shared informers and of
+// their event handlers.  Informers are identified by the name given with
+// WithInformerName, or by their object type, and handlers by
+// HandlerOptions.Name, or by their type.  Durations are in seconds.
+type InformerMetricsProvider interface {
+	// NewPendingNotificationsMetric returns a gauge of the notifications
+	// handed to a handler and not yet handled.
+	NewPendingNotificationsMetric(informer, handler string) GaugeMetric
+	// NewDistributionLatencyMetric returns a summary of the time from a
+	// notification being handed to a handler to the handler being called.
+	// Notifications spilled to disk are not observed.
+	NewDistributionLatencyMetric(informer, handler string) SummaryMetric
+	// NewHandlerDurationMetric returns a summary of the time a handler takes
+	// to handle a notification.
+	NewHandlerDurationMetric(informer, handler string) SummaryMetric
+	// NewHandlerPanicsMetric returns a counter of the panics of a handler.
+	NewHandlerPanicsMetric(informer, handler string) CounterMetric
+
+	// NewResyncsMetric returns a counter of the resyncs of an informer.
+	NewResyncsMetric(informer string) CounterMetric
+	// NewHandleDeltasDurationMetric returns a summary of the time an informer
+	// takes to apply a batch of deltas to its cache and distribute them.
+	NewHandleDeltasDurationMetric(informer string) SummaryMetric
+}
+
+type noopInformerMetricsProvider struct{}
+
+func (noopInformerMetricsProvider) NewPendingNotificationsMetric(informer, handler string) GaugeMetric {
+	return noopMetric{}
+}
+func (noopInformerMetricsProvider) NewDistributionLatencyMetric(informer, handler string) SummaryMetric {
+	return noopMetric{}
+}
+func (noopInformerMetricsProvider) NewHandlerDurationMetric(informer, handler string) SummaryMetric {
+	return noopMetric{}
+}
+func (noopInformerMetricsProvider) NewHandlerPanicsMetric(informer, handler string) CounterMetric {
+	return noopMetric{}
+}
+func (noopInformerMetricsProvider) NewResyncsMetric(informer string) CounterMetric {
+	return noopMetric{}
+}
+func (noopInformerMetricsProvider) NewHandleDeltasDurationMetric(informer string) SummaryMetric {
+	return noopMetric{}
+}
+
+var informerMetricsFactory = struct {
+	metricsProvider InformerMetricsProvider
+	setProviders    sync.Once
+}{
+	metricsProvider: noopInformerMetricsProvider{},
+}
+
+// SetInformerMetricsProvider sets the metrics provider of shared informers.
+// Only the first call has an effect, and only informers and handlers created
+// after it are instrumented.
+func SetInformerMetricsProvider(metricsProvider InformerMetricsProvider) {
+	informerMetricsFactory.setProviders.Do(func() {
+		informerMetricsFactory.metricsProvider = metricsProvider
+	})
+}
+
+type informerMetrics struct {
+	resyncs              CounterMetric
+	handleDeltasDuration SummaryMetric
+}
+
+// newInformerMetrics returns the metrics of the named informer, or nil if no
+// metrics provider is set.
+func newInformerMetrics(informer string) *informerMetrics {
+	mp := informerMetricsFactory.metricsProvider
+	if mp == (noopInformerMetricsProvider{}) {
+		return nil
+	}
+	return &informerMetrics{
+		resyncs:              mp.NewResyncsMetric(informer),
+		handleDeltasDuration: mp.NewHandleDeltasDurationMetric(informer),
+	}
+}
+
+type listenerMetrics struct {
+	pendingNotifications GaugeMetric
+	distributionLatency  SummaryMetric
+	handlerDuration      SummaryMetric
+	panics               CounterMetric
+}
+
+// newListenerMetrics returns the metrics of the named handler of the named
+// informer, or nil if no metrics provider is set.
+func newListenerMetrics(informer, handler string) *listenerMetrics {
+	mp := informerMetricsFactory.metricsProvider
+	if mp == (noopInformerMetricsProvider{}) {
+		return nil
+	}
+	return &listenerMetrics{
+		pendingNotifications: mp.NewPendingNotificationsMetric(informer, handler),
+		distributionLatency:  mp.NewDistributionLatencyMetric(informer, handler),
+		handlerDuration:      mp.NewHandlerDurationMetric(informer, handler),
+		panics:               mp.NewHandlerPanicsMetric(informer, handler),
+	}
+}
+
+// newSlowNotificationsMetric returns the counter of the slow notifications of
+// the named handler of the named informer, or nil if the metrics provider is
+// not a SlowListenerMetricsProvider.
+func newSlowNotificationsMetric(informer, handler string) CounterMetric {
+	mp, ok := informerMetricsFactory.metricsProvider.(SlowListenerMetricsProvider)
+	if !ok {
+		return nil
+	}
+	return mp.NewSlowNotificationsMetric(informer, handler)
+}
+
+// stampNotification records when notification was handed to a listener.
+func stampNotification(notification interface{}, now time.Time) interface{} {
+	switch n := notification.(type) {
+	case addNotification:
+		n.distributed = now
+		return n
+	case updateNotification:
+		n.distributed = now
+		return n
+	case deleteNotification:
+		n.distributed = now
+		return n
+	}
+	return notification
+}
+
+// notificationDistributed returns when notification was handed to a
+// listener, or the zero time if it was not recorded.
+func notificationDistributed(notification interface{}) time.Time {
+	switch n := notification.(type) {
+	case addNotification:
+		return n.distributed
+	case updateNotification:
+		return n.distributed
+	case deleteNotification:
+		return n.distributed
+	}
+	return time.Time{}
+}
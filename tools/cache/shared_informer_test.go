@@ -17,17 +17,28 @@ limitations under the License.
 package cache
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime/pprof"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/clock"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
 	fcache "k8s.io/client-go/tools/cache/testing"
 )
 
@@ -263,3 +274,1670 @@ func TestSharedInformerInitializationRace(t *testing.T) {
 	go informer.Run(stop)
 	close(stop)
 }
+
+func TestStrictDeliveryOrderingSkipsStalledListeners(t *testing.T) {
+	informer := NewSharedInformer(nil, &v1.Pod{}, 0, WithStrictDeliveryOrdering(50*time.Millisecond)).(*sharedIndexInformer)
+	processor := informer.processor
+
+	received := make(chan interface{}, 1)
+	ready := newProcessListener(ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { received <- obj },
+	}, 0, 0, time.Now(), initialBufferSize)
+	// stalled is never started, so it never accepts a notification.
+	stalled := newProcessListener(ResourceEventHandlerFuncs{}, 0, 0, time.Now(), initialBufferSize)
+	processor.addListener(stalled)
+	processor.addListener(ready)
+
+	var wg wait.Group
+	defer wg.Wait()
+	defer close(ready.addCh)
+	wg.Start(ready.run)
+	wg.Start(ready.pop)
+
+	done := make(chan struct{})
+	go func() {
+		processor.distribute(addNotification{newObj: "obj"}, false)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatalf("distribute blocked on a stalled listener")
+	}
+	select {
+	case obj := <-received:
+		if obj != "obj" {
+			t.Errorf("expected obj, got %v", obj)
+		}
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatalf("ready listener did not receive the notification")
+	}
+	if added := stalled.added; added != 0 {
+		t.Errorf("expected the stalled listener not to count the dropped notification, got %d", added)
+	}
+}
+
+func TestSharedInformerStopReason(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	informer := NewSharedInformer(source, &v1.Pod{}, 0).(*sharedIndexInformer)
+	if err := informer.StopReason(); err != nil {
+		t.Errorf("expected no stop reason before start, got %v", err)
+	}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		informer.Run(stop)
+	}()
+	WaitForCacheSync(stop, informer.HasSynced)
+	if err := informer.StopReason(); err != nil {
+		t.Errorf("expected no stop reason while running, got %v", err)
+	}
+	close(stop)
+	<-done
+	if err := informer.StopReason(); err != ErrInformerStopRequested {
+		t.Errorf("expected %v, got %v", ErrInformerStopRequested, err)
+	}
+
+	// The panic is re-raised once it has been recorded.  Unwinding waits
+	// for the reflector, so the stop channel is closed before panicking.
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}})
+	stop = make(chan struct{})
+	panicking := NewSharedIndexInformer(source, &v1.Pod{}, 0, Indexers{
+		"panic": func(obj interface{}) ([]string, error) {
+			close(stop)
+			panic("index function failed")
+		},
+	}).(*sharedIndexInformer)
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("expected the panic to be re-raised")
+			}
+		}()
+		panicking.Run(stop)
+	}()
+	if err, ok := panicking.StopReason().(*InformerPanicError); !ok || err.Value != "index function failed" {
+		t.Errorf("expected the panic to be recorded, got %v", panicking.StopReason())
+	}
+}
+
+func TestRemoveEventHandler(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}})
+	informer := NewSharedInformer(source, &v1.Pod{}, 0)
+
+	kept := newTestListener("kept", 0, "pod1", "pod2")
+	if _, err := informer.AddEventHandler(kept); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	removed := newTestListener("removed", 0, "pod1")
+	handle, err := informer.AddEventHandler(removed)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		informer.Run(stop)
+	}()
+	if !removed.ok() {
+		t.Fatalf("%s: expected %v, got %v", removed.name, removed.expectedItemNames, removed.receivedItemNames)
+	}
+
+	if err := informer.RemoveEventHandler(handle); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := informer.RemoveEventHandler(handle); err != nil {
+		t.Errorf("unexpected error removing a handler twice: %v", err)
+	}
+	if err := informer.RemoveEventHandler(nil); err == nil {
+		t.Errorf("expected an error removing an invalid registration")
+	}
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod2"}})
+	for _, listener := range []*testListener{kept, removed} {
+		if !listener.ok() {
+			t.Errorf("%s: expected %v, got %v", listener.name, listener.expectedItemNames, listener.receivedItemNames)
+		}
+	}
+
+	close(stop)
+	<-done
+	if _, err := informer.AddEventHandler(newTestListener("late", 0)); err == nil {
+		t.Errorf("expected an error adding a handler to a stopped informer")
+	}
+}
+
+func TestRemoveEventHandlerWhileStopping(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}})
+	informer := NewSharedInformer(source, &v1.Pod{}, 0).(*sharedIndexInformer)
+
+	stop := make(chan struct{})
+	handling := make(chan struct{})
+	var handle ResourceEventHandlerRegistration
+	var removeErr error
+	handle, err := informer.AddEventHandler(ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			close(handling)
+			for informer.StopReason() == nil {
+				time.Sleep(10 * time.Millisecond)
+			}
+			// Give the informer time to stop its handlers, waiting for
+			// this one.
+			time.Sleep(100 * time.Millisecond)
+			removeErr = informer.RemoveEventHandler(handle)
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		informer.Run(stop)
+	}()
+	<-handling
+	close(stop)
+	select {
+	case <-done:
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatalf("the informer did not stop")
+	}
+	if removeErr != nil {
+		t.Errorf("unexpected error: %v", removeErr)
+	}
+}
+
+func TestEventHandlerRegistrationHasSynced(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}})
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod2"}})
+	informer := NewSharedInformer(source, &v1.Pod{}, 0)
+
+	release := make(chan struct{})
+	blocked := ResourceEventHandlerFuncs{AddFunc: func(obj interface{}) { <-release }}
+	handle, err := informer.AddEventHandler(blocked)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if handle.HasSynced() {
+		t.Errorf("expected a handler of an informer that has not started not to be synced")
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	if !WaitForCacheSync(stop, informer.HasSynced) {
+		t.Fatalf("informer did not sync")
+	}
+	if handle.HasSynced() {
+		t.Errorf("expected a handler that has not handled the initial list not to be synced")
+	}
+	close(release)
+	if err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) { return handle.HasSynced(), nil }); err != nil {
+		t.Errorf("expected the handler to sync once it handled the initial list")
+	}
+
+	late := newTestListener("late", 0, "pod1", "pod2")
+	lateHandle, err := informer.AddEventHandler(late)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !WaitForCacheSync(stop, lateHandle.HasSynced) {
+		t.Fatalf("late handler did not sync")
+	}
+	if !late.satisfiedExpectations() {
+		t.Errorf("expected the late handler to have received %v when synced, got %v", late.expectedItemNames.List(), late.receivedItemNames)
+	}
+}
+
+func TestSharedIndexInformerTransform(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Annotations: map[string]string{"large": "value"}}})
+	informer := NewSharedIndexInformer(source, &v1.Pod{}, 0, Indexers{})
+	if err := informer.SetTransform(func(obj interface{}) (interface{}, error) {
+		pod := obj.(*v1.Pod)
+		pod.Annotations = nil
+		pod.Labels = map[string]string{"transformed": "true"}
+		return pod, nil
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	received := make(chan *v1.Pod, 10)
+	informer.AddEventHandler(ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { received <- obj.(*v1.Pod) },
+		UpdateFunc: func(old, new interface{}) { received <- new.(*v1.Pod) },
+	})
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	if !WaitForCacheSync(stop, informer.HasSynced) {
+		t.Fatalf("informer did not sync")
+	}
+	if err := informer.SetTransform(nil); err == nil {
+		t.Errorf("expected an error setting the transform of a running informer")
+	}
+
+	source.Modify(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Annotations: map[string]string{"large": "other"}}})
+	for i := 0; i < 2; i++ {
+		select {
+		case pod := <-received:
+			if len(pod.Annotations) != 0 || pod.Labels["transformed"] != "true" {
+				t.Errorf("expected a transformed pod to be distributed, got %v", pod.ObjectMeta)
+			}
+		case <-time.After(wait.ForeverTestTimeout):
+			t.Fatalf("timed out waiting for notifications")
+		}
+	}
+	for _, obj := range informer.GetStore().List() {
+		if pod := obj.(*v1.Pod); len(pod.Annotations) != 0 || pod.Labels["transformed"] != "true" {
+			t.Errorf("expected a transformed pod to be stored, got %v", pod.ObjectMeta)
+		}
+	}
+}
+
+type recordingHandler struct {
+	lock   sync.Mutex
+	events []string
+}
+
+func (h *recordingHandler) record(event string, obj interface{}) {
+	key, _ := DeletionHandlingMetaNamespaceKeyFunc(obj)
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.events = append(h.events, event+" "+key)
+}
+
+func (h *recordingHandler) OnAdd(obj interface{})         { h.record("add", obj) }
+func (h *recordingHandler) OnUpdate(old, new interface{}) { h.record("update", new) }
+func (h *recordingHandler) OnDelete(obj interface{})      { h.record("delete", obj) }
+
+func (h *recordingHandler) waitFor(t *testing.T, expected ...string) {
+	err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		h.lock.Lock()
+		defer h.lock.Unlock()
+		return len(h.events) >= len(expected), nil
+	})
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	// Only the notifications of a single object are ordered.
+	if err != nil || len(h.events) != len(expected) || !sets.NewString(h.events...).Equal(sets.NewString(expected...)) {
+		t.Errorf("expected events %v, got %v", expected, h.events)
+	}
+}
+
+func TestAddEventHandlerWithFilter(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Labels: map[string]string{"app": "a"}}})
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod2", Labels: map[string]string{"app": "b"}}})
+	informer := NewSharedInformer(source, &v1.Pod{}, 0).(*sharedIndexInformer)
+	isA := func(obj interface{}) bool {
+		if d, ok := obj.(DeletedFinalStateUnknown); ok {
+			obj = d.Obj
+		}
+		return obj.(*v1.Pod).Labels["app"] == "a"
+	}
+
+	early := &recordingHandler{}
+	if _, err := informer.AddEventHandlerWithOptions(early, HandlerOptions{Filter: isA}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	if !WaitForCacheSync(stop, informer.HasSynced) {
+		t.Fatalf("informer did not sync")
+	}
+	late := &recordingHandler{}
+	if _, err := informer.AddEventHandlerWithOptions(late, HandlerOptions{Filter: isA}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// pod2 starts passing the filter, pod1 stops passing it.
+	source.Modify(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod2", Labels: map[string]string{"app": "a"}}})
+	source.Modify(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Labels: map[string]string{"app": "b"}}})
+	source.Modify(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod2", Labels: map[string]string{"app": "a", "version": "2"}}})
+	source.Delete(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Labels: map[string]string{"app": "b"}}})
+	source.Delete(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod2", Labels: map[string]string{"app": "a", "version": "2"}}})
+
+	early.waitFor(t, "add pod1", "add pod2", "delete pod1", "update pod2", "delete pod2")
+	late.waitFor(t, "add pod1", "add pod2", "delete pod1", "update pod2", "delete pod2")
+}
+
+func TestNamedEventHandlerProfileLabel(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}})
+	informer := NewSharedInformer(source, &v1.Pod{}, 0).(*sharedIndexInformer)
+
+	handling := make(chan struct{})
+	release := make(chan struct{})
+	if _, err := informer.AddEventHandlerWithOptions(ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			close(handling)
+			<-release
+		},
+	}, HandlerOptions{Name: "pod-watcher"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	defer close(release)
+	go informer.Run(stop)
+
+	select {
+	case <-handling:
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatalf("timed out waiting for the handler")
+	}
+	var profile bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&profile, 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if label := fmt.Sprintf("%q:%q", HandlerProfileLabel, "pod-watcher"); !strings.Contains(profile.String(), label) {
+		t.Errorf("expected the goroutine profile to contain the label %s", label)
+	}
+}
+
+func TestSharedInformerWatchErrorHandler(t *testing.T) {
+	forbidden := apierrors.NewForbidden(v1.Resource("pods"), "", fmt.Errorf("no access"))
+	lw := &testLW{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return nil, forbidden
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return nil, forbidden
+		},
+	}
+	informer := NewSharedInformer(lw, &v1.Pod{}, 0).(*sharedIndexInformer)
+	stop := make(chan struct{})
+	var handled error
+	if err := informer.SetWatchErrorHandler(func(r *Reflector, err error) {
+		handled = err
+		close(stop)
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		informer.Run(stop)
+	}()
+	select {
+	case <-done:
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatalf("expected the handler to stop the informer")
+	}
+	if listErr, ok := handled.(*ListError); !ok || !apierrors.IsForbidden(listErr.Err) {
+		t.Errorf("expected a ListError caused by a Forbidden error, got %v", handled)
+	}
+	if err := informer.SetWatchErrorHandler(nil); err == nil {
+		t.Errorf("expected an error setting the watch error handler of a started informer")
+	}
+}
+
+func TestSharedInformerRunWithContext(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}})
+	informer := NewSharedInformer(source, &v1.Pod{}, 0).(*sharedIndexInformer)
+
+	handling := make(chan struct{})
+	release := make(chan struct{})
+	informer.AddEventHandler(ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			close(handling)
+			<-release
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	result := make(chan error, 1)
+	go func() {
+		result <- informer.RunWithContext(ctx)
+	}()
+	<-handling
+	cancel()
+
+	select {
+	case <-informer.Done():
+		t.Fatalf("expected the informer not to be done while a handler is running")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(release)
+	select {
+	case <-informer.Done():
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatalf("expected the informer to be done once the handler returned")
+	}
+	if err := <-result; err != ErrInformerStopRequested {
+		t.Errorf("expected %v, got %v", ErrInformerStopRequested, err)
+	}
+}
+
+type recordingMetric struct {
+	lock         sync.Mutex
+	value        float64
+	observations int
+}
+
+func (m *recordingMetric) Inc() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.value++
+}
+func (m *recordingMetric) Set(value float64) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.value = value
+}
+func (m *recordingMetric) Observe(float64) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.observations++
+}
+func (m *recordingMetric) get() float64 {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.value
+}
+func (m *recordingMetric) count() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.observations
+}
+
+func TestSharedInformerMetrics(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	for _, name := range []string{"pod1", "pod2", "pod3"} {
+		source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name}})
+	}
+	informer := NewSharedInformer(source, &v1.Pod{}, 0).(*sharedIndexInformer)
+	handleDeltas := &recordingMetric{}
+	informer.metrics = &informerMetrics{resyncs: &recordingMetric{}, handleDeltasDuration: handleDeltas}
+
+	release := make(chan struct{})
+	handle, err := informer.AddEventHandler(ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { <-release },
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pending, latency, duration := &recordingMetric{}, &recordingMetric{}, &recordingMetric{}
+	handle.(*processorListener).metrics = &listenerMetrics{pendingNotifications: pending, distributionLatency: latency, handlerDuration: duration}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	if !WaitForCacheSync(stop, informer.HasSynced) {
+		t.Fatalf("informer did not sync")
+	}
+	if value := pending.get(); value != 3 {
+		t.Errorf("expected 3 pending notifications, got %v", value)
+	}
+	close(release)
+	if !WaitForCacheSync(stop, handle.HasSynced) {
+		t.Fatalf("handler did not sync")
+	}
+	if value := pending.get(); value != 0 {
+		t.Errorf("expected no pending notifications, got %v", value)
+	}
+	if latency.count() != 3 || duration.count() != 3 {
+		t.Errorf("expected 3 latency and duration observations, got %d and %d", latency.count(), duration.count())
+	}
+	if handleDeltas.count() != 3 {
+		t.Errorf("expected 3 HandleDeltas observations, got %d", handleDeltas.count())
+	}
+}
+
+func TestSharedInformerWatchBookmarks(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}})
+	informer := NewSharedInformer(source, &v1.Pod{}, 0)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	if !WaitForCacheSync(stop, informer.HasSynced) {
+		t.Fatalf("informer did not sync")
+	}
+
+	// A change the watch does not deliver, as if filtered out by the server,
+	// followed by a bookmark.
+	source.ModifyDropWatch(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Labels: map[string]string{"dropped": "true"}}})
+	source.Bookmark(&v1.Pod{})
+	err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		return informer.LastSyncResourceVersion() == "2", nil
+	})
+	if err != nil {
+		t.Fatalf("expected the bookmark to advance the resource version, got %q", informer.LastSyncResourceVersion())
+	}
+	obj, _, _ := informer.GetStore().GetByKey("pod1")
+	if len(obj.(*v1.Pod).Labels) != 0 {
+		t.Errorf("expected the bookmark not to change the cache, got %v", obj)
+	}
+}
+
+func TestSharedInformerPageSize(t *testing.T) {
+	pods := make([]v1.Pod, 5)
+	for i := range pods {
+		pods[i] = v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("pod-%d", i)}}
+	}
+	lw := &testLW{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			if options.Limit != 2 || options.ResourceVersion != "0" {
+				return nil, fmt.Errorf("expected a limit of 2 at resource version 0, got %d at %q", options.Limit, options.ResourceVersion)
+			}
+			start := 0
+			if len(options.Continue) > 0 {
+				fmt.Sscan(options.Continue, &start)
+			}
+			list := &v1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: "1"}}
+			end := start + 2
+			if end < len(pods) {
+				list.Continue = fmt.Sprint(end)
+			} else {
+				end = len(pods)
+			}
+			list.Items = pods[start:end]
+			return list, nil
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return watch.NewFake(), nil
+		},
+	}
+	informer := NewSharedInformer(lw, &v1.Pod{}, 0, WithPageSize(2))
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	if !WaitForCacheSync(stop, informer.HasSynced) {
+		t.Fatalf("informer did not sync")
+	}
+	if keys := informer.GetStore().ListKeys(); len(keys) != len(pods) {
+		t.Errorf("expected %d pods, got %v", len(pods), keys)
+	}
+}
+
+func TestSharedInformerWatchList(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}})
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod2"}})
+	lw := &ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return nil, fmt.Errorf("unexpected list")
+		},
+		WatchFunc:              source.Watch,
+		InitialEventsWatchFunc: source.WatchWithInitialEvents,
+	}
+	informer := NewSharedInformer(lw, &v1.Pod{}, 0, WithWatchList())
+	handler := &recordingHandler{}
+	informer.AddEventHandler(handler)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	if !WaitForCacheSync(stop, informer.HasSynced) {
+		t.Fatalf("informer did not sync")
+	}
+	if rv := informer.LastSyncResourceVersion(); rv != "2" {
+		t.Errorf("expected the informer to sync at resource version 2, got %q", rv)
+	}
+
+	// The initial events watch goes on once they have ended.
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod3"}})
+	handler.waitFor(t, "add pod1", "add pod2", "add pod3")
+}
+
+func TestSharedInformerObjectFreezing(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}})
+	informer := NewSharedInformer(source, &v1.Pod{}, 0, WithObjectFreezing())
+
+	// mutate is a careless handler labelling every object it gets.
+	mutate := func(obj interface{}) {
+		if pod, ok := obj.(*v1.Pod); ok {
+			pod.Labels = map[string]string{"mutated": "true"}
+		}
+	}
+	handler := &recordingHandler{}
+	informer.AddEventHandler(ResourceEventHandlerFuncs{
+		AddFunc:    mutate,
+		UpdateFunc: func(oldObj, newObj interface{}) { mutate(oldObj); mutate(newObj) },
+	})
+	informer.AddEventHandler(handler)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	if !WaitForCacheSync(stop, informer.HasSynced) {
+		t.Fatalf("informer did not sync")
+	}
+	source.Modify(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}})
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod2"}})
+	handler.waitFor(t, "add pod1", "update pod1", "add pod2")
+	// A late handler gets the objects of the cache.
+	late := &recordingHandler{}
+	informer.AddEventHandler(ResourceEventHandlerFuncs{AddFunc: func(obj interface{}) {
+		mutate(obj)
+		late.OnAdd(obj)
+	}})
+	late.waitFor(t, "add pod1", "add pod2")
+
+	for _, obj := range informer.GetStore().List() {
+		if pod := obj.(*v1.Pod); len(pod.Labels) != 0 {
+			t.Errorf("expected the handlers not to mutate the cache, got %v", pod.ObjectMeta)
+		}
+	}
+}
+
+// progressRecordingHandler records its notifications, including the sync
+// progress, in order.
+type progressRecordingHandler struct {
+	recordingHandler
+}
+
+func (h *progressRecordingHandler) OnAdd(obj interface{}) { h.record("add", obj) }
+func (h *progressRecordingHandler) OnSyncProgress(resourceVersion string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.events = append(h.events, "progress "+resourceVersion)
+}
+
+func TestSharedInformerResyncJitter(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	informer := NewSharedInformer(source, &v1.Pod{}, time.Hour, WithResyncJitter(0.1)).(*sharedIndexInformer)
+	clock := clock.NewFakeClock(time.Now())
+	informer.clock = clock
+	informer.processor.clock = clock
+
+	nextResyncs := sets.NewInt64()
+	for i := 0; i < 20; i++ {
+		informer.AddEventHandler(&recordingHandler{})
+	}
+	for _, listener := range informer.processor.listeners {
+		delay := listener.nextResync.Sub(clock.Now())
+		if delay < 54*time.Minute || delay > 66*time.Minute {
+			t.Errorf("expected the next resync within 10%% of an hour, got %v", delay)
+		}
+		nextResyncs.Insert(int64(delay))
+	}
+	if nextResyncs.Len() < 2 {
+		t.Errorf("expected the resyncs of the handlers to be spread, got %v", nextResyncs.List())
+	}
+}
+
+func TestSharedInformerSetResyncPeriods(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}})
+	informer := NewSharedInformer(source, &v1.Pod{}, 0).(*sharedIndexInformer)
+	fakeClock := clock.NewFakeClock(time.Now())
+	informer.clock = fakeClock
+	informer.processor.clock = fakeClock
+
+	handler := &recordingHandler{}
+	registration, err := informer.AddEventHandler(handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := &recordingHandler{}
+	informer.AddEventHandler(other)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	handler.waitFor(t, "add pod1")
+	other.waitFor(t, "add pod1")
+
+	waitForTimers := func(expected bool) {
+		t.Helper()
+		err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+			return fakeClock.HasWaiters() == expected, nil
+		})
+		if err != nil {
+			t.Fatalf("expected the resync timer running=%v", expected)
+		}
+	}
+
+	// The informer does not resync until a handler asks for it, which
+	// starts the resync timer of the running reflector.
+	if err := informer.SetHandlerResyncPeriod(registration, time.Minute); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := time.Minute, informer.resyncCheckPeriod; e != a {
+		t.Errorf("expected a resync check period of %v, got %v", e, a)
+	}
+	waitForTimers(true)
+	fakeClock.Step(time.Minute)
+	handler.waitFor(t, "add pod1", "update pod1")
+
+	// Disabling resyncs stops the timer, and leaves the handlers alone.
+	informer.SetResyncCheckPeriod(0)
+	waitForTimers(false)
+	other.lock.Lock()
+	if len(other.events) != 1 {
+		t.Errorf("expected the other handler not to resync, got %v", other.events)
+	}
+	other.lock.Unlock()
+
+	informer.RemoveEventHandler(registration)
+	if err := informer.SetHandlerResyncPeriod(registration, time.Minute); err == nil {
+		t.Errorf("expected an error changing the resync period of a removed handler")
+	}
+}
+
+func TestSharedInformerHandlerPriority(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	informer := NewSharedInformer(source, &v1.Pod{}, 0).(*sharedIndexInformer)
+
+	release := make(chan struct{})
+	slow := ResourceEventHandlerFuncs{AddFunc: func(interface{}) { <-release }}
+	critical := &recordingHandler{}
+	normal := &recordingHandler{}
+	for _, handler := range []struct {
+		name     string
+		handler  ResourceEventHandler
+		priority HandlerPriority
+	}{
+		{"slow", slow, HandlerPriorityLow},
+		{"normal-1", normal, HandlerPriorityNormal},
+		{"critical", critical, HandlerPriorityHigh},
+		{"normal-2", &recordingHandler{}, HandlerPriorityNormal},
+	} {
+		if _, err := informer.AddEventHandlerWithOptions(handler.handler, HandlerOptions{Name: handler.name, Priority: handler.priority}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Notifications are handed to higher priorities first, and to handlers
+	// of the same priority in the order they were added.
+	expected := []string{"critical", "normal-1", "normal-2", "slow"}
+	for _, listeners := range [][]*processorListener{informer.processor.listeners, informer.processor.syncingListeners} {
+		var names []string
+		for _, listener := range listeners {
+			names = append(names, listener.name)
+		}
+		if !reflect.DeepEqual(names, expected) {
+			t.Errorf("expected listeners %v, got %v", expected, names)
+		}
+	}
+
+	// A slow handler does not hold back the others.
+	stop := make(chan struct{})
+	defer close(stop)
+	defer close(release)
+	go informer.Run(stop)
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}})
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod2"}})
+	critical.waitFor(t, "add pod1", "add pod2")
+	normal.waitFor(t, "add pod1", "add pod2")
+}
+
+func TestSharedInformerReplacedDeltas(t *testing.T) {
+	pod := func(name, resourceVersion string) v1.Pod {
+		return v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, ResourceVersion: resourceVersion}}
+	}
+	var lock sync.Mutex
+	lists, watches := 0, 0
+	firstWatch, liveWatch := watch.NewFake(), watch.NewFake()
+	lw := &testLW{
+		ListFunc: func(metav1.ListOptions) (runtime.Object, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			lists++
+			if lists == 1 {
+				return &v1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: "1"}, Items: []v1.Pod{pod("pod1", "1"), pod("pod2", "1")}}, nil
+			}
+			return &v1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: "2"}, Items: []v1.Pod{pod("pod1", "2"), pod("pod2", "1")}}, nil
+		},
+		WatchFunc: func(metav1.ListOptions) (watch.Interface, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			watches++
+			switch watches {
+			case 1:
+				return firstWatch, nil
+			case 2:
+				// Makes the reflector relist.
+				return nil, fmt.Errorf("watch failed")
+			}
+			return liveWatch, nil
+		},
+	}
+	informer := NewSharedInformer(lw, &v1.Pod{}, 0, WithDeltaFIFOOptions(DeltaFIFOOptions{EmitDeltaTypeReplaced: true}))
+	handler := &recordingHandler{}
+	informer.AddEventHandler(handler)
+	originHandler := &updateOriginRecordingHandler{}
+	informer.AddEventHandler(originHandler)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	handler.waitFor(t, "add pod1", "add pod2")
+	// Nothing is being resynced from now on.
+	informer.(*sharedIndexInformer).processor.shouldResync()
+	firstWatch.Stop()
+
+	// The object the relist found changed is notified as an update,
+	// although the handler is not being resynced, and the unchanged object
+	// is not.
+	time.Sleep(100 * time.Millisecond)
+	handler.waitFor(t, "add pod1", "add pod2", "update pod1")
+	originHandler.waitFor(t, "add pod1", "add pod2", "relist update pod1")
+
+	// A watched update is told apart from the relist.
+	pod2 := pod("pod2", "3")
+	liveWatch.Modify(&pod2)
+	originHandler.waitFor(t, "add pod1", "add pod2", "relist update pod1", "watch update pod2")
+}
+
+// updateOriginRecordingHandler records its notifications, prefixing updates
+// with their origin.
+type updateOriginRecordingHandler struct {
+	recordingHandler
+}
+
+func (h *updateOriginRecordingHandler) OnUpdateWithOrigin(old, new interface{}, origin UpdateOrigin) {
+	prefix := map[UpdateOrigin]string{UpdateFromWatch: "watch", UpdateFromRelist: "relist", UpdateFromResync: "resync"}[origin]
+	h.record(prefix+" update", new)
+}
+
+func TestSharedInformerHandlerPanic(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	informer := NewSharedInformer(source, &v1.Pod{}, 0).(*sharedIndexInformer)
+
+	handler := &recordingHandler{}
+	panicking := ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if obj.(*v1.Pod).Name == "bad" {
+				panic("bad pod")
+			}
+			handler.OnAdd(obj)
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			if obj.(*v1.Pod).Name == "bad" {
+				panic("bad pod")
+			}
+			handler.OnUpdate(nil, obj)
+		},
+	}
+	panics := make(chan HandlerPanic, 10)
+	_, err := informer.AddEventHandlerWithOptions(panicking, HandlerOptions{
+		Name:         "panicking",
+		PanicHandler: func(p HandlerPanic) { panics <- p },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	waitForPanic := func(count int) {
+		select {
+		case p := <-panics:
+			if p.Handler != "panicking" || p.Key != "bad" || p.Value != "bad pod" || p.Count != count || len(p.Stack) == 0 {
+				t.Errorf("unexpected panic %d: %+v", count, p)
+			}
+		case <-time.After(wait.ForeverTestTimeout):
+			t.Fatalf("timed out waiting for panic %d", count)
+		}
+	}
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "bad"}})
+	waitForPanic(1)
+	source.Modify(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "bad"}})
+	waitForPanic(2)
+
+	// The notifications following a panic are still delivered.
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "good"}})
+	handler.waitFor(t, "add good")
+}
+
+// initialSyncRecordingHandler records its notifications, including the end
+// of the initial sync, in order.
+type initialSyncRecordingHandler struct {
+	recordingHandler
+}
+
+func (h *initialSyncRecordingHandler) OnInitialSyncDone() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.events = append(h.events, "initial sync done")
+}
+
+func (h *initialSyncRecordingHandler) indexOf(event string) int {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for i, e := range h.events {
+		if e == event {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestSharedInformerInitialSyncDone(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}})
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod2"}})
+	informer := NewSharedInformer(source, &v1.Pod{}, 0)
+	handler := &initialSyncRecordingHandler{}
+	informer.AddEventHandler(handler)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	handler.waitFor(t, "add pod1", "add pod2", "initial sync done")
+	if i := handler.indexOf("initial sync done"); i != 2 {
+		t.Errorf("expected the initial sync to end after the initial list, got %v", handler.events)
+	}
+
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod3"}})
+	handler.waitFor(t, "add pod1", "add pod2", "initial sync done", "add pod3")
+
+	// A handler added later is told once it has been replayed the cache.
+	late := &initialSyncRecordingHandler{}
+	informer.AddEventHandler(late)
+	late.waitFor(t, "add pod1", "add pod2", "add pod3", "initial sync done")
+	if i := late.indexOf("initial sync done"); i != 3 {
+		t.Errorf("expected the initial sync to end after the cache contents, got %v", late.events)
+	}
+}
+
+func TestSharedInformerSyncProgress(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}})
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod2"}})
+	informer := NewSharedInformer(source, &v1.Pod{}, 0)
+	handler := &progressRecordingHandler{}
+	informer.AddEventHandler(handler)
+	// Handlers without OnSyncProgress are not affected.
+	plain := &recordingHandler{}
+	informer.AddEventHandler(plain)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	if !WaitForCacheSync(stop, informer.HasSynced) {
+		t.Fatalf("informer did not sync")
+	}
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod3"}})
+	source.Bookmark(&v1.Pod{})
+	handler.waitFor(t, "add pod1", "add pod2", "progress 2", "add pod3", "progress 3")
+	plain.waitFor(t, "add pod1", "add pod2", "add pod3")
+
+	// The progress follows the notifications it covers.
+	handler.lock.Lock()
+	defer handler.lock.Unlock()
+	if events := handler.events; events[2] != "progress 2" || events[4] != "progress 3" {
+		t.Errorf("expected the progress to follow the notifications, got %v", events)
+	}
+}
+
+func TestSharedInformerStoreStats(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	for _, pod := range []*v1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "pod1"}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "pod2"}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "b", Name: "pod3"}},
+	} {
+		source.Add(pod)
+	}
+	informer := NewSharedIndexInformer(source, &v1.Pod{}, 0, Indexers{NamespaceIndex: MetaNamespaceIndexFunc})
+	if stats := informer.GetStoreStats(); stats.Objects != 0 || !stats.LastRelistTime.IsZero() {
+		t.Errorf("expected no objects and no relist before the informer starts, got %+v", stats)
+	}
+
+	start := time.Now()
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	if !WaitForCacheSync(stop, informer.HasSynced) {
+		t.Fatal("the informer did not sync")
+	}
+
+	stats := informer.GetStoreStats()
+	if stats.Objects != 3 {
+		t.Errorf("expected 3 objects, got %d", stats.Objects)
+	}
+	if stats.ApproximateBytes <= 0 {
+		t.Errorf("expected the size of the objects to be estimated, got %d", stats.ApproximateBytes)
+	}
+	if e, a := map[string]int{NamespaceIndex: 2}, stats.IndexCardinality; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected index cardinality %v, got %v", e, a)
+	}
+	if stats.LastRelistTime.Before(start) {
+		t.Errorf("expected a relist after %v, got %v", start, stats.LastRelistTime)
+	}
+}
+
+func TestSharedInformerPauseResume(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}})
+	informer := NewSharedInformer(source, &v1.Pod{}, 0).(*sharedIndexInformer)
+	handler := &recordingHandler{}
+	informer.AddEventHandler(handler)
+	informer.Pause()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	if !WaitForCacheSync(stop, informer.HasSynced) {
+		t.Fatal("the informer did not sync")
+	}
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod2"}})
+	err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		_, exists, err := informer.GetStore().GetByKey("pod2")
+		return exists, err
+	})
+	if err != nil {
+		t.Fatalf("the cache was not updated while paused: %v", err)
+	}
+	late := &recordingHandler{}
+	informer.AddEventHandler(late)
+
+	time.Sleep(100 * time.Millisecond)
+	for _, h := range []*recordingHandler{handler, late} {
+		h.lock.Lock()
+		if len(h.events) > 0 {
+			t.Errorf("expected no events while paused, got %v", h.events)
+		}
+		h.lock.Unlock()
+	}
+
+	informer.Resume()
+	handler.waitFor(t, "add pod1", "add pod2")
+	late.waitFor(t, "add pod1", "add pod2")
+}
+
+func TestSharedInformerSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "informer-snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "pods.json")
+
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}})
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod2"}})
+	saved := NewSharedIndexInformer(source, &v1.Pod{}, 0, Indexers{}).(*sharedIndexInformer)
+	if err := saved.SaveSnapshot(path); err == nil {
+		t.Error("expected an error saving a snapshot before the informer syncs")
+	}
+	stop := make(chan struct{})
+	go saved.Run(stop)
+	err = wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		return saved.SaveSnapshot(path) == nil, nil
+	})
+	close(stop)
+	if err != nil {
+		t.Fatalf("unable to save a snapshot: %v", err)
+	}
+	savedResourceVersion := saved.LastSyncResourceVersion()
+
+	// restore starts an informer from the snapshot, whose first watch fails
+	// with watchErr.  It returns the handler of the informer, its watcher,
+	// the resource versions it watches from and its stop channel.
+	restore := func(watchErr error) (*recordingHandler, *watch.FakeWatcher, chan string, chan struct{}) {
+		watcher := watch.NewFake()
+		watches := make(chan string, 10)
+		lw := &testLW{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				return &v1.PodList{
+					ListMeta: metav1.ListMeta{ResourceVersion: "100"},
+					Items:    []v1.Pod{{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}}},
+				}, nil
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				watches <- options.ResourceVersion
+				if options.ResourceVersion == savedResourceVersion && watchErr != nil {
+					return nil, watchErr
+				}
+				return watcher, nil
+			},
+		}
+		informer := NewSharedIndexInformer(lw, &v1.Pod{}, 0, Indexers{}).(*sharedIndexInformer)
+		if err := informer.LoadSnapshot(path); err != nil {
+			t.Fatalf("unable to load the snapshot: %v", err)
+		}
+		handler := &recordingHandler{}
+		informer.AddEventHandler(handler)
+		stop := make(chan struct{})
+		go informer.Run(stop)
+		return handler, watcher, watches, stop
+	}
+
+	handler, watcher, watches, stop := restore(nil)
+	defer close(stop)
+	handler.waitFor(t, "add pod1", "add pod2")
+	if rv := <-watches; rv != savedResourceVersion {
+		t.Errorf("expected a watch from resource version %q, got %q", savedResourceVersion, rv)
+	}
+	watcher.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod3", ResourceVersion: "101"}})
+	handler.waitFor(t, "add pod1", "add pod2", "add pod3")
+
+	handler, _, watches, expiredStop := restore(apierrors.NewResourceExpired("too old resource version"))
+	defer close(expiredStop)
+	handler.waitFor(t, "add pod1", "add pod2", "update pod1", "delete pod2")
+	if e, a := []string{savedResourceVersion, "100"}, []string{<-watches, <-watches}; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected watches from resource versions %v, got %v", e, a)
+	}
+}
+
+// countingIndexer is an Indexer counting the objects added to it.
+type countingIndexer struct {
+	Indexer
+	lock  sync.Mutex
+	added int
+}
+
+func (i *countingIndexer) Add(obj interface{}) error {
+	i.lock.Lock()
+	i.added++
+	i.lock.Unlock()
+	return i.Indexer.Add(obj)
+}
+
+func TestSharedInformerWithIndexer(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod1"}})
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod2"}})
+	indexer := &countingIndexer{Indexer: NewIndexer(DeletionHandlingMetaNamespaceKeyFunc, Indexers{})}
+	informer := NewSharedIndexInformer(source, &v1.Pod{}, 0, Indexers{NamespaceIndex: MetaNamespaceIndexFunc}, WithIndexer(indexer))
+	if informer.GetIndexer() != indexer {
+		t.Fatalf("expected the informer to use the indexer passed to WithIndexer")
+	}
+	if _, exists := indexer.GetIndexers()[NamespaceIndex]; !exists {
+		t.Errorf("expected the indexers of the informer to be added to the indexer")
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	if !WaitForCacheSync(stop, informer.HasSynced) {
+		t.Fatal("the informer did not sync")
+	}
+	source.Delete(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod2"}})
+	err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		keys, err := indexer.IndexKeys(NamespaceIndex, "ns")
+		return len(keys) == 1, err
+	})
+	if err != nil {
+		t.Errorf("expected the indexer to be kept up to date: %v", err)
+	}
+	indexer.lock.Lock()
+	defer indexer.lock.Unlock()
+	if indexer.added != 2 {
+		t.Errorf("expected 2 objects added to the indexer, got %d", indexer.added)
+	}
+}
+
+func TestSharedInformerWithIndexerAndStoreOption(t *testing.T) {
+	for name, options := range map[string]func(Indexer) []SharedIndexInformerOption{
+		"WithIndexer first": func(indexer Indexer) []SharedIndexInformerOption {
+			return []SharedIndexInformerOption{WithIndexer(indexer), WithKeyFilter(NewKeyFilter(10, 0.01))}
+		},
+		"WithIndexer last": func(indexer Indexer) []SharedIndexInformerOption {
+			return []SharedIndexInformerOption{WithKeyFunction(MetaNamespaceKeyFunc), WithIndexer(indexer)}
+		},
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("%s: expected combining WithIndexer and a store option to panic", name)
+				}
+			}()
+			indexer := NewIndexer(DeletionHandlingMetaNamespaceKeyFunc, Indexers{})
+			NewSharedIndexInformer(fcache.NewFakeControllerSource(), &v1.Pod{}, 0, Indexers{},
+				options(&countingIndexer{Indexer: indexer})...)
+		}()
+	}
+}
+
+func TestSharedInformerTTL(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}})
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod2"}})
+	informer := NewSharedInformer(source, &v1.Pod{}, 0, WithTTL(time.Minute)).(*sharedIndexInformer)
+	fakeClock := clock.NewFakeClock(time.Now())
+	informer.clock = fakeClock
+	informer.processor.clock = fakeClock
+	handler := &recordingHandler{}
+	informer.AddEventHandler(handler)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	handler.waitFor(t, "add pod1", "add pod2")
+
+	fakeClock.Step(30 * time.Second)
+	source.Modify(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod2"}})
+	handler.waitFor(t, "add pod1", "add pod2", "update pod2")
+
+	// Only pod1 has not been written for more than a minute.
+	fakeClock.Step(40 * time.Second)
+	handler.waitFor(t, "add pod1", "add pod2", "update pod2", "delete pod1")
+	if keys := informer.GetStore().ListKeys(); !reflect.DeepEqual(keys, []string{"pod2"}) {
+		t.Errorf("expected only pod2 to be left, got %v", keys)
+	}
+}
+
+func TestWaitForCacheSyncWithContext(t *testing.T) {
+	var lock sync.Mutex
+	podsSynced := false
+	cacheSyncs := map[string]InformerSynced{
+		"pods": func() bool {
+			lock.Lock()
+			defer lock.Unlock()
+			return podsSynced
+		},
+		"nodes":    func() bool { return true },
+		"services": func() bool { return false },
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	err := WaitForCacheSyncWithContext(ctx, cacheSyncs)
+	syncErr, ok := err.(*CacheSyncError)
+	if !ok {
+		t.Fatalf("expected a *CacheSyncError, got %v", err)
+	}
+	if e := []string{"pods", "services"}; !reflect.DeepEqual(e, syncErr.Unsynced) || syncErr.Err != context.DeadlineExceeded {
+		t.Errorf("expected caches %v to time out, got %v", e, err)
+	}
+
+	lock.Lock()
+	podsSynced = true
+	lock.Unlock()
+	delete(cacheSyncs, "services")
+	if err := WaitForCacheSyncWithContext(context.Background(), cacheSyncs); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSharedInformerSkipResyncs(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}})
+	informer := NewSharedInformer(source, &v1.Pod{}, time.Minute).(*sharedIndexInformer)
+	fakeClock := clock.NewFakeClock(time.Now())
+	informer.clock = fakeClock
+	informer.processor.clock = fakeClock
+
+	handler := &recordingHandler{}
+	informer.AddEventHandler(handler)
+	skipping := &recordingHandler{}
+	informer.AddEventHandlerWithOptions(skipping, HandlerOptions{SkipResyncs: true})
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	handler.waitFor(t, "add pod1")
+	skipping.waitFor(t, "add pod1")
+
+	err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		return fakeClock.HasWaiters(), nil
+	})
+	if err != nil {
+		t.Fatal("expected the resync timer to run")
+	}
+	fakeClock.Step(time.Minute)
+	handler.waitFor(t, "add pod1", "update pod1")
+
+	source.Modify(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}})
+	handler.waitFor(t, "add pod1", "update pod1", "update pod1")
+	skipping.waitFor(t, "add pod1", "update pod1")
+}
+
+func TestSharedInformerWorkers(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "slow"}})
+	informer := NewSharedInformer(source, &v1.Pod{}, 0).(*sharedIndexInformer)
+
+	// The add of slow blocks until fast is added, which a single goroutine
+	// would never get to.
+	unblock := make(chan struct{})
+	handler := &recordingHandler{}
+	informer.AddEventHandlerWithOptions(ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			switch obj.(*v1.Pod).Name {
+			case "slow":
+				select {
+				case <-unblock:
+				case <-time.After(wait.ForeverTestTimeout):
+					t.Errorf("expected the add of fast to be handled while the add of slow is in progress")
+				}
+			case "fast":
+				close(unblock)
+			}
+			handler.record("add", obj)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			handler.record("update", newObj)
+		},
+	}, HandlerOptions{Workers: 2})
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	if !WaitForCacheSync(stop, informer.HasSynced) {
+		t.Fatal("informer did not sync")
+	}
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "fast"}})
+	source.Modify(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "slow"}})
+	handler.waitFor(t, "add fast", "add slow", "update slow")
+}
+
+func TestSharedInformerPriorState(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "changed"}})
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "new"}})
+	informer := NewSharedInformer(source, &v1.Pod{}, 0).(*sharedIndexInformer)
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	if !WaitForCacheSync(stop, informer.HasSynced) {
+		t.Fatal("informer did not sync")
+	}
+
+	handler := &recordingHandler{}
+	var oldResourceVersion string
+	informer.AddEventHandlerWithOptions(ResourceEventHandlerFuncs{
+		AddFunc: handler.OnAdd,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldResourceVersion = oldObj.(*v1.Pod).ResourceVersion
+			handler.OnUpdate(oldObj, newObj)
+		},
+		DeleteFunc: handler.OnDelete,
+	}, HandlerOptions{PriorState: map[string]interface{}{
+		"changed": &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "changed", ResourceVersion: "prior"}},
+		"gone":    &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "gone"}},
+	}})
+	handler.waitFor(t, "update changed", "add new", "delete gone")
+	handler.lock.Lock()
+	defer handler.lock.Unlock()
+	if e, a := "prior", oldResourceVersion; e != a {
+		t.Errorf("expected the update from resource version %q, got %q", e, a)
+	}
+}
+
+func TestSharedInformerMaxObjects(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	exceeded := make(chan int, 10)
+	informer := NewSharedIndexInformer(source, &v1.Pod{}, 0, Indexers{}, WithMaxObjects(2, func(objects int) {
+		exceeded <- objects
+	})).(*sharedIndexInformer)
+	handler := &recordingHandler{}
+	informer.AddEventHandler(handler)
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+
+	pod := func(name string) *v1.Pod {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	}
+	expectExceeded := func(expected int) {
+		t.Helper()
+		select {
+		case objects := <-exceeded:
+			if objects != expected {
+				t.Errorf("expected the limit to be exceeded with %d objects, got %d", expected, objects)
+			}
+		case <-time.After(wait.ForeverTestTimeout):
+			t.Fatalf("expected the limit to be exceeded with %d objects", expected)
+		}
+	}
+	for _, name := range []string{"a", "b", "c", "d"} {
+		source.Add(pod(name))
+	}
+	handler.waitFor(t, "add a", "add b", "add c", "add d")
+	expectExceeded(3)
+
+	source.Delete(pod("c"))
+	source.Delete(pod("d"))
+	source.Add(pod("e"))
+	handler.waitFor(t, "add a", "add b", "add c", "add d", "delete c", "delete d", "add e")
+	expectExceeded(3)
+	select {
+	case objects := <-exceeded:
+		t.Errorf("expected the limit to be exceeded twice, got another call with %d objects", objects)
+	default:
+	}
+}
+
+// listKeysCountingIndexer counts the calls to ListKeys.
+type listKeysCountingIndexer struct {
+	Indexer
+	lock     sync.Mutex
+	listKeys int
+}
+
+func (i *listKeysCountingIndexer) ListKeys() []string {
+	i.lock.Lock()
+	i.listKeys++
+	i.lock.Unlock()
+	return i.Indexer.ListKeys()
+}
+
+func TestSharedInformerMaxObjectsCountsOnce(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	indexer := &listKeysCountingIndexer{Indexer: NewIndexer(DeletionHandlingMetaNamespaceKeyFunc, Indexers{})}
+	exceeded := make(chan int, 10)
+	informer := NewSharedIndexInformer(source, &v1.Pod{}, 0, Indexers{}, WithIndexer(indexer), WithMaxObjects(3, func(objects int) {
+		exceeded <- objects
+	}))
+	handler := &recordingHandler{}
+	informer.AddEventHandler(handler)
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	if !WaitForCacheSync(stop, informer.HasSynced) {
+		t.Fatal("informer did not sync")
+	}
+	// Not counting the list of the relist.
+	indexer.lock.Lock()
+	indexer.listKeys = 0
+	indexer.lock.Unlock()
+
+	for _, name := range []string{"a", "b", "c", "d"} {
+		source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name}})
+	}
+	handler.waitFor(t, "add a", "add b", "add c", "add d")
+	source.Delete(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "d"}})
+	handler.waitFor(t, "add a", "add b", "add c", "add d", "delete d")
+	select {
+	case objects := <-exceeded:
+		if objects != 4 {
+			t.Errorf("expected the limit to be exceeded with 4 objects, got %d", objects)
+		}
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatalf("expected the limit to be exceeded")
+	}
+	// The objects are counted once, not listed on every change.
+	indexer.lock.Lock()
+	defer indexer.lock.Unlock()
+	if indexer.listKeys != 1 {
+		t.Errorf("expected the objects to be listed once, got %d lists", indexer.listKeys)
+	}
+}
+
+func TestSharedInformerKeyFunction(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod1", Labels: map[string]string{"tenant": "a"}}})
+	keyFunc := func(obj interface{}) (string, error) {
+		pod := obj.(*v1.Pod)
+		return pod.Labels["tenant"] + ":" + pod.Name, nil
+	}
+	informer := NewSharedIndexInformer(source, &v1.Pod{}, 0, Indexers{}, WithKeyFunction(keyFunc)).(*sharedIndexInformer)
+	handler := &recordingHandler{}
+	informer.AddEventHandler(handler)
+	panicKeys := make(chan string, 1)
+	informer.AddEventHandlerWithOptions(ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { panic("handler failure") },
+	}, HandlerOptions{PanicHandler: func(panic HandlerPanic) {
+		select {
+		case panicKeys <- panic.Key:
+		default:
+		}
+	}})
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	handler.waitFor(t, "add ns/pod1")
+	select {
+	case key := <-panicKeys:
+		if key != "a:pod1" {
+			t.Errorf("expected the panic to be reported with the key of the informer, got %q", key)
+		}
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatalf("expected the panic to be reported")
+	}
+
+	if _, exists, _ := informer.GetStore().GetByKey("a:pod1"); !exists {
+		t.Errorf("expected pod1 to be keyed by its tenant, got keys %v", informer.GetStore().ListKeys())
+	}
+	source.Delete(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod1", Labels: map[string]string{"tenant": "a"}}})
+	handler.waitFor(t, "add ns/pod1", "delete ns/pod1")
+	if keys := informer.GetStore().ListKeys(); len(keys) != 0 {
+		t.Errorf("expected the cache to be empty, got %v", keys)
+	}
+}
+
+func TestSharedInformerLabelIndex(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod1", Labels: map[string]string{"app": "frontend"}}})
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod2", Labels: map[string]string{"app": "backend"}}})
+	informer := NewSharedIndexInformer(source, &v1.Pod{}, 0, Indexers{NamespaceIndex: MetaNamespaceIndexFunc}, WithLabelIndex())
+	handler := &recordingHandler{}
+	informer.AddEventHandler(handler)
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	handler.waitFor(t, "add ns/pod1", "add ns/pod2")
+
+	objs, err := informer.GetIndexer().ByIndex(LabelIndex, LabelIndexValue("app", "frontend"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(objs) != 1 || objs[0].(*v1.Pod).Name != "pod1" {
+		t.Errorf("expected pod1, got %v", objs)
+	}
+	if _, exists := informer.GetIndexer().GetIndexers()[NamespaceIndex]; !exists {
+		t.Errorf("expected the informer to keep its namespace index")
+	}
+}
+
+// batchRecordingHandler records the sizes of its batches.
+type batchRecordingHandler struct {
+	recordingHandler
+}
+
+func (h *batchRecordingHandler) OnAddBatch(objs []interface{}) {
+	h.record(fmt.Sprintf("add %d", len(objs)), nil)
+}
+
+func (h *batchRecordingHandler) OnUpdateBatch(updates []ObjectUpdate) {
+	h.record(fmt.Sprintf("update %d", len(updates)), nil)
+}
+
+func (h *batchRecordingHandler) OnDeleteBatch(objs []interface{}) {
+	h.record(fmt.Sprintf("delete %d", len(objs)), nil)
+}
+
+func TestSharedInformerBatchHandler(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	for _, name := range []string{"a", "b", "c"} {
+		source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name}})
+	}
+	informer := NewSharedInformer(source, &v1.Pod{}, 0).(*sharedIndexInformer)
+	handler := &batchRecordingHandler{}
+	informer.AddEventHandlerWithOptions(handler, HandlerOptions{BatchSize: 3, BatchDelay: time.Second})
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	handler.waitFor(t, "add 3 ")
+
+	source.Modify(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "a"}})
+	source.Delete(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "b"}})
+	source.Delete(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "c"}})
+	handler.waitFor(t, "add 3 ", "update 1 ", "delete 2 ")
+}
+
+// detailsRecordingHandler records the details of its notifications.
+type detailsRecordingHandler struct {
+	recordingHandler
+	details []string
+}
+
+func (h *detailsRecordingHandler) recordDetails(event string, obj interface{}, details EventDetails) {
+	h.record(event, obj)
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.details = append(h.details, fmt.Sprintf("%s %s %s->%s tombstone=%v", event, details.Type, details.OldResourceVersion, details.NewResourceVersion, details.Tombstone))
+}
+
+func (h *detailsRecordingHandler) OnAddDetailed(obj interface{}, details EventDetails) {
+	h.recordDetails("add", obj, details)
+}
+
+func (h *detailsRecordingHandler) OnUpdateDetailed(oldObj, newObj interface{}, details EventDetails) {
+	h.recordDetails("update", newObj, details)
+}
+
+func (h *detailsRecordingHandler) OnDeleteDetailed(obj interface{}, details EventDetails) {
+	h.recordDetails("delete", obj, details)
+}
+
+func TestSharedInformerDetailedHandler(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}})
+	informer := NewSharedInformer(source, &v1.Pod{}, 0)
+	handler := &detailsRecordingHandler{}
+	informer.AddEventHandler(handler)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	handler.waitFor(t, "add pod1")
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod2"}})
+	handler.waitFor(t, "add pod1", "add pod2")
+	source.Modify(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod2"}})
+	handler.waitFor(t, "add pod1", "add pod2", "update pod2")
+	source.Delete(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod2"}})
+	handler.waitFor(t, "add pod1", "add pod2", "update pod2", "delete pod2")
+
+	// The fake source bumps the resource version of every change.
+	expected := []string{
+		"add Sync ->1 tombstone=false",
+		"add Added ->2 tombstone=false",
+		"update Updated 2->3 tombstone=false",
+		"delete Deleted 4-> tombstone=false",
+	}
+	handler.lock.Lock()
+	defer handler.lock.Unlock()
+	if !reflect.DeepEqual(expected, handler.details) {
+		t.Errorf("expected details %v, got %v", expected, handler.details)
+	}
+}
+
+func TestResourceEventHandlerDetailedFuncsTombstone(t *testing.T) {
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", ResourceVersion: "7"}}
+	var got []string
+	handler := ResourceEventHandlerDetailedFuncs{
+		DeleteFunc: func(obj interface{}, details EventDetails) {
+			got = append(got, fmt.Sprintf("%s %s %v", obj.(*v1.Pod).Name, details.OldResourceVersion, details.Tombstone))
+		},
+	}
+	tombstone := DeletedFinalStateUnknown{Key: "pod1", Obj: pod}
+	deliverNotification(handler, deleteNotification{oldObj: tombstone})
+	handler.OnDelete(tombstone)
+	handler.OnDelete(pod)
+	if e := []string{"pod1 7 true", "pod1 7 true", "pod1 7 false"}; !reflect.DeepEqual(e, got) {
+		t.Errorf("expected deletes %v, got %v", e, got)
+	}
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/klog"
+)
+
+// SlowListenerConfig configures the detection of event handlers that are slow
+// to accept notifications, see WithSlowListenerDetection.
+type SlowListenerConfig struct {
+	// Threshold is how long handing a notification to a handler may block
+	// before the handler is reported as slow.  It must be positive.
+	Threshold time.Duration
+	// Overflow makes the notifications of a slow handler go to an unbounded
+	// overflow buffer rather than block the informer any further.  The
+	// handler gets them, in order, once it accepts notifications again.
+	Overflow bool
+}
+
+// WithSlowListenerDetection reports the event handlers that block the
+// informer from handing them a notification for longer than
+// config.Threshold, with a log naming the handler and the counter of
+// SlowListenerMetricsProvider.  With config.Overflow the informer then stops
+// waiting for them, so that one blocked handler does not stall the others.
+// It panics if the config is invalid.
+func WithSlowListenerDetection(config SlowListenerConfig) SharedIndexInformerOption {
+	if config.Threshold <= 0 {
+		panic(fmt.Errorf("slow listener threshold must be positive, got %v", config.Threshold))
+	}
+	return func(informer *sharedIndexInformer) *sharedIndexInformer {
+		informer.slowListener = &config
+		return informer
+	}
+}
+
+// SlowListenerMetricsProvider is implemented by the InformerMetricsProviders
+// that count the slow notifications of event handlers, see
+// WithSlowListenerDetection.
+type SlowListenerMetricsProvider interface {
+	// NewSlowNotificationsMetric returns a counter of the notifications a
+	// handler did not accept within the threshold.
+	NewSlowNotificationsMetric(informer, handler string) CounterMetric
+}
+
+// addWatched hands notification to pop like add, reporting the listener if
+// it has a slow listener threshold and pop does not accept notification
+// within it.  With a positive timeout, addWatched gives up once timeout has
+// passed and returns false.
+func (p *processorListener) addWatched(notification interface{}, timeout time.Duration) bool {
+	p.overflowLock.Lock()
+	if len(p.overflow) > 0 {
+		// Keep the order: pop has not taken the overflow yet.
+		p.overflowLocked(notification)
+		p.overflowLock.Unlock()
+		return true
+	}
+	p.overflowLock.Unlock()
+
+	select {
+	case p.addCh <- notification:
+		return true
+	default:
+	}
+	// A nil deadline never fires.
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		deadlineTimer := time.NewTimer(timeout)
+		defer deadlineTimer.Stop()
+		deadline = deadlineTimer.C
+	}
+
+	if p.slowListener != nil {
+		timer := time.NewTimer(p.slowListener.Threshold)
+		defer timer.Stop()
+		select {
+		case p.addCh <- notification:
+			return true
+		case <-timer.C:
+		case <-deadline:
+			return false
+		}
+
+		klog.Warningf("Event handler %s did not accept a %T within %v", p, notification, p.slowListener.Threshold)
+		if p.slowNotifications != nil {
+			p.slowNotifications.Inc()
+		}
+		if p.slowListener.Overflow {
+			p.overflowLock.Lock()
+			p.overflowLocked(notification)
+			p.overflowLock.Unlock()
+			return true
+		}
+	}
+	select {
+	case p.addCh <- notification:
+		return true
+	case <-deadline:
+		return false
+	}
+}
+
+// overflowLocked queues notification in the overflow buffer and wakes pop up.
+// overflowLock must be held.
+func (p *processorListener) overflowLocked(notification interface{}) {
+	p.overflow = append(p.overflow, notification)
+	select {
+	case p.overflowed <- struct{}{}:
+	default:
+	}
+}
+
+// takeOverflow returns the notifications of the overflow buffer, oldest
+// first, and empties it.
+func (p *processorListener) takeOverflow() []interface{} {
+	p.overflowLock.Lock()
+	defer p.overflowLock.Unlock()
+	overflow := p.overflow
+	p.overflow = nil
+	return overflow
+}
@@ -0,0 +1,240 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// snapshotLock serializes the snapshots of every SnapshotGroup, which lock
+// several stores at once.
+var snapshotLock sync.Mutex
+
+// SnapshotGroup takes point-in-time snapshots of several indexers at once, so
+// that a controller reading from several informers within one reconcile pass
+// does not see some of them change in the middle of it.  The snapshots are
+// consistent with each other: every indexer is captured at the same instant.
+// They are copy-on-write: taking one is cheap, and the first write to an
+// indexer after a snapshot copies its items and indices.
+//
+// Only the indexers built by NewIndexer, possibly wrapped by the shared
+// informers of this package, can be added.
+type SnapshotGroup struct {
+	lock     sync.Mutex
+	indexers map[string]snapshotSource
+}
+
+// snapshotSource is an indexer of a SnapshotGroup.
+type snapshotSource struct {
+	cache   *cache
+	storage *threadSafeMap
+	// deepCopy makes the snapshot hand out deep copies, like the indexer.
+	deepCopy bool
+}
+
+// NewSnapshotGroup returns an empty SnapshotGroup.
+func NewSnapshotGroup() *SnapshotGroup {
+	return &SnapshotGroup{indexers: map[string]snapshotSource{}}
+}
+
+// Add adds indexer to the group under name.  It returns an error if name is
+// already taken or if indexer cannot be snapshotted.
+func (g *SnapshotGroup) Add(name string, indexer Indexer) error {
+	source, err := newSnapshotSource(indexer)
+	if err != nil {
+		return fmt.Errorf("cannot snapshot indexer %s: %v", name, err)
+	}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if _, exists := g.indexers[name]; exists {
+		return fmt.Errorf("indexer %s is already in the snapshot group", name)
+	}
+	g.indexers[name] = source
+	return nil
+}
+
+func newSnapshotSource(indexer Indexer) (snapshotSource, error) {
+	deepCopy := false
+	for {
+		switch i := indexer.(type) {
+		case *deepCopyIndexer:
+			deepCopy = true
+			indexer = i.Indexer
+			continue
+		case *recordingIndexer:
+			indexer = i.Indexer
+			continue
+		case *cache:
+			storage, ok := i.cacheStorage.(*threadSafeMap)
+			if !ok {
+				return snapshotSource{}, fmt.Errorf("unsupported thread safe store %T", i.cacheStorage)
+			}
+			return snapshotSource{cache: i, storage: storage, deepCopy: deepCopy}, nil
+		}
+		return snapshotSource{}, fmt.Errorf("unsupported indexer %T", indexer)
+	}
+}
+
+// Snapshot captures every indexer of the group, and returns the read-only
+// snapshots by name.
+func (g *SnapshotGroup) Snapshot() *GroupSnapshot {
+	g.lock.Lock()
+	names := make([]string, 0, len(g.indexers))
+	for name := range g.indexers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sources := make([]snapshotSource, len(names))
+	for i, name := range names {
+		sources[i] = g.indexers[name]
+	}
+	g.lock.Unlock()
+
+	snapshotLock.Lock()
+	defer snapshotLock.Unlock()
+	// Hold every store at once; an indexer added under several names is
+	// only locked once.
+	locked := map[*threadSafeMap]bool{}
+	for _, source := range sources {
+		if !locked[source.storage] {
+			source.storage.lock.Lock()
+			locked[source.storage] = true
+		}
+	}
+	snapshot := &GroupSnapshot{indexers: make(map[string]Indexer, len(names))}
+	for i, name := range names {
+		snapshot.indexers[name] = sources[i].snapshotLocked()
+	}
+	for storage := range locked {
+		storage.lock.Unlock()
+	}
+	return snapshot
+}
+
+// snapshotLocked returns a read-only Indexer holding the current items of s.
+// The lock of the store must be held.
+func (s snapshotSource) snapshotLocked() Indexer {
+	storage := s.storage
+	storage.shared = true
+	indexers := make(Indexers, len(storage.indexers))
+	for name, indexFunc := range storage.indexers {
+		indexers[name] = indexFunc
+	}
+	snapshotStorage := &threadSafeMap{
+		items:    storage.items,
+		indexers: indexers,
+		indices:  storage.indices,
+		codec:    storage.codec,
+		shared:   true,
+	}
+	if storage.sortedKeys != nil {
+		snapshotStorage.sortedKeys = storage.sortedKeys.clone()
+	}
+	var indexer Indexer = &snapshotIndexer{cache: &cache{
+		cacheStorage: snapshotStorage,
+		keyFunc:      s.cache.keyFunc,
+	}}
+	if s.deepCopy {
+		indexer = NewDeepCopyIndexer(indexer)
+	}
+	return indexer
+}
+
+// GroupSnapshot holds the snapshots of the indexers of a SnapshotGroup taken
+// at the same instant.
+type GroupSnapshot struct {
+	indexers map[string]Indexer
+}
+
+// Indexer returns the read-only snapshot of the named indexer, or nil if the
+// group had no such indexer.  Its mutating methods return errors.
+func (s *GroupSnapshot) Indexer(name string) Indexer {
+	return s.indexers[name]
+}
+
+// snapshotIndexer is the read-only Indexer of a snapshot.
+type snapshotIndexer struct {
+	*cache
+}
+
+func errReadOnlySnapshot(operation string) error {
+	return fmt.Errorf("%s is not supported on a read-only snapshot", operation)
+}
+
+func (s *snapshotIndexer) Add(obj interface{}) error {
+	return errReadOnlySnapshot("Add")
+}
+
+func (s *snapshotIndexer) Update(obj interface{}) error {
+	return errReadOnlySnapshot("Update")
+}
+
+func (s *snapshotIndexer) Delete(obj interface{}) error {
+	return errReadOnlySnapshot("Delete")
+}
+
+func (s *snapshotIndexer) Replace(list []interface{}, resourceVersion string) error {
+	return errReadOnlySnapshot("Replace")
+}
+
+func (s *snapshotIndexer) Resync() error {
+	return errReadOnlySnapshot("Resync")
+}
+
+func (s *snapshotIndexer) AddIndexers(newIndexers Indexers) error {
+	return errReadOnlySnapshot("AddIndexers")
+}
+
+func (s *snapshotIndexer) AddIndexersAfterStart(newIndexers Indexers) error {
+	return errReadOnlySnapshot("AddIndexersAfterStart")
+}
+
+func (s *snapshotIndexer) RemoveIndexer(name string) error {
+	return errReadOnlySnapshot("RemoveIndexer")
+}
+
+// unshareLocked gives the store its own copies of the items and indices
+// referenced by snapshots, before they are written to.  The lock must be
+// held.
+func (c *threadSafeMap) unshareLocked() {
+	if !c.shared {
+		return
+	}
+	c.items = copyItems(c.items)
+	indices := make(Indices, len(c.indices))
+	for name, index := range c.indices {
+		copied := make(Index, len(index))
+		for value, keys := range index {
+			copied[value] = sets.NewString(keys.UnsortedList()...)
+		}
+		indices[name] = copied
+	}
+	c.indices = indices
+	c.shared = false
+}
+
+func copyItems(items map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(items))
+	for key, item := range items {
+		copied[key] = item
+	}
+	return copied
+}
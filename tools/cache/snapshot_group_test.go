@@ -0,0 +1,95 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+func TestSnapshotGroup(t *testing.T) {
+	mkPod := func(name, node string) *v1.Pod {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name}, Spec: v1.PodSpec{NodeName: node}}
+	}
+	nodeIndex := func(obj interface{}) ([]string, error) {
+		return []string{obj.(*v1.Pod).Spec.NodeName}, nil
+	}
+	pods := NewIndexer(MetaNamespaceKeyFunc, Indexers{"node": nodeIndex})
+	pods.Add(mkPod("a", "node1"))
+	pods.Add(mkPod("b", "node1"))
+	nodes := NewIndexer(MetaNamespaceKeyFunc, Indexers{})
+	nodes.Add(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}})
+
+	group := NewSnapshotGroup()
+	if err := group.Add("pods", pods); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := group.Add("nodes", NewDeepCopyIndexer(nodes)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := group.Add("pods", pods); err == nil {
+		t.Errorf("expected an error adding pods twice")
+	}
+	if err := group.Add("view", &viewIndexer{indexer: pods}); err == nil {
+		t.Errorf("expected an error adding an unsupported indexer")
+	}
+
+	snapshot := group.Snapshot()
+	pods.Update(mkPod("a", "node2"))
+	pods.Delete(mkPod("b", "node1"))
+	pods.Add(mkPod("c", "node1"))
+	nodes.Replace([]interface{}{}, "2")
+
+	podNames := func(objs []interface{}) sets.String {
+		names := sets.NewString()
+		for _, obj := range objs {
+			names.Insert(obj.(*v1.Pod).Name)
+		}
+		return names
+	}
+	podSnapshot := snapshot.Indexer("pods")
+	if e, a := sets.NewString("a", "b"), podNames(podSnapshot.List()); !e.Equal(a) {
+		t.Errorf("expected snapshot pods %v, got %v", e.List(), a.List())
+	}
+	if objs, _ := podSnapshot.ByIndex("node", "node1"); !sets.NewString("a", "b").Equal(podNames(objs)) {
+		t.Errorf("expected pods a and b on node1 in the snapshot, got %v", podNames(objs).List())
+	}
+	if e, a := sets.NewString("a", "c"), podNames(pods.List()); !e.Equal(a) {
+		t.Errorf("expected pods %v, got %v", e.List(), a.List())
+	}
+	if objs, _ := pods.ByIndex("node", "node1"); !sets.NewString("c").Equal(podNames(objs)) {
+		t.Errorf("expected pod c on node1, got %v", podNames(objs).List())
+	}
+
+	node, exists, _ := snapshot.Indexer("nodes").GetByKey("node1")
+	if !exists {
+		t.Fatalf("expected node1 in the snapshot")
+	}
+	node.(*v1.Node).Labels = map[string]string{"mutated": "true"}
+	if node, _, _ := snapshot.Indexer("nodes").GetByKey("node1"); node.(*v1.Node).Labels != nil {
+		t.Errorf("expected the snapshot of a deep copying indexer to hand out copies")
+	}
+	if err := podSnapshot.Add(mkPod("d", "node1")); err == nil {
+		t.Errorf("expected snapshots to be read-only")
+	}
+	if snapshot.Indexer("unknown") != nil {
+		t.Errorf("expected no snapshot of an unknown indexer")
+	}
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"sort"
+	"strings"
+)
+
+// orderedKeys is the set of keys of a store, kept in ascending order.
+type orderedKeys struct {
+	keys []string
+	// shared is true while keys is referenced by a clone.  It is copied
+	// before being written to.
+	shared bool
+}
+
+// newOrderedKeys returns the sorted keys of items.
+func newOrderedKeys(items map[string]interface{}) *orderedKeys {
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return &orderedKeys{keys: keys}
+}
+
+// clone returns a copy of the set sharing its keys until either one is
+// written to.
+func (s *orderedKeys) clone() *orderedKeys {
+	s.shared = true
+	return &orderedKeys{keys: s.keys, shared: true}
+}
+
+// unshare copies the keys if they are shared.
+func (s *orderedKeys) unshare() {
+	if !s.shared {
+		return
+	}
+	s.keys = append([]string(nil), s.keys...)
+	s.shared = false
+}
+
+// insert adds key, which must not be in the set yet.
+func (s *orderedKeys) insert(key string) {
+	s.unshare()
+	i := sort.SearchStrings(s.keys, key)
+	s.keys = append(s.keys, "")
+	copy(s.keys[i+1:], s.keys[i:])
+	s.keys[i] = key
+}
+
+// delete removes key if it is in the set.
+func (s *orderedKeys) delete(key string) {
+	i := sort.SearchStrings(s.keys, key)
+	if i < len(s.keys) && s.keys[i] == key {
+		s.unshare()
+		s.keys = append(s.keys[:i], s.keys[i+1:]...)
+	}
+}
+
+// ascend calls fn in ascending order with each key from from, included, to
+// to, excluded, until fn returns false.  An empty to does not bound the
+// range.
+func (s *orderedKeys) ascend(from, to string, fn func(key string) bool) {
+	for i := sort.SearchStrings(s.keys, from); i < len(s.keys); i++ {
+		if to != "" && s.keys[i] >= to {
+			return
+		}
+		if !fn(s.keys[i]) {
+			return
+		}
+	}
+}
+
+// prefixEnd returns the smallest key greater than all the keys starting with
+// prefix, or "" if there is none.
+func prefixEnd(prefix string) string {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return string(end[:i+1])
+		}
+	}
+	return ""
+}
+
+// setSortedKeys makes the store keep its keys sorted, so that RangeKeys and
+// ListKeysWithPrefix only visit the keys in range.
+func (c *threadSafeMap) setSortedKeys() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.rebuildSortedKeysLocked()
+}
+
+func (c *threadSafeMap) rebuildSortedKeysLocked() {
+	c.sortedKeys = newOrderedKeys(c.items)
+}
+
+// RangeKeys calls fn in ascending order with each key from from, included,
+// to to, excluded, until fn returns false.  An empty to does not bound the
+// range.  fn is called with the store read-locked and must not write to it.
+func (c *threadSafeMap) RangeKeys(from, to string, fn func(key string) bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.sortedKeys != nil {
+		c.sortedKeys.ascend(from, to, fn)
+		return
+	}
+
+	var keys []string
+	for key := range c.items {
+		if key >= from && (to == "" || key < to) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !fn(key) {
+			return
+		}
+	}
+}
+
+// ListKeysWithPrefix returns the keys starting with prefix, sorted.
+func (c *threadSafeMap) ListKeysWithPrefix(prefix string) []string {
+	keys := []string{}
+	c.RangeKeys(prefix, prefixEnd(prefix), func(key string) bool {
+		if !strings.HasPrefix(key, prefix) {
+			return false
+		}
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+// NewSortedKeysIndexer returns an Indexer like NewIndexer which also keeps
+// its keys sorted, so that RangeKeys and ListKeysWithPrefix, for example
+// with a "namespace/" prefix, do not go through all its keys.  It costs a
+// sorted slice of the keys, in which every added or deleted key is moved
+// into place.
+func NewSortedKeysIndexer(keyFunc KeyFunc, indexers Indexers) Indexer {
+	storage := &threadSafeMap{
+		items:    map[string]interface{}{},
+		indexers: indexers,
+		indices:  Indices{},
+	}
+	storage.setSortedKeys()
+	return &cache{
+		cacheStorage: storage,
+		keyFunc:      keyFunc,
+	}
+}
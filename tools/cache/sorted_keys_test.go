@@ -0,0 +1,93 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSortedKeys(t *testing.T) {
+	for name, newIndexer := range map[string]func(KeyFunc, Indexers) Indexer{
+		"unsorted": NewIndexer,
+		"sorted":   NewSortedKeysIndexer,
+		"sharded": func(keyFunc KeyFunc, indexers Indexers) Indexer {
+			return NewShardedIndexer(keyFunc, indexers, 4)
+		},
+	} {
+		indexer := newIndexer(testStoreKeyFunc, Indexers{})
+		for _, key := range []string{"b/w", "a/y", "ab/z", "a/x", "\xff"} {
+			indexer.Add(testStoreObject{id: key})
+		}
+
+		rangeKeys := func(from, to string, max int) []string {
+			keys := []string{}
+			indexer.RangeKeys(from, to, func(key string) bool {
+				keys = append(keys, key)
+				return len(keys) < max
+			})
+			return keys
+		}
+		tests := []struct {
+			found    []string
+			expected []string
+		}{
+			{indexer.ListKeysWithPrefix("a/"), []string{"a/x", "a/y"}},
+			{indexer.ListKeysWithPrefix("a"), []string{"a/x", "a/y", "ab/z"}},
+			{indexer.ListKeysWithPrefix("c"), []string{}},
+			{indexer.ListKeysWithPrefix("\xff"), []string{"\xff"}},
+			{indexer.ListKeysWithPrefix(""), []string{"a/x", "a/y", "ab/z", "b/w", "\xff"}},
+			{rangeKeys("a/y", "b/w", 10), []string{"a/y", "ab/z"}},
+			{rangeKeys("ab", "", 10), []string{"ab/z", "b/w", "\xff"}},
+			{rangeKeys("", "", 2), []string{"a/x", "a/y"}},
+		}
+		for i, test := range tests {
+			if !reflect.DeepEqual(test.found, test.expected) {
+				t.Errorf("%s, query %d: expected %q, got %q", name, i, test.expected, test.found)
+			}
+		}
+
+		indexer.Delete(testStoreObject{id: "a/x"})
+		indexer.Add(testStoreObject{id: "a/w"})
+		if keys := indexer.ListKeysWithPrefix("a/"); !reflect.DeepEqual(keys, []string{"a/w", "a/y"}) {
+			t.Errorf("%s: expected the keys to follow the writes, got %q", name, keys)
+		}
+		indexer.Replace([]interface{}{testStoreObject{id: "a/v"}, testStoreObject{id: "c/u"}}, "1")
+		if keys := indexer.ListKeysWithPrefix(""); !reflect.DeepEqual(keys, []string{"a/v", "c/u"}) {
+			t.Errorf("%s: expected the keys to follow the replace, got %q", name, keys)
+		}
+	}
+}
+
+func TestSortedKeysSnapshot(t *testing.T) {
+	indexer := NewSortedKeysIndexer(testStoreKeyFunc, Indexers{})
+	indexer.Add(testStoreObject{id: "a/x"})
+	group := NewSnapshotGroup()
+	if err := group.Add("test", indexer); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	snapshot := group.Snapshot().Indexer("test")
+	indexer.Add(testStoreObject{id: "a/y"})
+	indexer.Delete(testStoreObject{id: "a/x"})
+
+	if keys := snapshot.ListKeysWithPrefix("a/"); !reflect.DeepEqual(keys, []string{"a/x"}) {
+		t.Errorf("expected the snapshot to keep its keys, got %q", keys)
+	}
+	if keys := indexer.ListKeysWithPrefix("a/"); !reflect.DeepEqual(keys, []string{"a/y"}) {
+		t.Errorf("expected the indexer to have the new keys, got %q", keys)
+	}
+}
@@ -18,9 +18,12 @@ package cache
 
 import (
 	"fmt"
+	"reflect"
+	"sort"
 	"strings"
 
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 // Store is a generic object storage interface. Reflector knows how to watch a server
@@ -106,6 +109,60 @@ func SplitMetaNamespaceKey(key string) (namespace, name string, err error) {
 	return "", "", fmt.Errorf("unexpected key format: %q", key)
 }
 
+// clusterKeySeparator separates the cluster from the namespace and name in
+// the keys made by ClusterObjectKeyFunc.
+const clusterKeySeparator = "|"
+
+// ClusterObjectKeyFunc is a KeyFunc for caches holding objects of several
+// clusters.  The key uses the format <cluster>|<namespace>/<name>, dropping
+// the parts that are empty like MetaNamespaceKeyFunc does, so the key of an
+// object without a cluster is its MetaNamespaceKeyFunc key.
+func ClusterObjectKeyFunc(obj interface{}) (string, error) {
+	if key, ok := obj.(ExplicitKey); ok {
+		return string(key), nil
+	}
+	meta, err := meta.Accessor(obj)
+	if err != nil {
+		return "", fmt.Errorf("object has no meta: %v", err)
+	}
+	if strings.Contains(meta.GetClusterName(), clusterKeySeparator) {
+		return "", fmt.Errorf("cluster name %q contains %q", meta.GetClusterName(), clusterKeySeparator)
+	}
+	return ClusterObjectKey(meta.GetClusterName(), meta.GetNamespace(), meta.GetName()), nil
+}
+
+// ClusterObjectKey returns the key ClusterObjectKeyFunc makes for the object
+// with the given cluster, namespace and name, for looking the object up in
+// a cache.
+func ClusterObjectKey(cluster, namespace, name string) string {
+	key := name
+	if len(namespace) > 0 {
+		key = namespace + "/" + name
+	}
+	if len(cluster) > 0 {
+		key = cluster + clusterKeySeparator + key
+	}
+	return key
+}
+
+// SplitMetaClusterNamespaceKey returns the cluster, namespace and name that
+// ClusterObjectKeyFunc encoded into key.  It also accepts the keys made by
+// MetaNamespaceKeyFunc, returning an empty cluster.
+func SplitMetaClusterNamespaceKey(key string) (cluster, namespace, name string, err error) {
+	namespacedKey := key
+	if i := strings.Index(key, clusterKeySeparator); i >= 0 {
+		cluster, namespacedKey = key[:i], key[i+1:]
+		if len(cluster) == 0 {
+			return "", "", "", fmt.Errorf("unexpected key format: %q", key)
+		}
+	}
+	namespace, name, err = SplitMetaNamespaceKey(namespacedKey)
+	if err != nil || strings.Contains(name, clusterKeySeparator) {
+		return "", "", "", fmt.Errorf("unexpected key format: %q", key)
+	}
+	return cluster, namespace, name, nil
+}
+
 // cache responsibilities are limited to:
 //	1. Computing keys for objects via keyFunc
 //  2. Invoking methods of a ThreadSafeStorage interface
@@ -185,10 +242,37 @@ func (c *cache) ByIndex(indexName, indexKey string) ([]interface{}, error) {
 	return c.cacheStorage.ByIndex(indexName, indexKey)
 }
 
+// ForEachByIndex calls fn with each item that matches an exact value on the
+// index function, until fn returns false
+func (c *cache) ForEachByIndex(indexName, indexKey string, fn func(obj interface{}) bool) error {
+	return c.cacheStorage.ForEachByIndex(indexName, indexKey, fn)
+}
+
+// ByIndexes returns a list of items matching the union or the intersection
+// of the given index queries
+func (c *cache) ByIndexes(mode IndexQueryMode, queries ...IndexQuery) ([]interface{}, error) {
+	return c.cacheStorage.ByIndexes(mode, queries...)
+}
+
+// GetIndexStats returns the stats of the named index
+func (c *cache) GetIndexStats(indexName string) (IndexStats, error) {
+	return c.cacheStorage.GetIndexStats(indexName)
+}
+
 func (c *cache) AddIndexers(newIndexers Indexers) error {
 	return c.cacheStorage.AddIndexers(newIndexers)
 }
 
+// AddIndexersAfterStart adds indexers, indexing the objects of the cache.
+func (c *cache) AddIndexersAfterStart(newIndexers Indexers) error {
+	return c.cacheStorage.AddIndexersAfterStart(newIndexers)
+}
+
+// RemoveIndexer removes the named indexer and its index.
+func (c *cache) RemoveIndexer(name string) error {
+	return c.cacheStorage.RemoveIndexer(name)
+}
+
 // Get returns the requested item, or sets exists=false.
 // Get is completely threadsafe as long as you treat all items as immutable.
 func (c *cache) Get(obj interface{}) (item interface{}, exists bool, err error) {
@@ -222,6 +306,117 @@ func (c *cache) Replace(list []interface{}, resourceVersion string) error {
 	return nil
 }
 
+// DiffReplacer is implemented by the stores returned by NewStore and
+// NewIndexer, which can report the changes a Replace makes, for components
+// that periodically load the complete state of something into a store.
+type DiffReplacer interface {
+	// ReplaceWithDiff replaces the contents of the store like Replace, then
+	// calls the OnAdd, OnUpdate and OnDelete methods of handler for every
+	// object it added, changed or deleted.  An object is changed if it is
+	// not deeply equal to the object it replaced.  Adds and updates are
+	// reported in the order of list, deletes in the order of their keys.
+	ReplaceWithDiff(list []interface{}, resourceVersion string, handler ResourceEventHandler) error
+}
+
+var _ DiffReplacer = &cache{}
+
+// ReplaceWithDiff implements DiffReplacer.
+func (c *cache) ReplaceWithDiff(list []interface{}, resourceVersion string, handler ResourceEventHandler) error {
+	storage, ok := c.cacheStorage.(*threadSafeMap)
+	if !ok {
+		return fmt.Errorf("store of type %T cannot compute a diff", c.cacheStorage)
+	}
+	items := make(map[string]interface{}, len(list))
+	keys := make([]string, 0, len(list))
+	for _, item := range list {
+		key, err := c.keyFunc(item)
+		if err != nil {
+			return KeyError{item, err}
+		}
+		if _, exists := items[key]; !exists {
+			keys = append(keys, key)
+		}
+		items[key] = item
+	}
+	old := storage.swap(items, resourceVersion)
+
+	for _, key := range keys {
+		item := items[key]
+		oldItem, exists := old[key]
+		switch {
+		case !exists:
+			handler.OnAdd(item)
+		case !reflect.DeepEqual(oldItem, item):
+			handler.OnUpdate(oldItem, item)
+		}
+	}
+	var deleted []string
+	for key := range old {
+		if _, exists := items[key]; !exists {
+			deleted = append(deleted, key)
+		}
+	}
+	sort.Strings(deleted)
+	for _, key := range deleted {
+		handler.OnDelete(old[key])
+	}
+	return nil
+}
+
+// SelectorLister is implemented by the stores returned by NewStore and
+// NewIndexer, such as the store of a shared informer, which can filter their
+// objects by label while iterating over them rather than copying them all
+// first.
+type SelectorLister interface {
+	// ListWithSelector returns the objects whose labels match selector.
+	ListWithSelector(selector labels.Selector) ([]interface{}, error)
+	// ListKeysWithSelector returns the keys of the objects whose labels
+	// match selector.
+	ListKeysWithSelector(selector labels.Selector) ([]string, error)
+}
+
+var _ SelectorLister = &cache{}
+
+// ListWithSelector implements SelectorLister.
+func (c *cache) ListWithSelector(selector labels.Selector) ([]interface{}, error) {
+	storage, ok := c.cacheStorage.(*threadSafeMap)
+	if !ok {
+		var list []interface{}
+		err := filterBySelector(c.cacheStorage.List(), selector, func(obj interface{}) {
+			list = append(list, obj)
+		})
+		return list, err
+	}
+	return storage.listWithSelector(selector)
+}
+
+// ListKeysWithSelector implements SelectorLister.
+func (c *cache) ListKeysWithSelector(selector labels.Selector) ([]string, error) {
+	storage, ok := c.cacheStorage.(*threadSafeMap)
+	if !ok {
+		var keys []string
+		err := filterBySelector(c.cacheStorage.List(), selector, func(obj interface{}) {
+			if key, err := c.keyFunc(obj); err == nil {
+				keys = append(keys, key)
+			}
+		})
+		return keys, err
+	}
+	return storage.listKeysWithSelector(selector)
+}
+
+// ListKeysWithPrefix returns the keys of the cache starting with prefix,
+// sorted
+func (c *cache) ListKeysWithPrefix(prefix string) []string {
+	return c.cacheStorage.ListKeysWithPrefix(prefix)
+}
+
+// RangeKeys calls fn in ascending order with the keys of the cache in the
+// given range
+func (c *cache) RangeKeys(from, to string, fn func(key string) bool) {
+	c.cacheStorage.RangeKeys(from, to, fn)
+}
+
 // Resync touches all items in the store to force processing
 func (c *cache) Resync() error {
 	return c.cacheStorage.Resync()
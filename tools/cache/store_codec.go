@@ -0,0 +1,123 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+)
+
+// StoreCodec encodes the objects a store keeps at rest, see WithStoreCodec.
+type StoreCodec interface {
+	// Encode returns the encoding of obj.
+	Encode(obj interface{}) ([]byte, error)
+	// Decode returns a new object equal to the one data is the encoding of.
+	Decode(data []byte) (interface{}, error)
+}
+
+// NewCompressingStoreCodec returns a StoreCodec encoding objects with codec,
+// such as a protobuf codec of a scheme, and compressing the result with gzip.
+// codec must decode the objects it encodes to their original type.
+func NewCompressingStoreCodec(codec runtime.Codec) StoreCodec {
+	return &compressingStoreCodec{codec: codec}
+}
+
+type compressingStoreCodec struct {
+	codec runtime.Codec
+}
+
+func (c *compressingStoreCodec) Encode(obj interface{}) ([]byte, error) {
+	object, ok := obj.(runtime.Object)
+	if !ok {
+		return nil, fmt.Errorf("cannot encode %T, which is not a runtime.Object", obj)
+	}
+	buf := &bytes.Buffer{}
+	w, err := gzip.NewWriterLevel(buf, gzip.BestSpeed)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.codec.Encode(object, w); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *compressingStoreCodec) Decode(data []byte) (interface{}, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	decompressed, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return runtime.Decode(c.codec, decompressed)
+}
+
+// encodedObject is an object a threadSafeMap keeps encoded by its codec.
+type encodedObject []byte
+
+// encode returns obj encoded by the codec of c, if any, or obj itself if it
+// has no codec or obj cannot be encoded.
+func (c *threadSafeMap) encode(obj interface{}) interface{} {
+	if c.codec == nil {
+		return obj
+	}
+	data, err := c.codec.Encode(obj)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("storing an object unencoded: %v", err))
+		return obj
+	}
+	return encodedObject(data)
+}
+
+// decode returns the object item is the encoding of, or item itself if it is
+// not encoded.  It returns nil if item cannot be decoded.
+func (c *threadSafeMap) decode(item interface{}) interface{} {
+	data, ok := item.(encodedObject)
+	if !ok {
+		return item
+	}
+	obj, err := c.codec.Decode(data)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("unable to decode a stored object: %v", err))
+		return nil
+	}
+	return obj
+}
+
+// setCodec makes c keep its items encoded by codec.
+func (c *threadSafeMap) setCodec(codec StoreCodec) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.unshareLocked()
+	for key, item := range c.items {
+		c.items[key] = c.decode(item)
+	}
+	c.codec = codec
+	for key, item := range c.items {
+		c.items[key] = c.encode(item)
+	}
+}
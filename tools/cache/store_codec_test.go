@@ -0,0 +1,78 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+func TestStoreCodec(t *testing.T) {
+	mkPod := func(name, image string) *v1.Pod {
+		return &v1.Pod{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name},
+			Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "c", Image: image}}},
+		}
+	}
+	imageIndex := func(obj interface{}) ([]string, error) {
+		return []string{obj.(*v1.Pod).Spec.Containers[0].Image}, nil
+	}
+	store := NewIndexer(MetaNamespaceKeyFunc, Indexers{"image": imageIndex})
+	store.Add(mkPod("before", "a"))
+	storage := store.(*cache).cacheStorage.(*threadSafeMap)
+	storage.setCodec(NewCompressingStoreCodec(spillTestCodec()))
+	store.Add(mkPod("after", "a"))
+
+	for key, item := range storage.items {
+		if _, ok := item.(encodedObject); !ok {
+			t.Errorf("expected %s to be stored encoded, got %T", key, item)
+		}
+	}
+	obj, exists, err := store.GetByKey("ns/after")
+	if err != nil || !exists {
+		t.Fatalf("expected ns/after to exist, got %v, %v", exists, err)
+	}
+	if e, a := mkPod("after", "a"), obj.(*v1.Pod); !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %#v, got %#v", e, a)
+	}
+
+	store.Update(mkPod("before", "b"))
+	if pods, _ := store.ByIndex("image", "a"); len(pods) != 1 || pods[0].(*v1.Pod).Name != "after" {
+		t.Errorf("expected only ns/after to use image a, got %v", pods)
+	}
+	store.Delete(mkPod("before", "b"))
+	if keys, _ := store.IndexKeys("image", "b"); len(keys) != 0 {
+		t.Errorf("expected no pod to use image b, got %v", keys)
+	}
+
+	store.Replace([]interface{}{mkPod("replaced", "c")}, "2")
+	if pods, _ := store.ByIndex("image", "c"); len(pods) != 1 || !reflect.DeepEqual(pods[0], mkPod("replaced", "c")) {
+		t.Errorf("expected ns/replaced to use image c, got %v", pods)
+	}
+	names := sets.NewString()
+	for _, obj := range store.List() {
+		names.Insert(obj.(*v1.Pod).Name)
+	}
+	if e := sets.NewString("replaced"); !e.Equal(names) {
+		t.Errorf("expected pods %v, got %v", e.List(), names.List())
+	}
+}
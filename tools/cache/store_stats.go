@@ -0,0 +1,92 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"time"
+)
+
+// StoreStats describes the contents of the cache of an informer, see
+// SharedIndexInformer.GetStoreStats.
+type StoreStats struct {
+	// Objects is the number of objects in the cache.
+	Objects int
+	// ApproximateBytes estimates the memory the objects take, as the size
+	// of their protobuf encoding, or of their encoding by the StoreCodec of
+	// the cache.  The decoded objects are usually several times larger.
+	// Objects without a known size do not count.
+	ApproximateBytes int64
+	// IndexCardinality maps the name of every index to the number of
+	// distinct values it holds.
+	IndexCardinality map[string]int
+	// LastRelistTime is when the cache was last replaced by a list, or the
+	// zero time if it has not been yet.
+	LastRelistTime time.Time
+}
+
+// sizer is implemented by the protobuf-generated API types.
+type sizer interface {
+	Size() int
+}
+
+// approximateSize returns the size of item, as described by
+// StoreStats.ApproximateBytes.
+func approximateSize(item interface{}) int64 {
+	switch item := item.(type) {
+	case encodedObject:
+		return int64(len(item))
+	case sizer:
+		return int64(item.Size())
+	}
+	return 0
+}
+
+// stats returns the statistics of c, except for the relist time.
+func (c *threadSafeMap) stats() StoreStats {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	stats := StoreStats{
+		Objects:          len(c.items),
+		IndexCardinality: make(map[string]int, len(c.indexers)),
+	}
+	for _, item := range c.items {
+		stats.ApproximateBytes += approximateSize(item)
+	}
+	for name := range c.indexers {
+		stats.IndexCardinality[name] = len(c.indices[name])
+	}
+	return stats
+}
+
+// indexerStats returns the statistics of indexer, except for the relist
+// time.
+func indexerStats(indexer Indexer) StoreStats {
+	if c, ok := indexer.(*cache); ok {
+		if storage, ok := c.cacheStorage.(*threadSafeMap); ok {
+			return storage.stats()
+		}
+	}
+	stats := StoreStats{IndexCardinality: map[string]int{}}
+	for _, item := range indexer.List() {
+		stats.Objects++
+		stats.ApproximateBytes += approximateSize(item)
+	}
+	for name := range indexer.GetIndexers() {
+		stats.IndexCardinality[name] = len(indexer.ListIndexFuncValues(name))
+	}
+	return stats
+}
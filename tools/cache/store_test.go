@@ -17,8 +17,12 @@ limitations under the License.
 package cache
 
 import (
+	"reflect"
 	"testing"
 
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/sets"
 )
 
@@ -154,3 +158,138 @@ func TestUndeltaStore(t *testing.T) {
 func TestIndex(t *testing.T) {
 	doTestIndex(t, NewIndexer(testStoreKeyFunc, testStoreIndexers()))
 }
+
+func TestClusterObjectKeyFunc(t *testing.T) {
+	tests := []struct {
+		cluster, namespace, name string
+		key                      string
+	}{
+		{"", "", "name", "name"},
+		{"", "ns", "name", "ns/name"},
+		{"cluster", "", "name", "cluster|name"},
+		{"cluster", "ns", "name", "cluster|ns/name"},
+	}
+	for _, test := range tests {
+		obj := &metav1.ObjectMeta{ClusterName: test.cluster, Namespace: test.namespace, Name: test.name}
+		key, err := ClusterObjectKeyFunc(obj)
+		if err != nil {
+			t.Errorf("unexpected error for %v: %v", obj, err)
+			continue
+		}
+		if key != test.key {
+			t.Errorf("expected key %q, got %q", test.key, key)
+		}
+		if test.cluster == "" {
+			if metaKey, _ := MetaNamespaceKeyFunc(obj); metaKey != key {
+				t.Errorf("expected key %q of an object without cluster to match %q", key, metaKey)
+			}
+		}
+		cluster, namespace, name, err := SplitMetaClusterNamespaceKey(key)
+		if err != nil || cluster != test.cluster || namespace != test.namespace || name != test.name {
+			t.Errorf("expected %q to split into %q, %q, %q, got %q, %q, %q, %v", key, test.cluster, test.namespace, test.name, cluster, namespace, name, err)
+		}
+	}
+
+	if _, err := ClusterObjectKeyFunc(&metav1.ObjectMeta{ClusterName: "a|b", Name: "name"}); err == nil {
+		t.Errorf("expected an error for a cluster name containing the separator")
+	}
+	for _, key := range []string{"|ns/name", "cluster|a/b/c", "a|b|c", "a/b/c"} {
+		if _, _, _, err := SplitMetaClusterNamespaceKey(key); err == nil {
+			t.Errorf("expected an error splitting %q", key)
+		}
+	}
+}
+
+func TestClusterIndexFuncs(t *testing.T) {
+	indexer := NewIndexer(ClusterObjectKeyFunc, Indexers{
+		ClusterIndex:          MetaClusterIndexFunc,
+		ClusterNamespaceIndex: MetaClusterNamespaceIndexFunc,
+	})
+	for _, obj := range []*metav1.ObjectMeta{
+		{ClusterName: "east", Namespace: "ns", Name: "a"},
+		{ClusterName: "east", Namespace: "other", Name: "a"},
+		{ClusterName: "west", Namespace: "ns", Name: "a"},
+	} {
+		indexer.Add(obj)
+	}
+
+	keys, err := indexer.IndexKeys(ClusterIndex, "east")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := sets.NewString("east|ns/a", "east|other/a"), sets.NewString(keys...); !e.Equal(a) {
+		t.Errorf("expected %v, got %v", e.List(), a.List())
+	}
+	keys, err = indexer.IndexKeys(ClusterNamespaceIndex, ClusterNamespaceIndexValue("west", "ns"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := sets.NewString("west|ns/a"), sets.NewString(keys...); !e.Equal(a) {
+		t.Errorf("expected %v, got %v", e.List(), a.List())
+	}
+}
+
+func TestReplaceWithDiff(t *testing.T) {
+	mkPod := func(name, image string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name},
+			Spec:       v1.PodSpec{Containers: []v1.Container{{Image: image}}},
+		}
+	}
+	store := NewIndexer(MetaNamespaceKeyFunc, Indexers{NamespaceIndex: MetaNamespaceIndexFunc})
+	for _, pod := range []*v1.Pod{mkPod("kept", "a"), mkPod("changed", "a"), mkPod("deleted-b", "a"), mkPod("deleted-a", "a")} {
+		store.Add(pod)
+	}
+
+	handler := &recordingHandler{}
+	err := store.(DiffReplacer).ReplaceWithDiff([]interface{}{
+		mkPod("added", "a"), mkPod("kept", "a"), mkPod("changed", "b"),
+	}, "2", handler)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"add ns/added", "update ns/changed", "delete ns/deleted-a", "delete ns/deleted-b"}
+	if !reflect.DeepEqual(handler.events, expected) {
+		t.Errorf("expected events %v, got %v", expected, handler.events)
+	}
+	if e, a := sets.NewString("ns/added", "ns/kept", "ns/changed"), sets.NewString(store.ListKeys()...); !e.Equal(a) {
+		t.Errorf("expected keys %v, got %v", e.List(), a.List())
+	}
+	if pods, _ := store.ByIndex(NamespaceIndex, "ns"); len(pods) != 3 {
+		t.Errorf("expected the index to be rebuilt, got %d pods", len(pods))
+	}
+}
+
+func TestListWithSelector(t *testing.T) {
+	mkPod := func(name, app string) *v1.Pod {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name, Labels: map[string]string{"app": app}}}
+	}
+	store := NewStore(MetaNamespaceKeyFunc)
+	for _, pod := range []*v1.Pod{mkPod("a1", "a"), mkPod("a2", "a"), mkPod("b1", "b")} {
+		store.Add(pod)
+	}
+	lister := store.(SelectorLister)
+
+	selector := labels.SelectorFromSet(labels.Set{"app": "a"})
+	keys, err := lister.ListKeysWithSelector(selector)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := sets.NewString("ns/a1", "ns/a2"), sets.NewString(keys...); !e.Equal(a) {
+		t.Errorf("expected keys %v, got %v", e.List(), a.List())
+	}
+	objs, err := lister.ListWithSelector(selector)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	names := sets.NewString()
+	for _, obj := range objs {
+		names.Insert(obj.(*v1.Pod).Name)
+	}
+	if e := sets.NewString("a1", "a2"); !e.Equal(names) {
+		t.Errorf("expected pods %v, got %v", e.List(), names.List())
+	}
+	if objs, _ := lister.ListWithSelector(labels.Everything()); len(objs) != 3 {
+		t.Errorf("expected every pod to match an empty selector, got %d", len(objs))
+	}
+}
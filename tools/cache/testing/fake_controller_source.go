@@ -114,6 +114,21 @@ func (f *FakeControllerSource) DeleteDropWatch(lastValue runtime.Object) {
 	f.Change(watch.Event{Type: watch.Deleted, Object: lastValue}, 0)
 }
 
+// Bookmark sends a bookmark event to watchers, carrying the resource version
+// of the latest change in obj, as a server does when watchers request
+// bookmarks.  Unlike the other changes, it is not replayed to later watches.
+func (f *FakeControllerSource) Bookmark(obj runtime.Object) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		panic(err) // this is test code only
+	}
+	accessor.SetResourceVersion(strconv.Itoa(len(f.changes)))
+	f.Broadcaster.Action(watch.Bookmark, obj)
+}
+
 func (f *FakeControllerSource) key(accessor metav1.Object) nnu {
 	return nnu{accessor.GetNamespace(), accessor.GetName(), accessor.GetUID()}
 }
@@ -246,6 +261,28 @@ func (f *FakeControllerSource) Watch(options metav1.ListOptions) (watch.Interfac
 	return f.Broadcaster.Watch(), nil
 }
 
+// WatchWithInitialEvents returns a watch starting with an Added event for
+// every object, followed by a bookmark annotated as the end of the initial
+// events, as a server supporting the watch-list protocol does.
+func (f *FakeControllerSource) WatchWithInitialEvents(options metav1.ListOptions) (watch.Interface, error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	list, err := f.getListItemsLocked()
+	if err != nil {
+		return nil, err
+	}
+	events := make([]watch.Event, 0, len(list)+1)
+	for _, obj := range list {
+		events = append(events, watch.Event{Type: watch.Added, Object: obj})
+	}
+	bookmark := &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		ResourceVersion: strconv.Itoa(len(f.changes)),
+		Annotations:     map[string]string{"k8s.io/initial-events-end": "true"},
+	}}
+	events = append(events, watch.Event{Type: watch.Bookmark, Object: bookmark})
+	return f.Broadcaster.WatchWithPrefix(events), nil
+}
+
 // Shutdown closes the underlying broadcaster, waiting for events to be
 // delivered. It's an error to call any method after calling shutdown. This is
 // enforced by Shutdown() leaving f locked.
@@ -20,6 +20,8 @@ import (
 	"fmt"
 	"sync"
 
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
 	"k8s.io/apimachinery/pkg/util/sets"
 )
 
@@ -41,16 +43,30 @@ type ThreadSafeStore interface {
 	Get(key string) (item interface{}, exists bool)
 	List() []interface{}
 	ListKeys() []string
+	// ListKeysWithPrefix returns the keys starting with prefix, sorted.
+	ListKeysWithPrefix(prefix string) []string
+	// RangeKeys calls fn in ascending order with each key from from,
+	// included, to to, excluded, until fn returns false.  An empty to does
+	// not bound the range.  fn must not write to the store.
+	RangeKeys(from, to string, fn func(key string) bool)
 	Replace(map[string]interface{}, string)
 	Index(indexName string, obj interface{}) ([]interface{}, error)
 	IndexKeys(indexName, indexKey string) ([]string, error)
 	ListIndexFuncValues(name string) []string
 	ByIndex(indexName, indexKey string) ([]interface{}, error)
+	ForEachByIndex(indexName, indexKey string, fn func(obj interface{}) bool) error
+	ByIndexes(mode IndexQueryMode, queries ...IndexQuery) ([]interface{}, error)
+	GetIndexStats(indexName string) (IndexStats, error)
 	GetIndexers() Indexers
 
 	// AddIndexers adds more indexers to this store.  If you call this after you already have data
 	// in the store, the results are undefined.
 	AddIndexers(newIndexers Indexers) error
+	// AddIndexersAfterStart adds more indexers to this store and indexes
+	// the items it already holds.
+	AddIndexersAfterStart(newIndexers Indexers) error
+	// RemoveIndexer removes the named indexer and its index.
+	RemoveIndexer(name string) error
 	Resync() error
 }
 
@@ -63,37 +79,81 @@ type threadSafeMap struct {
 	indexers Indexers
 	// indices maps a name to an Index
 	indices Indices
+
+	// keyFilter, if set, tracks the keys of items.
+	keyFilter *KeyFilter
+	// codec, if set, encodes the items, see WithStoreCodec.
+	codec StoreCodec
+	// shared is true while items and indices are referenced by a snapshot,
+	// see SnapshotGroup.  They are copied before being written to.
+	shared bool
+	// sortedKeys, if set, keeps the keys of items sorted, see
+	// NewSortedKeysIndexer.
+	sortedKeys *orderedKeys
 }
 
 func (c *threadSafeMap) Add(key string, obj interface{}) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
-	oldObject := c.items[key]
-	c.items[key] = obj
-	c.updateIndices(oldObject, obj, key)
+	c.setLocked(key, obj)
 }
 
 func (c *threadSafeMap) Update(key string, obj interface{}) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
-	oldObject := c.items[key]
-	c.items[key] = obj
+	c.setLocked(key, obj)
+}
+
+func (c *threadSafeMap) setLocked(key string, obj interface{}) {
+	c.unshareLocked()
+	oldObject, exists := c.items[key]
+	c.items[key] = c.encode(obj)
+	if exists && len(c.indexers) > 0 {
+		oldObject = c.decode(oldObject)
+	}
 	c.updateIndices(oldObject, obj, key)
+	if !exists && c.keyFilter != nil {
+		c.keyFilter.add(key)
+	}
+	if !exists && c.sortedKeys != nil {
+		c.sortedKeys.insert(key)
+	}
 }
 
 func (c *threadSafeMap) Delete(key string) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 	if obj, exists := c.items[key]; exists {
-		c.deleteFromIndices(obj, key)
+		c.unshareLocked()
+		if len(c.indexers) > 0 {
+			c.deleteFromIndices(c.decode(obj), key)
+		}
 		delete(c.items, key)
+		if c.keyFilter != nil {
+			c.keyFilter.remove(key)
+		}
+		if c.sortedKeys != nil {
+			c.sortedKeys.delete(key)
+		}
 	}
 }
 
+// setKeyFilter makes filter track the keys of the items.
+func (c *threadSafeMap) setKeyFilter(filter *KeyFilter) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.keyFilter = filter
+	filter.rebuild(c.items)
+}
+
 func (c *threadSafeMap) Get(key string) (item interface{}, exists bool) {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
 	item, exists = c.items[key]
+	if exists {
+		item = c.decode(item)
+		exists = item != nil
+	}
 	return item, exists
 }
 
@@ -102,7 +162,9 @@ func (c *threadSafeMap) List() []interface{} {
 	defer c.lock.RUnlock()
 	list := make([]interface{}, 0, len(c.items))
 	for _, item := range c.items {
-		list = append(list, item)
+		if item = c.decode(item); item != nil {
+			list = append(list, item)
+		}
 	}
 	return list
 }
@@ -119,9 +181,122 @@ func (c *threadSafeMap) ListKeys() []string {
 	return list
 }
 
+// listWithSelector returns the items whose labels match selector.
+func (c *threadSafeMap) listWithSelector(selector labels.Selector) ([]interface{}, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	var list []interface{}
+	err := c.forEachMatchingLocked(selector, func(key string, item interface{}) {
+		list = append(list, item)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// listKeysWithSelector returns the keys of the items whose labels match
+// selector.
+func (c *threadSafeMap) listKeysWithSelector(selector labels.Selector) ([]string, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	var keys []string
+	err := c.forEachMatchingLocked(selector, func(key string, item interface{}) {
+		keys = append(keys, key)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// forEachMatchingLocked calls fn with each item whose labels match selector.
+// Only the candidates found in the label index are checked when the selector
+// requires label values and the store has a LabelIndex.
+func (c *threadSafeMap) forEachMatchingLocked(selector labels.Selector, fn func(key string, item interface{})) error {
+	match := func(key string, item interface{}) error {
+		if item = c.decode(item); item == nil {
+			return nil
+		}
+		matches, err := matchesSelector(item, selector)
+		if err == nil && matches {
+			fn(key, item)
+		}
+		return err
+	}
+
+	candidates, indexed := c.labelIndexCandidatesLocked(selector)
+	if !indexed {
+		for key, item := range c.items {
+			if err := match(key, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for key := range candidates {
+		item, exists := c.items[key]
+		if !exists {
+			continue
+		}
+		if err := match(key, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// labelIndexCandidatesLocked returns the keys of the items whose labels have
+// the values required by selector, according to the label index, and whether
+// the index could narrow them down at all.
+func (c *threadSafeMap) labelIndexCandidatesLocked(selector labels.Selector) (sets.String, bool) {
+	if c.indexers[LabelIndex] == nil {
+		return nil, false
+	}
+	requirements, selectable := selector.Requirements()
+	if !selectable {
+		return nil, false
+	}
+	index := c.indices[LabelIndex]
+	var candidates sets.String
+	for _, requirement := range requirements {
+		switch requirement.Operator() {
+		case selection.Equals, selection.DoubleEquals, selection.In:
+		default:
+			continue
+		}
+		keys := sets.NewString()
+		for value := range requirement.Values() {
+			keys = keys.Union(index[LabelIndexValue(requirement.Key(), value)])
+		}
+		if candidates == nil {
+			candidates = keys
+		} else {
+			candidates = candidates.Intersection(keys)
+		}
+	}
+	return candidates, candidates != nil
+}
+
 func (c *threadSafeMap) Replace(items map[string]interface{}, resourceVersion string) {
+	c.swap(items, resourceVersion)
+}
+
+// swap replaces the items like Replace and returns the replaced ones, which
+// are no longer referenced by c.
+func (c *threadSafeMap) swap(items map[string]interface{}, resourceVersion string) map[string]interface{} {
 	c.lock.Lock()
 	defer c.lock.Unlock()
+	return c.swapLocked(items)
+}
+
+func (c *threadSafeMap) swapLocked(items map[string]interface{}) map[string]interface{} {
+	old := c.items
+	if c.shared && c.codec != nil {
+		// The old items are decoded below, keep those of the snapshots.
+		old = copyItems(old)
+	}
+	c.shared = false
 	c.items = items
 
 	// rebuild any index
@@ -129,6 +304,21 @@ func (c *threadSafeMap) Replace(items map[string]interface{}, resourceVersion st
 	for key, item := range c.items {
 		c.updateIndices(nil, item, key)
 	}
+	if c.keyFilter != nil {
+		c.keyFilter.rebuild(c.items)
+	}
+	if c.sortedKeys != nil {
+		c.rebuildSortedKeysLocked()
+	}
+	if c.codec != nil {
+		for key, item := range c.items {
+			c.items[key] = c.encode(item)
+		}
+		for key, item := range old {
+			old[key] = c.decode(item)
+		}
+	}
+	return old
 }
 
 // Index returns a list of items that match on the index function
@@ -166,7 +356,9 @@ func (c *threadSafeMap) Index(indexName string, obj interface{}) ([]interface{},
 
 	list := make([]interface{}, 0, returnKeySet.Len())
 	for absoluteKey := range returnKeySet {
-		list = append(list, c.items[absoluteKey])
+		if item := c.decode(c.items[absoluteKey]); item != nil {
+			list = append(list, item)
+		}
 	}
 	return list, nil
 }
@@ -186,12 +378,71 @@ func (c *threadSafeMap) ByIndex(indexName, indexKey string) ([]interface{}, erro
 	set := index[indexKey]
 	list := make([]interface{}, 0, set.Len())
 	for key := range set {
-		list = append(list, c.items[key])
+		if item := c.decode(c.items[key]); item != nil {
+			list = append(list, item)
+		}
 	}
 
 	return list, nil
 }
 
+// ForEachByIndex calls fn with each item that matches an exact value on the
+// index function, until fn returns false.  fn is called with the store
+// read-locked and must not write to it.
+func (c *threadSafeMap) ForEachByIndex(indexName, indexKey string, fn func(obj interface{}) bool) error {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.indexers[indexName] == nil {
+		return fmt.Errorf("Index with name %s does not exist", indexName)
+	}
+	for key := range c.indices[indexName][indexKey] {
+		item := c.decode(c.items[key])
+		if item == nil {
+			continue
+		}
+		if !fn(item) {
+			break
+		}
+	}
+	return nil
+}
+
+// ByIndexes returns a list of items matching the union or the intersection
+// of the given queries, computed on the index sets.
+func (c *threadSafeMap) ByIndexes(mode IndexQueryMode, queries ...IndexQuery) ([]interface{}, error) {
+	if mode != IndexUnion && mode != IndexIntersect {
+		return nil, fmt.Errorf("unknown index query mode %d", mode)
+	}
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	var keys sets.String
+	for i, query := range queries {
+		if c.indexers[query.IndexName] == nil {
+			return nil, fmt.Errorf("Index with name %s does not exist", query.IndexName)
+		}
+		set := c.indices[query.IndexName][query.IndexedValue]
+		switch {
+		case i == 0:
+			keys = sets.NewString().Union(set)
+		case mode == IndexUnion:
+			keys = keys.Union(set)
+		default:
+			keys = keys.Intersection(set)
+		}
+	}
+
+	list := make([]interface{}, 0, keys.Len())
+	for key := range keys {
+		if item := c.decode(c.items[key]); item != nil {
+			list = append(list, item)
+		}
+	}
+	return list, nil
+}
+
 // IndexKeys returns a list of keys that match on the index function.
 // IndexKeys is thread-safe so long as you treat all items as immutable.
 func (c *threadSafeMap) IndexKeys(indexName, indexKey string) ([]string, error) {
@@ -222,7 +473,13 @@ func (c *threadSafeMap) ListIndexFuncValues(indexName string) []string {
 }
 
 func (c *threadSafeMap) GetIndexers() Indexers {
-	return c.indexers
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	indexers := make(Indexers, len(c.indexers))
+	for name, indexFunc := range c.indexers {
+		indexers[name] = indexFunc
+	}
+	return indexers
 }
 
 func (c *threadSafeMap) AddIndexers(newIndexers Indexers) error {
@@ -246,6 +503,82 @@ func (c *threadSafeMap) AddIndexers(newIndexers Indexers) error {
 	return nil
 }
 
+func (c *threadSafeMap) AddIndexersAfterStart(newIndexers Indexers) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	oldKeys := sets.StringKeySet(c.indexers)
+	newKeys := sets.StringKeySet(newIndexers)
+	if oldKeys.HasAny(newKeys.List()...) {
+		return fmt.Errorf("indexer conflict: %v", oldKeys.Intersection(newKeys))
+	}
+
+	// Build the new indices aside, so that a failing index function leaves
+	// the store unchanged.
+	indices, err := c.buildIndicesLocked(newIndexers)
+	if err != nil {
+		return err
+	}
+	c.setIndicesLocked(newIndexers, indices)
+	return nil
+}
+
+// buildIndicesLocked returns the indices of the items for newIndexers,
+// without adding them to the store.
+func (c *threadSafeMap) buildIndicesLocked(newIndexers Indexers) (Indices, error) {
+	indices := make(Indices, len(newIndexers))
+	for name, indexFunc := range newIndexers {
+		index := Index{}
+		for key, item := range c.items {
+			if item = c.decode(item); item == nil {
+				continue
+			}
+			indexValues, err := indexFunc(item)
+			if err != nil {
+				return nil, fmt.Errorf("unable to calculate an index entry for key %q on index %q: %v", key, name, err)
+			}
+			for _, indexValue := range indexValues {
+				set := index[indexValue]
+				if set == nil {
+					set = sets.String{}
+					index[indexValue] = set
+				}
+				set.Insert(key)
+			}
+		}
+		indices[name] = index
+	}
+	return indices, nil
+}
+
+// setIndicesLocked adds newIndexers and their indices to the store.
+func (c *threadSafeMap) setIndicesLocked(newIndexers Indexers, indices Indices) {
+	c.unshareLocked()
+	if c.indexers == nil {
+		c.indexers = Indexers{}
+	}
+	if c.indices == nil {
+		c.indices = Indices{}
+	}
+	for name, indexFunc := range newIndexers {
+		c.indexers[name] = indexFunc
+		c.indices[name] = indices[name]
+	}
+}
+
+func (c *threadSafeMap) RemoveIndexer(name string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if _, exists := c.indexers[name]; !exists {
+		return fmt.Errorf("Index with name %s does not exist", name)
+	}
+	c.unshareLocked()
+	delete(c.indexers, name)
+	delete(c.indices, name)
+	return nil
+}
+
 // updateIndices modifies the objects location in the managed indexes, if this is an update, you must provide an oldObj
 // updateIndices must be called from a function that already has a lock on the cache
 func (c *threadSafeMap) updateIndices(oldObj interface{}, newObj interface{}, key string) {
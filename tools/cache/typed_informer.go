@@ -0,0 +1,139 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+)
+
+// TypedResourceEventHandlerFuncs is like ResourceEventHandlerFuncs, but its
+// functions take the objects with the type of the informer's objects instead
+// of interface{}: AddFunc and DeleteFunc are for example func(pod *v1.Pod),
+// and UpdateFunc func(oldPod, newPod *v1.Pod).  Each of them may be nil.
+// DeleteFunc is passed the last known state of the objects whose deletion was
+// missed, unwrapped from their DeletedFinalStateUnknown.
+type TypedResourceEventHandlerFuncs struct {
+	AddFunc    interface{}
+	UpdateFunc interface{}
+	DeleteFunc interface{}
+}
+
+// NewTypedResourceEventHandler returns a ResourceEventHandler calling the
+// functions of funcs with the notified objects, which must have the type of
+// objType.  It returns an error if a function does not take objects of that
+// type.  Notifications of objects of another type are reported through
+// utilruntime.HandleError and dropped.
+func NewTypedResourceEventHandler(objType runtime.Object, funcs TypedResourceEventHandlerFuncs) (ResourceEventHandler, error) {
+	handler := typedResourceEventHandler{objType: reflect.TypeOf(objType)}
+	var err error
+	if handler.add, err = typedFunc("AddFunc", funcs.AddFunc, handler.objType, 1); err != nil {
+		return nil, err
+	}
+	if handler.update, err = typedFunc("UpdateFunc", funcs.UpdateFunc, handler.objType, 2); err != nil {
+		return nil, err
+	}
+	if handler.delete, err = typedFunc("DeleteFunc", funcs.DeleteFunc, handler.objType, 1); err != nil {
+		return nil, err
+	}
+	return handler, nil
+}
+
+// typedFunc checks that fn, if not nil, is a function taking args objects of
+// objType and returning nothing.
+func typedFunc(name string, fn interface{}, objType reflect.Type, args int) (reflect.Value, error) {
+	if fn == nil {
+		return reflect.Value{}, nil
+	}
+	value := reflect.ValueOf(fn)
+	fnType := value.Type()
+	valid := fnType.Kind() == reflect.Func && fnType.NumIn() == args && fnType.NumOut() == 0
+	for i := 0; valid && i < args; i++ {
+		valid = fnType.In(i) == objType
+	}
+	if !valid {
+		return reflect.Value{}, fmt.Errorf("%s must be a func taking %d %v and returning nothing, got %T", name, args, objType, fn)
+	}
+	return value, nil
+}
+
+type typedResourceEventHandler struct {
+	objType             reflect.Type
+	add, update, delete reflect.Value
+}
+
+func (h typedResourceEventHandler) OnAdd(obj interface{}) {
+	h.call(h.add, obj)
+}
+
+func (h typedResourceEventHandler) OnUpdate(oldObj, newObj interface{}) {
+	h.call(h.update, oldObj, newObj)
+}
+
+func (h typedResourceEventHandler) OnDelete(obj interface{}) {
+	if tombstone, ok := obj.(DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	h.call(h.delete, obj)
+}
+
+// call calls fn, if set, with objs.
+func (h typedResourceEventHandler) call(fn reflect.Value, objs ...interface{}) {
+	if !fn.IsValid() {
+		return
+	}
+	args := make([]reflect.Value, len(objs))
+	for i, obj := range objs {
+		if reflect.TypeOf(obj) != h.objType {
+			utilruntime.HandleError(fmt.Errorf("expected a %v, got a %T", h.objType, obj))
+			return
+		}
+		args[i] = reflect.ValueOf(obj)
+	}
+	fn.Call(args)
+}
+
+// TypedSharedIndexInformer is a SharedIndexInformer whose event handlers can
+// take its objects with their own type.
+type TypedSharedIndexInformer struct {
+	SharedIndexInformer
+	objType runtime.Object
+}
+
+// NewTypedSharedIndexInformer returns a TypedSharedIndexInformer of the
+// objects of the type of objType, created like by NewSharedIndexInformer.
+func NewTypedSharedIndexInformer(lw ListerWatcher, objType runtime.Object, defaultEventHandlerResyncPeriod time.Duration, indexers Indexers, options ...SharedIndexInformerOption) *TypedSharedIndexInformer {
+	return &TypedSharedIndexInformer{
+		SharedIndexInformer: NewSharedIndexInformer(lw, objType, defaultEventHandlerResyncPeriod, indexers, options...),
+		objType:             objType,
+	}
+}
+
+// AddTypedEventHandler adds an event handler calling the functions of funcs
+// like AddEventHandler.  It returns an error if a function does not take the
+// objects of the informer.
+func (i *TypedSharedIndexInformer) AddTypedEventHandler(funcs TypedResourceEventHandlerFuncs) (ResourceEventHandlerRegistration, error) {
+	handler, err := NewTypedResourceEventHandler(i.objType, funcs)
+	if err != nil {
+		return nil, err
+	}
+	return i.AddEventHandler(handler)
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	fcache "k8s.io/client-go/tools/cache/testing"
+)
+
+func TestTypedSharedIndexInformer(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}})
+	informer := NewTypedSharedIndexInformer(source, &v1.Pod{}, 0, Indexers{})
+
+	var lock sync.Mutex
+	var events []string
+	record := func(event string) {
+		lock.Lock()
+		defer lock.Unlock()
+		events = append(events, event)
+	}
+	_, err := informer.AddTypedEventHandler(TypedResourceEventHandlerFuncs{
+		AddFunc: func(pod *v1.Pod) {
+			record("add " + pod.Name)
+		},
+		UpdateFunc: func(oldPod, newPod *v1.Pod) {
+			record("update " + oldPod.Labels["version"] + " " + newPod.Labels["version"])
+		},
+		DeleteFunc: func(pod *v1.Pod) {
+			record("delete " + pod.Name)
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	if !WaitForCacheSync(stop, informer.HasSynced) {
+		t.Fatal("the informer did not sync")
+	}
+	source.Modify(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Labels: map[string]string{"version": "2"}}})
+	source.Delete(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}})
+
+	expected := []string{"add pod1", "update  2", "delete pod1"}
+	err = wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(events) == len(expected), nil
+	})
+	if err != nil {
+		t.Fatalf("expected events %v, got %v", expected, events)
+	}
+	for i := range expected {
+		if events[i] != expected[i] {
+			t.Errorf("expected events %v, got %v", expected, events)
+			break
+		}
+	}
+}
+
+func TestTypedResourceEventHandler(t *testing.T) {
+	for name, funcs := range map[string]TypedResourceEventHandlerFuncs{
+		"not a func":       {AddFunc: "add"},
+		"wrong type":       {AddFunc: func(node *v1.Node) {}},
+		"wrong arity":      {UpdateFunc: func(pod *v1.Pod) {}},
+		"returning values": {DeleteFunc: func(pod *v1.Pod) error { return nil }},
+	} {
+		if _, err := NewTypedResourceEventHandler(&v1.Pod{}, funcs); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	var deleted []string
+	handler, err := NewTypedResourceEventHandler(&v1.Pod{}, TypedResourceEventHandlerFuncs{
+		DeleteFunc: func(pod *v1.Pod) {
+			deleted = append(deleted, pod.Name)
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	handler.OnAdd(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "added"}})
+	handler.OnDelete(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}})
+	handler.OnDelete(DeletedFinalStateUnknown{Key: "pod2", Obj: &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod2"}}})
+	handler.OnDelete(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}})
+	if len(deleted) != 2 || deleted[0] != "pod1" || deleted[1] != "pod2" {
+		t.Errorf("expected pod1 and pod2 to be deleted, got %v", deleted)
+	}
+}
@@ -56,6 +56,7 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"sync"
 	"time"
 
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -190,6 +191,11 @@ type LeaderElector struct {
 
 	// name is the name of the resource lock for debugging
 	name string
+
+	// fencingLock guards the fencing token of the current term, if leading.
+	fencingLock  sync.Mutex
+	fencingToken int64
+	leading      bool
 }
 
 // Run starts the leader election loop
@@ -231,6 +237,27 @@ func (le *LeaderElector) IsLeader() bool {
 	return le.observedRecord.HolderIdentity == le.config.Lock.Identity()
 }
 
+// FencingToken returns the fencing token of the current term and true if this
+// client holds the lock, or false otherwise.  The token is greater than the
+// tokens of every previous holder of the lock, so systems written to by the
+// leader can reject writes carrying a smaller token than the largest they
+// have seen, such as those of a leader that lost the lock without noticing.
+// Tokens restart if the lock object is deleted.
+func (le *LeaderElector) FencingToken() (int64, bool) {
+	le.fencingLock.Lock()
+	defer le.fencingLock.Unlock()
+	return le.fencingToken, le.leading
+}
+
+// setLeading records whether this client holds the lock, and the fencing
+// token of its term.
+func (le *LeaderElector) setLeading(leading bool, fencingToken int64) {
+	le.fencingLock.Lock()
+	defer le.fencingLock.Unlock()
+	le.leading = leading
+	le.fencingToken = fencingToken
+}
+
 // acquire loops calling tryAcquireOrRenew and returns true immediately when tryAcquireOrRenew succeeds.
 // Returns false if ctx signals done.
 func (le *LeaderElector) acquire(ctx context.Context) bool {
@@ -283,6 +310,7 @@ func (le *LeaderElector) renew(ctx context.Context) {
 			return
 		}
 		le.config.Lock.RecordEvent("stopped leading")
+		le.setLeading(false, 0)
 		le.metrics.leaderOff(le.config.Name)
 		klog.Infof("failed to renew lease %v: %v", desc, err)
 		cancel()
@@ -301,11 +329,13 @@ func (le *LeaderElector) release() bool {
 	}
 	leaderElectionRecord := rl.LeaderElectionRecord{
 		LeaderTransitions: le.observedRecord.LeaderTransitions,
+		FencingToken:      le.observedRecord.FencingToken,
 	}
 	if err := le.config.Lock.Update(leaderElectionRecord); err != nil {
 		klog.Errorf("Failed to release lock: %v", err)
 		return false
 	}
+	le.setLeading(false, 0)
 	le.observedRecord = leaderElectionRecord
 	le.observedTime = le.clock.Now()
 	return true
@@ -336,6 +366,7 @@ func (le *LeaderElector) tryAcquireOrRenew() bool {
 		}
 		le.observedRecord = leaderElectionRecord
 		le.observedTime = le.clock.Now()
+		le.setLeading(true, leaderElectionRecord.FencingToken)
 		return true
 	}
 
@@ -348,6 +379,7 @@ func (le *LeaderElector) tryAcquireOrRenew() bool {
 		le.observedTime.Add(le.config.LeaseDuration).After(now.Time) &&
 		!le.IsLeader() {
 		klog.V(4).Infof("lock is held by %v and has not yet expired", oldLeaderElectionRecord.HolderIdentity)
+		le.setLeading(false, 0)
 		return false
 	}
 
@@ -359,6 +391,7 @@ func (le *LeaderElector) tryAcquireOrRenew() bool {
 	} else {
 		leaderElectionRecord.LeaderTransitions = oldLeaderElectionRecord.LeaderTransitions + 1
 	}
+	leaderElectionRecord.FencingToken = int64(leaderElectionRecord.LeaderTransitions)
 
 	// update the lock itself
 	if err = le.config.Lock.Update(leaderElectionRecord); err != nil {
@@ -367,6 +400,7 @@ func (le *LeaderElector) tryAcquireOrRenew() bool {
 	}
 	le.observedRecord = leaderElectionRecord
 	le.observedTime = le.clock.Now()
+	le.setLeading(true, leaderElectionRecord.FencingToken)
 	return true
 }
 
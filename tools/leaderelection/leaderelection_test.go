@@ -307,6 +307,13 @@ func testTryAcquireOrRenew(t *testing.T, objectType string) {
 			if !test.transitionLeader && le.observedRecord.LeaderTransitions != 0 {
 				t.Errorf("leader should not have transitioned but did")
 			}
+			fencingToken, leading := le.FencingToken()
+			if leading != test.expectSuccess {
+				t.Errorf("expected leading=%v, got %v", test.expectSuccess, leading)
+			}
+			if leading && fencingToken != int64(le.observedRecord.LeaderTransitions) {
+				t.Errorf("expected the fencing token to follow the transitions, got %d for %d transitions", fencingToken, le.observedRecord.LeaderTransitions)
+			}
 
 			le.maybeReportTransition()
 			wg.Wait()
@@ -327,6 +334,66 @@ func TestTryAcquireOrRenewLeases(t *testing.T) {
 	testTryAcquireOrRenew(t, "leases")
 }
 
+func TestFencingTokenIncreasesAcrossLeaders(t *testing.T) {
+	for _, objectType := range []string{"endpoints", "configmaps", "leases"} {
+		t.Run(objectType, func(t *testing.T) {
+			c := fake.NewSimpleClientset()
+			newElector := func(identity string) *LeaderElector {
+				lock, err := rl.New(objectType, "foo", "bar", c.CoreV1(), c.CoordinationV1(), rl.ResourceLockConfig{Identity: identity})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return &LeaderElector{
+					config: LeaderElectionConfig{Lock: lock, LeaseDuration: 10 * time.Second},
+					clock:  clock.RealClock{},
+				}
+			}
+			first, second := newElector("first"), newElector("second")
+
+			if !first.tryAcquireOrRenew() {
+				t.Fatalf("first elector failed to acquire the lock")
+			}
+			firstToken, leading := first.FencingToken()
+			if !leading {
+				t.Fatalf("expected the first elector to lead")
+			}
+			if second.tryAcquireOrRenew() {
+				t.Fatalf("second elector acquired a held lock")
+			}
+			if _, leading := second.FencingToken(); leading {
+				t.Errorf("expected the second elector not to lead")
+			}
+
+			if !first.release() {
+				t.Fatalf("first elector failed to release the lock")
+			}
+			if _, leading := first.FencingToken(); leading {
+				t.Errorf("expected the first elector to stop leading")
+			}
+			if !second.tryAcquireOrRenew() {
+				t.Fatalf("second elector failed to acquire the released lock")
+			}
+			secondToken, leading := second.FencingToken()
+			if !leading || secondToken <= firstToken {
+				t.Errorf("expected a fencing token greater than %d, got %d (leading=%v)", firstToken, secondToken, leading)
+			}
+			if !second.tryAcquireOrRenew() {
+				t.Fatalf("second elector failed to renew the lock")
+			}
+			if token, _ := second.FencingToken(); token != secondToken {
+				t.Errorf("expected renewing to keep the fencing token %d, got %d", secondToken, token)
+			}
+			record, err := second.config.Lock.Get()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if record.FencingToken != secondToken {
+				t.Errorf("expected the lock record to hold fencing token %d, got %d", secondToken, record.FencingToken)
+			}
+		})
+	}
+}
+
 func TestLeaseSpecToLeaderElectionRecordRoundTrip(t *testing.T) {
 	holderIdentity := "foo"
 	leaseDurationSeconds := int32(10)
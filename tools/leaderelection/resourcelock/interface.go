@@ -47,6 +47,11 @@ type LeaderElectionRecord struct {
 	AcquireTime          metav1.Time `json:"acquireTime"`
 	RenewTime            metav1.Time `json:"renewTime"`
 	LeaderTransitions    int         `json:"leaderTransitions"`
+	// FencingToken identifies the term of the holder.  It is derived from
+	// LeaderTransitions, so it increases every time the lock changes hands,
+	// and lets systems written to by the leader reject writes carrying the
+	// token of a previous leader.
+	FencingToken int64 `json:"fencingToken,omitempty"`
 }
 
 // EventRecorder records a change in the ResourceLock.
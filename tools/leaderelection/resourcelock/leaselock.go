@@ -108,6 +108,7 @@ func LeaseSpecToLeaderElectionRecord(spec *coordinationv1.LeaseSpec) *LeaderElec
 		AcquireTime:          metav1.Time{spec.AcquireTime.Time},
 		RenewTime:            metav1.Time{spec.RenewTime.Time},
 		LeaderTransitions:    leaseTransitions,
+		FencingToken:         int64(leaseTransitions),
 	}
 }
 
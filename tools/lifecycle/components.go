@@ -0,0 +1,113 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// InformerStarter is implemented by informer factories.
+type InformerStarter interface {
+	// Start starts the informers of the factory, which stop when stopCh is
+	// closed.
+	Start(stopCh <-chan struct{})
+}
+
+// Informers returns a component starting the informers of factory, which is
+// ready once every cacheSyncs returns true.  Informers must be obtained from
+// the factory before it is started.
+func Informers(name string, factory InformerStarter, cacheSyncs ...cache.InformerSynced) Component {
+	return Component{
+		Name: name,
+		Start: func(ctx context.Context) error {
+			factory.Start(ctx.Done())
+			if !cache.WaitForNamedCacheSync(name, ctx.Done(), cacheSyncs...) {
+				return fmt.Errorf("caches did not sync")
+			}
+			return nil
+		},
+	}
+}
+
+// WorkQueue returns a component shutting queue down when it is stopped, so
+// that the workers getting items from it return.  It should depend on the
+// component of these workers, so that it is stopped before them.
+func WorkQueue(name string, queue workqueue.Interface) Component {
+	return Component{
+		Name:  name,
+		Start: func(context.Context) error { return nil },
+		Stop: func(context.Context) error {
+			queue.ShutDown()
+			return nil
+		},
+	}
+}
+
+// Workers returns a component running worker on the given number of
+// goroutines, calling it again a second after it returns, until the component
+// is stopped.  Stopping the component waits for the running workers to
+// return, so the work queue they process, if any, must be shut down before:
+// see WorkQueue.
+func Workers(name string, workers int, worker func(ctx context.Context)) Component {
+	var wg sync.WaitGroup
+	return Component{
+		Name: name,
+		Start: func(ctx context.Context) error {
+			for i := 0; i < workers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					wait.UntilWithContext(ctx, worker, time.Second)
+				}()
+			}
+			return nil
+		},
+		Stop: func(context.Context) error {
+			wg.Wait()
+			return nil
+		},
+	}
+}
+
+// Runnable returns a component calling run, for instance the Run method of a
+// leaderelection.LeaderElector, on its own goroutine until it returns.  run
+// must return when its context is cancelled, which happens when the
+// component is stopped.
+func Runnable(name string, run func(ctx context.Context)) Component {
+	done := make(chan struct{})
+	return Component{
+		Name: name,
+		Start: func(ctx context.Context) error {
+			go func() {
+				defer close(done)
+				run(ctx)
+			}()
+			return nil
+		},
+		Stop: func(context.Context) error {
+			<-done
+			return nil
+		},
+	}
+}
@@ -0,0 +1,259 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package lifecycle starts the components of a controller binary, such as
+// informer factories, leader election, work queues and worker pools, in the
+// order of their dependencies, and stops them in the reverse order:
+//
+//	manager := lifecycle.NewManager()
+//	manager.Add(lifecycle.Informers("informers", factory, podInformer.HasSynced))
+//	manager.Add(lifecycle.Workers("workers", 5, worker).After("informers"))
+//	manager.Add(lifecycle.WorkQueue("queue", queue).After("workers"))
+//	if err := manager.Run(lifecycle.SignalContext()); err != nil {
+//		klog.Fatal(err)
+//	}
+package lifecycle // import "k8s.io/client-go/tools/lifecycle"
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog"
+)
+
+// DefaultStopTimeout is how long a component is given to stop if it does not
+// set a StopTimeout.
+const DefaultStopTimeout = 30 * time.Second
+
+// Component is a part of a controller binary managed by a Manager.
+type Component struct {
+	// Name identifies the component, in dependencies and errors.
+	Name string
+	// DependsOn are the names of the components that must be started before
+	// this one, and stopped after it.
+	DependsOn []string
+	// Start starts the component and returns once it is ready, for
+	// instance once its caches have synced.  ctx is cancelled when the
+	// component must stop, and is the only way to stop components without
+	// a Stop function.  Start must return if ctx is cancelled before the
+	// component is ready.
+	Start func(ctx context.Context) error
+	// Stop, if set, is called after ctx is cancelled and returns once the
+	// component has stopped, or once its own ctx is cancelled after
+	// StopTimeout.
+	Stop func(ctx context.Context) error
+	// StopTimeout bounds the time Stop may take, DefaultStopTimeout if
+	// zero.  The components depending on this one are not waited for
+	// longer than their own StopTimeout.
+	StopTimeout time.Duration
+}
+
+// After returns a copy of the component that also depends on the named
+// components.
+func (c Component) After(names ...string) Component {
+	c.DependsOn = append(append([]string(nil), c.DependsOn...), names...)
+	return c
+}
+
+// Manager starts and stops components in the order of their dependencies.
+type Manager struct {
+	lock       sync.Mutex
+	components []Component
+	running    bool
+}
+
+// NewManager returns a Manager without components.
+func NewManager() *Manager {
+	return &Manager{}
+}
+
+// Add adds a component to the manager.  It returns an error if the manager is
+// running or already has a component of the same name.
+func (m *Manager) Add(component Component) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.running {
+		return fmt.Errorf("cannot add component %q to a running manager", component.Name)
+	}
+	if component.Start == nil {
+		return fmt.Errorf("component %q has no Start function", component.Name)
+	}
+	for _, c := range m.components {
+		if c.Name == component.Name {
+			return fmt.Errorf("duplicate component %q", component.Name)
+		}
+	}
+	m.components = append(m.components, component)
+	return nil
+}
+
+// Run starts the components, a component only once those it depends on are
+// ready, then waits for ctx to be cancelled, and stops the components in the
+// reverse order.  If a component fails to start, the components started so
+// far are stopped.  Run returns the errors of the components, aggregated.  A
+// Manager can only be run once.
+func (m *Manager) Run(ctx context.Context) error {
+	m.lock.Lock()
+	if m.running {
+		m.lock.Unlock()
+		return fmt.Errorf("the manager is already running")
+	}
+	m.running = true
+	components := m.components
+	m.lock.Unlock()
+
+	ordered, err := startOrder(components)
+	if err != nil {
+		return err
+	}
+
+	var started []*runningComponent
+	var errs []error
+	for _, component := range ordered {
+		if ctx.Err() != nil {
+			break
+		}
+		klog.V(2).Infof("Starting %s", component.Name)
+		running := &runningComponent{component: component}
+		running.ctx, running.cancel = context.WithCancel(context.Background())
+		// A component still starting when the manager is stopped is
+		// stopped right away.
+		startDone := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				running.cancel()
+			case <-startDone:
+			}
+		}()
+		err := component.Start(running.ctx)
+		close(startDone)
+		started = append(started, running)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to start %s: %v", component.Name, err))
+			break
+		}
+	}
+	if len(errs) == 0 {
+		<-ctx.Done()
+	}
+
+	for i := len(started) - 1; i >= 0; i-- {
+		if err := started[i].stop(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+type runningComponent struct {
+	component Component
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+// stop cancels the context of the component and waits for its Stop function,
+// if any, for at most its StopTimeout.
+func (r *runningComponent) stop() error {
+	klog.V(2).Infof("Stopping %s", r.component.Name)
+	r.cancel()
+	if r.component.Stop == nil {
+		return nil
+	}
+	timeout := r.component.StopTimeout
+	if timeout == 0 {
+		timeout = DefaultStopTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- r.component.Stop(ctx)
+	}()
+	select {
+	case err := <-stopped:
+		if err != nil {
+			return fmt.Errorf("failed to stop %s: %v", r.component.Name, err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%s did not stop within %v", r.component.Name, timeout)
+	}
+}
+
+// startOrder sorts components so that every component comes after those it
+// depends on, keeping the order in which they were added otherwise.
+func startOrder(components []Component) ([]Component, error) {
+	byName := make(map[string]Component, len(components))
+	for _, component := range components {
+		byName[component.Name] = component
+	}
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int, len(components))
+	ordered := make([]Component, 0, len(components))
+	var visit func(component Component, path []string) error
+	visit = func(component Component, path []string) error {
+		switch state[component.Name] {
+		case visiting:
+			return fmt.Errorf("dependency cycle: %v", append(path, component.Name))
+		case visited:
+			return nil
+		}
+		state[component.Name] = visiting
+		for _, name := range component.DependsOn {
+			dependency, ok := byName[name]
+			if !ok {
+				return fmt.Errorf("component %q depends on unknown component %q", component.Name, name)
+			}
+			if err := visit(dependency, append(path, component.Name)); err != nil {
+				return err
+			}
+		}
+		state[component.Name] = visited
+		ordered = append(ordered, component)
+		return nil
+	}
+	for _, component := range components {
+		if err := visit(component, nil); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// SignalContext returns a context cancelled on SIGINT or SIGTERM.  A second
+// signal exits the process with status 1.  It must be called only once.
+func SignalContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		cancel()
+		<-signals
+		os.Exit(1)
+	}()
+	return ctx
+}
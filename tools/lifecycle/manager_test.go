@@ -0,0 +1,201 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/util/workqueue"
+)
+
+// recorder records the starts and stops of components.
+type recorder struct {
+	lock   sync.Mutex
+	events []string
+}
+
+func (r *recorder) record(event string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) component(name string, startErr error, dependsOn ...string) Component {
+	return Component{
+		Name:      name,
+		DependsOn: dependsOn,
+		Start: func(context.Context) error {
+			r.record("start " + name)
+			return startErr
+		},
+		Stop: func(context.Context) error {
+			r.record("stop " + name)
+			return nil
+		},
+	}
+}
+
+func TestManagerOrder(t *testing.T) {
+	r := &recorder{}
+	m := NewManager()
+	for _, c := range []Component{
+		r.component("workers", nil, "informers", "election"),
+		r.component("informers", nil),
+		r.component("election", nil, "informers"),
+	} {
+		if err := m.Add(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Add(r.component("informers", nil)); err == nil {
+		t.Errorf("expected an error adding a duplicate component")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := m.Run(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The context is cancelled before anything starts.
+	if len(r.events) != 0 {
+		t.Errorf("expected nothing to start, got %v", r.events)
+	}
+
+	r = &recorder{}
+	m = NewManager()
+	m.Add(r.component("workers", nil, "informers", "election"))
+	m.Add(r.component("informers", nil))
+	m.Add(r.component("election", nil, "informers"))
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if err := m.Run(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{
+		"start informers", "start election", "start workers",
+		"stop workers", "stop election", "stop informers",
+	}
+	if !reflect.DeepEqual(r.events, expected) {
+		t.Errorf("expected %v, got %v", expected, r.events)
+	}
+}
+
+func TestManagerStartFailure(t *testing.T) {
+	r := &recorder{}
+	m := NewManager()
+	m.Add(r.component("informers", nil))
+	m.Add(r.component("election", errors.New("no lock"), "informers"))
+	m.Add(r.component("workers", nil, "election"))
+
+	err := m.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "failed to start election: no lock") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	expected := []string{"start informers", "start election", "stop election", "stop informers"}
+	if !reflect.DeepEqual(r.events, expected) {
+		t.Errorf("expected %v, got %v", expected, r.events)
+	}
+}
+
+func TestManagerInvalidDependencies(t *testing.T) {
+	r := &recorder{}
+	m := NewManager()
+	m.Add(r.component("a", nil, "b"))
+	m.Add(r.component("b", nil, "a"))
+	if err := m.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "dependency cycle") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	m = NewManager()
+	m.Add(r.component("a", nil, "missing"))
+	if err := m.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "unknown component") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(r.events) != 0 {
+		t.Errorf("expected nothing to start, got %v", r.events)
+	}
+}
+
+func TestManagerStopTimeout(t *testing.T) {
+	r := &recorder{}
+	m := NewManager()
+	m.Add(r.component("first", nil))
+	m.Add(Component{
+		Name:        "stuck",
+		DependsOn:   []string{"first"},
+		Start:       func(context.Context) error { return nil },
+		Stop:        func(context.Context) error { select {} },
+		StopTimeout: 10 * time.Millisecond,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go cancel()
+	err := m.Run(ctx)
+	if err == nil || !strings.Contains(err.Error(), "stuck did not stop within 10ms") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	// The components the stuck one depends on are stopped nonetheless.
+	if expected := []string{"start first", "stop first"}; !reflect.DeepEqual(r.events, expected) {
+		t.Errorf("expected %v, got %v", expected, r.events)
+	}
+}
+
+func TestWorkQueueWorkers(t *testing.T) {
+	queue := workqueue.New()
+	processed := make(chan interface{}, 1)
+	worker := func(ctx context.Context) {
+		for {
+			item, shutdown := queue.Get()
+			if shutdown {
+				return
+			}
+			processed <- item
+			queue.Done(item)
+		}
+	}
+	m := NewManager()
+	m.Add(Workers("workers", 2, worker))
+	m.Add(WorkQueue("queue", queue).After("workers"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- m.Run(ctx)
+	}()
+	queue.Add("item")
+	if item := <-processed; item != "item" {
+		t.Errorf("unexpected item %v", item)
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(DefaultStopTimeout):
+		t.Fatalf("the manager did not stop")
+	}
+}
@@ -0,0 +1,245 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package quota aggregates the resources consumed by the objects of
+// informers per namespace, so that controllers enforcing or reporting quotas
+// share a running total instead of each recomputing it from full lists:
+//
+//	accountant := quota.NewAccountant()
+//	accountant.AddInformer(podInformer, quota.PodEvaluator)
+//	accountant.AddInformer(pvcInformer, quota.PersistentVolumeClaimEvaluator)
+//	...
+//	cpu := accountant.Usage("default")[v1.ResourceRequestsCPU]
+package quota // import "k8s.io/client-go/tools/quota"
+
+import (
+	"sort"
+	"sync"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+)
+
+// UsageListener is notified when the usage of a namespace changes, with the
+// usage before and after the change.  A namespace whose objects are all gone
+// has an empty usage.
+type UsageListener func(namespace string, oldUsage, newUsage v1.ResourceList)
+
+// Accountant maintains the resource usage of every namespace from the
+// notifications of informers.  It is safe for concurrent use.
+type Accountant struct {
+	lock sync.RWMutex
+	// objects holds the usage of every object, by informer and key.
+	objects map[objectID]objectUsage
+	// namespaces holds the total usage of every namespace.
+	namespaces    map[string]v1.ResourceList
+	registrations []cache.ResourceEventHandlerRegistration
+	listeners     []UsageListener
+	sources       int
+}
+
+// objectID identifies an object across informers, which may use the same
+// keys for objects of different resources.
+type objectID struct {
+	source int
+	key    string
+}
+
+type objectUsage struct {
+	namespace string
+	usage     v1.ResourceList
+}
+
+// NewAccountant returns an Accountant without informers.
+func NewAccountant() *Accountant {
+	return &Accountant{
+		objects:    map[objectID]objectUsage{},
+		namespaces: map[string]v1.ResourceList{},
+	}
+}
+
+// AddInformer accounts for the objects of informer, whose usage is returned
+// by evaluator.  Objects the evaluator fails on are logged and consume
+// nothing.
+func (a *Accountant) AddInformer(informer cache.SharedInformer, evaluator Evaluator) error {
+	a.lock.Lock()
+	source := a.sources
+	a.sources++
+	a.lock.Unlock()
+
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			a.update(source, obj, evaluator)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			a.update(source, newObj, evaluator)
+		},
+		DeleteFunc: func(obj interface{}) {
+			a.delete(source, obj)
+		},
+	}
+	registration, err := informer.AddEventHandler(handler)
+	if err != nil {
+		return err
+	}
+	a.lock.Lock()
+	a.registrations = append(a.registrations, registration)
+	a.lock.Unlock()
+	return nil
+}
+
+// AddListener registers listener to be notified of the changes of usage.
+// Listeners are called from the handlers of the informers, one informer
+// at a time, so they should return quickly; the changes caused by
+// different informers may be notified concurrently.
+func (a *Accountant) AddListener(listener UsageListener) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.listeners = append(a.listeners, listener)
+}
+
+// HasSynced returns true once every informer has synced and its objects
+// have been accounted for.
+func (a *Accountant) HasSynced() bool {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	for _, registration := range a.registrations {
+		if !registration.HasSynced() {
+			return false
+		}
+	}
+	return true
+}
+
+// Usage returns the resources consumed in namespace.  The returned list is
+// a copy owned by the caller.
+func (a *Accountant) Usage(namespace string) v1.ResourceList {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	return a.namespaces[namespace].DeepCopy()
+}
+
+// TotalUsage returns the resources consumed across all namespaces.
+func (a *Accountant) TotalUsage() v1.ResourceList {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	total := v1.ResourceList{}
+	for _, usage := range a.namespaces {
+		addResources(total, usage)
+	}
+	return total
+}
+
+// Namespaces returns the sorted namespaces consuming resources.
+func (a *Accountant) Namespaces() []string {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	namespaces := make([]string, 0, len(a.namespaces))
+	for namespace := range a.namespaces {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+func (a *Accountant) update(source int, obj interface{}, evaluator Evaluator) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	objMeta, err := meta.Accessor(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	usage, err := evaluator.Usage(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		usage = nil
+	}
+	a.set(objectID{source, key}, objectUsage{namespace: objMeta.GetNamespace(), usage: usage})
+}
+
+func (a *Accountant) delete(source int, obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	a.set(objectID{source, key}, objectUsage{})
+}
+
+// set replaces the usage of an object, an empty usage removing it, and
+// notifies the listeners of the namespaces whose usage changed.
+func (a *Accountant) set(id objectID, next objectUsage) {
+	type change struct {
+		namespace          string
+		oldUsage, newUsage v1.ResourceList
+	}
+	var changes []change
+
+	a.lock.Lock()
+	previous := a.objects[id]
+	if len(next.usage) == 0 {
+		delete(a.objects, id)
+	} else {
+		a.objects[id] = next
+	}
+	for _, namespace := range affectedNamespaces(previous, next) {
+		oldUsage := a.namespaces[namespace]
+		newUsage := oldUsage.DeepCopy()
+		if newUsage == nil {
+			newUsage = v1.ResourceList{}
+		}
+		if previous.namespace == namespace {
+			subtractResources(newUsage, previous.usage)
+		}
+		if next.namespace == namespace {
+			addResources(newUsage, next.usage)
+		}
+		if equalResources(oldUsage, newUsage) {
+			continue
+		}
+		if len(newUsage) == 0 {
+			delete(a.namespaces, namespace)
+		} else {
+			a.namespaces[namespace] = newUsage
+		}
+		changes = append(changes, change{namespace, oldUsage, newUsage.DeepCopy()})
+	}
+	listeners := a.listeners
+	a.lock.Unlock()
+
+	for _, c := range changes {
+		for _, listener := range listeners {
+			listener(c.namespace, c.oldUsage, c.newUsage)
+		}
+	}
+}
+
+func affectedNamespaces(previous, next objectUsage) []string {
+	var namespaces []string
+	if len(previous.usage) > 0 {
+		namespaces = append(namespaces, previous.namespace)
+	}
+	if len(next.usage) > 0 && (len(namespaces) == 0 || next.namespace != previous.namespace) {
+		namespaces = append(namespaces, next.namespace)
+	}
+	return namespaces
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	fcache "k8s.io/client-go/tools/cache/testing"
+)
+
+func newPod(namespace, name, cpu, memory string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{{
+				Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu)},
+					Limits:   v1.ResourceList{v1.ResourceMemory: resource.MustParse(memory)},
+				},
+			}},
+		},
+	}
+}
+
+func expectUsage(t *testing.T, usage v1.ResourceList, expected map[v1.ResourceName]string) {
+	t.Helper()
+	list := v1.ResourceList{}
+	for name, quantity := range expected {
+		list[name] = resource.MustParse(quantity)
+	}
+	if !equalResources(usage, list) {
+		t.Errorf("expected usage %v, got %v", list, usage)
+	}
+}
+
+func TestPodEvaluator(t *testing.T) {
+	pod := newPod("ns", "pod", "100m", "64Mi")
+	pod.Spec.Containers = append(pod.Spec.Containers, pod.Spec.Containers[0])
+	pod.Spec.InitContainers = []v1.Container{{
+		Resources: v1.ResourceRequirements{
+			Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("500m")},
+			Limits:   v1.ResourceList{v1.ResourceMemory: resource.MustParse("32Mi")},
+		},
+	}}
+	pod.Spec.Overhead = v1.ResourceList{v1.ResourceCPU: resource.MustParse("10m")}
+
+	usage, err := PodEvaluator.Usage(pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectUsage(t, usage, map[v1.ResourceName]string{
+		v1.ResourcePods:        "1",
+		"count/pods":           "1",
+		v1.ResourceRequestsCPU: "510m",
+		v1.ResourceLimitsCPU:   "10m",
+		"limits.memory":        "128Mi",
+	})
+
+	pod.Status.Phase = v1.PodSucceeded
+	if usage, err := PodEvaluator.Usage(pod); err != nil || len(usage) != 0 {
+		t.Errorf("expected a terminated pod to consume nothing, got %v, %v", usage, err)
+	}
+	if _, err := PodEvaluator.Usage(&v1.Service{}); err == nil {
+		t.Errorf("expected an error evaluating a service")
+	}
+}
+
+func TestAccountant(t *testing.T) {
+	pods := fcache.NewFakeControllerSource()
+	pods.Add(newPod("ns1", "pod1", "100m", "64Mi"))
+	pods.Add(newPod("ns2", "pod2", "200m", "64Mi"))
+	pvcs := fcache.NewFakeControllerSource()
+	pvcs.Add(&v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "pvc"},
+		Spec: v1.PersistentVolumeClaimSpec{
+			Resources: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse("1Gi")}},
+		},
+	})
+	podInformer := cache.NewSharedInformer(pods, &v1.Pod{}, 0)
+	pvcInformer := cache.NewSharedInformer(pvcs, &v1.PersistentVolumeClaim{}, 0)
+
+	accountant := NewAccountant()
+	if err := accountant.AddInformer(podInformer, PodEvaluator); err != nil {
+		t.Fatal(err)
+	}
+	if err := accountant.AddInformer(pvcInformer, PersistentVolumeClaimEvaluator); err != nil {
+		t.Fatal(err)
+	}
+	if err := accountant.AddInformer(podInformer, ObjectCountEvaluator(schema.GroupResource{Resource: "mypods"})); err != nil {
+		t.Fatal(err)
+	}
+	var lock sync.Mutex
+	changes := map[string]v1.ResourceList{}
+	accountant.AddListener(func(namespace string, oldUsage, newUsage v1.ResourceList) {
+		lock.Lock()
+		defer lock.Unlock()
+		changes[namespace] = newUsage
+	})
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go podInformer.Run(stop)
+	go pvcInformer.Run(stop)
+	if !cache.WaitForCacheSync(stop, accountant.HasSynced) {
+		t.Fatal("accountant hasn't synced")
+	}
+
+	expectUsage(t, accountant.Usage("ns1"), map[v1.ResourceName]string{
+		v1.ResourcePods:                   "1",
+		"count/pods":                      "1",
+		"count/mypods":                    "1",
+		v1.ResourceRequestsCPU:            "100m",
+		"limits.memory":                   "64Mi",
+		v1.ResourcePersistentVolumeClaims: "1",
+		"count/persistentvolumeclaims":    "1",
+		v1.ResourceRequestsStorage:        "1Gi",
+	})
+	if cpu := accountant.TotalUsage()[v1.ResourceRequestsCPU]; cpu.Cmp(resource.MustParse("300m")) != 0 {
+		t.Errorf("expected a total of 300m CPU, got %v", cpu.String())
+	}
+	if namespaces := accountant.Namespaces(); len(namespaces) != 2 || namespaces[0] != "ns1" || namespaces[1] != "ns2" {
+		t.Errorf("expected namespaces ns1 and ns2, got %v", namespaces)
+	}
+
+	waitForChange := func(namespace string, check func(v1.ResourceList) bool) {
+		t.Helper()
+		err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			usage, found := changes[namespace]
+			return found && check(usage), nil
+		})
+		if err != nil {
+			t.Fatalf("no change notified for %s", namespace)
+		}
+	}
+	cpu := func(quantity string) func(v1.ResourceList) bool {
+		return func(usage v1.ResourceList) bool {
+			requested := usage[v1.ResourceRequestsCPU]
+			return requested.Cmp(resource.MustParse(quantity)) == 0
+		}
+	}
+
+	pods.Modify(newPod("ns2", "pod2", "1", "64Mi"))
+	waitForChange("ns2", cpu("1"))
+	pods.Add(newPod("ns2", "pod3", "500m", "64Mi"))
+	waitForChange("ns2", cpu("1500m"))
+	expectUsage(t, accountant.Usage("ns2"), map[v1.ResourceName]string{
+		v1.ResourcePods:        "2",
+		"count/pods":           "2",
+		"count/mypods":         "2",
+		v1.ResourceRequestsCPU: "1500m",
+		"limits.memory":        "128Mi",
+	})
+
+	pods.Delete(newPod("ns2", "pod2", "1", "64Mi"))
+	pods.Delete(newPod("ns2", "pod3", "500m", "64Mi"))
+	waitForChange("ns2", func(usage v1.ResourceList) bool { return len(usage) == 0 })
+	if usage := accountant.Usage("ns2"); len(usage) != 0 {
+		t.Errorf("expected ns2 to consume nothing, got %v", usage)
+	}
+	if namespaces := accountant.Namespaces(); len(namespaces) != 1 || namespaces[0] != "ns1" {
+		t.Errorf("expected namespace ns1 only, got %v", namespaces)
+	}
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"fmt"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Evaluator returns the resources an object consumes, named like the
+// resources of a ResourceQuota.
+type Evaluator interface {
+	Usage(obj interface{}) (v1.ResourceList, error)
+}
+
+// EvaluatorFunc is an Evaluator implemented by a function.
+type EvaluatorFunc func(obj interface{}) (v1.ResourceList, error)
+
+// Usage implements Evaluator.
+func (f EvaluatorFunc) Usage(obj interface{}) (v1.ResourceList, error) {
+	return f(obj)
+}
+
+// PodEvaluator counts pods, and the requests and limits of their containers
+// as the scheduler sees them: the largest request of an init container
+// counts if it exceeds the sum of the requests of the other containers, and
+// the pod overhead is added.  Pods that have terminated consume nothing.
+var PodEvaluator Evaluator = EvaluatorFunc(podUsage)
+
+// PersistentVolumeClaimEvaluator counts persistent volume claims and the
+// storage they request.
+var PersistentVolumeClaimEvaluator Evaluator = EvaluatorFunc(pvcUsage)
+
+// ObjectCountEvaluator counts objects of groupResource under "count/<resource>",
+// the name of object count quotas.
+func ObjectCountEvaluator(groupResource schema.GroupResource) Evaluator {
+	name := v1.ResourceName("count/" + groupResource.String())
+	return EvaluatorFunc(func(obj interface{}) (v1.ResourceList, error) {
+		return v1.ResourceList{name: *resource.NewQuantity(1, resource.DecimalSI)}, nil
+	})
+}
+
+func podUsage(obj interface{}) (v1.ResourceList, error) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return nil, fmt.Errorf("expected a *v1.Pod, got %T", obj)
+	}
+	if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+		return nil, nil
+	}
+
+	requests, limits := v1.ResourceList{}, v1.ResourceList{}
+	for _, container := range pod.Spec.Containers {
+		addResources(requests, container.Resources.Requests)
+		addResources(limits, container.Resources.Limits)
+	}
+	for _, container := range pod.Spec.InitContainers {
+		maxResources(requests, container.Resources.Requests)
+		maxResources(limits, container.Resources.Limits)
+	}
+	addResources(requests, pod.Spec.Overhead)
+	addResources(limits, pod.Spec.Overhead)
+
+	usage := v1.ResourceList{
+		v1.ResourcePods:               *resource.NewQuantity(1, resource.DecimalSI),
+		v1.ResourceName("count/pods"): *resource.NewQuantity(1, resource.DecimalSI),
+	}
+	for name, quantity := range requests {
+		usage[v1.ResourceName(v1.DefaultResourceRequestsPrefix+string(name))] = quantity
+	}
+	for name, quantity := range limits {
+		usage[v1.ResourceName("limits."+string(name))] = quantity
+	}
+	return usage, nil
+}
+
+func pvcUsage(obj interface{}) (v1.ResourceList, error) {
+	pvc, ok := obj.(*v1.PersistentVolumeClaim)
+	if !ok {
+		return nil, fmt.Errorf("expected a *v1.PersistentVolumeClaim, got %T", obj)
+	}
+	usage := v1.ResourceList{
+		v1.ResourcePersistentVolumeClaims:               *resource.NewQuantity(1, resource.DecimalSI),
+		v1.ResourceName("count/persistentvolumeclaims"): *resource.NewQuantity(1, resource.DecimalSI),
+	}
+	if storage, found := pvc.Spec.Resources.Requests[v1.ResourceStorage]; found {
+		usage[v1.ResourceRequestsStorage] = storage.DeepCopy()
+	}
+	return usage, nil
+}
+
+// addResources adds the quantities of delta to list.
+func addResources(list, delta v1.ResourceList) {
+	for name, quantity := range delta {
+		if current, found := list[name]; found {
+			current.Add(quantity)
+			list[name] = current
+		} else {
+			list[name] = quantity.DeepCopy()
+		}
+	}
+}
+
+// subtractResources subtracts the quantities of delta from list, dropping
+// the resources that reach zero.
+func subtractResources(list, delta v1.ResourceList) {
+	for name, quantity := range delta {
+		current := list[name]
+		current.Sub(quantity)
+		if current.IsZero() {
+			delete(list, name)
+		} else {
+			list[name] = current
+		}
+	}
+}
+
+// maxResources raises the quantities of list to those of other.
+func maxResources(list, other v1.ResourceList) {
+	for name, quantity := range other {
+		if current, found := list[name]; !found || quantity.Cmp(current) > 0 {
+			list[name] = quantity.DeepCopy()
+		}
+	}
+}
+
+// equalResources returns whether a and b hold the same quantities.
+func equalResources(a, b v1.ResourceList) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, quantity := range a {
+		other, found := b[name]
+		if !found || quantity.Cmp(other) != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// EventSeq is a push iterator over watch events: it calls yield with every
+// event in turn, and stops as soon as yield returns false.  It has the shape
+// of a Go iterator, so an iter.Seq[watch.Event] can be converted to it.
+type EventSeq func(yield func(watch.Event) bool)
+
+// adapter is a watch.Interface fed by a goroutine.
+type adapter struct {
+	result chan watch.Event
+	done   chan struct{}
+	once   sync.Once
+}
+
+func newAdapter() *adapter {
+	return &adapter{
+		result: make(chan watch.Event),
+		done:   make(chan struct{}),
+	}
+}
+
+// Stop stops delivering events and closes the result channel.  It is safe
+// to call Stop more than once.
+func (a *adapter) Stop() {
+	a.once.Do(func() { close(a.done) })
+}
+
+func (a *adapter) ResultChan() <-chan watch.Event {
+	return a.result
+}
+
+// send delivers event, returning false if the adapter was stopped instead.
+func (a *adapter) send(event watch.Event) bool {
+	select {
+	case a.result <- event:
+		return true
+	case <-a.done:
+		return false
+	}
+}
+
+// FromChannel returns a watch.Interface delivering the events received from
+// ch.  Its result channel is closed once ch is closed or the watch is
+// stopped.  Stopping the watch does not drain ch.
+func FromChannel(ch <-chan watch.Event) watch.Interface {
+	a := newAdapter()
+	go func() {
+		defer close(a.result)
+		for {
+			select {
+			case event, ok := <-ch:
+				if !ok || !a.send(event) {
+					return
+				}
+			case <-a.done:
+				return
+			}
+		}
+	}()
+	return a
+}
+
+// FromEvents returns a watch.Interface delivering events, then closing its
+// result channel.
+func FromEvents(events ...watch.Event) watch.Interface {
+	return FromSeq(func(yield func(watch.Event) bool) {
+		for _, event := range events {
+			if !yield(event) {
+				return
+			}
+		}
+	})
+}
+
+// FromSeq returns a watch.Interface delivering the events of seq, which runs
+// in its own goroutine.  Its result channel is closed once seq returns.
+// Stopping the watch makes yield return false.
+func FromSeq(seq EventSeq) watch.Interface {
+	a := newAdapter()
+	go func() {
+		defer close(a.result)
+		seq(a.send)
+	}()
+	return a
+}
+
+// Events returns an EventSeq over the events of w.  w is stopped when the
+// iteration ends, whether w closed its result channel or yield returned
+// false.
+func Events(w watch.Interface) EventSeq {
+	return func(yield func(watch.Event) bool) {
+		defer w.Stop()
+		for event := range w.ResultChan() {
+			if !yield(event) {
+				return
+			}
+		}
+	}
+}
+
+// Collect returns the events of w until it closes its result channel.
+func Collect(w watch.Interface) []watch.Event {
+	var events []watch.Event
+	Events(w)(func(event watch.Event) bool {
+		events = append(events, event)
+		return true
+	})
+	return events
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func testEvents() []watch.Event {
+	return []watch.Event{
+		{Type: watch.Added, Object: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "a"}}},
+		{Type: watch.Modified, Object: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "a"}}},
+		{Type: watch.Deleted, Object: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "a"}}},
+	}
+}
+
+func expectClosed(t *testing.T, w watch.Interface) {
+	t.Helper()
+	select {
+	case _, ok := <-w.ResultChan():
+		if ok {
+			t.Errorf("expected the result channel to be closed")
+		}
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Errorf("timed out waiting for the result channel to close")
+	}
+}
+
+func TestFromEvents(t *testing.T) {
+	events := testEvents()
+	if got := Collect(FromEvents(events...)); !reflect.DeepEqual(got, events) {
+		t.Errorf("expected %v, got %v", events, got)
+	}
+}
+
+func TestFromChannel(t *testing.T) {
+	events := testEvents()
+	ch := make(chan watch.Event, len(events))
+	for _, event := range events {
+		ch <- event
+	}
+	close(ch)
+	if got := Collect(FromChannel(ch)); !reflect.DeepEqual(got, events) {
+		t.Errorf("expected %v, got %v", events, got)
+	}
+
+	// Stopping does not wait for the channel.
+	w := FromChannel(make(chan watch.Event))
+	w.Stop()
+	w.Stop()
+	expectClosed(t, w)
+}
+
+func TestFromSeqStop(t *testing.T) {
+	returned := make(chan bool, 1)
+	w := FromSeq(func(yield func(watch.Event) bool) {
+		for {
+			if !yield(watch.Event{Type: watch.Bookmark}) {
+				returned <- true
+				return
+			}
+		}
+	})
+	<-w.ResultChan()
+	w.Stop()
+	select {
+	case <-returned:
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatalf("expected yield to return false once the watch is stopped")
+	}
+	expectClosed(t, w)
+}
+
+func TestEventsStopsWatch(t *testing.T) {
+	fw := watch.NewFakeWithChanSize(3, false)
+	for _, event := range testEvents() {
+		fw.Action(event.Type, event.Object)
+	}
+	var got []watch.EventType
+	Events(fw)(func(event watch.Event) bool {
+		got = append(got, event.Type)
+		return len(got) < 2
+	})
+	if e := []watch.EventType{watch.Added, watch.Modified}; !reflect.DeepEqual(got, e) {
+		t.Errorf("expected %v, got %v", e, got)
+	}
+	if !fw.IsStopped() {
+		t.Errorf("expected the watch to be stopped when the iteration ended")
+	}
+}
@@ -0,0 +1,353 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certificate
+
+import (
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sync"
+	"time"
+
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/util/cert"
+	"k8s.io/client-go/util/connrotation"
+	"k8s.io/client-go/util/keyutil"
+	"k8s.io/klog"
+)
+
+// spiffeScheme is the URI scheme of SPIFFE IDs.
+const spiffeScheme = "spiffe"
+
+// X509SVID is an X.509 SPIFFE Verifiable Identity Document: a certificate
+// chain whose leaf carries a SPIFFE ID as its only URI SAN, together with its
+// private key.
+type X509SVID struct {
+	// ID is the SPIFFE ID of the SVID, for example
+	// spiffe://example.org/ns/kube-system/sa/controller.
+	ID string
+	// Certificates is the certificate chain, leaf first.
+	Certificates []*x509.Certificate
+	// PrivateKey is the private key of the leaf certificate.
+	PrivateKey crypto.PrivateKey
+	// Bundle holds the trust bundle of the SVID's trust domain, if known.
+	Bundle []*x509.Certificate
+}
+
+// SVIDSource provides the current X.509 SVID of the workload.  A SPIFFE
+// Workload API client can be adapted to this interface; NewSVIDFileSource
+// reads SVIDs written to disk, for example by the SPIRE agent helper.
+type SVIDSource interface {
+	// FetchX509SVID returns the current SVID.  It is called periodically and
+	// must return a new SVID once the previous one was rotated.
+	FetchX509SVID() (*X509SVID, error)
+}
+
+type svidFileSource struct {
+	certFile   string
+	keyFile    string
+	bundleFile string
+}
+
+// NewSVIDFileSource returns an SVIDSource reading a PEM encoded certificate
+// chain from certFile and its private key from keyFile.  bundleFile, if not
+// empty, holds the PEM encoded trust bundle.  The files are read on every
+// fetch, so rewriting them rotates the SVID.
+func NewSVIDFileSource(certFile, keyFile, bundleFile string) SVIDSource {
+	return &svidFileSource{certFile: certFile, keyFile: keyFile, bundleFile: bundleFile}
+}
+
+func (s *svidFileSource) FetchX509SVID() (*X509SVID, error) {
+	certPEM, err := ioutil.ReadFile(s.certFile)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := ioutil.ReadFile(s.keyFile)
+	if err != nil {
+		return nil, err
+	}
+	certs, err := cert.ParseCertsPEM(certPEM)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse SVID certificates in %q: %v", s.certFile, err)
+	}
+	key, err := keyutil.ParsePrivateKeyPEM(keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse SVID key in %q: %v", s.keyFile, err)
+	}
+	id, err := SPIFFEIDFromCertificate(certs[0])
+	if err != nil {
+		return nil, err
+	}
+	svid := &X509SVID{ID: id, Certificates: certs, PrivateKey: key}
+	if len(s.bundleFile) > 0 {
+		bundlePEM, err := ioutil.ReadFile(s.bundleFile)
+		if err != nil {
+			return nil, err
+		}
+		if svid.Bundle, err = cert.ParseCertsPEM(bundlePEM); err != nil {
+			return nil, fmt.Errorf("unable to parse SVID bundle in %q: %v", s.bundleFile, err)
+		}
+	}
+	return svid, nil
+}
+
+// SPIFFEIDFromCertificate returns the SPIFFE ID of an X.509 SVID, which must
+// be its only URI SAN.
+func SPIFFEIDFromCertificate(certificate *x509.Certificate) (string, error) {
+	if len(certificate.URIs) != 1 {
+		return "", fmt.Errorf("an X.509 SVID must have exactly one URI SAN, found %d", len(certificate.URIs))
+	}
+	id := certificate.URIs[0]
+	if id.Scheme != spiffeScheme || len(id.Host) == 0 {
+		return "", fmt.Errorf("%q is not a valid SPIFFE ID", id.String())
+	}
+	return id.String(), nil
+}
+
+// SVIDManager is a Manager whose certificates are X.509 SVIDs obtained from
+// an SVIDSource rather than requested through the certificates API.
+type SVIDManager interface {
+	Manager
+	// SVID returns the current SVID, or nil if none was fetched yet.
+	SVID() *X509SVID
+	// AddRotationHandler registers a function called after the SVID has
+	// changed.
+	AddRotationHandler(handler func(svid *X509SVID))
+}
+
+// SVIDManagerConfig is the set of configuration parameters available for a
+// new SVIDManager.
+type SVIDManagerConfig struct {
+	// Source provides the SVIDs.  It must be set.
+	Source SVIDSource
+	// TrustDomain, if set, is the trust domain the SVIDs must belong to,
+	// for example example.org.
+	TrustDomain string
+	// RefreshPeriod is how often the source is polled for a new SVID.
+	// Defaults to 30 seconds.
+	RefreshPeriod time.Duration
+	// CertificateExpiration will record a metric that shows the remaining
+	// lifetime of the SVID.
+	CertificateExpiration Gauge
+}
+
+type svidManager struct {
+	source                SVIDSource
+	trustDomain           string
+	refreshPeriod         time.Duration
+	certificateExpiration Gauge
+
+	lock         sync.RWMutex
+	svid         *X509SVID
+	cert         *tls.Certificate
+	serverHealth bool
+	handlers     []func(svid *X509SVID)
+
+	stopLock sync.Mutex
+	stopCh   chan struct{}
+	stopped  bool
+}
+
+// NewSVIDManager returns an SVIDManager that fetches an SVID from its source
+// right away and, once started, whenever the refresh period elapses.  A
+// failing first fetch is not fatal; Current returns nil until one succeeds.
+func NewSVIDManager(config *SVIDManagerConfig) (SVIDManager, error) {
+	if config.Source == nil {
+		return nil, fmt.Errorf("an SVID source is required")
+	}
+	m := &svidManager{
+		source:                config.Source,
+		trustDomain:           config.TrustDomain,
+		refreshPeriod:         config.RefreshPeriod,
+		certificateExpiration: config.CertificateExpiration,
+		stopCh:                make(chan struct{}),
+	}
+	if m.refreshPeriod <= 0 {
+		m.refreshPeriod = 30 * time.Second
+	}
+	if err := m.refresh(); err != nil {
+		utilruntime.HandleError(err)
+	}
+	return m, nil
+}
+
+// Current returns the certificate of the current SVID, or nil if no SVID was
+// fetched yet or it has expired.
+func (m *svidManager) Current() *tls.Certificate {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	if m.cert != nil && m.cert.Leaf != nil && time.Now().After(m.cert.Leaf.NotAfter) {
+		klog.V(2).Infof("Current SVID is expired.")
+		return nil
+	}
+	return m.cert
+}
+
+// ServerHealthy returns true if the last fetch from the source succeeded.
+func (m *svidManager) ServerHealthy() bool {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.serverHealth
+}
+
+func (m *svidManager) SVID() *X509SVID {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.svid
+}
+
+func (m *svidManager) AddRotationHandler(handler func(svid *X509SVID)) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.handlers = append(m.handlers, handler)
+}
+
+// Start begins polling the source for rotated SVIDs.
+func (m *svidManager) Start() {
+	klog.V(2).Infof("SVID rotation is enabled.")
+	go wait.Until(func() {
+		if err := m.refresh(); err != nil {
+			utilruntime.HandleError(err)
+		}
+	}, m.refreshPeriod, m.stopCh)
+}
+
+// Stop terminates the manager.
+func (m *svidManager) Stop() {
+	m.stopLock.Lock()
+	defer m.stopLock.Unlock()
+	if m.stopped {
+		return
+	}
+	close(m.stopCh)
+	m.stopped = true
+}
+
+func (m *svidManager) refresh() error {
+	svid, err := m.fetch()
+	m.lock.Lock()
+	m.serverHealth = err == nil
+	if err != nil {
+		m.lock.Unlock()
+		return err
+	}
+	if m.cert != nil && reflect.DeepEqual(m.cert.Certificate, svid.rawCertificates()) {
+		m.svid = svid
+		m.lock.Unlock()
+		return nil
+	}
+	m.svid = svid
+	m.cert = &tls.Certificate{
+		Certificate: svid.rawCertificates(),
+		PrivateKey:  svid.PrivateKey,
+		Leaf:        svid.Certificates[0],
+	}
+	handlers := m.handlers
+	m.lock.Unlock()
+
+	klog.V(2).Infof("Rotated SVID %s, expiring at %v", svid.ID, svid.Certificates[0].NotAfter)
+	if m.certificateExpiration != nil {
+		m.certificateExpiration.Set(float64(svid.Certificates[0].NotAfter.Unix()))
+	}
+	for _, handler := range handlers {
+		handler(svid)
+	}
+	return nil
+}
+
+func (m *svidManager) fetch() (*X509SVID, error) {
+	svid, err := m.source.FetchX509SVID()
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch SVID: %v", err)
+	}
+	if len(svid.Certificates) == 0 || svid.PrivateKey == nil {
+		return nil, fmt.Errorf("SVID %q has no certificate or private key", svid.ID)
+	}
+	if len(m.trustDomain) > 0 {
+		id, err := url.Parse(svid.ID)
+		if err != nil || id.Host != m.trustDomain {
+			return nil, fmt.Errorf("SVID %q does not belong to trust domain %q", svid.ID, m.trustDomain)
+		}
+	}
+	return svid, nil
+}
+
+func (s *X509SVID) rawCertificates() [][]byte {
+	raw := make([][]byte, 0, len(s.Certificates))
+	for _, certificate := range s.Certificates {
+		raw = append(raw, certificate.Raw)
+	}
+	return raw
+}
+
+// ConfigureSVIDTransport makes clients built from config authenticate with
+// the SVIDs of m.  The CA and server name of config are kept, any client
+// certificate it names is not allowed.  Connections are closed whenever the
+// SVID rotates, so that new ones present the new certificate.
+func ConfigureSVIDTransport(config *restclient.Config, m SVIDManager) error {
+	if config.Transport != nil {
+		return fmt.Errorf("an SVID transport cannot be configured on top of a custom transport")
+	}
+	if len(config.CertData) > 0 || len(config.CertFile) > 0 || len(config.KeyData) > 0 || len(config.KeyFile) > 0 {
+		return fmt.Errorf("an SVID transport cannot be configured together with a client certificate")
+	}
+	tlsConfig, err := restclient.TLSConfigFor(config)
+	if err != nil {
+		return err
+	}
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+	tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		if current := m.Current(); current != nil {
+			return current, nil
+		}
+		// An empty certificate makes the server fall back to other
+		// authentication methods.
+		return &tls.Certificate{}, nil
+	}
+
+	dial := config.Dial
+	if dial == nil {
+		dial = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	}
+	dialer := connrotation.NewDialer(dial)
+	m.AddRotationHandler(func(svid *X509SVID) {
+		klog.V(2).Infof("Closing connections authenticated with the previous SVID")
+		dialer.CloseAll()
+	})
+
+	config.Transport = utilnet.SetTransportDefaults(&http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		TLSHandshakeTimeout: 10 * time.Second,
+		TLSClientConfig:     tlsConfig,
+		MaxIdleConnsPerHost: 25,
+		DialContext:         dialer.DialContext,
+	})
+	config.TLSClientConfig = restclient.TLSClientConfig{}
+	config.Dial = nil
+	return nil
+}
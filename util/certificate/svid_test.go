@@ -0,0 +1,145 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certificate
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	cryptorand "crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/util/keyutil"
+)
+
+func writeTestSVID(t *testing.T, dir, id string, serial int64) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), cryptorand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uri, err := url.Parse(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		URIs:         []*url.URL{uri},
+	}
+	der, err := x509.CreateCertificate(cryptorand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM, err := keyutil.MarshalPrivateKeyToPEM(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "svid.pem"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "svid_key.pem"), keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSVIDManager(t *testing.T) {
+	dir, err := ioutil.TempDir("", "svid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeTestSVID(t, dir, "spiffe://example.org/controller", 1)
+	source := NewSVIDFileSource(filepath.Join(dir, "svid.pem"), filepath.Join(dir, "svid_key.pem"), "")
+	m, err := NewSVIDManager(&SVIDManagerConfig{Source: source, TrustDomain: "example.org"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.Current() == nil || !m.ServerHealthy() {
+		t.Fatalf("expected an SVID to be fetched on creation")
+	}
+	if id := m.SVID().ID; id != "spiffe://example.org/controller" {
+		t.Errorf("unexpected SPIFFE ID %q", id)
+	}
+
+	rotated := 0
+	m.AddRotationHandler(func(*X509SVID) { rotated++ })
+	sm := m.(*svidManager)
+	if err := sm.refresh(); err != nil || rotated != 0 {
+		t.Errorf("expected an unchanged SVID not to rotate, got %d rotations, %v", rotated, err)
+	}
+	writeTestSVID(t, dir, "spiffe://example.org/controller", 2)
+	if err := sm.refresh(); err != nil || rotated != 1 {
+		t.Errorf("expected a rewritten SVID to rotate, got %d rotations, %v", rotated, err)
+	}
+	if serial := m.Current().Leaf.SerialNumber.Int64(); serial != 2 {
+		t.Errorf("expected the rotated certificate, got serial %d", serial)
+	}
+
+	writeTestSVID(t, dir, "spiffe://other.org/controller", 3)
+	if err := sm.refresh(); err == nil || m.ServerHealthy() {
+		t.Errorf("expected an SVID of another trust domain to be rejected")
+	}
+	if serial := m.Current().Leaf.SerialNumber.Int64(); serial != 2 {
+		t.Errorf("expected the previous certificate to be kept, got serial %d", serial)
+	}
+}
+
+func TestConfigureSVIDTransport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "svid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeTestSVID(t, dir, "spiffe://example.org/controller", 1)
+	m, err := NewSVIDManager(&SVIDManagerConfig{Source: NewSVIDFileSource(filepath.Join(dir, "svid.pem"), filepath.Join(dir, "svid_key.pem"), "")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	config := &restclient.Config{Host: "https://127.0.0.1", TLSClientConfig: restclient.TLSClientConfig{ServerName: "apiserver"}}
+	if err := ConfigureSVIDTransport(config, m); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	transport, ok := config.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("expected an http transport, got %T", config.Transport)
+	}
+	if transport.TLSClientConfig.ServerName != "apiserver" {
+		t.Errorf("expected the server name to be kept, got %q", transport.TLSClientConfig.ServerName)
+	}
+	if current, err := transport.TLSClientConfig.GetClientCertificate(nil); err != nil || current != m.Current() {
+		t.Errorf("expected the SVID to be presented, got %v, %v", current, err)
+	}
+	if _, err := restclient.TransportFor(config); err != nil {
+		t.Errorf("expected the configured transport to be accepted, got %v", err)
+	}
+
+	if err := ConfigureSVIDTransport(&restclient.Config{TLSClientConfig: restclient.TLSClientConfig{CertFile: "client.crt"}}, m); err == nil {
+		t.Errorf("expected a client certificate to conflict with the SVID")
+	}
+}
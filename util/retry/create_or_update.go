@@ -0,0 +1,141 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package retry
+
+import (
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// OperationResult is the action taken on an object by CreateOrUpdate.
+type OperationResult string
+
+const (
+	// OperationResultNone means the object already had the desired state.
+	OperationResultNone OperationResult = "unchanged"
+	// OperationResultCreated means the object was created.
+	OperationResultCreated OperationResult = "created"
+	// OperationResultUpdated means the object was updated.
+	OperationResultUpdated OperationResult = "updated"
+)
+
+// ObjectFuncs adapts a client of a single resource, typed or not, for use by
+// CreateOrUpdate and GetOrCreate; dynamic.UnstructuredFuncs returns those of
+// a dynamic client.  For a typed client:
+//
+//	pods := client.CoreV1().Pods("mynamespace")
+//	funcs := retry.ObjectFuncs{
+//	  Get:    func(name string) (runtime.Object, error) { return pods.Get(name, metav1.GetOptions{}) },
+//	  Create: func(obj runtime.Object) (runtime.Object, error) { return pods.Create(obj.(*v1.Pod)) },
+//	  Update: func(obj runtime.Object) (runtime.Object, error) { return pods.Update(obj.(*v1.Pod)) },
+//	}
+type ObjectFuncs struct {
+	Get    func(name string) (runtime.Object, error)
+	Create func(obj runtime.Object) (runtime.Object, error)
+	Update func(obj runtime.Object) (runtime.Object, error)
+}
+
+// MutateFunc returns the desired state of an object given its current state,
+// which is nil if the object does not exist.  The current state is a copy the
+// function is free to modify and return.  It may be called several times, and
+// must not change the name of the object.
+type MutateFunc func(existing runtime.Object) (runtime.Object, error)
+
+// CreateOrUpdate makes the object called name have the state returned by
+// mutate, creating it if it does not exist.  The update is skipped when it
+// would not change the object.  Conflicts, an object created or deleted by
+// someone else in the meantime, are retried with backoff from a fresh read.
+//
+//	pod, result, err := retry.CreateOrUpdate(retry.DefaultRetry, funcs, "mypod", func(existing runtime.Object) (runtime.Object, error) {
+//	  pod, ok := existing.(*v1.Pod)
+//	  if !ok {
+//	    pod = &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "mypod"}}
+//	  }
+//	  pod.Labels = map[string]string{"app": "myapp"}
+//	  return pod, nil
+//	})
+func CreateOrUpdate(backoff wait.Backoff, funcs ObjectFuncs, name string, mutate MutateFunc) (runtime.Object, OperationResult, error) {
+	var obj runtime.Object
+	result := OperationResultNone
+	var raced bool
+	err := OnError(backoff, func(error) bool { return raced }, func() error {
+		raced = false
+		existing, err := funcs.Get(name)
+		if errors.IsNotFound(err) {
+			desired, err := mutate(nil)
+			if err != nil {
+				return err
+			}
+			obj, err = funcs.Create(desired)
+			// Someone else created the object since we read it.
+			raced = errors.IsAlreadyExists(err)
+			result = OperationResultCreated
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		desired, err := mutate(existing.DeepCopyObject())
+		if err != nil {
+			return err
+		}
+		if equality.Semantic.DeepEqual(existing, desired) {
+			obj, result = existing, OperationResultNone
+			return nil
+		}
+		obj, err = funcs.Update(desired)
+		// Someone else changed or deleted the object since we read it.
+		raced = errors.IsConflict(err) || errors.IsNotFound(err)
+		result = OperationResultUpdated
+		return err
+	})
+	if err != nil {
+		return nil, OperationResultNone, err
+	}
+	return obj, result, nil
+}
+
+// GetOrCreate returns the object called name, creating it from newObj if it
+// does not exist.  It reports whether the object was created.  If someone
+// else creates the object first, the object they created is returned.
+func GetOrCreate(backoff wait.Backoff, funcs ObjectFuncs, name string, newObj func() (runtime.Object, error)) (runtime.Object, bool, error) {
+	var obj runtime.Object
+	var created, raced bool
+	err := OnError(backoff, func(error) bool { return raced }, func() error {
+		raced = false
+		var err error
+		obj, err = funcs.Get(name)
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		desired, err := newObj()
+		if err != nil {
+			return err
+		}
+		obj, err = funcs.Create(desired)
+		// Read the object someone else created since we looked.
+		raced = errors.IsAlreadyExists(err)
+		created = err == nil
+		return err
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return obj, created, nil
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package retry
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func configMapFuncs(client corev1.ConfigMapInterface) ObjectFuncs {
+	return ObjectFuncs{
+		Get: func(name string) (runtime.Object, error) {
+			return client.Get(name, metav1.GetOptions{})
+		},
+		Create: func(obj runtime.Object) (runtime.Object, error) {
+			return client.Create(obj.(*v1.ConfigMap))
+		},
+		Update: func(obj runtime.Object) (runtime.Object, error) {
+			return client.Update(obj.(*v1.ConfigMap))
+		},
+	}
+}
+
+func setData(value string) MutateFunc {
+	return func(existing runtime.Object) (runtime.Object, error) {
+		cm, ok := existing.(*v1.ConfigMap)
+		if !ok {
+			cm = &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "cm"}}
+		}
+		cm.Data = map[string]string{"key": value}
+		return cm, nil
+	}
+}
+
+func TestCreateOrUpdate(t *testing.T) {
+	backoff := wait.Backoff{Factor: 1.0, Steps: 3}
+	client := fake.NewSimpleClientset()
+	funcs := configMapFuncs(client.CoreV1().ConfigMaps("ns"))
+
+	for _, test := range []struct {
+		value    string
+		expected OperationResult
+	}{
+		{"a", OperationResultCreated},
+		{"a", OperationResultNone},
+		{"b", OperationResultUpdated},
+	} {
+		obj, result, err := CreateOrUpdate(backoff, funcs, "cm", setData(test.value))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result != test.expected {
+			t.Errorf("expected %s, got %s", test.expected, result)
+		}
+		if obj.(*v1.ConfigMap).Data["key"] != test.value {
+			t.Errorf("expected %q, got %v", test.value, obj)
+		}
+	}
+
+	// The first update conflicts with a concurrent one, and is retried.
+	conflicts := 1
+	client.PrependReactor("update", "configmaps", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		if conflicts == 0 {
+			return false, nil, nil
+		}
+		conflicts--
+		return true, nil, errors.NewConflict(schema.GroupResource{Resource: "configmaps"}, "cm", nil)
+	})
+	if _, result, err := CreateOrUpdate(backoff, funcs, "cm", setData("c")); err != nil || result != OperationResultUpdated {
+		t.Errorf("expected the update to be retried, got %s, %v", result, err)
+	}
+
+	// Errors other than races are not retried.
+	calls := 0
+	_, _, err := CreateOrUpdate(backoff, funcs, "cm", func(existing runtime.Object) (runtime.Object, error) {
+		calls++
+		return nil, errors.NewBadRequest("invalid")
+	})
+	if !errors.IsBadRequest(err) || calls != 1 {
+		t.Errorf("expected a single failed attempt, got %d, %v", calls, err)
+	}
+}
+
+func TestCreateOrUpdateCreateRace(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	// Someone else creates the object between our get and our create.
+	client.PrependReactor("create", "configmaps", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		client.ReactionChain = client.ReactionChain[1:]
+		client.Tracker().Add(&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "cm"}})
+		return true, nil, errors.NewAlreadyExists(schema.GroupResource{Resource: "configmaps"}, "cm")
+	})
+	obj, result, err := CreateOrUpdate(DefaultRetry, configMapFuncs(client.CoreV1().ConfigMaps("ns")), "cm", setData("a"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != OperationResultUpdated || obj.(*v1.ConfigMap).Data["key"] != "a" {
+		t.Errorf("expected the object created by someone else to be updated, got %s, %v", result, obj)
+	}
+}
+
+func TestGetOrCreate(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	funcs := configMapFuncs(client.CoreV1().ConfigMaps("ns"))
+	newObj := func(value string) func() (runtime.Object, error) {
+		return func() (runtime.Object, error) { return setData(value)(nil) }
+	}
+
+	obj, created, err := GetOrCreate(DefaultRetry, funcs, "cm", newObj("a"))
+	if err != nil || !created || obj.(*v1.ConfigMap).Data["key"] != "a" {
+		t.Errorf("expected the object to be created, got %v, %v, %v", obj, created, err)
+	}
+	obj, created, err = GetOrCreate(DefaultRetry, funcs, "cm", newObj("b"))
+	if err != nil || created || obj.(*v1.ConfigMap).Data["key"] != "a" {
+		t.Errorf("expected the existing object, got %v, %v, %v", obj, created, err)
+	}
+
+	// Someone else creates the object between our get and our create.
+	client = fake.NewSimpleClientset()
+	client.PrependReactor("create", "configmaps", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		client.ReactionChain = client.ReactionChain[1:]
+		client.Tracker().Add(&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "cm"}, Data: map[string]string{"key": "theirs"}})
+		return true, nil, errors.NewAlreadyExists(schema.GroupResource{Resource: "configmaps"}, "cm")
+	})
+	obj, created, err = GetOrCreate(DefaultRetry, configMapFuncs(client.CoreV1().ConfigMaps("ns")), "cm", newObj("b"))
+	if err != nil || created || obj.(*v1.ConfigMap).Data["key"] != "theirs" {
+		t.Errorf("expected the object created by someone else, got %v, %v, %v", obj, created, err)
+	}
+}
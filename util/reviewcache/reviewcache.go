@@ -0,0 +1,226 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package reviewcache implements a client for TokenReviews and
+// SubjectAccessReviews that caches their results, for webhook servers that
+// authenticate and authorize the same callers over and over.
+package reviewcache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	utilcache "k8s.io/apimachinery/pkg/util/cache"
+	authenticationclient "k8s.io/client-go/kubernetes/typed/authentication/v1"
+	authorizationclient "k8s.io/client-go/kubernetes/typed/authorization/v1"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	defaultCacheSize     = 4096
+	defaultPositiveTTL   = 2 * time.Minute
+	defaultNegativeTTL   = 10 * time.Second
+	defaultBatchParallel = 4
+)
+
+// Config is the set of configuration parameters available for a new Client.
+// Zero TTLs select the defaults of two minutes for positive and ten seconds
+// for negative results; a negative TTL disables caching of those results.
+type Config struct {
+	// TokenReviews is used to review tokens.  It may be nil if tokens are
+	// not reviewed.
+	TokenReviews authenticationclient.TokenReviewInterface
+	// SubjectAccessReviews is used to review access.  It may be nil if
+	// access is not reviewed.
+	SubjectAccessReviews authorizationclient.SubjectAccessReviewInterface
+
+	// AuthenticatedTTL is how long a successful token review is cached.
+	AuthenticatedTTL time.Duration
+	// UnauthenticatedTTL is how long a rejected token is cached.
+	UnauthenticatedTTL time.Duration
+	// AllowedTTL is how long an allowed access review is cached.
+	AllowedTTL time.Duration
+	// DeniedTTL is how long a denied access review is cached.
+	DeniedTTL time.Duration
+
+	// CacheSize is the maximum number of results kept per review kind.
+	// Defaults to 4096.
+	CacheSize int
+	// BatchParallelism is the number of reviews a batch call sends at
+	// once.  Defaults to 4.
+	BatchParallelism int
+
+	// Clock is used to expire cached results.  Defaults to the real clock.
+	Clock utilcache.Clock
+}
+
+// Client reviews tokens and access, caching the results.  Errors are never
+// cached.  It is safe for concurrent use.
+type Client interface {
+	// ReviewToken returns the status of a TokenReview of token for the
+	// given audiences.
+	ReviewToken(token string, audiences []string) (*authenticationv1.TokenReviewStatus, error)
+	// ReviewTokens reviews several tokens for the same audiences at once.
+	// The statuses and errors are indexed like tokens.
+	ReviewTokens(tokens []string, audiences []string) ([]*authenticationv1.TokenReviewStatus, []error)
+	// ReviewAccess returns the status of a SubjectAccessReview of spec.
+	ReviewAccess(spec authorizationv1.SubjectAccessReviewSpec) (*authorizationv1.SubjectAccessReviewStatus, error)
+	// ReviewAccesses reviews several specs at once.  The statuses and
+	// errors are indexed like specs.
+	ReviewAccesses(specs []authorizationv1.SubjectAccessReviewSpec) ([]*authorizationv1.SubjectAccessReviewStatus, []error)
+}
+
+type client struct {
+	tokenReviews         authenticationclient.TokenReviewInterface
+	subjectAccessReviews authorizationclient.SubjectAccessReviewInterface
+
+	authenticatedTTL   time.Duration
+	unauthenticatedTTL time.Duration
+	allowedTTL         time.Duration
+	deniedTTL          time.Duration
+	batchParallelism   int
+
+	tokens   *utilcache.LRUExpireCache
+	accesses *utilcache.LRUExpireCache
+}
+
+// New returns a Client configured by config.
+func New(config Config) Client {
+	c := &client{
+		tokenReviews:         config.TokenReviews,
+		subjectAccessReviews: config.SubjectAccessReviews,
+		authenticatedTTL:     ttlOrDefault(config.AuthenticatedTTL, defaultPositiveTTL),
+		unauthenticatedTTL:   ttlOrDefault(config.UnauthenticatedTTL, defaultNegativeTTL),
+		allowedTTL:           ttlOrDefault(config.AllowedTTL, defaultPositiveTTL),
+		deniedTTL:            ttlOrDefault(config.DeniedTTL, defaultNegativeTTL),
+		batchParallelism:     config.BatchParallelism,
+	}
+	if c.batchParallelism <= 0 {
+		c.batchParallelism = defaultBatchParallel
+	}
+	size := config.CacheSize
+	if size <= 0 {
+		size = defaultCacheSize
+	}
+	if config.Clock != nil {
+		c.tokens = utilcache.NewLRUExpireCacheWithClock(size, config.Clock)
+		c.accesses = utilcache.NewLRUExpireCacheWithClock(size, config.Clock)
+	} else {
+		c.tokens = utilcache.NewLRUExpireCache(size)
+		c.accesses = utilcache.NewLRUExpireCache(size)
+	}
+	return c
+}
+
+func ttlOrDefault(ttl, defaultTTL time.Duration) time.Duration {
+	if ttl == 0 {
+		return defaultTTL
+	}
+	return ttl
+}
+
+// hashKey hashes the parts of a cache key, so that tokens are never kept in
+// memory longer than needed.
+func hashKey(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		fmt.Fprintf(h, "%d:%s", len(part), part)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *client) ReviewToken(token string, audiences []string) (*authenticationv1.TokenReviewStatus, error) {
+	if c.tokenReviews == nil {
+		return nil, fmt.Errorf("no TokenReview client is configured")
+	}
+	key := hashKey(token, strings.Join(audiences, ","))
+	if cached, ok := c.tokens.Get(key); ok {
+		return cached.(*authenticationv1.TokenReviewStatus).DeepCopy(), nil
+	}
+
+	result, err := c.tokenReviews.Create(&authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token, Audiences: audiences},
+	})
+	if err != nil {
+		return nil, err
+	}
+	status := result.Status.DeepCopy()
+	ttl := c.authenticatedTTL
+	if !status.Authenticated {
+		ttl = c.unauthenticatedTTL
+	}
+	if ttl > 0 && len(status.Error) == 0 {
+		c.tokens.Add(key, status.DeepCopy(), ttl)
+	}
+	return status, nil
+}
+
+func (c *client) ReviewTokens(tokens []string, audiences []string) ([]*authenticationv1.TokenReviewStatus, []error) {
+	statuses := make([]*authenticationv1.TokenReviewStatus, len(tokens))
+	errs := make([]error, len(tokens))
+	c.batch(len(tokens), func(i int) {
+		statuses[i], errs[i] = c.ReviewToken(tokens[i], audiences)
+	})
+	return statuses, errs
+}
+
+func (c *client) ReviewAccess(spec authorizationv1.SubjectAccessReviewSpec) (*authorizationv1.SubjectAccessReviewStatus, error) {
+	if c.subjectAccessReviews == nil {
+		return nil, fmt.Errorf("no SubjectAccessReview client is configured")
+	}
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	key := hashKey(string(data))
+	if cached, ok := c.accesses.Get(key); ok {
+		return cached.(*authorizationv1.SubjectAccessReviewStatus).DeepCopy(), nil
+	}
+
+	result, err := c.subjectAccessReviews.Create(&authorizationv1.SubjectAccessReview{Spec: spec})
+	if err != nil {
+		return nil, err
+	}
+	status := result.Status.DeepCopy()
+	ttl := c.allowedTTL
+	if !status.Allowed {
+		ttl = c.deniedTTL
+	}
+	if ttl > 0 && len(status.EvaluationError) == 0 {
+		c.accesses.Add(key, status.DeepCopy(), ttl)
+	}
+	return status, nil
+}
+
+func (c *client) ReviewAccesses(specs []authorizationv1.SubjectAccessReviewSpec) ([]*authorizationv1.SubjectAccessReviewStatus, []error) {
+	statuses := make([]*authorizationv1.SubjectAccessReviewStatus, len(specs))
+	errs := make([]error, len(specs))
+	c.batch(len(specs), func(i int) {
+		statuses[i], errs[i] = c.ReviewAccess(specs[i])
+	})
+	return statuses, errs
+}
+
+func (c *client) batch(pieces int, review func(i int)) {
+	workqueue.ParallelizeUntil(context.Background(), c.batchParallelism, pieces, review)
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reviewcache
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestReviewToken(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	var calls int32
+	client.PrependReactor("create", "tokenreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		atomic.AddInt32(&calls, 1)
+		review := action.(clienttesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		switch review.Spec.Token {
+		case "good":
+			review.Status = authenticationv1.TokenReviewStatus{Authenticated: true, User: authenticationv1.UserInfo{Username: "alice"}}
+		case "broken":
+			return true, &authenticationv1.TokenReview{}, errors.New("apiserver unavailable")
+		}
+		return true, review, nil
+	})
+
+	fakeClock := clock.NewFakeClock(time.Now())
+	c := New(Config{
+		TokenReviews:       client.AuthenticationV1().TokenReviews(),
+		AuthenticatedTTL:   time.Minute,
+		UnauthenticatedTTL: time.Second,
+		Clock:              fakeClock,
+	})
+
+	statuses, errs := c.ReviewTokens([]string{"good", "bad", "broken", "good"}, nil)
+	if !statuses[0].Authenticated || statuses[0].User.Username != "alice" || errs[0] != nil {
+		t.Errorf("expected the good token to be authenticated, got %v, %v", statuses[0], errs[0])
+	}
+	if statuses[1].Authenticated || errs[1] != nil {
+		t.Errorf("expected the bad token to be rejected, got %v, %v", statuses[1], errs[1])
+	}
+	if errs[2] == nil {
+		t.Errorf("expected the review error to be returned")
+	}
+
+	calls = 0
+	c.ReviewToken("good", nil)
+	c.ReviewToken("bad", nil)
+	if calls != 0 {
+		t.Errorf("expected cached results, got %d calls", calls)
+	}
+	c.ReviewToken("broken", nil)
+	if calls != 1 {
+		t.Errorf("expected errors not to be cached, got %d calls", calls)
+	}
+	c.ReviewToken("good", []string{"other"})
+	if calls != 2 {
+		t.Errorf("expected audiences to be part of the cache key, got %d calls", calls)
+	}
+
+	fakeClock.Step(2 * time.Second)
+	calls = 0
+	c.ReviewToken("good", nil)
+	c.ReviewToken("bad", nil)
+	if calls != 1 {
+		t.Errorf("expected only the negative result to expire, got %d calls", calls)
+	}
+}
+
+func TestReviewAccess(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	var calls int32
+	client.PrependReactor("create", "subjectaccessreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		atomic.AddInt32(&calls, 1)
+		review := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		review.Status.Allowed = review.Spec.User == "alice"
+		return true, review, nil
+	})
+	c := New(Config{SubjectAccessReviews: client.AuthorizationV1().SubjectAccessReviews(), DeniedTTL: -1})
+
+	get := authorizationv1.ResourceAttributes{Verb: "get", Resource: "pods"}
+	specs := []authorizationv1.SubjectAccessReviewSpec{
+		{User: "alice", ResourceAttributes: &get},
+		{User: "bob", ResourceAttributes: &get},
+	}
+	statuses, errs := c.ReviewAccesses(specs)
+	if errs[0] != nil || errs[1] != nil || !statuses[0].Allowed || statuses[1].Allowed {
+		t.Errorf("unexpected results %v, %v", statuses, errs)
+	}
+
+	calls = 0
+	c.ReviewAccesses(specs)
+	if calls != 1 {
+		t.Errorf("expected only the uncached denial to be reviewed again, got %d calls", calls)
+	}
+
+	if _, err := New(Config{}).ReviewAccess(specs[0]); err == nil {
+		t.Errorf("expected an error without a SubjectAccessReview client")
+	}
+}